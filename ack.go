@@ -0,0 +1,91 @@
+package natty
+
+import (
+	"context"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/pkg/errors"
+)
+
+// NakWithDelay negatively acknowledges a consumed JetStream message and asks
+// the server to wait for delay before redelivering it; useful for avoiding a
+// hot retry loop when a handler encounters a transient error (ie. a downstream
+// service is unavailable). A delay of 0 requests immediate redelivery.
+func NakWithDelay(msg *nats.Msg, delay time.Duration) error {
+	if msg == nil {
+		return errors.New("msg cannot be nil")
+	}
+
+	if delay < 0 {
+		return errors.New("delay cannot be negative")
+	}
+
+	if err := msg.NakWithDelay(delay); err != nil {
+		return errors.Wrap(err, "unable to nak message")
+	}
+
+	return nil
+}
+
+// InProgress tells the server that msg is still being processed, resetting
+// its ack wait timer so that it is not redelivered. Errors are annotated with
+// the stream, consumer and stream sequence of msg.
+func (n *Natty) InProgress(msg *nats.Msg) error {
+	if msg == nil {
+		return errors.New("msg cannot be nil")
+	}
+
+	if err := msg.InProgress(); err != nil {
+		meta, metaErr := msg.Metadata()
+		if metaErr != nil {
+			return errors.Wrap(err, "unable to mark message as in progress")
+		}
+
+		return errors.Wrapf(err, "unable to mark message as in progress (stream: '%s', consumer: '%s', seq: %d)",
+			meta.Stream, meta.Consumer, meta.Sequence.Stream)
+	}
+
+	return nil
+}
+
+// AutoInProgress calls InProgress() for msg every interval (in a goroutine)
+// until ctx is done or the returned stop func is called; stop should be called
+// once msg has been ACK'd or NAK'd. interval should be shorter than the
+// consumer's ack wait; if it is not greater than 0, an error is logged, no
+// signals are sent and a no-op stop func is returned.
+func (n *Natty) AutoInProgress(ctx context.Context, msg *nats.Msg, interval time.Duration) func() {
+	if interval <= 0 {
+		errorw(n.log, "unable to start sending in progress signals: interval must be greater than 0",
+			"interval", interval)
+		return func() {}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := n.InProgress(msg); err != nil {
+					errorw(n.log, "unable to send in progress signal", "subject", msg.Subject, "error", err)
+				}
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+
+		// Ensure no signal is sent after stop returns (ie. after an ACK)
+		<-done
+	}
+}
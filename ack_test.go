@@ -0,0 +1,137 @@
+package natty
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Ack helpers", func() {
+	var (
+		n            *Natty
+		streamName   string
+		consumerName string
+	)
+
+	BeforeEach(func() {
+		var err error
+
+		n, err = New(NewConfig())
+		Expect(err).ToNot(HaveOccurred())
+
+		streamName = strings.ToUpper(GetRandomName("test", 1))
+		consumerName = GetRandomName("test", 1)
+		testStreams = append(testStreams, streamName)
+
+		Expect(n.CreateStream(context.Background(), streamName, []string{streamName + ".*"})).To(Succeed())
+		Expect(n.CreateConsumer(context.Background(), streamName, consumerName)).To(Succeed())
+	})
+
+	Describe("NakWithDelay", func() {
+		It("should not redeliver the message before the delay expires", func() {
+			_, err := n.js.Publish(streamName+".foo", []byte("bar"))
+			Expect(err).ToNot(HaveOccurred())
+
+			opts := FetchOptions{MaxMessages: 1, NoWait: true}
+
+			msgs, err := n.FetchWithOptions(context.Background(), streamName, consumerName, opts)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(msgs).To(HaveLen(1))
+
+			nakedAt := time.Now()
+
+			Expect(NakWithDelay(msgs[0], time.Second)).To(Succeed())
+
+			// Not redelivered before the delay expires
+			msgs, err = n.FetchWithOptions(context.Background(), streamName, consumerName,
+				FetchOptions{MaxMessages: 1, ExpiresIn: 200 * time.Millisecond})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(msgs).To(BeEmpty())
+
+			// Redelivered once the delay expires
+			msgs, err = n.FetchWithOptions(context.Background(), streamName, consumerName,
+				FetchOptions{MaxMessages: 1, ExpiresIn: 3 * time.Second})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(msgs).To(HaveLen(1))
+			Expect(time.Since(nakedAt)).To(BeNumerically(">=", 900*time.Millisecond))
+
+			meta, err := msgs[0].Metadata()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(meta.NumDelivered).To(Equal(uint64(2)))
+			Expect(string(msgs[0].Data)).To(Equal("bar"))
+
+			Expect(msgs[0].Ack()).To(Succeed())
+		})
+
+		It("should error on a nil message or negative delay", func() {
+			Expect(NakWithDelay(nil, time.Second)).ToNot(Succeed())
+			Expect(NakWithDelay(nats.NewMsg("foo"), -time.Second)).ToNot(Succeed())
+		})
+
+		It("should error on a message that was not consumed from JetStream", func() {
+			Expect(NakWithDelay(nats.NewMsg("foo"), time.Second)).ToNot(Succeed())
+		})
+	})
+
+	Describe("AutoInProgress", func() {
+		It("should prevent redelivery while the message is being processed", func() {
+			consumer := GetRandomName("test", 1)
+
+			_, err := n.js.AddConsumer(streamName, &nats.ConsumerConfig{
+				Durable:   consumer,
+				AckPolicy: nats.AckExplicitPolicy,
+				AckWait:   time.Second,
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = n.js.Publish(streamName+".foo", []byte("bar"))
+			Expect(err).ToNot(HaveOccurred())
+
+			opts := FetchOptions{MaxMessages: 1, NoWait: true}
+
+			msgs, err := n.FetchWithOptions(context.Background(), streamName, consumer, opts)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(msgs).To(HaveLen(1))
+
+			stop := n.AutoInProgress(context.Background(), msgs[0], 250*time.Millisecond)
+
+			// Wait well beyond the ack wait
+			time.Sleep(2500 * time.Millisecond)
+
+			redelivered, err := n.FetchWithOptions(context.Background(), streamName, consumer, opts)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(redelivered).To(BeEmpty())
+
+			stop()
+
+			Expect(msgs[0].Ack()).To(Succeed())
+		})
+
+		It("should error when marking a non-JetStream message as in progress", func() {
+			Expect(n.InProgress(nats.NewMsg("foo"))).ToNot(Succeed())
+			Expect(n.InProgress(nil)).ToNot(Succeed())
+		})
+	})
+
+})
+
+var _ = Describe("AutoInProgress", func() {
+	It("should return a no-op stop func for a non-positive interval", func() {
+		n := &Natty{log: &NoOpLogger{}}
+
+		for _, interval := range []time.Duration{0, -time.Second} {
+			var stop func()
+
+			Expect(func() {
+				stop = n.AutoInProgress(context.Background(), nats.NewMsg("foo"), interval)
+			}).ToNot(Panic())
+
+			Expect(stop).ToNot(BeNil())
+			stop()
+		}
+	})
+})
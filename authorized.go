@@ -0,0 +1,502 @@
+package natty
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/pkg/errors"
+)
+
+// ACL operations; read covers methods that only fetch data (ie. Get, Keys,
+// Watch), write covers methods that modify data (ie. Put, Delete, Lock).
+const (
+	ACLOpRead  = "read"
+	ACLOpWrite = "write"
+
+	// ACLAnyPrincipal may be used in BucketACL.AllowedPrincipals to allow
+	// every principal (including requests without a principal)
+	ACLAnyPrincipal = "*"
+)
+
+var (
+	ErrPermissionDenied = errors.New("permission denied")
+)
+
+// BucketACL grants AllowedPrincipals the AllowedOps (ACLOpRead and/or
+// ACLOpWrite) on Bucket.
+type BucketACL struct {
+	Bucket            string
+	AllowedOps        []string
+	AllowedPrincipals []string
+}
+
+// AuthorizedOption configures an AuthorizedNatty
+type AuthorizedOption func(*AuthorizedNatty)
+
+// AuthorizedNatty wraps an INatty and enforces per-bucket ACLs on all methods
+// that operate on a named KV or object store bucket; the principal making the
+// call is read from the context (see WithPrincipal). Calls are rejected with
+// ErrPermissionDenied if no ACL grants the principal the required operation
+// on the bucket - buckets without an ACL are not accessible.
+//
+// NOTE: Methods that do not operate on a named bucket (ie. Publish, Consume,
+// TemporaryBucket) are passed through unchecked.
+type AuthorizedNatty struct {
+	INatty
+
+	acls map[string][]BucketACL
+}
+
+type principalCtxKey struct{}
+
+// NewAuthorizedNatty wraps given INatty
+func NewAuthorizedNatty(n INatty, opts ...AuthorizedOption) *AuthorizedNatty {
+	a := &AuthorizedNatty{
+		INatty: n,
+		acls:   make(map[string][]BucketACL),
+	}
+
+	for _, opt := range opts {
+		opt(a)
+	}
+
+	return a
+}
+
+// WithBucketACL adds ACLs to an AuthorizedNatty; multiple ACLs for the same
+// bucket are combined.
+func WithBucketACL(acls []BucketACL) AuthorizedOption {
+	return func(a *AuthorizedNatty) {
+		for _, acl := range acls {
+			a.acls[acl.Bucket] = append(a.acls[acl.Bucket], acl)
+		}
+	}
+}
+
+// WithPrincipal returns a copy of ctx carrying the principal (ie. a user or
+// service name) that AuthorizedNatty checks ACLs against.
+func WithPrincipal(ctx context.Context, principal string) context.Context {
+	return context.WithValue(ctx, principalCtxKey{}, principal)
+}
+
+// PrincipalFromContext returns the principal set via WithPrincipal (if any)
+func PrincipalFromContext(ctx context.Context) (string, bool) {
+	if ctx == nil {
+		return "", false
+	}
+
+	principal, ok := ctx.Value(principalCtxKey{}).(string)
+
+	return principal, ok
+}
+
+// authorize returns ErrPermissionDenied (wrapped) if the principal in ctx may
+// not perform op on bucket
+func (a *AuthorizedNatty) authorize(ctx context.Context, bucket, op string) error {
+	principal, _ := PrincipalFromContext(ctx)
+
+	for _, acl := range a.acls[bucket] {
+		if contains(acl.AllowedOps, op) &&
+			(contains(acl.AllowedPrincipals, principal) || contains(acl.AllowedPrincipals, ACLAnyPrincipal)) {
+			return nil
+		}
+	}
+
+	return errors.Wrapf(ErrPermissionDenied, "principal '%s' may not %s bucket '%s'", principal, op, bucket)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (a *AuthorizedNatty) Get(ctx context.Context, bucket string, key string) ([]byte, error) {
+	if err := a.authorize(ctx, bucket, ACLOpRead); err != nil {
+		return nil, err
+	}
+
+	return a.INatty.Get(ctx, bucket, key)
+}
+
+func (a *AuthorizedNatty) Create(ctx context.Context, bucket string, key string, data []byte, keyTTL ...time.Duration) error {
+	if err := a.authorize(ctx, bucket, ACLOpWrite); err != nil {
+		return err
+	}
+
+	return a.INatty.Create(ctx, bucket, key, data, keyTTL...)
+}
+
+func (a *AuthorizedNatty) Put(ctx context.Context, bucket string, key string, data []byte, ttl ...time.Duration) error {
+	if err := a.authorize(ctx, bucket, ACLOpWrite); err != nil {
+		return err
+	}
+
+	return a.INatty.Put(ctx, bucket, key, data, ttl...)
+}
+
+func (a *AuthorizedNatty) GetEntry(ctx context.Context, bucket string, key string) (*KVEntry, error) {
+	if err := a.authorize(ctx, bucket, ACLOpRead); err != nil {
+		return nil, err
+	}
+
+	return a.INatty.GetEntry(ctx, bucket, key)
+}
+
+func (a *AuthorizedNatty) GetLatestEntry(ctx context.Context, bucket string, key string) (*KVEntry, error) {
+	if err := a.authorize(ctx, bucket, ACLOpRead); err != nil {
+		return nil, err
+	}
+
+	return a.INatty.GetLatestEntry(ctx, bucket, key)
+}
+
+func (a *AuthorizedNatty) GetIfNewer(ctx context.Context, bucket, key string, sinceRevision uint64) ([]byte, uint64, error) {
+	if err := a.authorize(ctx, bucket, ACLOpRead); err != nil {
+		return nil, 0, err
+	}
+
+	return a.INatty.GetIfNewer(ctx, bucket, key, sinceRevision)
+}
+
+func (a *AuthorizedNatty) GetIfModifiedSince(ctx context.Context, bucket, key string, since time.Time) ([]byte, bool, error) {
+	if err := a.authorize(ctx, bucket, ACLOpRead); err != nil {
+		return nil, false, err
+	}
+
+	return a.INatty.GetIfModifiedSince(ctx, bucket, key, since)
+}
+
+func (a *AuthorizedNatty) CompareRevisions(ctx context.Context, bucket, key1, key2 string) (same bool, rev1, rev2 uint64, err error) {
+	if err := a.authorize(ctx, bucket, ACLOpRead); err != nil {
+		return false, 0, 0, err
+	}
+
+	return a.INatty.CompareRevisions(ctx, bucket, key1, key2)
+}
+
+func (a *AuthorizedNatty) GetJSON(ctx context.Context, bucket, key string, out interface{}) error {
+	if err := a.authorize(ctx, bucket, ACLOpRead); err != nil {
+		return err
+	}
+
+	return a.INatty.GetJSON(ctx, bucket, key, out)
+}
+
+func (a *AuthorizedNatty) PutJSON(ctx context.Context, bucket, key string, v interface{}, ttl ...time.Duration) error {
+	if err := a.authorize(ctx, bucket, ACLOpWrite); err != nil {
+		return err
+	}
+
+	return a.INatty.PutJSON(ctx, bucket, key, v, ttl...)
+}
+
+func (a *AuthorizedNatty) Delete(ctx context.Context, bucket string, key string) error {
+	if err := a.authorize(ctx, bucket, ACLOpWrite); err != nil {
+		return err
+	}
+
+	return a.INatty.Delete(ctx, bucket, key)
+}
+
+func (a *AuthorizedNatty) CreateBucket(ctx context.Context, bucket string, ttl time.Duration, description ...string) error {
+	if err := a.authorize(ctx, bucket, ACLOpWrite); err != nil {
+		return err
+	}
+
+	return a.INatty.CreateBucket(ctx, bucket, ttl, description...)
+}
+
+func (a *AuthorizedNatty) CreateBucketWithConfig(ctx context.Context, cfg *nats.KeyValueConfig) (nats.KeyValue, error) {
+	if cfg == nil {
+		return nil, nats.ErrKeyValueConfigRequired
+	}
+
+	if err := a.authorize(ctx, cfg.Bucket, ACLOpWrite); err != nil {
+		return nil, err
+	}
+
+	return a.INatty.CreateBucketWithConfig(ctx, cfg)
+}
+
+func (a *AuthorizedNatty) DeleteBucket(ctx context.Context, bucket string) error {
+	if err := a.authorize(ctx, bucket, ACLOpWrite); err != nil {
+		return err
+	}
+
+	return a.INatty.DeleteBucket(ctx, bucket)
+}
+
+func (a *AuthorizedNatty) Increment(ctx context.Context, bucket, key string, delta int64) (int64, error) {
+	if err := a.authorize(ctx, bucket, ACLOpWrite); err != nil {
+		return 0, err
+	}
+
+	return a.INatty.Increment(ctx, bucket, key, delta)
+}
+
+func (a *AuthorizedNatty) Decrement(ctx context.Context, bucket, key string, delta int64) (int64, error) {
+	if err := a.authorize(ctx, bucket, ACLOpWrite); err != nil {
+		return 0, err
+	}
+
+	return a.INatty.Decrement(ctx, bucket, key, delta)
+}
+
+func (a *AuthorizedNatty) TemporaryKey(ctx context.Context, bucket string, fn func(key string) error) error {
+	if err := a.authorize(ctx, bucket, ACLOpWrite); err != nil {
+		return err
+	}
+
+	return a.INatty.TemporaryKey(ctx, bucket, fn)
+}
+
+func (a *AuthorizedNatty) Keys(ctx context.Context, bucket string) ([]string, error) {
+	if err := a.authorize(ctx, bucket, ACLOpRead); err != nil {
+		return nil, err
+	}
+
+	return a.INatty.Keys(ctx, bucket)
+}
+
+func (a *AuthorizedNatty) Watch(ctx context.Context, bucket, key string) (<-chan *KVEntry, error) {
+	if err := a.authorize(ctx, bucket, ACLOpRead); err != nil {
+		return nil, err
+	}
+
+	return a.INatty.Watch(ctx, bucket, key)
+}
+
+func (a *AuthorizedNatty) CompactHistory(ctx context.Context, bucket, key string, keepRevisions int) error {
+	if err := a.authorize(ctx, bucket, ACLOpWrite); err != nil {
+		return err
+	}
+
+	return a.INatty.CompactHistory(ctx, bucket, key, keepRevisions)
+}
+
+func (a *AuthorizedNatty) BucketChecksum(ctx context.Context, bucket string) ([]byte, error) {
+	if err := a.authorize(ctx, bucket, ACLOpRead); err != nil {
+		return nil, err
+	}
+
+	return a.INatty.BucketChecksum(ctx, bucket)
+}
+
+func (a *AuthorizedNatty) Reconcile(ctx context.Context, bucket string, desired map[string][]byte) (ReconcileResult, error) {
+	if err := a.authorize(ctx, bucket, ACLOpWrite); err != nil {
+		return ReconcileResult{}, err
+	}
+
+	return a.INatty.Reconcile(ctx, bucket, desired)
+}
+
+func (a *AuthorizedNatty) ExportToCSV(ctx context.Context, bucket string, w io.Writer) error {
+	if err := a.authorize(ctx, bucket, ACLOpRead); err != nil {
+		return err
+	}
+
+	return a.INatty.ExportToCSV(ctx, bucket, w)
+}
+
+func (a *AuthorizedNatty) ImportFromCSV(ctx context.Context, bucket string, rd io.Reader) error {
+	if err := a.authorize(ctx, bucket, ACLOpWrite); err != nil {
+		return err
+	}
+
+	return a.INatty.ImportFromCSV(ctx, bucket, rd)
+}
+
+func (a *AuthorizedNatty) PutObject(ctx context.Context, bucket, name string, rd io.Reader) (*nats.ObjectInfo, error) {
+	if err := a.authorize(ctx, bucket, ACLOpWrite); err != nil {
+		return nil, err
+	}
+
+	return a.INatty.PutObject(ctx, bucket, name, rd)
+}
+
+func (a *AuthorizedNatty) GetObject(ctx context.Context, bucket, name string) (io.ReadCloser, error) {
+	if err := a.authorize(ctx, bucket, ACLOpRead); err != nil {
+		return nil, err
+	}
+
+	return a.INatty.GetObject(ctx, bucket, name)
+}
+
+func (a *AuthorizedNatty) DeleteObject(ctx context.Context, bucket, name string) error {
+	if err := a.authorize(ctx, bucket, ACLOpWrite); err != nil {
+		return err
+	}
+
+	return a.INatty.DeleteObject(ctx, bucket, name)
+}
+
+func (a *AuthorizedNatty) ListObjects(ctx context.Context, bucket string) ([]*nats.ObjectInfo, error) {
+	if err := a.authorize(ctx, bucket, ACLOpRead); err != nil {
+		return nil, err
+	}
+
+	return a.INatty.ListObjects(ctx, bucket)
+}
+
+func (a *AuthorizedNatty) Lock(ctx context.Context, bucket, lockKey string, ttl time.Duration) (func() error, error) {
+	if err := a.authorize(ctx, bucket, ACLOpWrite); err != nil {
+		return nil, err
+	}
+
+	return a.INatty.Lock(ctx, bucket, lockKey, ttl)
+}
+
+func (a *AuthorizedNatty) Hydrate(ctx context.Context, bucket string, data map[string][]byte) error {
+	if err := a.authorize(ctx, bucket, ACLOpWrite); err != nil {
+		return err
+	}
+
+	return a.INatty.Hydrate(ctx, bucket, data)
+}
+
+func (a *AuthorizedNatty) IsHydrated(ctx context.Context, bucket string) (bool, error) {
+	if err := a.authorize(ctx, bucket, ACLOpRead); err != nil {
+		return false, err
+	}
+
+	return a.INatty.IsHydrated(ctx, bucket)
+}
+
+func (a *AuthorizedNatty) KeysStream(ctx context.Context, bucket string) (<-chan string, <-chan error) {
+	if err := a.authorize(ctx, bucket, ACLOpRead); err != nil {
+		keys := make(chan string)
+		errs := make(chan error, 1)
+
+		errs <- err
+
+		close(keys)
+		close(errs)
+
+		return keys, errs
+	}
+
+	return a.INatty.KeysStream(ctx, bucket)
+}
+
+func (a *AuthorizedNatty) Snapshot(ctx context.Context, bucket string) (*KVSnapshot, error) {
+	if err := a.authorize(ctx, bucket, ACLOpRead); err != nil {
+		return nil, err
+	}
+
+	return a.INatty.Snapshot(ctx, bucket)
+}
+
+func (a *AuthorizedNatty) CopyKey(ctx context.Context, srcBucket, srcKey, dstBucket, dstKey string) error {
+	if err := a.authorize(ctx, srcBucket, ACLOpRead); err != nil {
+		return err
+	}
+
+	if err := a.authorize(ctx, dstBucket, ACLOpWrite); err != nil {
+		return err
+	}
+
+	return a.INatty.CopyKey(ctx, srcBucket, srcKey, dstBucket, dstKey)
+}
+
+func (a *AuthorizedNatty) UpdateBucketConfig(ctx context.Context, bucket string, cfg *nats.KeyValueConfig) error {
+	if err := a.authorize(ctx, bucket, ACLOpWrite); err != nil {
+		return err
+	}
+
+	return a.INatty.UpdateBucketConfig(ctx, bucket, cfg)
+}
+
+func (a *AuthorizedNatty) WatchBucket(ctx context.Context, bucket string) (<-chan *KVEntry, error) {
+	if err := a.authorize(ctx, bucket, ACLOpRead); err != nil {
+		return nil, err
+	}
+
+	return a.INatty.WatchBucket(ctx, bucket)
+}
+
+func (a *AuthorizedNatty) GetOrCreate(ctx context.Context, bucket, key string, ttl time.Duration, factory func() ([]byte, error)) ([]byte, bool, error) {
+	if err := a.authorize(ctx, bucket, ACLOpWrite); err != nil {
+		return nil, false, err
+	}
+
+	return a.INatty.GetOrCreate(ctx, bucket, key, ttl, factory)
+}
+
+func (a *AuthorizedNatty) Merge(ctx context.Context, bucket, key string, values [][]byte, resolver func(values [][]byte) []byte) error {
+	if err := a.authorize(ctx, bucket, ACLOpWrite); err != nil {
+		return err
+	}
+
+	return a.INatty.Merge(ctx, bucket, key, values, resolver)
+}
+
+func (a *AuthorizedNatty) GetWithTTLHint(ctx context.Context, bucket, key string) ([]byte, time.Duration, error) {
+	if err := a.authorize(ctx, bucket, ACLOpRead); err != nil {
+		return nil, 0, err
+	}
+
+	return a.INatty.GetWithTTLHint(ctx, bucket, key)
+}
+
+func (a *AuthorizedNatty) DeletePrefix(ctx context.Context, bucket, prefix string) (int, error) {
+	if err := a.authorize(ctx, bucket, ACLOpWrite); err != nil {
+		return 0, err
+	}
+
+	return a.INatty.DeletePrefix(ctx, bucket, prefix)
+}
+
+func (a *AuthorizedNatty) Exists(ctx context.Context, bucket, key string) (bool, error) {
+	if err := a.authorize(ctx, bucket, ACLOpRead); err != nil {
+		return false, err
+	}
+
+	return a.INatty.Exists(ctx, bucket, key)
+}
+
+func (a *AuthorizedNatty) BucketExists(ctx context.Context, bucket string) (bool, error) {
+	if err := a.authorize(ctx, bucket, ACLOpRead); err != nil {
+		return false, err
+	}
+
+	return a.INatty.BucketExists(ctx, bucket)
+}
+
+func (a *AuthorizedNatty) BatchPutWithTTL(ctx context.Context, bucket string, entries map[string][]byte, ttl time.Duration) error {
+	if err := a.authorize(ctx, bucket, ACLOpWrite); err != nil {
+		return err
+	}
+
+	return a.INatty.BatchPutWithTTL(ctx, bucket, entries, ttl)
+}
+
+func (a *AuthorizedNatty) WatchWithBackpressure(ctx context.Context, bucket, key string) (<-chan *KVEntry, error) {
+	if err := a.authorize(ctx, bucket, ACLOpRead); err != nil {
+		return nil, err
+	}
+
+	return a.INatty.WatchWithBackpressure(ctx, bucket, key)
+}
+
+func (a *AuthorizedNatty) WatchCheckpointed(ctx context.Context, bucket, key, checkpointKey string, ch chan<- *KVEntry) error {
+	if err := a.authorize(ctx, bucket, ACLOpWrite); err != nil {
+		return err
+	}
+
+	return a.INatty.WatchCheckpointed(ctx, bucket, key, checkpointKey, ch)
+}
+
+func (a *AuthorizedNatty) DeleteByPattern(ctx context.Context, bucket, keyPattern string) (int, error) {
+	if err := a.authorize(ctx, bucket, ACLOpWrite); err != nil {
+		return 0, err
+	}
+
+	return a.INatty.DeleteByPattern(ctx, bucket, keyPattern)
+}
@@ -0,0 +1,101 @@
+package natty
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+)
+
+var _ = Describe("AuthorizedNatty", func() {
+	var (
+		stub *stubNatty
+		a    *AuthorizedNatty
+	)
+
+	BeforeEach(func() {
+		stub = &stubNatty{}
+
+		a = NewAuthorizedNatty(stub, WithBucketACL([]BucketACL{
+			{
+				Bucket:            "readonly",
+				AllowedOps:        []string{ACLOpRead},
+				AllowedPrincipals: []string{"alice", "bob"},
+			},
+			{
+				Bucket:            "readwrite",
+				AllowedOps:        []string{ACLOpRead, ACLOpWrite},
+				AllowedPrincipals: []string{"alice"},
+			},
+			{
+				Bucket:            "public",
+				AllowedOps:        []string{ACLOpRead},
+				AllowedPrincipals: []string{ACLAnyPrincipal},
+			},
+		}))
+	})
+
+	alice := WithPrincipal(context.Background(), "alice")
+	bob := WithPrincipal(context.Background(), "bob")
+
+	It("should allow reads but deny writes on a read-only bucket", func() {
+		_, err := a.Get(alice, "readonly", "key")
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = a.Keys(alice, "readonly")
+		Expect(err).ToNot(HaveOccurred())
+
+		err = a.Put(alice, "readonly", "key", []byte("value"))
+		Expect(errors.Is(err, ErrPermissionDenied)).To(BeTrue())
+
+		Expect(stub.calls).To(Equal([]string{"Get", "Keys"}))
+	})
+
+	It("should allow reads and writes on a read-write bucket", func() {
+		_, err := a.Get(alice, "readwrite", "key")
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(a.Put(alice, "readwrite", "key", []byte("value"))).To(Succeed())
+
+		Expect(stub.calls).To(Equal([]string{"Get", "Put"}))
+	})
+
+	It("should deny principals that are not listed", func() {
+		_, err := a.Get(bob, "readwrite", "key")
+		Expect(errors.Is(err, ErrPermissionDenied)).To(BeTrue())
+
+		err = a.Put(bob, "readwrite", "key", []byte("value"))
+		Expect(errors.Is(err, ErrPermissionDenied)).To(BeTrue())
+
+		_, err = a.Get(context.Background(), "readonly", "key")
+		Expect(errors.Is(err, ErrPermissionDenied)).To(BeTrue())
+
+		Expect(stub.calls).To(BeEmpty())
+	})
+
+	It("should deny access to buckets without an ACL", func() {
+		_, err := a.Get(alice, "other", "key")
+		Expect(errors.Is(err, ErrPermissionDenied)).To(BeTrue())
+	})
+
+	It("should require read on the source and write on the destination of a copy", func() {
+		Expect(a.CopyKey(alice, "readonly", "key", "readwrite", "key")).To(Succeed())
+
+		err := a.CopyKey(alice, "readwrite", "key", "readonly", "key")
+		Expect(errors.Is(err, ErrPermissionDenied)).To(BeTrue())
+
+		Expect(stub.calls).To(Equal([]string{"CopyKey"}))
+	})
+
+	It("should allow any principal with a wildcard", func() {
+		_, err := a.Get(context.Background(), "public", "key")
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = a.Get(bob, "public", "key")
+		Expect(err).ToNot(HaveOccurred())
+
+		err = a.Put(bob, "public", "key", []byte("value"))
+		Expect(errors.Is(err, ErrPermissionDenied)).To(BeTrue())
+	})
+})
@@ -0,0 +1,236 @@
+package natty
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+
+	"github.com/nats-io/nats.go"
+	"github.com/pkg/errors"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+)
+
+// restoreChunkSize is the size of the chunks uploaded by RestoreStream()
+const restoreChunkSize = 128 * 1024
+
+// streamSnapshotRequest mirrors the JetStream API snapshot request
+type streamSnapshotRequest struct {
+	DeliverSubject string `json:"deliver_subject"`
+	ChunkSize      int    `json:"chunk_size,omitempty"`
+}
+
+// streamRestoreRequest mirrors the JetStream API restore request
+type streamRestoreRequest struct {
+	Config *nats.StreamConfig `json:"config"`
+	State  nats.StreamState   `json:"state"`
+}
+
+// SnapshotStream writes a snapshot (messages and consumers, in the server's
+// s2 compressed tar format) of the stream to w; the snapshot can be restored
+// via RestoreStream().
+//
+// NOTE: The NATS Go client does not expose snapshots so this implements the
+// JetStream API snapshot protocol directly.
+func (n *Natty) SnapshotStream(ctx context.Context, name string, w io.Writer) error {
+	span, ctx := tracer.StartSpanFromContext(ctx, "natty.SnapshotStream")
+	defer span.Finish()
+
+	if err := n.snapshotStream(ctx, name, w); err != nil {
+		span.SetTag("error", err)
+		return err
+	}
+
+	return nil
+}
+
+func (n *Natty) snapshotStream(ctx context.Context, name string, w io.Writer) error {
+	if n.isClosed() {
+		return ErrConnectionClosed
+	}
+
+	if name == "" {
+		return ErrEmptyStreamName
+	}
+
+	// The inbox must be on the connection the request is sent on
+	nc := n.getConn()
+	inbox := nats.NewInbox()
+
+	sub, err := nc.SubscribeSync(inbox)
+	if err != nil {
+		return errors.Wrap(err, "unable to subscribe to snapshot inbox")
+	}
+
+	defer func() {
+		if err := sub.Unsubscribe(); err != nil {
+			errorw(n.log, "unable to unsubscribe from snapshot inbox", "error", err)
+		}
+	}()
+
+	data, err := json.Marshal(&streamSnapshotRequest{DeliverSubject: inbox})
+	if err != nil {
+		return errors.Wrap(err, "unable to marshal snapshot request")
+	}
+
+	msg, err := nc.RequestWithContext(ctx, n.jsAPISubject("STREAM.SNAPSHOT."+name), data)
+	if err != nil {
+		return errors.Wrap(err, "unable to send snapshot request")
+	}
+
+	var resp jsAPIResponse
+
+	if err := json.Unmarshal(msg.Data, &resp); err != nil {
+		return errors.Wrap(err, "unable to unmarshal snapshot response")
+	}
+
+	if err := resp.err(); err != nil {
+		if err == nats.ErrStreamNotFound {
+			return err
+		}
+
+		return errors.Wrap(err, "unable to snapshot stream")
+	}
+
+	for {
+		chunk, err := sub.NextMsgWithContext(ctx)
+		if err != nil {
+			return errors.Wrap(err, "unable to receive snapshot chunk")
+		}
+
+		// An empty message ends the snapshot; errors are sent as a status
+		if len(chunk.Data) == 0 {
+			if status := chunk.Header.Get("Status"); status != "" && status != "200" {
+				return errors.Errorf("snapshot failed: %s %s", status, chunk.Header.Get("Description"))
+			}
+
+			return nil
+		}
+
+		if _, err := w.Write(chunk.Data); err != nil {
+			return errors.Wrap(err, "unable to write snapshot chunk")
+		}
+
+		// The server waits for chunks to be ACK'd (flow control)
+		if chunk.Reply != "" {
+			if err := chunk.Respond(nil); err != nil {
+				return errors.Wrap(err, "unable to ack snapshot chunk")
+			}
+		}
+	}
+}
+
+// RestoreStream creates the stream described by cfg from a snapshot created
+// via SnapshotStream(); the stream must not exist. cfg.Name is the name of
+// the restored stream.
+//
+// NOTE: Like SnapshotStream(), this implements the JetStream API restore
+// protocol directly.
+func (n *Natty) RestoreStream(ctx context.Context, cfg *nats.StreamConfig, r io.Reader) (*nats.StreamInfo, error) {
+	span, ctx := tracer.StartSpanFromContext(ctx, "natty.RestoreStream")
+	defer span.Finish()
+
+	info, err := n.restoreStream(ctx, cfg, r)
+	if err != nil {
+		span.SetTag("error", err)
+		return nil, err
+	}
+
+	return info, nil
+}
+
+func (n *Natty) restoreStream(ctx context.Context, cfg *nats.StreamConfig, r io.Reader) (*nats.StreamInfo, error) {
+	if n.isClosed() {
+		return nil, ErrConnectionClosed
+	}
+
+	if cfg == nil || cfg.Name == "" {
+		return nil, ErrEmptyStreamName
+	}
+
+	data, err := json.Marshal(&streamRestoreRequest{Config: cfg})
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to marshal restore request")
+	}
+
+	msg, err := n.getConn().RequestWithContext(ctx, n.jsAPISubject("STREAM.RESTORE."+cfg.Name), data)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to send restore request")
+	}
+
+	var resp struct {
+		jsAPIResponse
+		DeliverSubject string `json:"deliver_subject"`
+	}
+
+	if err := json.Unmarshal(msg.Data, &resp); err != nil {
+		return nil, errors.Wrap(err, "unable to unmarshal restore response")
+	}
+
+	if err := resp.err(); err != nil {
+		return nil, errors.Wrap(err, "unable to restore stream")
+	}
+
+	buf := make([]byte, restoreChunkSize)
+
+	for {
+		size, readErr := io.ReadFull(r, buf)
+		if size > 0 {
+			if err := n.sendRestoreChunk(ctx, resp.DeliverSubject, buf[:size]); err != nil {
+				return nil, err
+			}
+		}
+
+		if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
+			break
+		}
+
+		if readErr != nil {
+			return nil, errors.Wrap(readErr, "unable to read snapshot")
+		}
+	}
+
+	// An empty message signals the end of the snapshot; the server responds
+	// once the stream has been restored
+	msg, err = n.getConn().RequestWithContext(ctx, resp.DeliverSubject, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to complete restore")
+	}
+
+	var infoResp struct {
+		jsAPIResponse
+		nats.StreamInfo
+	}
+
+	if err := json.Unmarshal(msg.Data, &infoResp); err != nil {
+		return nil, errors.Wrap(err, "unable to unmarshal restore result")
+	}
+
+	if err := infoResp.err(); err != nil {
+		return nil, errors.Wrap(err, "unable to restore stream")
+	}
+
+	return &infoResp.StreamInfo, nil
+}
+
+func (n *Natty) sendRestoreChunk(ctx context.Context, subject string, chunk []byte) error {
+	msg, err := n.getConn().RequestWithContext(ctx, subject, chunk)
+	if err != nil {
+		return errors.Wrap(err, "unable to upload snapshot chunk")
+	}
+
+	if len(msg.Data) == 0 {
+		return nil
+	}
+
+	var resp jsAPIResponse
+
+	if err := json.Unmarshal(msg.Data, &resp); err != nil {
+		return errors.Wrap(err, "unable to unmarshal chunk response")
+	}
+
+	if err := resp.err(); err != nil {
+		return errors.Wrap(err, "unable to upload snapshot chunk")
+	}
+
+	return nil
+}
@@ -0,0 +1,64 @@
+package natty
+
+import (
+	"bytes"
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/nats-io/nats.go"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SnapshotStream/RestoreStream", func() {
+	var (
+		n          *Natty
+		streamName string
+	)
+
+	BeforeEach(func() {
+		var err error
+
+		n, err = New(NewConfig())
+		Expect(err).ToNot(HaveOccurred())
+
+		streamName = strings.ToUpper(GetRandomName("test", 1))
+		testStreams = append(testStreams, streamName)
+
+		Expect(n.CreateStream(context.Background(), streamName, []string{streamName + ".*"})).To(Succeed())
+	})
+
+	It("should restore a deleted stream from a snapshot", func() {
+		for i := 0; i < 25; i++ {
+			_, err := n.js.Publish(streamName+".foo", []byte(strconv.Itoa(i)))
+			Expect(err).ToNot(HaveOccurred())
+		}
+
+		info, err := n.js.StreamInfo(streamName)
+		Expect(err).ToNot(HaveOccurred())
+
+		cfg := info.Config
+
+		buf := &bytes.Buffer{}
+
+		Expect(n.SnapshotStream(context.Background(), streamName, buf)).To(Succeed())
+		Expect(buf.Len()).To(BeNumerically(">", 0))
+
+		Expect(n.DeleteStream(context.Background(), streamName)).To(Succeed())
+
+		restored, err := n.RestoreStream(context.Background(), &cfg, buf)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(restored.Config.Name).To(Equal(streamName))
+		Expect(restored.State.Msgs).To(Equal(uint64(25)))
+
+		msg, err := n.js.GetMsg(streamName, 25)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(msg.Data).To(Equal([]byte("24")))
+	})
+
+	It("should return ErrStreamNotFound for a missing stream", func() {
+		err := n.SnapshotStream(context.Background(), "MISSING_"+strings.ToUpper(GetRandomName("test", 1)), &bytes.Buffer{})
+		Expect(err).To(Equal(nats.ErrStreamNotFound))
+	})
+})
@@ -0,0 +1,92 @@
+package natty
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/nats-io/nats.go"
+	"github.com/pkg/errors"
+)
+
+// CDCEvent operations; kept for compatibility, see KVEventOpPut etc.
+const (
+	CDCOpPut    = KVEventOpPut
+	CDCOpDelete = KVEventOpDelete
+	CDCOpPurge  = KVEventOpPurge
+)
+
+// CDCEvent is the JSON payload published by KVCDC for every KV change
+type CDCEvent = KeyValueEvent
+
+// KVCDC (change data capture) publishes every change made to a bucket to a
+// JetStream stream so that downstream consumers do not need to watch the
+// bucket themselves.
+type KVCDC struct {
+	n *Natty
+}
+
+// NewKVCDC creates a new KVCDC; call Start() to begin publishing
+func (n *Natty) NewKVCDC() *KVCDC {
+	return &KVCDC{n: n}
+}
+
+// Start watches every key in watchBucket and publishes a CDCEvent for each
+// change to the subject "<publishStream>.<watchBucket>" (ie. publishStream
+// should be created with a "<publishStream>.>" subject). The current values
+// in the bucket are published first, followed by updates. Start returns once
+// the watch is running; publishing stops when ctx is cancelled.
+//
+// Events carry a Nats-Msg-Id of "<bucket>.<key>.<revision>" so that restarting
+// CDC within the stream's duplicate window does not re-publish changes.
+//
+// Changes are never dropped: if publishing does not keep up, the watch is
+// slowed down instead (see WatchWithBackpressure()).
+//
+// NOTE: Publish failures are logged and the event is skipped.
+func (c *KVCDC) Start(ctx context.Context, watchBucket, publishStream string) error {
+	n := c.n
+
+	if n.isClosed() {
+		return ErrConnectionClosed
+	}
+
+	if _, err := n.getJS().StreamInfo(publishStream); err != nil {
+		return errors.Wrapf(err, "unable to fetch stream '%s'", publishStream)
+	}
+
+	entries, err := n.WatchWithBackpressure(ctx, watchBucket, ">")
+	if err != nil {
+		return errors.Wrapf(err, "unable to watch bucket '%s'", watchBucket)
+	}
+
+	subject := publishStream + "." + watchBucket
+
+	go func() {
+		for entry := range entries {
+			if err := c.publish(subject, publishStream, entry); err != nil {
+				errorw(n.log, "unable to publish cdc event", "bucket", watchBucket,
+					"key", entry.Key, "revision", entry.Revision, "error", err)
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (c *KVCDC) publish(subject, stream string, entry *KVEntry) error {
+	data, err := json.Marshal(newKeyValueEvent(entry))
+	if err != nil {
+		return errors.Wrap(err, "unable to marshal cdc event")
+	}
+
+	msg := nats.NewMsg(subject)
+	msg.Data = data
+	msg.Header.Set(nats.MsgIdHdr, fmt.Sprintf("%s.%s.%d", entry.Bucket, entry.Key, entry.Revision))
+
+	if _, err := c.n.getJS().PublishMsg(msg, nats.ExpectStream(stream)); err != nil {
+		return errors.Wrap(err, "unable to publish cdc event")
+	}
+
+	return nil
+}
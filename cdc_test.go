@@ -0,0 +1,76 @@
+package natty
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	uuid "github.com/satori/go.uuid"
+)
+
+var _ = Describe("KVCDC", func() {
+	var (
+		n      *Natty
+		ctx    context.Context
+		cancel context.CancelFunc
+	)
+
+	BeforeEach(func() {
+		var err error
+
+		n, err = New(NewConfig())
+		Expect(err).ToNot(HaveOccurred())
+
+		ctx, cancel = context.WithCancel(context.Background())
+	})
+
+	AfterEach(func() {
+		cancel()
+	})
+
+	It("should publish an event for every change", func() {
+		bucket, _, _ := NewKVSet()
+		stream := "cdc-" + uuid.NewV4().String()
+
+		Expect(n.CreateBucket(ctx, bucket, time.Minute)).To(Succeed())
+		Expect(n.CreateStream(ctx, stream, []string{stream + ".>"})).To(Succeed())
+
+		defer CleanupStreams([]string{stream})
+
+		Expect(n.NewKVCDC().Start(ctx, bucket, stream)).To(Succeed())
+
+		for i := 0; i < 5; i++ {
+			Expect(n.Put(ctx, bucket, "key-"+strconv.Itoa(i), []byte("value"))).To(Succeed())
+		}
+
+		Eventually(func() uint64 {
+			info, err := n.js.StreamInfo(stream)
+			Expect(err).ToNot(HaveOccurred())
+
+			return info.State.Msgs
+		}, 5*time.Second).Should(Equal(uint64(5)))
+
+		msg, err := n.js.GetMsg(stream, 1)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(msg.Subject).To(Equal(stream + "." + bucket))
+
+		event := &KeyValueEvent{}
+		Expect(json.Unmarshal(msg.Data, event)).To(Succeed())
+		Expect(event.Bucket).To(Equal(bucket))
+		Expect(event.Key).To(Equal("key-0"))
+		Expect(event.Value).To(Equal([]byte("value")))
+		Expect(event.Operation).To(Equal(KVEventOpPut))
+	})
+
+	It("should error if the stream does not exist", func() {
+		bucket, _, _ := NewKVSet()
+
+		Expect(n.CreateBucket(ctx, bucket, time.Minute)).To(Succeed())
+
+		err := n.NewKVCDC().Start(ctx, bucket, "missing-"+uuid.NewV4().String())
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,124 @@
+package natty
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/nats-io/nats.go"
+	"github.com/pkg/errors"
+)
+
+// Headers used by NATS to mark KV deletes and purges
+const (
+	kvOperationHeader = "KV-Operation"
+	kvOperationDelete = "DEL"
+	kvOperationPurge  = "PURGE"
+)
+
+// WatchCheckpointed streams changes to key (which may contain wildcards) in
+// bucket into ch and stores the revision of every delivered entry in
+// checkpointKey (in the same bucket). When called again with the same
+// checkpointKey, the watch resumes after the stored revision so that entries
+// that were already delivered are not replayed; without a checkpoint, the
+// complete history of key is delivered.
+//
+// This is a blocking call; it returns nil once ctx is cancelled. Changes to
+// checkpointKey itself are not delivered.
+//
+// NOTE: The NATS KV watcher cannot start from a revision, so this reads from
+// the bucket's underlying stream via an ordered consumer instead.
+func (n *Natty) WatchCheckpointed(ctx context.Context, bucket, key, checkpointKey string, ch chan<- *KVEntry) error {
+	if n.isClosed() {
+		return ErrConnectionClosed
+	}
+
+	if checkpointKey == "" {
+		return errors.New("checkpoint key cannot be empty")
+	}
+
+	if _, err := n.getBucket(ctx, bucket, false, 0); err != nil {
+		return err
+	}
+
+	startOpt := nats.DeliverAll()
+
+	data, err := n.Get(ctx, bucket, checkpointKey)
+	if err != nil {
+		if err != nats.ErrKeyNotFound {
+			return errors.Wrap(err, "unable to fetch checkpoint")
+		}
+	} else {
+		revision, err := strconv.ParseUint(string(data), 10, 64)
+		if err != nil {
+			return errors.Wrapf(err, "checkpoint key '%s' contains an invalid revision", checkpointKey)
+		}
+
+		startOpt = nats.StartSequence(revision + 1)
+	}
+
+	subjectPrefix := kvSubject(bucket, "")
+	msgs := make(chan *nats.Msg, WatchBufferSize)
+
+	sub, err := n.getJS().ChanSubscribe(kvSubject(bucket, key), msgs,
+		nats.BindStream(kvStreamPrefix+bucket), nats.OrderedConsumer(), startOpt)
+	if err != nil {
+		return errors.Wrap(err, "unable to start watcher")
+	}
+
+	defer sub.Unsubscribe()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg := <-msgs:
+			entry, err := newKVEntryFromMsg(bucket, subjectPrefix, msg)
+			if err != nil {
+				return err
+			}
+
+			if entry.Key == checkpointKey {
+				continue
+			}
+
+			select {
+			case ch <- entry:
+			case <-ctx.Done():
+				return nil
+			}
+
+			if err := n.Put(ctx, bucket, checkpointKey, []byte(strconv.FormatUint(entry.Revision, 10))); err != nil {
+				return errors.Wrap(err, "unable to store checkpoint")
+			}
+		}
+	}
+}
+
+// newKVEntryFromMsg converts a message read directly from a bucket's stream
+// into a KVEntry
+func newKVEntryFromMsg(bucket, subjectPrefix string, msg *nats.Msg) (*KVEntry, error) {
+	meta, err := msg.Metadata()
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read message metadata")
+	}
+
+	entry := &KVEntry{
+		Bucket:    bucket,
+		Key:       strings.TrimPrefix(msg.Subject, subjectPrefix),
+		Value:     msg.Data,
+		Revision:  meta.Sequence.Stream,
+		Delta:     meta.NumPending,
+		Created:   meta.Timestamp,
+		Operation: nats.KeyValuePut,
+	}
+
+	switch msg.Header.Get(kvOperationHeader) {
+	case kvOperationDelete:
+		entry.Operation = nats.KeyValueDelete
+	case kvOperationPurge:
+		entry.Operation = nats.KeyValuePurge
+	}
+
+	return entry, nil
+}
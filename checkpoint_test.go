@@ -0,0 +1,91 @@
+package natty
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/nats-io/nats.go"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("WatchCheckpointed", func() {
+	var n *Natty
+
+	BeforeEach(func() {
+		var err error
+
+		n, err = New(NewConfig())
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	// watch runs WatchCheckpointed until count entries were received
+	watch := func(bucket string, count int) []*KVEntry {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		ch := make(chan *KVEntry)
+		done := make(chan error, 1)
+
+		go func() {
+			done <- n.WatchCheckpointed(ctx, bucket, ">", "checkpoint", ch)
+		}()
+
+		entries := make([]*KVEntry, 0, count)
+
+		for len(entries) < count {
+			var entry *KVEntry
+			Eventually(ch).Should(Receive(&entry))
+
+			entries = append(entries, entry)
+		}
+
+		cancel()
+
+		Eventually(done).Should(Receive(BeNil()))
+
+		return entries
+	}
+
+	It("should resume after the last delivered revision", func() {
+		bucket, _, _ := NewKVSet()
+
+		for i := 1; i <= 5; i++ {
+			Expect(n.Put(context.Background(), bucket, "event", []byte(strconv.Itoa(i)))).To(Succeed())
+		}
+
+		entries := watch(bucket, 5)
+
+		for i, entry := range entries {
+			Expect(entry.Key).To(Equal("event"))
+			Expect(entry.Value).To(Equal([]byte(strconv.Itoa(i + 1))))
+		}
+
+		for i := 6; i <= 7; i++ {
+			Expect(n.Put(context.Background(), bucket, "event", []byte(strconv.Itoa(i)))).To(Succeed())
+		}
+
+		entries = watch(bucket, 2)
+
+		Expect(entries[0].Value).To(Equal([]byte("6")))
+		Expect(entries[1].Value).To(Equal([]byte("7")))
+	})
+
+	It("should deliver deletes", func() {
+		bucket, key, value := NewKVSet()
+
+		Expect(n.Put(context.Background(), bucket, key, value)).To(Succeed())
+		Expect(n.Delete(context.Background(), bucket, key)).To(Succeed())
+
+		// The purge rolls up (removes) the earlier put
+		entries := watch(bucket, 1)
+
+		Expect(entries[0].Key).To(Equal(key))
+		Expect(entries[0].Operation).To(Equal(nats.KeyValuePurge))
+	})
+
+	It("should error for a missing bucket", func() {
+		err := n.WatchCheckpointed(context.Background(), GetRandomName("test", 1), ">", "checkpoint", make(chan *KVEntry))
+		Expect(err).To(Equal(nats.ErrBucketNotFound))
+	})
+})
@@ -0,0 +1,217 @@
+package natty
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/pkg/errors"
+)
+
+const (
+	DefaultCircuitOpenDuration = 30 * time.Second
+)
+
+var (
+	ErrCircuitOpen = errors.New("circuit breaker is open")
+)
+
+// CircuitState is the state of a circuit breaker
+type CircuitState int
+
+const (
+	// CircuitClosed allows all calls through
+	CircuitClosed CircuitState = iota
+
+	// CircuitOpen rejects all calls with ErrCircuitOpen
+	CircuitOpen
+
+	// CircuitHalfOpen allows a single probe call through; if it succeeds the
+	// circuit is closed, otherwise it is re-opened.
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// CircuitBreakerConfig configures the circuit breaker wrapping KV and publish
+// calls. The breaker is disabled unless Threshold is greater than 0.
+type CircuitBreakerConfig struct {
+	// Threshold is the number of consecutive failures after which the circuit
+	// is opened
+	Threshold int
+
+	// OpenDuration is how long the circuit stays open before a probe call is
+	// allowed through (default: DefaultCircuitOpenDuration)
+	OpenDuration time.Duration
+}
+
+type circuitBreaker struct {
+	threshold    int
+	openDuration time.Duration
+
+	// now is overridden in tests
+	now func() time.Time
+
+	mutex    *sync.Mutex
+	state    CircuitState
+	failures int
+	openedAt time.Time
+	probing  bool
+
+	// generation is incremented on every state change; results of calls
+	// allowed in an earlier generation are ignored by record()
+	generation uint64
+}
+
+// newCircuitBreaker returns nil (ie. a disabled breaker) if cfg.Threshold is 0
+func newCircuitBreaker(cfg CircuitBreakerConfig) *circuitBreaker {
+	if cfg.Threshold <= 0 {
+		return nil
+	}
+
+	openDuration := cfg.OpenDuration
+	if openDuration <= 0 {
+		openDuration = DefaultCircuitOpenDuration
+	}
+
+	return &circuitBreaker{
+		threshold:    cfg.Threshold,
+		openDuration: openDuration,
+		now:          time.Now,
+		mutex:        &sync.Mutex{},
+	}
+}
+
+// do calls fn if the circuit allows it and records the result
+func (cb *circuitBreaker) do(fn func() error) error {
+	generation, err := cb.allow()
+	if err != nil {
+		return err
+	}
+
+	err = fn()
+	cb.record(generation, err)
+
+	return err
+}
+
+// allow returns ErrCircuitOpen if a call should not be attempted; every call
+// that is allowed MUST be followed by a call to record() with the returned
+// generation.
+func (cb *circuitBreaker) allow() (uint64, error) {
+	if cb == nil {
+		return 0, nil
+	}
+
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	if cb.state == CircuitOpen && cb.now().Sub(cb.openedAt) >= cb.openDuration {
+		cb.setState(CircuitHalfOpen)
+	}
+
+	switch cb.state {
+	case CircuitOpen:
+		return 0, ErrCircuitOpen
+	case CircuitHalfOpen:
+		// Only one probe at a time
+		if cb.probing {
+			return 0, ErrCircuitOpen
+		}
+
+		cb.probing = true
+	}
+
+	return cb.generation, nil
+}
+
+// record updates the circuit state with the result of a call allowed in
+// generation; results of calls allowed before the last state change (ie. a
+// slow call that completes after the circuit opened) are ignored.
+func (cb *circuitBreaker) record(generation uint64, err error) {
+	if cb == nil {
+		return
+	}
+
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	if generation != cb.generation {
+		return
+	}
+
+	if !isCircuitFailure(err) {
+		if cb.state != CircuitClosed {
+			cb.setState(CircuitClosed)
+		}
+
+		cb.failures = 0
+
+		return
+	}
+
+	cb.failures++
+
+	if cb.state == CircuitHalfOpen || cb.failures >= cb.threshold {
+		cb.setState(CircuitOpen)
+		cb.openedAt = cb.now()
+	}
+}
+
+// setState changes the state and starts a new generation; caller must hold
+// the mutex
+func (cb *circuitBreaker) setState(state CircuitState) {
+	cb.state = state
+	cb.probing = false
+	cb.generation++
+}
+
+// State returns the current circuit state
+func (cb *circuitBreaker) State() CircuitState {
+	if cb == nil {
+		return CircuitClosed
+	}
+
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	if cb.state == CircuitOpen && cb.now().Sub(cb.openedAt) >= cb.openDuration {
+		return CircuitHalfOpen
+	}
+
+	return cb.state
+}
+
+// isCircuitFailure returns false for nil and for errors that indicate a
+// healthy server responding normally (ie. a missing key).
+func isCircuitFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	for _, e := range []error{
+		nats.ErrKeyNotFound,
+		nats.ErrBucketNotFound,
+		nats.ErrNoKeysFound,
+		ErrKeyExists,
+		context.Canceled,
+	} {
+		if errors.Is(err, e) {
+			return false
+		}
+	}
+
+	return !isWrongLastSequence(err)
+}
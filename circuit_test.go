@@ -0,0 +1,155 @@
+package natty
+
+import (
+	"time"
+
+	"github.com/nats-io/nats.go"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+)
+
+var _ = Describe("circuitBreaker", func() {
+	var (
+		cb  *circuitBreaker
+		now time.Time
+	)
+
+	fail := func() error {
+		return nats.ErrTimeout
+	}
+
+	succeed := func() error {
+		return nil
+	}
+
+	BeforeEach(func() {
+		now = time.Now()
+
+		cb = newCircuitBreaker(CircuitBreakerConfig{Threshold: 3, OpenDuration: time.Minute})
+		cb.now = func() time.Time { return now }
+	})
+
+	It("should be disabled when Threshold is 0", func() {
+		cb = newCircuitBreaker(CircuitBreakerConfig{})
+		Expect(cb).To(BeNil())
+
+		for i := 0; i < 10; i++ {
+			Expect(cb.do(fail)).To(Equal(nats.ErrTimeout))
+		}
+
+		Expect(cb.State()).To(Equal(CircuitClosed))
+	})
+
+	It("should open after Threshold consecutive failures", func() {
+		for i := 0; i < 3; i++ {
+			Expect(cb.State()).To(Equal(CircuitClosed))
+			Expect(cb.do(fail)).To(Equal(nats.ErrTimeout))
+		}
+
+		Expect(cb.State()).To(Equal(CircuitOpen))
+
+		var called bool
+
+		err := cb.do(func() error {
+			called = true
+			return nil
+		})
+
+		Expect(err).To(Equal(ErrCircuitOpen))
+		Expect(called).To(BeFalse())
+	})
+
+	It("should reset the failure count on success", func() {
+		Expect(cb.do(fail)).ToNot(Succeed())
+		Expect(cb.do(fail)).ToNot(Succeed())
+		Expect(cb.do(succeed)).To(Succeed())
+		Expect(cb.do(fail)).ToNot(Succeed())
+		Expect(cb.do(fail)).ToNot(Succeed())
+
+		Expect(cb.State()).To(Equal(CircuitClosed))
+	})
+
+	It("should not count expected errors as failures", func() {
+		for i := 0; i < 5; i++ {
+			Expect(cb.do(func() error { return nats.ErrKeyNotFound })).ToNot(Succeed())
+			Expect(cb.do(func() error { return errors.Wrap(ErrKeyExists, "unable to put key") })).ToNot(Succeed())
+		}
+
+		Expect(cb.State()).To(Equal(CircuitClosed))
+	})
+
+	It("should close after a successful probe in half-open state", func() {
+		for i := 0; i < 3; i++ {
+			Expect(cb.do(fail)).ToNot(Succeed())
+		}
+
+		now = now.Add(time.Minute)
+
+		Expect(cb.State()).To(Equal(CircuitHalfOpen))
+
+		// Only a single probe is allowed through
+		generation, err := cb.allow()
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = cb.allow()
+		Expect(err).To(Equal(ErrCircuitOpen))
+
+		cb.record(generation, nil)
+
+		Expect(cb.State()).To(Equal(CircuitClosed))
+		Expect(cb.do(succeed)).To(Succeed())
+	})
+
+	It("should re-open after a failed probe in half-open state", func() {
+		for i := 0; i < 3; i++ {
+			Expect(cb.do(fail)).ToNot(Succeed())
+		}
+
+		now = now.Add(time.Minute)
+
+		Expect(cb.do(fail)).To(Equal(nats.ErrTimeout))
+		Expect(cb.State()).To(Equal(CircuitOpen))
+		Expect(cb.do(succeed)).To(Equal(ErrCircuitOpen))
+
+		now = now.Add(time.Minute)
+
+		Expect(cb.do(succeed)).To(Succeed())
+		Expect(cb.State()).To(Equal(CircuitClosed))
+	})
+
+	It("should ignore results of calls that complete after the circuit opened", func() {
+		// Allowed while closed, completes after the circuit opened
+		slowSuccess, err := cb.allow()
+		Expect(err).ToNot(HaveOccurred())
+
+		slowFailure, err := cb.allow()
+		Expect(err).ToNot(HaveOccurred())
+
+		for i := 0; i < 3; i++ {
+			Expect(cb.do(fail)).ToNot(Succeed())
+		}
+
+		cb.record(slowSuccess, nil)
+		Expect(cb.State()).To(Equal(CircuitOpen))
+
+		now = now.Add(time.Minute)
+
+		probe, err := cb.allow()
+		Expect(err).ToNot(HaveOccurred())
+
+		// A stale failure must not end the probe and let a second one through
+		cb.record(slowFailure, nats.ErrTimeout)
+
+		_, err = cb.allow()
+		Expect(err).To(Equal(ErrCircuitOpen))
+
+		cb.record(probe, nil)
+		Expect(cb.State()).To(Equal(CircuitClosed))
+	})
+
+	It("should default OpenDuration", func() {
+		cb = newCircuitBreaker(CircuitBreakerConfig{Threshold: 1})
+		Expect(cb.openDuration).To(Equal(DefaultCircuitOpenDuration))
+	})
+})
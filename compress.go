@@ -0,0 +1,171 @@
+package natty
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"io/ioutil"
+
+	"github.com/klauspost/compress/s2"
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4/v4"
+	"github.com/pkg/errors"
+)
+
+// CompressionCodec compresses and decompresses values for CompressedBucket.
+// SnappyCodec, LZ4Codec, ZstdCodec, GzipCodec, ZlibCodec and FlateCodec are
+// provided; other algorithms can be used by implementing this interface.
+type CompressionCodec interface {
+	Name() string
+	Compress(data []byte) ([]byte, error)
+	Decompress(data []byte) ([]byte, error)
+}
+
+var (
+	SnappyCodec CompressionCodec = &streamCodec{name: "snappy", newWriter: newSnappyWriter, newReader: newSnappyReader}
+	LZ4Codec    CompressionCodec = &streamCodec{name: "lz4", newWriter: newLZ4Writer, newReader: newLZ4Reader}
+	ZstdCodec   CompressionCodec = &streamCodec{name: "zstd", newWriter: newZstdWriter, newReader: newZstdReader}
+	GzipCodec   CompressionCodec = &streamCodec{name: "gzip", newWriter: newGzipWriter, newReader: newGzipReader}
+	ZlibCodec   CompressionCodec = &streamCodec{name: "zlib", newWriter: newZlibWriter, newReader: zlib.NewReader}
+	FlateCodec  CompressionCodec = &streamCodec{name: "flate", newWriter: newFlateWriter, newReader: newFlateReader}
+)
+
+// streamCodec implements CompressionCodec on top of a streaming compressor
+type streamCodec struct {
+	name      string
+	newWriter func(w io.Writer) io.WriteCloser
+	newReader func(r io.Reader) (io.ReadCloser, error)
+}
+
+// newSnappyWriter writes the snappy framing format
+func newSnappyWriter(w io.Writer) io.WriteCloser {
+	return s2.NewWriter(w, s2.WriterSnappyCompat())
+}
+
+func newSnappyReader(r io.Reader) (io.ReadCloser, error) {
+	return ioutil.NopCloser(s2.NewReader(r)), nil
+}
+
+func newLZ4Writer(w io.Writer) io.WriteCloser {
+	return lz4.NewWriter(w)
+}
+
+func newLZ4Reader(r io.Reader) (io.ReadCloser, error) {
+	return ioutil.NopCloser(lz4.NewReader(r)), nil
+}
+
+func newZstdWriter(w io.Writer) io.WriteCloser {
+	// Only fails on invalid options
+	zw, _ := zstd.NewWriter(w)
+	return zw
+}
+
+func newZstdReader(r io.Reader) (io.ReadCloser, error) {
+	zr, err := zstd.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return zr.IOReadCloser(), nil
+}
+
+func newGzipWriter(w io.Writer) io.WriteCloser {
+	return gzip.NewWriter(w)
+}
+
+func newGzipReader(r io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(r)
+}
+
+func newZlibWriter(w io.Writer) io.WriteCloser {
+	return zlib.NewWriter(w)
+}
+
+func newFlateWriter(w io.Writer) io.WriteCloser {
+	// Only fails on an invalid level
+	fw, _ := flate.NewWriter(w, flate.DefaultCompression)
+	return fw
+}
+
+func newFlateReader(r io.Reader) (io.ReadCloser, error) {
+	return flate.NewReader(r), nil
+}
+
+func (c *streamCodec) Name() string {
+	return c.name
+}
+
+func (c *streamCodec) Compress(data []byte) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	w := c.newWriter(buf)
+
+	if _, err := w.Write(data); err != nil {
+		return nil, errors.Wrapf(err, "unable to %s compress data", c.name)
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, errors.Wrapf(err, "unable to %s compress data", c.name)
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (c *streamCodec) Decompress(data []byte) ([]byte, error) {
+	r, err := c.newReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to %s decompress data", c.name)
+	}
+	defer r.Close()
+
+	out, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to %s decompress data", c.name)
+	}
+
+	return out, nil
+}
+
+// CompressedBucket compresses every value written via Put, Create and PutJSON
+// with its codec; values read via Get, GetEntry and GetJSON are decompressed
+// before being returned. Reading a value that was not written through a
+// CompressedBucket using the same codec returns an error.
+//
+// NOTE: Only the methods listed above plus Delete and Keys are available;
+// the other KV methods (ie. Watch or BatchPutWithTTL) cannot be used with
+// compressed buckets.
+type CompressedBucket struct {
+	*transformKV
+
+	codec CompressionCodec
+}
+
+// CompressBucket wraps given INatty so that values are compressed with codec
+func CompressBucket(n INatty, codec CompressionCodec) *CompressedBucket {
+	c := &CompressedBucket{
+		codec: codec,
+	}
+
+	c.transformKV = &transformKV{n: n, t: c}
+
+	return c
+}
+
+func (c *CompressedBucket) encode(bucket, key string, data []byte) ([]byte, error) {
+	value, err := c.codec.Compress(data)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to compress value for key '%s' in bucket '%s'", key, bucket)
+	}
+
+	return value, nil
+}
+
+func (c *CompressedBucket) decode(bucket, key string, data []byte) ([]byte, error) {
+	value, err := c.codec.Decompress(data)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to decompress value for key '%s' in bucket '%s'", key, bucket)
+	}
+
+	return value, nil
+}
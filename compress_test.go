@@ -0,0 +1,92 @@
+package natty
+
+import (
+	"bytes"
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var codecs = []CompressionCodec{SnappyCodec, LZ4Codec, ZstdCodec, GzipCodec, ZlibCodec, FlateCodec}
+
+var _ = Describe("CompressionCodec", func() {
+	It("should round trip data", func() {
+		for _, codec := range codecs {
+			_, _, value := NewKVSetWithSize(64 * 1024)
+
+			compressed, err := codec.Compress(value)
+			Expect(err).ToNot(HaveOccurred(), codec.Name())
+
+			data, err := codec.Decompress(compressed)
+			Expect(err).ToNot(HaveOccurred(), codec.Name())
+			Expect(data).To(Equal(value), codec.Name())
+		}
+	})
+
+	It("should compress repetitive data", func() {
+		value := bytes.Repeat([]byte("natty"), 1024)
+
+		for _, codec := range codecs {
+			compressed, err := codec.Compress(value)
+			Expect(err).ToNot(HaveOccurred(), codec.Name())
+			Expect(len(compressed)).To(BeNumerically("<", len(value)), codec.Name())
+		}
+	})
+
+	It("should error on data that is not compressed", func() {
+		for _, codec := range codecs {
+			_, err := codec.Decompress([]byte("not compressed"))
+			Expect(err).To(HaveOccurred(), codec.Name())
+		}
+	})
+})
+
+var _ = Describe("CompressedBucket", func() {
+	var (
+		ctx = context.Background()
+		n   *Natty
+	)
+
+	BeforeEach(func() {
+		var err error
+
+		n, err = New(NewConfig())
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("should store and retrieve compressed values", func() {
+		for _, codec := range codecs {
+			c := CompressBucket(n, codec)
+
+			bucket, key, value := NewKVSetWithSize(64 * 1024)
+
+			Expect(c.Put(ctx, bucket, key, value)).To(Succeed())
+
+			data, err := c.Get(ctx, bucket, key)
+			Expect(err).ToNot(HaveOccurred(), codec.Name())
+			Expect(data).To(Equal(value), codec.Name())
+
+			entry, err := c.GetEntry(ctx, bucket, key)
+			Expect(err).ToNot(HaveOccurred(), codec.Name())
+			Expect(entry.Value).To(Equal(value), codec.Name())
+
+			raw, err := n.Get(ctx, bucket, key)
+			Expect(err).ToNot(HaveOccurred(), codec.Name())
+			Expect(raw).ToNot(Equal(value), codec.Name())
+		}
+	})
+
+	It("should round trip JSON values", func() {
+		c := CompressBucket(n, GzipCodec)
+
+		bucket, key, _ := NewKVSet()
+
+		in := map[string]string{"foo": "bar"}
+		out := make(map[string]string)
+
+		Expect(c.PutJSON(ctx, bucket, key, in)).To(Succeed())
+		Expect(c.GetJSON(ctx, bucket, key, &out)).To(Succeed())
+		Expect(out).To(Equal(in))
+	})
+})
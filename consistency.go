@@ -0,0 +1,75 @@
+package natty
+
+import (
+	"context"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/pkg/errors"
+)
+
+// ConsistencyPollInterval is how often WaitForConsistency() compares buckets
+const ConsistencyPollInterval = 100 * time.Millisecond
+
+// WaitForConsistency polls n1 and n2 until the contents of bucket are the same
+// on both (ie. a replica has caught up) or ctx is done; a missing bucket is
+// treated as empty. Intended for tests that run against a cluster or use a
+// Replicator. On timeout, the returned error counts the changes needed to
+// bring n2 up to date with n1 (ie. keys only present on n1 are "added").
+//
+// NOTE: Buckets are compared via Snapshot(), so every poll reads all keys of
+// the bucket from both instances.
+func WaitForConsistency(ctx context.Context, n1, n2 INatty, bucket string) error {
+	ticker := time.NewTicker(ConsistencyPollInterval)
+	defer ticker.Stop()
+
+	for {
+		diff, err := consistencyDiff(ctx, n1, n2, bucket)
+		if err != nil && ctx.Err() == nil {
+			return err
+		}
+
+		if err == nil && len(diff.Added)+len(diff.Removed)+len(diff.Changed) == 0 {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			if err != nil {
+				return errors.Wrapf(ctx.Err(), "bucket '%s' did not become consistent", bucket)
+			}
+
+			return errors.Wrapf(ctx.Err(), "bucket '%s' did not become consistent (%d added, %d removed, %d changed)",
+				bucket, len(diff.Added), len(diff.Removed), len(diff.Changed))
+		case <-ticker.C:
+		}
+	}
+}
+
+func consistencyDiff(ctx context.Context, n1, n2 INatty, bucket string) (*KVSnapshotDiff, error) {
+	s1, err := consistencySnapshot(ctx, n1, bucket)
+	if err != nil {
+		return nil, err
+	}
+
+	s2, err := consistencySnapshot(ctx, n2, bucket)
+	if err != nil {
+		return nil, err
+	}
+
+	return SnapshotDiff(s2, s1), nil
+}
+
+// consistencySnapshot returns nil (ie. empty) for a missing bucket
+func consistencySnapshot(ctx context.Context, n INatty, bucket string) (*KVSnapshot, error) {
+	snapshot, err := n.Snapshot(ctx, bucket)
+	if err != nil {
+		if errors.Cause(err) == nats.ErrBucketNotFound {
+			return nil, nil
+		}
+
+		return nil, errors.Wrapf(err, "unable to snapshot bucket '%s'", bucket)
+	}
+
+	return snapshot, nil
+}
@@ -0,0 +1,71 @@
+package natty
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("WaitForConsistency", func() {
+	var (
+		n1 *Natty
+		n2 *replicaNatty
+	)
+
+	BeforeEach(func() {
+		var err error
+
+		n1, err = New(NewConfig())
+		Expect(err).ToNot(HaveOccurred())
+
+		n, err := New(NewConfig())
+		Expect(err).ToNot(HaveOccurred())
+
+		n2 = &replicaNatty{Natty: n}
+	})
+
+	It("should return once a replica has caught up", func() {
+		bucket, _, _ := NewKVSet()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		Expect(n1.CreateBucket(ctx, bucket, 0)).To(Succeed())
+		Expect((&Replicator{}).Start(ctx, n1, n2, bucket)).To(Succeed())
+
+		for i := 0; i < 10; i++ {
+			Expect(n1.Put(ctx, bucket, "key"+strconv.Itoa(i), []byte(strconv.Itoa(i)))).To(Succeed())
+		}
+
+		waitCtx, waitCancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer waitCancel()
+
+		Expect(WaitForConsistency(waitCtx, n1, n2, bucket)).To(Succeed())
+
+		data, err := n2.Get(ctx, bucket, "key9")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(Equal([]byte("9")))
+	})
+
+	It("should treat missing buckets as empty", func() {
+		bucket, _, _ := NewKVSet()
+
+		Expect(WaitForConsistency(context.Background(), n1, n2, bucket)).To(Succeed())
+	})
+
+	It("should error if the buckets do not become consistent", func() {
+		bucket, key, value := NewKVSet()
+
+		Expect(n1.Put(context.Background(), bucket, key, value)).To(Succeed())
+
+		ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+		defer cancel()
+
+		err := WaitForConsistency(ctx, n1, n2, bucket)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("1 added, 0 removed"))
+	})
+})
@@ -0,0 +1,128 @@
+package natty
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/nats-io/nats.go"
+	"github.com/pkg/errors"
+)
+
+// Increment atomically adds delta to the integer counter stored at key and
+// returns the new value. The counter is created (with a value of delta) if it
+// does not exist; the bucket will be auto-created if it does not exist.
+//
+// Counters are stored as decimal ASCII strings (ie. "42") so that they can be
+// read with Get() or inspected via the NATS CLI. Increment performs a
+// read-modify-write loop using Update() (compare-and-set on the key revision)
+// and retries until the write succeeds or the context is cancelled (see
+// updateCAS()).
+func (n *Natty) Increment(ctx context.Context, bucket, key string, delta int64) (int64, error) {
+	var value int64
+
+	err := n.updateCAS(ctx, bucket, key, func(current []byte, exists bool) ([]byte, error) {
+		value = delta
+
+		if exists {
+			counter, err := strconv.ParseInt(string(current), 10, 64)
+			if err != nil {
+				return nil, errors.Wrap(err, "existing value is not a valid counter")
+			}
+
+			value += counter
+		}
+
+		return encodeCounter(value), nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return value, nil
+}
+
+// Decrement atomically subtracts delta from the integer counter stored at key
+// and returns the new value. See Increment() for details.
+func (n *Natty) Decrement(ctx context.Context, bucket, key string, delta int64) (int64, error) {
+	return n.Increment(ctx, bucket, key, -delta)
+}
+
+func encodeCounter(value int64) []byte {
+	return []byte(strconv.FormatInt(value, 10))
+}
+
+// updateCAS performs a compare-and-set read-modify-write of key: update is
+// called with the current value (exists is false if the key does not exist)
+// and returns the value to write, which is written via Create() or Update()
+// on the revision that was read. If the key changes in the meantime, it is
+// re-read and update is called again until the write succeeds, update
+// returns an error or ctx is done. Reads and writes go through runKV() (and
+// are thus retried); the bucket will be auto-created if it does not exist.
+func (n *Natty) updateCAS(ctx context.Context, bucket, key string, update func(current []byte, exists bool) ([]byte, error)) (err error) {
+	ctx, done := n.trackKV(ctx, KVOpUpdate, bucket, key)
+	defer done(&err)
+
+	if n.isClosed() {
+		return ErrConnectionClosed
+	}
+
+	kv, err := n.getBucket(ctx, bucket, true, 0)
+	if err != nil {
+		return errors.Wrap(err, "unable to fetch bucket")
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		var kve nats.KeyValueEntry
+
+		err := n.runKV(ctx, bucket, func() (err error) {
+			kve, err = kv.Get(key)
+			return err
+		})
+		if err != nil && err != nats.ErrKeyNotFound {
+			return errors.Wrap(err, "unable to fetch key")
+		}
+
+		exists := err == nil
+
+		var current []byte
+
+		if exists {
+			current = kve.Value()
+		}
+
+		value, err := update(current, exists)
+		if err != nil {
+			return err
+		}
+
+		err = n.runKV(ctx, bucket, func() error {
+			if !exists {
+				_, err := kv.Create(key, value)
+				return err
+			}
+
+			_, err := kv.Update(key, value, kve.Revision())
+			return err
+		})
+		if err != nil {
+			if isWrongLastSequence(err) {
+				// Someone else wrote the key since we read it; try again
+				continue
+			}
+
+			return errors.Wrap(err, "unable to write key")
+		}
+
+		return nil
+	}
+}
+
+// isWrongLastSequence returns true if err is a CAS failure on a KV write
+func isWrongLastSequence(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "wrong last sequence")
+}
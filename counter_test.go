@@ -0,0 +1,91 @@
+package natty
+
+import (
+	"context"
+	"sync"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Counter", func() {
+	var (
+		cfg *Config
+		n   *Natty
+	)
+
+	BeforeEach(func() {
+		var err error
+
+		cfg = NewConfig()
+
+		n, err = New(cfg)
+
+		Expect(err).To(BeNil())
+		Expect(n).NotTo(BeNil())
+	})
+
+	Describe("Increment", func() {
+		It("should create counter if it does not exist", func() {
+			bucket, key, _ := NewKVSet()
+
+			value, err := n.Increment(context.Background(), bucket, key, 5)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(value).To(Equal(int64(5)))
+
+			data, err := n.Get(context.Background(), bucket, key)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal([]byte("5")))
+		})
+
+		It("should be safe for concurrent use", func() {
+			bucket, key, _ := NewKVSet()
+
+			wg := &sync.WaitGroup{}
+
+			for i := 0; i < 20; i++ {
+				wg.Add(1)
+
+				go func() {
+					defer GinkgoRecover()
+					defer wg.Done()
+
+					for j := 0; j < 50; j++ {
+						_, err := n.Increment(context.Background(), bucket, key, 1)
+						Expect(err).ToNot(HaveOccurred())
+					}
+				}()
+			}
+
+			wg.Wait()
+
+			data, err := n.Get(context.Background(), bucket, key)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal([]byte("1000")))
+		})
+
+		It("should error if existing value is not a counter", func() {
+			bucket, key, value := NewKVSet()
+
+			err := n.Put(context.Background(), bucket, key, value)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = n.Increment(context.Background(), bucket, key, 1)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("not a valid counter"))
+		})
+	})
+
+	Describe("Decrement", func() {
+		It("should decrement counter", func() {
+			bucket, key, _ := NewKVSet()
+
+			_, err := n.Increment(context.Background(), bucket, key, 10)
+			Expect(err).ToNot(HaveOccurred())
+
+			value, err := n.Decrement(context.Background(), bucket, key, 3)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(value).To(Equal(int64(7)))
+		})
+	})
+})
@@ -0,0 +1,118 @@
+package natty
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/csv"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/pkg/errors"
+)
+
+var (
+	// CSVHeader is the header row written by ExportToCSV()
+	CSVHeader = []string{"bucket", "key", "revision", "value_b64", "timestamp"}
+)
+
+// ExportToCSV writes the current contents of a bucket to w in CSV format. The
+// first row is a header (see CSVHeader), followed by one row per key (sorted by
+// key). Values are base64 encoded; timestamps are in RFC3339 (nano) format.
+// Keys are read via GetEntry(), so the same retries, timeouts and hooks apply.
+func (n *Natty) ExportToCSV(ctx context.Context, bucket string, w io.Writer) error {
+	if n.isClosed() {
+		return ErrConnectionClosed
+	}
+
+	keys, err := n.Keys(ctx, bucket)
+	if err != nil {
+		return errors.Wrap(err, "unable to fetch keys")
+	}
+
+	sort.Strings(keys)
+
+	cw := csv.NewWriter(w)
+
+	if err := cw.Write(CSVHeader); err != nil {
+		return errors.Wrap(err, "unable to write csv header")
+	}
+
+	for _, key := range keys {
+		entry, err := n.GetEntry(ctx, bucket, key)
+		if err != nil {
+			if err == nats.ErrKeyNotFound {
+				// Key was deleted since we listed keys
+				continue
+			}
+
+			return errors.Wrapf(err, "unable to fetch key '%s'", key)
+		}
+
+		row := []string{
+			bucket,
+			key,
+			strconv.FormatUint(entry.Revision, 10),
+			base64.StdEncoding.EncodeToString(entry.Value),
+			entry.Created.UTC().Format(time.RFC3339Nano),
+		}
+
+		if err := cw.Write(row); err != nil {
+			return errors.Wrapf(err, "unable to write csv row for key '%s'", key)
+		}
+	}
+
+	cw.Flush()
+
+	if err := cw.Error(); err != nil {
+		return errors.Wrap(err, "unable to flush csv writer")
+	}
+
+	return nil
+}
+
+// ImportFromCSV reads CSV data (in the format written by ExportToCSV()) from r
+// and calls Put() for every row. The bucket column is ignored - all keys are
+// written to the given bucket (which will be auto-created if it does not exist).
+func (n *Natty) ImportFromCSV(ctx context.Context, bucket string, r io.Reader) error {
+	if n.isClosed() {
+		return ErrConnectionClosed
+	}
+
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = len(CSVHeader)
+
+	header, err := cr.Read()
+	if err != nil {
+		return errors.Wrap(err, "unable to read csv header")
+	}
+
+	if !reflect.DeepEqual(header, CSVHeader) {
+		return errors.Errorf("unexpected csv header '%v'", header)
+	}
+
+	for {
+		row, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return errors.Wrap(err, "unable to read csv row")
+		}
+
+		value, err := base64.StdEncoding.DecodeString(row[3])
+		if err != nil {
+			return errors.Wrapf(err, "unable to decode value for key '%s'", row[1])
+		}
+
+		if err := n.Put(ctx, bucket, row[1], value); err != nil {
+			return errors.Wrapf(err, "unable to put key '%s'", row[1])
+		}
+	}
+
+	return nil
+}
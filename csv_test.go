@@ -0,0 +1,139 @@
+package natty
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("CSV", func() {
+	var (
+		cfg *Config
+		n   *Natty
+	)
+
+	BeforeEach(func() {
+		var err error
+
+		cfg = NewConfig()
+
+		n, err = New(cfg)
+
+		Expect(err).To(BeNil())
+		Expect(n).NotTo(BeNil())
+	})
+
+	Describe("ExportToCSV", func() {
+		It("should export all keys in a bucket", func() {
+			bucket, _, _ := NewKVSet()
+
+			for i := 0; i < 10; i++ {
+				err := n.Put(context.Background(), bucket, fmt.Sprintf("key-%d", i), []byte(fmt.Sprintf("value-%d", i)))
+				Expect(err).ToNot(HaveOccurred())
+			}
+
+			buf := &bytes.Buffer{}
+
+			err := n.ExportToCSV(context.Background(), bucket, buf)
+			Expect(err).ToNot(HaveOccurred())
+
+			rows, err := csv.NewReader(buf).ReadAll()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rows).To(HaveLen(11))
+			Expect(rows[0]).To(Equal(CSVHeader))
+
+			for i, row := range rows[1:] {
+				Expect(row[0]).To(Equal(bucket))
+				Expect(row[1]).To(Equal(fmt.Sprintf("key-%d", i)))
+
+				_, err := strconv.ParseUint(row[2], 10, 64)
+				Expect(err).ToNot(HaveOccurred())
+
+				value, err := base64.StdEncoding.DecodeString(row[3])
+				Expect(err).ToNot(HaveOccurred())
+				Expect(value).To(Equal([]byte(fmt.Sprintf("value-%d", i))))
+
+				_, err = time.Parse(time.RFC3339Nano, row[4])
+				Expect(err).ToNot(HaveOccurred())
+			}
+		})
+
+		It("should read keys via GetEntry()", func() {
+			accessed := make([]string, 0)
+
+			hooked, err := New(NewConfig().WithBucketHooks(BucketHooks{
+				AfterGet: func(bucket, key string, err error) {
+					accessed = append(accessed, key)
+				},
+			}))
+			Expect(err).ToNot(HaveOccurred())
+
+			bucket, _, _ := NewKVSet()
+
+			Expect(hooked.Put(context.Background(), bucket, "key-1", []byte("value-1"))).To(Succeed())
+			Expect(hooked.Put(context.Background(), bucket, "key-2", []byte("value-2"))).To(Succeed())
+
+			Expect(hooked.ExportToCSV(context.Background(), bucket, &bytes.Buffer{})).To(Succeed())
+			Expect(accessed).To(Equal([]string{"key-1", "key-2"}))
+		})
+
+		It("should error if bucket does not exist", func() {
+			bucket, _, _ := NewKVSet()
+
+			err := n.ExportToCSV(context.Background(), bucket, &bytes.Buffer{})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("ImportFromCSV", func() {
+		It("should restore exported bucket contents", func() {
+			srcBucket, _, _ := NewKVSet()
+			dstBucket, _, _ := NewKVSet()
+
+			for i := 0; i < 10; i++ {
+				err := n.Put(context.Background(), srcBucket, fmt.Sprintf("key-%d", i), []byte(fmt.Sprintf("value-%d", i)))
+				Expect(err).ToNot(HaveOccurred())
+			}
+
+			buf := &bytes.Buffer{}
+
+			err := n.ExportToCSV(context.Background(), srcBucket, buf)
+			Expect(err).ToNot(HaveOccurred())
+
+			err = n.ImportFromCSV(context.Background(), dstBucket, buf)
+			Expect(err).ToNot(HaveOccurred())
+
+			srcKeys, err := n.Keys(context.Background(), srcBucket)
+			Expect(err).ToNot(HaveOccurred())
+
+			dstKeys, err := n.Keys(context.Background(), dstBucket)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(dstKeys).To(ConsistOf(srcKeys))
+
+			for _, key := range srcKeys {
+				srcValue, err := n.Get(context.Background(), srcBucket, key)
+				Expect(err).ToNot(HaveOccurred())
+
+				dstValue, err := n.Get(context.Background(), dstBucket, key)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(dstValue).To(Equal(srcValue))
+			}
+		})
+
+		It("should error on bad header", func() {
+			bucket, _, _ := NewKVSet()
+
+			err := n.ImportFromCSV(context.Background(), bucket, strings.NewReader("foo,bar,baz,qux,quux\n"))
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("unexpected csv header"))
+		})
+	})
+})
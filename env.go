@@ -0,0 +1,141 @@
+package natty
+
+import (
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/pkg/errors"
+)
+
+const (
+	EnvPrefix          = "NATTY_"
+	EnvURL             = "NATTY_URL"
+	EnvMaxReconnects   = "NATTY_MAX_RECONNECTS"
+	EnvReconnectWaitMS = "NATTY_RECONNECT_WAIT_MS"
+	EnvTLSCert         = "NATTY_TLS_CERT"
+	EnvTLSKey          = "NATTY_TLS_KEY"
+	EnvTLSCA           = "NATTY_TLS_CA"
+	EnvCredsFile       = "NATTY_CREDS_FILE"
+	EnvToken           = "NATTY_TOKEN"
+)
+
+var knownEnvVars = map[string]bool{
+	EnvURL:             true,
+	EnvMaxReconnects:   true,
+	EnvReconnectWaitMS: true,
+	EnvTLSCert:         true,
+	EnvTLSKey:          true,
+	EnvTLSCA:           true,
+	EnvCredsFile:       true,
+	EnvToken:           true,
+}
+
+// NewConfigFromEnv creates a Config from NATTY_* environment variables:
+//
+//	NATTY_URL               comma separated NATS URLs (default: nats.DefaultURL)
+//	NATTY_MAX_RECONNECTS    max reconnect attempts, -1 = forever (default: -1)
+//	NATTY_RECONNECT_WAIT_MS wait between reconnects in ms (default: 2000)
+//	NATTY_TLS_CERT          TLS client certificate file (requires NATTY_TLS_KEY)
+//	NATTY_TLS_KEY           TLS client key file (requires NATTY_TLS_CERT)
+//	NATTY_TLS_CA            TLS CA certificate file
+//	NATTY_CREDS_FILE        chained credentials file (JWT + NKey seed)
+//	NATTY_TOKEN             token used for token auth
+//
+// Setting any of the NATTY_TLS_* variables enables TLS. Returns an error if a
+// value is invalid or if an unknown NATTY_* variable is set (ie. a typo).
+func NewConfigFromEnv() (*Config, error) {
+	if err := validateEnv(); err != nil {
+		return nil, err
+	}
+
+	cfg := &Config{
+		NatsURL:       []string{nats.DefaultURL},
+		MaxReconnects: DefaultMaxReconnects,
+		ReconnectWait: DefaultReconnectWait,
+		TLSCACertFile: os.Getenv(EnvTLSCA),
+		CredsFile:     os.Getenv(EnvCredsFile),
+		Token:         os.Getenv(EnvToken),
+	}
+
+	if v, ok := os.LookupEnv(EnvURL); ok {
+		cfg.NatsURL = make([]string, 0)
+
+		for _, url := range strings.Split(v, ",") {
+			url = strings.TrimSpace(url)
+
+			if url == "" {
+				return nil, errors.Errorf("%s contains an empty URL: '%s'", EnvURL, v)
+			}
+
+			cfg.NatsURL = append(cfg.NatsURL, url)
+		}
+	}
+
+	if v, ok := os.LookupEnv(EnvMaxReconnects); ok {
+		maxReconnects, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, errors.Wrapf(err, "%s must be an integer", EnvMaxReconnects)
+		}
+
+		if maxReconnects < -1 {
+			return nil, errors.Errorf("%s must be -1 (forever) or greater, got '%d'", EnvMaxReconnects, maxReconnects)
+		}
+
+		// An explicit 0 means never reconnect (rather than the default)
+		if maxReconnects == 0 {
+			maxReconnects = NoReconnect
+		}
+
+		cfg.MaxReconnects = maxReconnects
+	}
+
+	if v, ok := os.LookupEnv(EnvReconnectWaitMS); ok {
+		ms, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, errors.Wrapf(err, "%s must be an integer", EnvReconnectWaitMS)
+		}
+
+		if ms <= 0 {
+			return nil, errors.Errorf("%s must be greater than 0, got '%d'", EnvReconnectWaitMS, ms)
+		}
+
+		cfg.ReconnectWait = time.Duration(ms) * time.Millisecond
+	}
+
+	cfg.TLSClientCertFile = os.Getenv(EnvTLSCert)
+	cfg.TLSClientKeyFile = os.Getenv(EnvTLSKey)
+
+	if (cfg.TLSClientCertFile == "") != (cfg.TLSClientKeyFile == "") {
+		return nil, errors.Errorf("%s and %s must be set together", EnvTLSCert, EnvTLSKey)
+	}
+
+	if cfg.TLSClientCertFile != "" || cfg.TLSCACertFile != "" {
+		cfg.UseTLS = true
+	}
+
+	return cfg, nil
+}
+
+// validateEnv returns an error if an unknown NATTY_* variable is set
+func validateEnv() error {
+	unknown := make([]string, 0)
+
+	for _, kv := range os.Environ() {
+		name := strings.SplitN(kv, "=", 2)[0]
+
+		if strings.HasPrefix(name, EnvPrefix) && !knownEnvVars[name] {
+			unknown = append(unknown, name)
+		}
+	}
+
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return errors.Errorf("unknown environment variable(s): %s", strings.Join(unknown, ", "))
+	}
+
+	return nil
+}
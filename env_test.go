@@ -0,0 +1,102 @@
+package natty
+
+import (
+	"os"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("NewConfigFromEnv", func() {
+	setEnv := func(env map[string]string) {
+		for k, v := range env {
+			Expect(os.Setenv(k, v)).To(Succeed())
+		}
+	}
+
+	AfterEach(func() {
+		for k := range knownEnvVars {
+			Expect(os.Unsetenv(k)).To(Succeed())
+		}
+
+		Expect(os.Unsetenv("NATTY_UNKNOWN")).To(Succeed())
+	})
+
+	It("should populate Config from env vars", func() {
+		setEnv(map[string]string{
+			EnvURL:             "nats://a:4222, nats://b:4222",
+			EnvMaxReconnects:   "10",
+			EnvReconnectWaitMS: "500",
+			EnvTLSCert:         "/certs/client.pem",
+			EnvTLSKey:          "/certs/client-key.pem",
+			EnvTLSCA:           "/certs/ca.pem",
+			EnvCredsFile:       "/creds/user.creds",
+			EnvToken:           "secret",
+		})
+
+		cfg, err := NewConfigFromEnv()
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(cfg.NatsURL).To(Equal([]string{"nats://a:4222", "nats://b:4222"}))
+		Expect(cfg.MaxReconnects).To(Equal(10))
+		Expect(cfg.ReconnectWait).To(Equal(500 * time.Millisecond))
+		Expect(cfg.UseTLS).To(BeTrue())
+		Expect(cfg.TLSClientCertFile).To(Equal("/certs/client.pem"))
+		Expect(cfg.TLSClientKeyFile).To(Equal("/certs/client-key.pem"))
+		Expect(cfg.TLSCACertFile).To(Equal("/certs/ca.pem"))
+		Expect(cfg.CredsFile).To(Equal("/creds/user.creds"))
+		Expect(cfg.Token).To(Equal("secret"))
+	})
+
+	It("should use defaults for missing optional vars", func() {
+		cfg, err := NewConfigFromEnv()
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(cfg.NatsURL).To(Equal([]string{nats.DefaultURL}))
+		Expect(cfg.MaxReconnects).To(Equal(DefaultMaxReconnects))
+		Expect(cfg.ReconnectWait).To(Equal(DefaultReconnectWait))
+		Expect(cfg.UseTLS).To(BeFalse())
+		Expect(cfg.CredsFile).To(BeEmpty())
+		Expect(cfg.Token).To(BeEmpty())
+	})
+
+	It("should treat an explicit 0 as NoReconnect", func() {
+		setEnv(map[string]string{EnvMaxReconnects: "0"})
+
+		cfg, err := NewConfigFromEnv()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cfg.MaxReconnects).To(Equal(NoReconnect))
+	})
+
+	It("should error on invalid values", func() {
+		setEnv(map[string]string{EnvMaxReconnects: "lots"})
+
+		_, err := NewConfigFromEnv()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring(EnvMaxReconnects))
+
+		setEnv(map[string]string{EnvMaxReconnects: "1", EnvReconnectWaitMS: "-5"})
+
+		_, err = NewConfigFromEnv()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring(EnvReconnectWaitMS))
+	})
+
+	It("should error when TLS cert is set without key", func() {
+		setEnv(map[string]string{EnvTLSCert: "/certs/client.pem"})
+
+		_, err := NewConfigFromEnv()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring(EnvTLSKey))
+	})
+
+	It("should error on unknown NATTY_ vars", func() {
+		setEnv(map[string]string{"NATTY_UNKNOWN": "foo"})
+
+		_, err := NewConfigFromEnv()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("NATTY_UNKNOWN"))
+	})
+})
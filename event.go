@@ -0,0 +1,47 @@
+package natty
+
+import (
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// KeyValueEvent operations
+const (
+	KVEventOpPut    = "put"
+	KVEventOpDelete = "delete"
+	KVEventOpPurge  = "purge"
+)
+
+// KeyValueEvent describes a single change to a key; it is what KVCDC publishes
+// (as JSON) and what KVPipeline hands to every KVSink.
+type KeyValueEvent struct {
+	Bucket    string    `json:"bucket"`
+	Key       string    `json:"key"`
+	Value     []byte    `json:"value,omitempty"`
+	Revision  uint64    `json:"revision"`
+	Operation string    `json:"operation"`
+	Created   time.Time `json:"created"`
+}
+
+func newKeyValueEvent(entry *KVEntry) *KeyValueEvent {
+	return &KeyValueEvent{
+		Bucket:    entry.Bucket,
+		Key:       entry.Key,
+		Value:     entry.Value,
+		Revision:  entry.Revision,
+		Operation: eventOperation(entry.Operation),
+		Created:   entry.Created,
+	}
+}
+
+func eventOperation(op nats.KeyValueOp) string {
+	switch op {
+	case nats.KeyValueDelete:
+		return KVEventOpDelete
+	case nats.KeyValuePurge:
+		return KVEventOpPurge
+	default:
+		return KVEventOpPut
+	}
+}
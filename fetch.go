@@ -0,0 +1,172 @@
+package natty
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/pkg/errors"
+)
+
+const (
+	// fetchGracePeriod is how much longer the client waits for a pull request
+	// to complete than the expiry sent to the server
+	fetchGracePeriod = 100 * time.Millisecond
+
+	// minFetchExpires is the minimum expiry sent to the server when the expiry
+	// is derived from a context deadline
+	minFetchExpires = 10 * time.Millisecond
+)
+
+// FetchOptions configures a single pull request made via FetchWithOptions()
+type FetchOptions struct {
+	// MaxMessages is the max number of messages to fetch (default: FetchSize)
+	MaxMessages int
+
+	// MaxBytes is the max total size of fetched messages; 0 means unlimited.
+	// Requires NATS server v2.8.3+.
+	MaxBytes int64
+
+	// ExpiresIn is how long the server will wait for MaxMessages to become
+	// available before returning the messages it has (default: FetchTimeout).
+	// If ctx has an earlier deadline, the deadline is used instead.
+	ExpiresIn time.Duration
+
+	// NoWait returns immediately with whatever messages (if any) are
+	// available instead of waiting for MaxMessages; ExpiresIn is not sent to
+	// the server and only bounds the wait for its response
+	NoWait bool
+}
+
+// pullRequest is the JetStream API request for the next batch of messages
+type pullRequest struct {
+	Batch    int           `json:"batch"`
+	Expires  time.Duration `json:"expires,omitempty"`
+	MaxBytes int64         `json:"max_bytes,omitempty"`
+	NoWait   bool          `json:"no_wait,omitempty"`
+}
+
+// FetchWithOptions performs a single pull request against a (pull) consumer
+// and returns the fetched messages; fewer than MaxMessages (including none)
+// are returned if the request expires or, with NoWait, if fewer messages are
+// available. Fetched messages must be explicitly ACK'd or NAK'd.
+func (n *Natty) FetchWithOptions(ctx context.Context, stream, consumer string, opts FetchOptions) ([]*nats.Msg, error) {
+	if n.isClosed() {
+		return nil, ErrConnectionClosed
+	}
+
+	if stream == "" {
+		return nil, ErrEmptyStreamName
+	}
+
+	if consumer == "" {
+		return nil, ErrEmptyConsumerName
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	req := &pullRequest{
+		Batch:    opts.MaxMessages,
+		MaxBytes: opts.MaxBytes,
+		NoWait:   opts.NoWait,
+	}
+
+	if req.Batch <= 0 {
+		req.Batch = n.FetchSize
+	}
+
+	expires := opts.ExpiresIn
+
+	if expires <= 0 {
+		expires = n.FetchTimeout
+	}
+
+	// Propagate the context deadline so the server gives up (and returns a
+	// partial batch) before the caller does
+	if deadline, ok := ctx.Deadline(); ok {
+		if remaining := time.Until(deadline) - fetchGracePeriod; remaining < expires {
+			expires = remaining
+		}
+
+		if expires < minFetchExpires {
+			expires = minFetchExpires
+		}
+	}
+
+	// A NoWait request with an expiry waits out the expiry if no messages
+	// are available (NATS server 2.8); expires then only bounds the wait for
+	// the server's response
+	if !opts.NoWait {
+		req.Expires = expires
+	}
+
+	data, err := json.Marshal(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to marshal pull request")
+	}
+
+	// The inbox must be on the connection the request is sent on
+	nc := n.getConn()
+	inbox := nats.NewInbox()
+
+	sub, err := nc.SubscribeSync(inbox)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to subscribe to inbox")
+	}
+
+	defer func() {
+		if err := sub.Unsubscribe(); err != nil {
+			errorw(n.log, "unable to unsubscribe from fetch inbox", "error", err)
+		}
+	}()
+
+	subject := n.jsAPISubject("CONSUMER.MSG.NEXT." + stream + "." + consumer)
+
+	if err := nc.PublishRequest(subject, inbox, data); err != nil {
+		return nil, errors.Wrap(err, "unable to send pull request")
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, expires+fetchGracePeriod)
+	defer cancel()
+
+	msgs := make([]*nats.Msg, 0, req.Batch)
+
+	for len(msgs) < req.Batch {
+		msg, err := sub.NextMsgWithContext(waitCtx)
+		if err != nil {
+			// Caller's context is done - return what we have so far
+			if ctx.Err() != nil {
+				return msgs, ctx.Err()
+			}
+
+			// Our wait expired without a terminal status from the server
+			if err == context.DeadlineExceeded {
+				return msgs, nil
+			}
+
+			return msgs, errors.Wrap(err, "unable to fetch message")
+		}
+
+		// Status messages (ie. "404 No Messages", "408 Request Timeout" or
+		// "409 Max Bytes Exceeded") end the request
+		if status := msg.Header.Get("Status"); status != "" && len(msg.Data) == 0 {
+			switch status {
+			case "100":
+				// Heartbeat
+				continue
+			case "404", "408", "409":
+				return msgs, nil
+			default:
+				return msgs, errors.Errorf("unexpected pull request status: %s %s",
+					status, msg.Header.Get("Description"))
+			}
+		}
+
+		msgs = append(msgs, msg)
+	}
+
+	return msgs, nil
+}
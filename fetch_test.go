@@ -0,0 +1,114 @@
+package natty
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("FetchWithOptions", func() {
+	var (
+		n            *Natty
+		streamName   string
+		consumerName string
+	)
+
+	BeforeEach(func() {
+		var err error
+
+		n, err = New(NewConfig())
+		Expect(err).ToNot(HaveOccurred())
+
+		streamName = strings.ToUpper(GetRandomName("test", 1))
+		consumerName = GetRandomName("test", 1)
+		testStreams = append(testStreams, streamName)
+
+		Expect(n.CreateStream(context.Background(), streamName, []string{streamName + ".*"})).To(Succeed())
+		Expect(n.CreateConsumer(context.Background(), streamName, consumerName)).To(Succeed())
+	})
+
+	publish := func(count int) {
+		for i := 0; i < count; i++ {
+			_, err := n.js.Publish(streamName+".foo", []byte(strconv.Itoa(i)))
+			Expect(err).ToNot(HaveOccurred())
+		}
+	}
+
+	fetch := func(opts FetchOptions) (int, time.Duration) {
+		start := time.Now()
+
+		msgs, err := n.FetchWithOptions(context.Background(), streamName, consumerName, opts)
+		Expect(err).ToNot(HaveOccurred())
+
+		for _, m := range msgs {
+			Expect(m.Ack()).To(Succeed())
+		}
+
+		return len(msgs), time.Since(start)
+	}
+
+	Context("with NoWait", func() {
+		It("should return available messages immediately", func() {
+			publish(3)
+
+			count, elapsed := fetch(FetchOptions{MaxMessages: 10, ExpiresIn: 5 * time.Second, NoWait: true})
+			Expect(count).To(Equal(3))
+			Expect(elapsed).To(BeNumerically("<", time.Second))
+		})
+
+		It("should return no messages immediately if none are available", func() {
+			count, elapsed := fetch(FetchOptions{MaxMessages: 10, ExpiresIn: 5 * time.Second, NoWait: true})
+			Expect(count).To(Equal(0))
+			Expect(elapsed).To(BeNumerically("<", time.Second))
+		})
+	})
+
+	Context("without NoWait", func() {
+		It("should return a partial batch once the request expires", func() {
+			publish(3)
+
+			count, elapsed := fetch(FetchOptions{MaxMessages: 10, ExpiresIn: 500 * time.Millisecond})
+			Expect(count).To(Equal(3))
+			Expect(elapsed).To(BeNumerically(">=", 400*time.Millisecond))
+		})
+
+		It("should return no messages once the request expires if none are available", func() {
+			count, elapsed := fetch(FetchOptions{MaxMessages: 10, ExpiresIn: 500 * time.Millisecond})
+			Expect(count).To(Equal(0))
+			Expect(elapsed).To(BeNumerically(">=", 400*time.Millisecond))
+			Expect(elapsed).To(BeNumerically("<", 2*time.Second))
+		})
+
+		It("should return as soon as MaxMessages are fetched", func() {
+			publish(5)
+
+			count, elapsed := fetch(FetchOptions{MaxMessages: 5, ExpiresIn: 5 * time.Second})
+			Expect(count).To(Equal(5))
+			Expect(elapsed).To(BeNumerically("<", time.Second))
+		})
+	})
+
+	It("should honor the context deadline", func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+		defer cancel()
+
+		start := time.Now()
+
+		msgs, err := n.FetchWithOptions(ctx, streamName, consumerName, FetchOptions{MaxMessages: 10, ExpiresIn: time.Minute})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(msgs).To(BeEmpty())
+		Expect(time.Since(start)).To(BeNumerically("<", time.Second))
+	})
+
+	It("should error on empty stream or consumer names", func() {
+		_, err := n.FetchWithOptions(context.Background(), "", consumerName, FetchOptions{})
+		Expect(err).To(Equal(ErrEmptyStreamName))
+
+		_, err = n.FetchWithOptions(context.Background(), streamName, "", FetchOptions{})
+		Expect(err).To(Equal(ErrEmptyConsumerName))
+	})
+})
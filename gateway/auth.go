@@ -0,0 +1,53 @@
+package gateway
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	BasicAuthRealm = "natty"
+)
+
+// WithBasicAuth requires requests to authenticate via HTTP basic auth using
+// one of the given username/password pairs (key = username).
+func WithBasicAuth(users map[string]string) Option {
+	return func(g *KVGateway) {
+		if g.users == nil {
+			g.users = make(map[string]string)
+		}
+
+		for u, p := range users {
+			g.users[u] = p
+		}
+	}
+}
+
+// authorize returns true if the request may proceed; otherwise a 401 response
+// has been written.
+func (g *KVGateway) authorize(w http.ResponseWriter, r *http.Request) bool {
+	if len(g.users) == 0 {
+		return true
+	}
+
+	username, password, ok := r.BasicAuth()
+	if ok {
+		expected, found := g.users[username]
+
+		// Compare hashes so that the comparison does not leak password length
+		given := sha256.Sum256([]byte(password))
+		want := sha256.Sum256([]byte(expected))
+
+		if subtle.ConstantTimeCompare(given[:], want[:]) == 1 && found {
+			return true
+		}
+	}
+
+	w.Header().Set("WWW-Authenticate", `Basic realm="`+BasicAuthRealm+`", charset="UTF-8"`)
+	writeError(w, http.StatusUnauthorized, errors.New("unauthorized"))
+
+	return false
+}
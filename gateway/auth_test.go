@@ -0,0 +1,61 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/batchcorp/natty/nattytest"
+)
+
+var _ = Describe("BasicAuth", func() {
+	var (
+		server *httptest.Server
+	)
+
+	BeforeEach(func() {
+		fake := nattytest.NewFakeNatty()
+		Expect(fake.Put(context.Background(), "bucket", "foo", []byte("bar"))).To(Succeed())
+
+		server = httptest.NewServer(NewKVGateway(fake, nil, WithBasicAuth(map[string]string{"user": "secret"})))
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	get := func(auth ...string) *http.Response {
+		req, err := http.NewRequest(http.MethodGet, server.URL+"/kv/bucket/foo", nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		if len(auth) == 2 {
+			req.SetBasicAuth(auth[0], auth[1])
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		Expect(err).ToNot(HaveOccurred())
+
+		resp.Body.Close()
+
+		return resp
+	}
+
+	It("should return 401 without credentials", func() {
+		resp := get()
+		Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+		Expect(resp.Header.Get("WWW-Authenticate")).To(ContainSubstring("Basic"))
+	})
+
+	It("should return 401 with invalid credentials", func() {
+		Expect(get("user", "wrong").StatusCode).To(Equal(http.StatusUnauthorized))
+		Expect(get("other", "secret").StatusCode).To(Equal(http.StatusUnauthorized))
+		Expect(get("other", "").StatusCode).To(Equal(http.StatusUnauthorized))
+	})
+
+	It("should return 200 with valid credentials", func() {
+		Expect(get("user", "secret").StatusCode).To(Equal(http.StatusOK))
+	})
+})
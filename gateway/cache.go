@@ -0,0 +1,96 @@
+package gateway
+
+import (
+	"sync"
+	"time"
+
+	"github.com/batchcorp/natty"
+)
+
+// WithGatewayCache caches values fetched via GET /kv/{bucket}/{key} in memory
+// for ttl. Writes made through the gateway invalidate the cached value; writes
+// made by other clients may not be visible until the cached value expires.
+func WithGatewayCache(ttl time.Duration) Option {
+	return func(g *KVGateway) {
+		g.cache = newEntryCache(ttl)
+	}
+}
+
+type cachedEntry struct {
+	entry   *natty.KVEntry
+	expires time.Time
+}
+
+type entryCache struct {
+	ttl time.Duration
+
+	// now is overridden in tests
+	now func() time.Time
+
+	mutex   *sync.RWMutex
+	entries map[string]*cachedEntry
+}
+
+func newEntryCache(ttl time.Duration) *entryCache {
+	return &entryCache{
+		ttl:     ttl,
+		now:     time.Now,
+		mutex:   &sync.RWMutex{},
+		entries: make(map[string]*cachedEntry),
+	}
+}
+
+// get returns a cached entry; always misses if caching is disabled
+func (c *entryCache) get(bucket, key string) (*natty.KVEntry, bool) {
+	if c == nil {
+		return nil, false
+	}
+
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	cached, ok := c.entries[cacheKey(bucket, key)]
+	if !ok || c.now().After(cached.expires) {
+		return nil, false
+	}
+
+	return cached.entry, true
+}
+
+func (c *entryCache) set(bucket, key string, entry *natty.KVEntry) {
+	if c == nil {
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := c.now()
+
+	// Expired entries are only removed on write to keep get() lock-free of writes
+	for k, v := range c.entries {
+		if now.After(v.expires) {
+			delete(c.entries, k)
+		}
+	}
+
+	c.entries[cacheKey(bucket, key)] = &cachedEntry{
+		entry:   entry,
+		expires: now.Add(c.ttl),
+	}
+}
+
+func (c *entryCache) invalidate(bucket, key string) {
+	if c == nil {
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	delete(c.entries, cacheKey(bucket, key))
+}
+
+func cacheKey(bucket, key string) string {
+	return bucket + "/" + key
+}
@@ -0,0 +1,88 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/batchcorp/natty"
+	"github.com/batchcorp/natty/nattytest"
+)
+
+var _ = Describe("Cache", func() {
+	var (
+		fake *nattytest.FakeNatty
+		mock *nattytest.MockClient
+		g    *KVGateway
+		now  time.Time
+	)
+
+	BeforeEach(func() {
+		fake = nattytest.NewFakeNatty()
+		Expect(fake.Put(context.Background(), "bucket", "foo", []byte("bar"))).To(Succeed())
+
+		// Record calls while delegating to the fake
+		mock = nattytest.NewMockClient()
+		mock.GetEntryFunc = fake.GetEntry
+		mock.PutFunc = fake.Put
+
+		g = NewKVGateway(mock, nil, WithGatewayCache(time.Minute))
+
+		now = time.Now()
+		g.cache.now = func() time.Time { return now }
+	})
+
+	serve := func(method string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/kv/bucket/foo", strings.NewReader(body))
+		rec := httptest.NewRecorder()
+
+		g.ServeHTTP(rec, req)
+
+		return rec
+	}
+
+	It("should serve a second GET within the TTL from cache", func() {
+		Expect(serve(http.MethodGet, "").Body.String()).To(Equal("bar"))
+		Expect(serve(http.MethodGet, "").Body.String()).To(Equal("bar"))
+
+		Expect(mock.CallsTo("GetEntry")).To(HaveLen(1))
+	})
+
+	It("should fetch again after the TTL expires", func() {
+		serve(http.MethodGet, "")
+
+		now = now.Add(time.Minute + time.Second)
+
+		serve(http.MethodGet, "")
+
+		Expect(mock.CallsTo("GetEntry")).To(HaveLen(2))
+	})
+
+	It("should invalidate the cached value on write", func() {
+		serve(http.MethodGet, "")
+
+		Expect(serve(http.MethodPut, "updated").Code).To(Equal(http.StatusNoContent))
+
+		rec := serve(http.MethodGet, "")
+		Expect(rec.Body.String()).To(Equal("updated"))
+		Expect(rec.Header().Get(HeaderRevision)).To(Equal("2"))
+
+		Expect(mock.CallsTo("GetEntry")).To(HaveLen(2))
+	})
+
+	It("should not cache errors", func() {
+		mock.GetEntryFunc = func(ctx context.Context, bucket, key string) (*natty.KVEntry, error) {
+			return fake.GetEntry(ctx, bucket, "missing")
+		}
+
+		Expect(serve(http.MethodGet, "").Code).To(Equal(http.StatusNotFound))
+		Expect(serve(http.MethodGet, "").Code).To(Equal(http.StatusNotFound))
+
+		Expect(mock.CallsTo("GetEntry")).To(HaveLen(2))
+	})
+})
@@ -0,0 +1,63 @@
+package gateway
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	// CORSMaxAge is how long (in seconds) browsers may cache preflight results
+	CORSMaxAge = 600
+)
+
+// WithCORSOrigins allows cross-origin (browser) requests from the given
+// origins (ie. "https://example.com"); use "*" to allow any origin.
+func WithCORSOrigins(origins ...string) Option {
+	return func(g *KVGateway) {
+		if g.corsOrigins == nil {
+			g.corsOrigins = make(map[string]bool)
+		}
+
+		for _, o := range origins {
+			g.corsOrigins[o] = true
+		}
+	}
+}
+
+// handleCORS sets CORS response headers for allowed origins; returns true if
+// the request was a preflight request that has been fully handled.
+func (g *KVGateway) handleCORS(w http.ResponseWriter, r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+
+	if origin == "" || len(g.corsOrigins) == 0 {
+		return false
+	}
+
+	w.Header().Add("Vary", "Origin")
+
+	if !g.corsOrigins[origin] && !g.corsOrigins["*"] {
+		return false
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", origin)
+	w.Header().Set("Access-Control-Expose-Headers", HeaderRevision)
+
+	// Preflight
+	if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+		w.Header().Set("Access-Control-Allow-Methods", strings.Join([]string{
+			http.MethodGet, http.MethodPut, http.MethodPost, http.MethodDelete,
+		}, ", "))
+
+		if headers := r.Header.Get("Access-Control-Request-Headers"); headers != "" {
+			w.Header().Set("Access-Control-Allow-Headers", headers)
+		}
+
+		w.Header().Set("Access-Control-Max-Age", strconv.Itoa(CORSMaxAge))
+		w.WriteHeader(http.StatusNoContent)
+
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,80 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/batchcorp/natty/nattytest"
+)
+
+var _ = Describe("CORS", func() {
+	var (
+		fake   *nattytest.FakeNatty
+		server *httptest.Server
+	)
+
+	BeforeEach(func() {
+		fake = nattytest.NewFakeNatty()
+		server = httptest.NewServer(NewKVGateway(fake, nil, WithCORSOrigins("https://example.com")))
+
+		Expect(fake.Put(context.Background(), "bucket", "foo", []byte("bar"))).To(Succeed())
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	do := func(method, origin string, headers ...string) *http.Response {
+		req, err := http.NewRequest(method, server.URL+"/kv/bucket/foo", nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		req.Header.Set("Origin", origin)
+
+		for i := 0; i+1 < len(headers); i += 2 {
+			req.Header.Set(headers[i], headers[i+1])
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		Expect(err).ToNot(HaveOccurred())
+
+		resp.Body.Close()
+
+		return resp
+	}
+
+	It("should set CORS headers for an allowed origin", func() {
+		resp := do(http.MethodGet, "https://example.com")
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(resp.Header.Get("Access-Control-Allow-Origin")).To(Equal("https://example.com"))
+		Expect(resp.Header.Get("Access-Control-Expose-Headers")).To(Equal(HeaderRevision))
+	})
+
+	It("should not set CORS headers for other origins", func() {
+		resp := do(http.MethodGet, "https://evil.com")
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(resp.Header.Get("Access-Control-Allow-Origin")).To(BeEmpty())
+	})
+
+	It("should handle preflight requests", func() {
+		resp := do(http.MethodOptions, "https://example.com",
+			"Access-Control-Request-Method", http.MethodPut,
+			"Access-Control-Request-Headers", "Content-Type")
+
+		Expect(resp.StatusCode).To(Equal(http.StatusNoContent))
+		Expect(resp.Header.Get("Access-Control-Allow-Origin")).To(Equal("https://example.com"))
+		Expect(resp.Header.Get("Access-Control-Allow-Methods")).To(ContainSubstring(http.MethodPut))
+		Expect(resp.Header.Get("Access-Control-Allow-Headers")).To(Equal("Content-Type"))
+	})
+
+	It("should allow any origin with a wildcard", func() {
+		server.Close()
+		server = httptest.NewServer(NewKVGateway(fake, nil, WithCORSOrigins("*")))
+
+		resp := do(http.MethodGet, "https://anywhere.com")
+		Expect(resp.Header.Get("Access-Control-Allow-Origin")).To(Equal("https://anywhere.com"))
+	})
+})
@@ -0,0 +1,384 @@
+// Package gateway exposes natty KV buckets over HTTP.
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/nats-io/nats.go"
+	"github.com/pkg/errors"
+
+	"github.com/batchcorp/natty"
+)
+
+const (
+	DefaultPrefix      = "/kv/"
+	DefaultMaxBodySize = 1024 * 1024 // NATS default max payload
+
+	HeaderRevision = "X-Natty-Revision"
+
+	// WatchPath is the path segment (following the bucket) of the watch endpoint
+	WatchPath = "watch"
+)
+
+// KVGateway is an http.Handler that exposes natty KV buckets as a REST API:
+//
+//	GET    /kv/{bucket}        list keys in bucket (JSON)
+//	GET    /kv/{bucket}/{key}  fetch value
+//	PUT    /kv/{bucket}/{key}  write value (creates bucket if necessary)
+//	POST   /kv/{bucket}/{key}  create value; 409 if key already exists
+//	DELETE /kv/{bucket}/{key}  delete key
+//	GET    /kv/{bucket}/watch  stream changes as Server-Sent Events
+//
+// Values are transferred as raw request/response bodies; the revision of a
+// fetched value is returned in the X-Natty-Revision header.
+//
+// NOTE: Because of the watch endpoint, a key named "watch" cannot be fetched
+// via GET.
+type KVGateway struct {
+	// Prefix is the path prefix the gateway is mounted on (default: /kv/)
+	Prefix string
+
+	// MaxBodySize is the maximum accepted value size (default: 1MB)
+	MaxBodySize int64
+
+	client natty.Client
+	log    natty.Logger
+
+	corsOrigins map[string]bool
+	users       map[string]string
+	limiter     *ipRateLimiter
+	cache       *entryCache
+}
+
+// Option configures a KVGateway
+type Option func(*KVGateway)
+
+// ListKeysResponse is returned by GET /kv/{bucket}
+type ListKeysResponse struct {
+	Bucket string   `json:"bucket"`
+	Keys   []string `json:"keys"`
+}
+
+// WatchEvent is sent as the data of each Server-Sent Event emitted by
+// GET /kv/{bucket}/watch; Value is base64 encoded.
+type WatchEvent struct {
+	Bucket    string `json:"bucket"`
+	Key       string `json:"key"`
+	Value     []byte `json:"value,omitempty"`
+	Revision  uint64 `json:"revision"`
+	Operation string `json:"operation"`
+}
+
+// ErrorResponse is returned for all non-2xx responses
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// NewKVGateway creates a KVGateway using given client and options; if logger
+// is nil, a NoOpLogger is used.
+func NewKVGateway(client natty.Client, logger natty.Logger, opts ...Option) *KVGateway {
+	if logger == nil {
+		logger = &natty.NoOpLogger{}
+	}
+
+	g := &KVGateway{
+		Prefix:      DefaultPrefix,
+		MaxBodySize: DefaultMaxBodySize,
+		client:      client,
+		log:         logger,
+	}
+
+	for _, opt := range opts {
+		opt(g)
+	}
+
+	return g
+}
+
+// ServeHTTP implements http.Handler
+func (g *KVGateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if g.handleCORS(w, r) {
+		return
+	}
+
+	if !g.authorize(w, r) {
+		return
+	}
+
+	if !g.limiter.allow(w, r) {
+		return
+	}
+
+	bucket, key, ok := g.parsePath(r.URL.Path)
+	if !ok {
+		writeError(w, http.StatusNotFound, errors.New("not found"))
+		return
+	}
+
+	if key == "" {
+		switch r.Method {
+		case http.MethodGet:
+			g.listKeys(w, r, bucket)
+		default:
+			w.Header().Set("Allow", http.MethodGet)
+			writeError(w, http.StatusMethodNotAllowed, errors.Errorf("method '%s' not allowed", r.Method))
+		}
+
+		return
+	}
+
+	if key == WatchPath && r.Method == http.MethodGet {
+		g.watch(w, r, bucket)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		g.get(w, r, bucket, key)
+	case http.MethodPut:
+		g.put(w, r, bucket, key)
+	case http.MethodPost:
+		g.create(w, r, bucket, key)
+	case http.MethodDelete:
+		g.delete(w, r, bucket, key)
+	default:
+		w.Header().Set("Allow", strings.Join([]string{http.MethodGet, http.MethodPut, http.MethodPost, http.MethodDelete}, ", "))
+		writeError(w, http.StatusMethodNotAllowed, errors.Errorf("method '%s' not allowed", r.Method))
+	}
+}
+
+func (g *KVGateway) listKeys(w http.ResponseWriter, r *http.Request, bucket string) {
+	keys, err := g.client.Keys(r.Context(), bucket)
+	if err != nil {
+		g.writeClientError(w, err)
+		return
+	}
+
+	sort.Strings(keys)
+
+	writeJSON(w, http.StatusOK, &ListKeysResponse{Bucket: bucket, Keys: keys})
+}
+
+func (g *KVGateway) get(w http.ResponseWriter, r *http.Request, bucket, key string) {
+	entry, ok := g.cache.get(bucket, key)
+	if !ok {
+		var err error
+
+		entry, err = g.client.GetEntry(r.Context(), bucket, key)
+		if err != nil {
+			g.writeClientError(w, err)
+			return
+		}
+
+		g.cache.set(bucket, key, entry)
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set(HeaderRevision, strconv.FormatUint(entry.Revision, 10))
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := w.Write(entry.Value); err != nil {
+		g.errorw("unable to write response", "bucket", bucket, "key", key, "error", err)
+	}
+}
+
+func (g *KVGateway) put(w http.ResponseWriter, r *http.Request, bucket, key string) {
+	data, ok := g.readBody(w, r)
+	if !ok {
+		return
+	}
+
+	err := g.client.Put(r.Context(), bucket, key, data)
+
+	// Invalidate regardless of outcome; the write may have been applied
+	g.cache.invalidate(bucket, key)
+
+	if err != nil {
+		g.writeClientError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (g *KVGateway) create(w http.ResponseWriter, r *http.Request, bucket, key string) {
+	data, ok := g.readBody(w, r)
+	if !ok {
+		return
+	}
+
+	err := g.client.Create(r.Context(), bucket, key, data)
+
+	g.cache.invalidate(bucket, key)
+
+	if err != nil {
+		g.writeClientError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (g *KVGateway) delete(w http.ResponseWriter, r *http.Request, bucket, key string) {
+	err := g.client.Delete(r.Context(), bucket, key)
+
+	g.cache.invalidate(bucket, key)
+
+	if err != nil {
+		g.writeClientError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// watch streams changes to the bucket as Server-Sent Events until the client
+// disconnects; the "key" query parameter may be used to restrict the watch to
+// a key or wildcard pattern (default: all keys). Each event is named after the
+// operation (put, delete or purge) and its id is the entry revision.
+func (g *KVGateway) watch(w http.ResponseWriter, r *http.Request, bucket string) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, errors.New("streaming is not supported"))
+		return
+	}
+
+	key := r.URL.Query().Get("key")
+	if key == "" {
+		key = ">"
+	}
+
+	entries, err := g.client.Watch(r.Context(), bucket, key)
+	if err != nil {
+		g.writeClientError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case entry, ok := <-entries:
+			if !ok {
+				return
+			}
+
+			if err := writeEvent(w, entry); err != nil {
+				g.errorw("unable to write watch event", "bucket", bucket, "error", err)
+				return
+			}
+
+			flusher.Flush()
+		}
+	}
+}
+
+// parsePath splits /{prefix}/{bucket}[/{key}] into bucket and key; keys may
+// contain slashes.
+func (g *KVGateway) parsePath(path string) (bucket, key string, ok bool) {
+	if !strings.HasPrefix(path, g.Prefix) {
+		return "", "", false
+	}
+
+	parts := strings.SplitN(strings.TrimPrefix(path, g.Prefix), "/", 2)
+
+	if parts[0] == "" {
+		return "", "", false
+	}
+
+	if len(parts) == 2 {
+		key = parts[1]
+	}
+
+	return parts[0], key, true
+}
+
+func (g *KVGateway) readBody(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	data, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, g.MaxBodySize))
+	if err != nil {
+		writeError(w, http.StatusRequestEntityTooLarge, errors.Wrap(err, "unable to read request body"))
+		return nil, false
+	}
+
+	return data, true
+}
+
+// writeClientError maps natty/NATS errors to HTTP status codes
+func (g *KVGateway) writeClientError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, nats.ErrKeyNotFound), errors.Is(err, nats.ErrBucketNotFound):
+		writeError(w, http.StatusNotFound, err)
+	case errors.Is(err, natty.ErrKeyExists):
+		writeError(w, http.StatusConflict, err)
+	default:
+		g.errorw("kv gateway request failed", "error", err)
+		writeError(w, http.StatusInternalServerError, err)
+	}
+}
+
+func writeEvent(w http.ResponseWriter, entry *natty.KVEntry) error {
+	data, err := json.Marshal(&WatchEvent{
+		Bucket:    entry.Bucket,
+		Key:       entry.Key,
+		Value:     entry.Value,
+		Revision:  entry.Revision,
+		Operation: operationName(entry.Operation),
+	})
+	if err != nil {
+		return errors.Wrap(err, "unable to marshal event")
+	}
+
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", entry.Revision, operationName(entry.Operation), data)
+
+	return err
+}
+
+func operationName(op nats.KeyValueOp) string {
+	switch op {
+	case nats.KeyValueDelete:
+		return "delete"
+	case nats.KeyValuePurge:
+		return "purge"
+	default:
+		return "put"
+	}
+}
+
+// errorw logs via the gateway logger's Errorw if it is a
+// natty.StructuredLogger; otherwise fields are appended as key=value.
+func (g *KVGateway) errorw(msg string, keysAndValues ...interface{}) {
+	if sl, ok := g.log.(natty.StructuredLogger); ok {
+		sl.Errorw(msg, keysAndValues...)
+		return
+	}
+
+	for i := 0; i+1 < len(keysAndValues); i += 2 {
+		msg += fmt.Sprintf(" %v=%v", keysAndValues[i], keysAndValues[i+1])
+	}
+
+	g.log.Error(msg)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, &ErrorResponse{Error: err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	// Nothing left to do if this fails; headers have already been sent
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,220 @@
+package gateway
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/batchcorp/natty/nattytest"
+)
+
+var _ = Describe("KVGateway", func() {
+	var (
+		fake   *nattytest.FakeNatty
+		server *httptest.Server
+	)
+
+	BeforeEach(func() {
+		fake = nattytest.NewFakeNatty()
+		server = httptest.NewServer(NewKVGateway(fake, nil))
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	do := func(method, path string, body []byte) (*http.Response, []byte) {
+		req, err := http.NewRequest(method, server.URL+path, bytes.NewReader(body))
+		Expect(err).ToNot(HaveOccurred())
+
+		resp, err := http.DefaultClient.Do(req)
+		Expect(err).ToNot(HaveOccurred())
+
+		defer resp.Body.Close()
+
+		data, err := ioutil.ReadAll(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+
+		return resp, data
+	}
+
+	Describe("GET /kv/{bucket}/{key}", func() {
+		It("should return the value and revision", func() {
+			Expect(fake.Put(context.Background(), "bucket", "foo", []byte("bar"))).To(Succeed())
+
+			resp, body := do(http.MethodGet, "/kv/bucket/foo", nil)
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			Expect(resp.Header.Get(HeaderRevision)).To(Equal("1"))
+			Expect(body).To(Equal([]byte("bar")))
+		})
+
+		It("should support keys containing slashes", func() {
+			Expect(fake.Put(context.Background(), "bucket", "foo/bar", []byte("baz"))).To(Succeed())
+
+			resp, body := do(http.MethodGet, "/kv/bucket/foo/bar", nil)
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			Expect(body).To(Equal([]byte("baz")))
+		})
+
+		It("should return 404 for a missing key", func() {
+			resp, body := do(http.MethodGet, "/kv/bucket/missing", nil)
+			Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+			Expect(resp.Header.Get("Content-Type")).To(Equal("application/json"))
+
+			errResp := &ErrorResponse{}
+			Expect(json.Unmarshal(body, errResp)).To(Succeed())
+			Expect(errResp.Error).ToNot(BeEmpty())
+		})
+	})
+
+	Describe("PUT /kv/{bucket}/{key}", func() {
+		It("should write the value", func() {
+			resp, _ := do(http.MethodPut, "/kv/bucket/foo", []byte("bar"))
+			Expect(resp.StatusCode).To(Equal(http.StatusNoContent))
+
+			resp, _ = do(http.MethodPut, "/kv/bucket/foo", []byte("baz"))
+			Expect(resp.StatusCode).To(Equal(http.StatusNoContent))
+
+			data, err := fake.Get(context.Background(), "bucket", "foo")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal([]byte("baz")))
+		})
+
+		It("should reject bodies larger than MaxBodySize", func() {
+			gw := NewKVGateway(fake, nil)
+			gw.MaxBodySize = 4
+
+			rec := httptest.NewRecorder()
+			gw.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/kv/bucket/foo", bytes.NewReader([]byte("too large"))))
+
+			Expect(rec.Code).To(Equal(http.StatusRequestEntityTooLarge))
+		})
+	})
+
+	Describe("POST /kv/{bucket}/{key}", func() {
+		It("should create the value", func() {
+			resp, _ := do(http.MethodPost, "/kv/bucket/foo", []byte("bar"))
+			Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+
+			data, err := fake.Get(context.Background(), "bucket", "foo")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal([]byte("bar")))
+		})
+
+		It("should return 409 if the key already exists", func() {
+			Expect(fake.Put(context.Background(), "bucket", "foo", []byte("bar"))).To(Succeed())
+
+			resp, _ := do(http.MethodPost, "/kv/bucket/foo", []byte("baz"))
+			Expect(resp.StatusCode).To(Equal(http.StatusConflict))
+		})
+	})
+
+	Describe("DELETE /kv/{bucket}/{key}", func() {
+		It("should delete the key", func() {
+			Expect(fake.Put(context.Background(), "bucket", "foo", []byte("bar"))).To(Succeed())
+
+			resp, _ := do(http.MethodDelete, "/kv/bucket/foo", nil)
+			Expect(resp.StatusCode).To(Equal(http.StatusNoContent))
+
+			resp, _ = do(http.MethodGet, "/kv/bucket/foo", nil)
+			Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+		})
+	})
+
+	Describe("GET /kv/{bucket}", func() {
+		It("should list keys", func() {
+			Expect(fake.Put(context.Background(), "bucket", "b", []byte("2"))).To(Succeed())
+			Expect(fake.Put(context.Background(), "bucket", "a", []byte("1"))).To(Succeed())
+
+			resp, body := do(http.MethodGet, "/kv/bucket", nil)
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+			list := &ListKeysResponse{}
+			Expect(json.Unmarshal(body, list)).To(Succeed())
+			Expect(list.Bucket).To(Equal("bucket"))
+			Expect(list.Keys).To(Equal([]string{"a", "b"}))
+		})
+
+		It("should return 404 for a missing bucket", func() {
+			resp, _ := do(http.MethodGet, "/kv/missing", nil)
+			Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+		})
+
+		It("should return 405 for unsupported methods", func() {
+			resp, _ := do(http.MethodPut, "/kv/bucket", nil)
+			Expect(resp.StatusCode).To(Equal(http.StatusMethodNotAllowed))
+			Expect(resp.Header.Get("Allow")).To(Equal(http.MethodGet))
+		})
+	})
+
+	Describe("GET /kv/{bucket}/watch", func() {
+		It("should stream changes as Server-Sent Events", func() {
+			Expect(fake.CreateBucket(context.Background(), "bucket", 0)).To(Succeed())
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/kv/bucket/watch", nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			resp, err := http.DefaultClient.Do(req)
+			Expect(err).ToNot(HaveOccurred())
+
+			defer resp.Body.Close()
+
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			Expect(resp.Header.Get("Content-Type")).To(Equal("text/event-stream"))
+
+			lines := make(chan string, 10)
+
+			go func() {
+				defer GinkgoRecover()
+
+				scanner := bufio.NewScanner(resp.Body)
+
+				for scanner.Scan() {
+					lines <- scanner.Text()
+				}
+			}()
+
+			Expect(fake.Put(context.Background(), "bucket", "foo", []byte("bar"))).To(Succeed())
+
+			Eventually(lines, time.Second).Should(Receive(Equal("id: 1")))
+			Eventually(lines, time.Second).Should(Receive(Equal("event: put")))
+
+			var line string
+			Eventually(lines, time.Second).Should(Receive(&line))
+			Expect(line).To(HavePrefix("data: "))
+
+			event := &WatchEvent{}
+			Expect(json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), event)).To(Succeed())
+			Expect(event.Bucket).To(Equal("bucket"))
+			Expect(event.Key).To(Equal("foo"))
+			Expect(event.Value).To(Equal([]byte("bar")))
+			Expect(event.Revision).To(Equal(uint64(1)))
+			Expect(event.Operation).To(Equal("put"))
+		})
+
+		It("should return 404 for a missing bucket", func() {
+			resp, _ := do(http.MethodGet, "/kv/missing/watch", nil)
+			Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+		})
+	})
+
+	It("should return 404 for paths outside of the prefix", func() {
+		resp, _ := do(http.MethodGet, "/other/bucket/foo", nil)
+		Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+
+		resp, _ = do(http.MethodGet, "/kv/", nil)
+		Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+	})
+})
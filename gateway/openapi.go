@@ -0,0 +1,233 @@
+package gateway
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+const (
+	OpenAPIVersion = "3.0.3"
+)
+
+// The following types model the subset of the OpenAPI 3.0 specification used
+// by OpenAPISpec().
+
+type openAPISpec struct {
+	OpenAPI    string                     `json:"openapi"`
+	Info       openAPIInfo                `json:"info"`
+	Paths      map[string]openAPIPathItem `json:"paths"`
+	Components openAPIComponents          `json:"components"`
+}
+
+type openAPIInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+// openAPIPathItem maps a lower-cased HTTP method to an operation
+type openAPIPathItem map[string]*openAPIOperation
+
+type openAPIOperation struct {
+	Summary     string                      `json:"summary"`
+	OperationID string                      `json:"operationId"`
+	Parameters  []*openAPIParameter         `json:"parameters,omitempty"`
+	RequestBody *openAPIRequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*openAPIResponse `json:"responses"`
+}
+
+type openAPIParameter struct {
+	Name        string         `json:"name"`
+	In          string         `json:"in"`
+	Description string         `json:"description,omitempty"`
+	Required    bool           `json:"required"`
+	Schema      *openAPISchema `json:"schema"`
+}
+
+type openAPIRequestBody struct {
+	Required bool                         `json:"required"`
+	Content  map[string]*openAPIMediaType `json:"content"`
+}
+
+type openAPIResponse struct {
+	Description string                       `json:"description"`
+	Headers     map[string]*openAPIHeader    `json:"headers,omitempty"`
+	Content     map[string]*openAPIMediaType `json:"content,omitempty"`
+}
+
+type openAPIHeader struct {
+	Description string         `json:"description,omitempty"`
+	Schema      *openAPISchema `json:"schema"`
+}
+
+type openAPIMediaType struct {
+	Schema *openAPISchema `json:"schema"`
+}
+
+type openAPISchema struct {
+	Ref        string                    `json:"$ref,omitempty"`
+	Type       string                    `json:"type,omitempty"`
+	Format     string                    `json:"format,omitempty"`
+	Properties map[string]*openAPISchema `json:"properties,omitempty"`
+	Items      *openAPISchema            `json:"items,omitempty"`
+	Required   []string                  `json:"required,omitempty"`
+}
+
+type openAPIComponents struct {
+	Schemas map[string]*openAPISchema `json:"schemas"`
+}
+
+// OpenAPISpec returns a JSON OpenAPI 3.0 spec describing the endpoints served
+// by the gateway (relative to Prefix).
+func (g *KVGateway) OpenAPISpec() []byte {
+	prefix := "/" + strings.Trim(g.Prefix, "/")
+
+	bucketParam := &openAPIParameter{Name: "bucket", In: "path", Description: "Bucket name", Required: true, Schema: stringSchema()}
+	keyParam := &openAPIParameter{Name: "key", In: "path", Description: "Key name", Required: true, Schema: stringSchema()}
+
+	spec := &openAPISpec{
+		OpenAPI: OpenAPIVersion,
+		Info: openAPIInfo{
+			Title:   "natty KV gateway",
+			Version: "1.0.0",
+		},
+		Paths: map[string]openAPIPathItem{
+			prefix + "/{bucket}": {
+				"get": {
+					Summary:     "List keys in bucket",
+					OperationID: "listKeys",
+					Parameters:  []*openAPIParameter{bucketParam},
+					Responses: map[string]*openAPIResponse{
+						"200": jsonResponse("Keys in bucket", "ListKeysResponse"),
+						"404": jsonResponse("Bucket not found", "ErrorResponse"),
+					},
+				},
+			},
+			prefix + "/{bucket}/{key}": {
+				"get": {
+					Summary:     "Fetch value",
+					OperationID: "getKey",
+					Parameters:  []*openAPIParameter{bucketParam, keyParam},
+					Responses: map[string]*openAPIResponse{
+						"200": {
+							Description: "Value",
+							Headers: map[string]*openAPIHeader{
+								HeaderRevision: {Description: "Revision of the value", Schema: &openAPISchema{Type: "integer", Format: "int64"}},
+							},
+							Content: binaryContent(),
+						},
+						"404": jsonResponse("Key or bucket not found", "ErrorResponse"),
+					},
+				},
+				"put": {
+					Summary:     "Write value (creates bucket if necessary)",
+					OperationID: "putKey",
+					Parameters:  []*openAPIParameter{bucketParam, keyParam},
+					RequestBody: &openAPIRequestBody{Required: true, Content: binaryContent()},
+					Responses: map[string]*openAPIResponse{
+						"204": {Description: "Value written"},
+						"413": jsonResponse("Value too large", "ErrorResponse"),
+					},
+				},
+				"post": {
+					Summary:     "Create value if key does not exist",
+					OperationID: "createKey",
+					Parameters:  []*openAPIParameter{bucketParam, keyParam},
+					RequestBody: &openAPIRequestBody{Required: true, Content: binaryContent()},
+					Responses: map[string]*openAPIResponse{
+						"201": {Description: "Value created"},
+						"409": jsonResponse("Key already exists", "ErrorResponse"),
+						"413": jsonResponse("Value too large", "ErrorResponse"),
+					},
+				},
+				"delete": {
+					Summary:     "Delete key",
+					OperationID: "deleteKey",
+					Parameters:  []*openAPIParameter{bucketParam, keyParam},
+					Responses: map[string]*openAPIResponse{
+						"204": {Description: "Key deleted"},
+					},
+				},
+			},
+			prefix + "/{bucket}/" + WatchPath: {
+				"get": {
+					Summary:     "Stream changes as Server-Sent Events",
+					OperationID: "watchBucket",
+					Parameters: []*openAPIParameter{
+						bucketParam,
+						{Name: "key", In: "query", Description: "Key or wildcard pattern (default: all keys)", Schema: stringSchema()},
+					},
+					Responses: map[string]*openAPIResponse{
+						"200": {
+							Description: "Stream of events; the data of each event is a WatchEvent",
+							Content: map[string]*openAPIMediaType{
+								"text/event-stream": {Schema: refSchema("WatchEvent")},
+							},
+						},
+						"404": jsonResponse("Bucket not found", "ErrorResponse"),
+					},
+				},
+			},
+		},
+		Components: openAPIComponents{
+			Schemas: map[string]*openAPISchema{
+				"ListKeysResponse": {
+					Type: "object",
+					Properties: map[string]*openAPISchema{
+						"bucket": stringSchema(),
+						"keys":   {Type: "array", Items: stringSchema()},
+					},
+					Required: []string{"bucket", "keys"},
+				},
+				"ErrorResponse": {
+					Type: "object",
+					Properties: map[string]*openAPISchema{
+						"error": stringSchema(),
+					},
+					Required: []string{"error"},
+				},
+				"WatchEvent": {
+					Type: "object",
+					Properties: map[string]*openAPISchema{
+						"bucket":    stringSchema(),
+						"key":       stringSchema(),
+						"value":     {Type: "string", Format: "byte"},
+						"revision":  {Type: "integer", Format: "int64"},
+						"operation": stringSchema(),
+					},
+					Required: []string{"bucket", "key", "revision", "operation"},
+				},
+			},
+		},
+	}
+
+	data, err := json.MarshalIndent(spec, "", "  ")
+	if err != nil {
+		// Should never happen; spec only contains marshalable types
+		panic(err)
+	}
+
+	return data
+}
+
+func stringSchema() *openAPISchema {
+	return &openAPISchema{Type: "string"}
+}
+
+func refSchema(name string) *openAPISchema {
+	return &openAPISchema{Ref: "#/components/schemas/" + name}
+}
+
+func jsonResponse(description, schema string) *openAPIResponse {
+	return &openAPIResponse{
+		Description: description,
+		Content: map[string]*openAPIMediaType{
+			"application/json": {Schema: refSchema(schema)},
+		},
+	}
+}
+
+func binaryContent() map[string]*openAPIMediaType {
+	return map[string]*openAPIMediaType{
+		"application/octet-stream": {Schema: &openAPISchema{Type: "string", Format: "binary"}},
+	}
+}
@@ -0,0 +1,55 @@
+package gateway
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/batchcorp/natty/nattytest"
+)
+
+var _ = Describe("OpenAPISpec", func() {
+	type spec struct {
+		OpenAPI    string                                `json:"openapi"`
+		Paths      map[string]map[string]json.RawMessage `json:"paths"`
+		Components struct {
+			Schemas map[string]json.RawMessage `json:"schemas"`
+		} `json:"components"`
+	}
+
+	It("should describe all endpoints and schemas", func() {
+		g := NewKVGateway(nattytest.NewFakeNatty(), nil)
+
+		s := &spec{}
+		Expect(json.Unmarshal(g.OpenAPISpec(), s)).To(Succeed())
+
+		Expect(s.OpenAPI).To(Equal(OpenAPIVersion))
+
+		Expect(s.Paths).To(HaveKey("/kv/{bucket}"))
+		Expect(s.Paths["/kv/{bucket}"]).To(HaveKey("get"))
+
+		Expect(s.Paths).To(HaveKey("/kv/{bucket}/{key}"))
+		Expect(s.Paths["/kv/{bucket}/{key}"]).To(HaveKey("get"))
+		Expect(s.Paths["/kv/{bucket}/{key}"]).To(HaveKey("put"))
+		Expect(s.Paths["/kv/{bucket}/{key}"]).To(HaveKey("post"))
+		Expect(s.Paths["/kv/{bucket}/{key}"]).To(HaveKey("delete"))
+
+		Expect(s.Paths).To(HaveKey("/kv/{bucket}/watch"))
+		Expect(s.Paths["/kv/{bucket}/watch"]).To(HaveKey("get"))
+
+		Expect(s.Components.Schemas).To(HaveKey("ListKeysResponse"))
+		Expect(s.Components.Schemas).To(HaveKey("ErrorResponse"))
+		Expect(s.Components.Schemas).To(HaveKey("WatchEvent"))
+	})
+
+	It("should use the configured prefix", func() {
+		g := NewKVGateway(nattytest.NewFakeNatty(), nil)
+		g.Prefix = "/api/kv/"
+
+		s := &spec{}
+		Expect(json.Unmarshal(g.OpenAPISpec(), s)).To(Succeed())
+
+		Expect(s.Paths).To(HaveKey("/api/kv/{bucket}/{key}"))
+	})
+})
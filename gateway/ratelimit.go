@@ -0,0 +1,112 @@
+package gateway
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// RateLimitIdleTimeout is how long a client's rate limiter is kept after
+	// its last request
+	RateLimitIdleTimeout = 5 * time.Minute
+)
+
+// WithGatewayRateLimit limits each client IP to rps requests per second (with
+// a burst of rps, min 1); requests over the limit receive a 429 response.
+func WithGatewayRateLimit(rps float64) Option {
+	return func(g *KVGateway) {
+		g.limiter = newIPRateLimiter(rps)
+	}
+}
+
+type ipLimiter struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+type ipRateLimiter struct {
+	rps   rate.Limit
+	burst int
+
+	mutex     *sync.Mutex
+	limiters  map[string]*ipLimiter
+	lastPurge time.Time
+}
+
+func newIPRateLimiter(rps float64) *ipRateLimiter {
+	burst := int(rps)
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &ipRateLimiter{
+		rps:       rate.Limit(rps),
+		burst:     burst,
+		mutex:     &sync.Mutex{},
+		limiters:  make(map[string]*ipLimiter),
+		lastPurge: time.Now(),
+	}
+}
+
+// allow returns true if the request may proceed; otherwise a 429 response
+// has been written. Always returns true if rate limiting is disabled.
+func (l *ipRateLimiter) allow(w http.ResponseWriter, r *http.Request) bool {
+	if l == nil {
+		return true
+	}
+
+	if l.get(clientIP(r)).Allow() {
+		return true
+	}
+
+	w.Header().Set("Retry-After", "1")
+	writeError(w, http.StatusTooManyRequests, errors.New("rate limit exceeded"))
+
+	return false
+}
+
+func (l *ipRateLimiter) get(ip string) *rate.Limiter {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := time.Now()
+
+	// Periodically forget idle clients so the map does not grow forever
+	if now.Sub(l.lastPurge) > RateLimitIdleTimeout {
+		for k, v := range l.limiters {
+			if now.Sub(v.lastSeen) > RateLimitIdleTimeout {
+				delete(l.limiters, k)
+			}
+		}
+
+		l.lastPurge = now
+	}
+
+	entry, ok := l.limiters[ip]
+	if !ok {
+		entry = &ipLimiter{limiter: rate.NewLimiter(l.rps, l.burst)}
+		l.limiters[ip] = entry
+	}
+
+	entry.lastSeen = now
+
+	return entry.limiter
+}
+
+// clientIP returns the IP of the remote end of the connection.
+//
+// NOTE: X-Forwarded-For is NOT honored as it can be spoofed by clients; when
+// running behind a proxy all clients will share a single limiter.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
@@ -0,0 +1,62 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/batchcorp/natty/nattytest"
+)
+
+var _ = Describe("RateLimit", func() {
+	var (
+		fake *nattytest.FakeNatty
+	)
+
+	BeforeEach(func() {
+		fake = nattytest.NewFakeNatty()
+		Expect(fake.Put(context.Background(), "bucket", "foo", []byte("bar"))).To(Succeed())
+	})
+
+	serve := func(g *KVGateway, remoteAddr string) int {
+		req := httptest.NewRequest(http.MethodGet, "/kv/bucket/foo", nil)
+		req.RemoteAddr = remoteAddr
+
+		rec := httptest.NewRecorder()
+		g.ServeHTTP(rec, req)
+
+		return rec.Code
+	}
+
+	It("should return 429 when requests exceed the limit", func() {
+		g := NewKVGateway(fake, nil, WithGatewayRateLimit(5))
+
+		codes := make(map[int]int)
+
+		for i := 0; i < 20; i++ {
+			codes[serve(g, "10.0.0.1:1234")]++
+		}
+
+		Expect(codes[http.StatusOK]).To(BeNumerically(">=", 5))
+		Expect(codes[http.StatusTooManyRequests]).To(BeNumerically(">", 0))
+	})
+
+	It("should rate limit each client IP separately", func() {
+		g := NewKVGateway(fake, nil, WithGatewayRateLimit(1))
+
+		Expect(serve(g, "10.0.0.1:1234")).To(Equal(http.StatusOK))
+		Expect(serve(g, "10.0.0.1:5678")).To(Equal(http.StatusTooManyRequests))
+		Expect(serve(g, "10.0.0.2:1234")).To(Equal(http.StatusOK))
+	})
+
+	It("should not rate limit by default", func() {
+		g := NewKVGateway(fake, nil)
+
+		for i := 0; i < 100; i++ {
+			Expect(serve(g, "10.0.0.1:1234")).To(Equal(http.StatusOK))
+		}
+	})
+})
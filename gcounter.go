@@ -0,0 +1,88 @@
+package natty
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/nats-io/nats.go"
+	"github.com/pkg/errors"
+)
+
+// GCounter is a grow-only counter (a CRDT) stored in a KV bucket. Every node
+// increments only its own entry ("<name>.<nodeID>"), so nodes never contend
+// with each other; the counter value is the sum of all node entries.
+type GCounter struct {
+	n      INatty
+	bucket string
+	name   string
+}
+
+// NewGCounter creates a grow-only counter called name in bucket; the bucket
+// is auto-created on the first increment. name and node IDs must be valid KV
+// key tokens (ie. no '.', '*' or '>').
+func NewGCounter(n INatty, bucket, name string) *GCounter {
+	return &GCounter{
+		n:      n,
+		bucket: bucket,
+		name:   name,
+	}
+}
+
+// Increment adds 1 to the entry for nodeID
+func (g *GCounter) Increment(ctx context.Context, nodeID string) error {
+	if nodeID == "" || strings.ContainsAny(nodeID, ".*>") {
+		return errors.Errorf("invalid node ID '%s'", nodeID)
+	}
+
+	if _, err := g.n.Increment(ctx, g.bucket, g.key(nodeID), 1); err != nil {
+		return errors.Wrapf(err, "unable to increment counter for node '%s'", nodeID)
+	}
+
+	return nil
+}
+
+// Value returns the sum of all node entries; a counter that has never been
+// incremented (or a missing bucket) has a value of 0.
+func (g *GCounter) Value(ctx context.Context) (int64, error) {
+	keys, err := g.n.Keys(ctx, g.bucket)
+	if err != nil {
+		if err == nats.ErrBucketNotFound {
+			return 0, nil
+		}
+
+		return 0, errors.Wrap(err, "unable to fetch keys")
+	}
+
+	prefix := g.key("")
+
+	var total int64
+
+	for _, key := range keys {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+
+		data, err := g.n.Get(ctx, g.bucket, key)
+		if err != nil {
+			if err == nats.ErrKeyNotFound {
+				continue
+			}
+
+			return 0, errors.Wrapf(err, "unable to fetch key '%s'", key)
+		}
+
+		value, err := strconv.ParseInt(string(data), 10, 64)
+		if err != nil {
+			return 0, errors.Wrapf(err, "key '%s' is not a valid counter", key)
+		}
+
+		total += value
+	}
+
+	return total, nil
+}
+
+func (g *GCounter) key(nodeID string) string {
+	return g.name + "." + nodeID
+}
@@ -0,0 +1,74 @@
+package natty
+
+import (
+	"context"
+	"strconv"
+	"sync"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("GCounter", func() {
+	var n *Natty
+
+	BeforeEach(func() {
+		var err error
+
+		n, err = New(NewConfig())
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("should sum increments from all nodes", func() {
+		bucket, _, _ := NewKVSet()
+
+		counter := NewGCounter(n, bucket, "hits")
+
+		const (
+			numNodes      = 5
+			numIncrements = 20
+		)
+
+		var wg sync.WaitGroup
+
+		for i := 0; i < numNodes; i++ {
+			wg.Add(1)
+
+			go func(node string) {
+				defer GinkgoRecover()
+				defer wg.Done()
+
+				for j := 0; j < numIncrements; j++ {
+					Expect(counter.Increment(context.Background(), node)).To(Succeed())
+				}
+			}("node-" + strconv.Itoa(i))
+		}
+
+		wg.Wait()
+
+		value, err := counter.Value(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(value).To(Equal(int64(numNodes * numIncrements)))
+	})
+
+	It("should only count its own entries", func() {
+		bucket, _, _ := NewKVSet()
+
+		Expect(NewGCounter(n, bucket, "a").Increment(context.Background(), "node")).To(Succeed())
+		Expect(n.Put(context.Background(), bucket, "unrelated", []byte("100"))).To(Succeed())
+
+		value, err := NewGCounter(n, bucket, "b").Value(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(value).To(BeZero())
+
+		value, err = NewGCounter(n, bucket, "a").Value(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(value).To(Equal(int64(1)))
+	})
+
+	It("should reject invalid node IDs", func() {
+		bucket, _, _ := NewKVSet()
+
+		Expect(NewGCounter(n, bucket, "hits").Increment(context.Background(), "a.b")).ToNot(Succeed())
+	})
+})
@@ -3,12 +3,24 @@ module github.com/batchcorp/natty
 go 1.16
 
 require (
+	github.com/alicebob/miniredis/v2 v2.30.0
+	github.com/go-redis/redis/v8 v8.11.4
+	github.com/klauspost/compress v1.15.9
+	github.com/mattn/go-sqlite3 v1.14.15
+	github.com/nats-io/nats-server/v2 v2.8.4
 	github.com/nats-io/nats.go v1.16.0
+	github.com/nats-io/nkeys v0.3.0
 	github.com/onsi/ginkgo v1.16.5
 	github.com/onsi/gomega v1.18.1
+	github.com/pierrec/lz4/v4 v4.1.15
 	github.com/pkg/errors v0.9.1
+	github.com/prometheus/client_golang v1.12.2
 	github.com/relistan/go-director v0.0.0-20200406104025-dbbf5d95248d
 	github.com/satori/go.uuid v1.2.0
 	github.com/sirupsen/logrus v1.7.0 // indirect
+	go.opentelemetry.io/otel v1.7.0
+	go.opentelemetry.io/otel/sdk v1.7.0
+	go.opentelemetry.io/otel/trace v1.7.0
+	golang.org/x/time v0.0.0-20211116232009-f0f3c7e86c11
 	gopkg.in/DataDog/dd-trace-go.v1 v1.37.1
 )
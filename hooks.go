@@ -0,0 +1,59 @@
+package natty
+
+// BucketHooks contains optional callbacks that are fired before and after KV
+// operations; nil callbacks are skipped. Hooks are called synchronously so
+// they should not block. The err passed to Before* hooks is always nil.
+type BucketHooks struct {
+	BeforeGet, AfterGet       func(bucket, key string, err error)
+	BeforePut, AfterPut       func(bucket, key string, value []byte, err error)
+	BeforeCreate, AfterCreate func(bucket, key string, value []byte, err error)
+	BeforeDelete, AfterDelete func(bucket, key string, err error)
+}
+
+func (h *BucketHooks) beforeGet(bucket, key string) {
+	if h.BeforeGet != nil {
+		h.BeforeGet(bucket, key, nil)
+	}
+}
+
+func (h *BucketHooks) afterGet(bucket, key string, err error) {
+	if h.AfterGet != nil {
+		h.AfterGet(bucket, key, err)
+	}
+}
+
+func (h *BucketHooks) beforePut(bucket, key string, value []byte) {
+	if h.BeforePut != nil {
+		h.BeforePut(bucket, key, value, nil)
+	}
+}
+
+func (h *BucketHooks) afterPut(bucket, key string, value []byte, err error) {
+	if h.AfterPut != nil {
+		h.AfterPut(bucket, key, value, err)
+	}
+}
+
+func (h *BucketHooks) beforeCreate(bucket, key string, value []byte) {
+	if h.BeforeCreate != nil {
+		h.BeforeCreate(bucket, key, value, nil)
+	}
+}
+
+func (h *BucketHooks) afterCreate(bucket, key string, value []byte, err error) {
+	if h.AfterCreate != nil {
+		h.AfterCreate(bucket, key, value, err)
+	}
+}
+
+func (h *BucketHooks) beforeDelete(bucket, key string) {
+	if h.BeforeDelete != nil {
+		h.BeforeDelete(bucket, key, nil)
+	}
+}
+
+func (h *BucketHooks) afterDelete(bucket, key string, err error) {
+	if h.AfterDelete != nil {
+		h.AfterDelete(bucket, key, err)
+	}
+}
@@ -0,0 +1,75 @@
+package natty
+
+import (
+	"context"
+	"sync"
+
+	"github.com/nats-io/nats.go"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("BucketHooks", func() {
+	It("should fire AfterGet for every get", func() {
+		var mutex sync.Mutex
+
+		accessed := make([]string, 0)
+
+		n, err := New(NewConfig().WithBucketHooks(BucketHooks{
+			AfterGet: func(bucket, key string, err error) {
+				mutex.Lock()
+				defer mutex.Unlock()
+
+				accessed = append(accessed, key)
+			},
+		}))
+		Expect(err).ToNot(HaveOccurred())
+
+		bucket, key, value := NewKVSet()
+
+		Expect(n.Put(context.Background(), bucket, key, value)).To(Succeed())
+
+		for i := 0; i < 3; i++ {
+			_, err := n.Get(context.Background(), bucket, key)
+			Expect(err).ToNot(HaveOccurred())
+		}
+
+		Expect(accessed).To(Equal([]string{key, key, key}))
+	})
+
+	It("should fire Before and After hooks with the operation result", func() {
+		calls := make([]string, 0)
+
+		var getErr error
+
+		n, err := New(NewConfig().WithBucketHooks(BucketHooks{
+			BeforePut: func(bucket, key string, value []byte, err error) {
+				calls = append(calls, "before-put:"+string(value))
+			},
+			AfterPut: func(bucket, key string, value []byte, err error) {
+				calls = append(calls, "after-put:"+string(value))
+			},
+			AfterGet: func(bucket, key string, err error) {
+				getErr = err
+			},
+			BeforeDelete: func(bucket, key string, err error) {
+				calls = append(calls, "before-delete")
+			},
+			AfterDelete: func(bucket, key string, err error) {
+				calls = append(calls, "after-delete")
+			},
+		}))
+		Expect(err).ToNot(HaveOccurred())
+
+		bucket, key, _ := NewKVSet()
+
+		Expect(n.Put(context.Background(), bucket, key, []byte("foo"))).To(Succeed())
+		Expect(n.Delete(context.Background(), bucket, key)).To(Succeed())
+
+		_, err = n.Get(context.Background(), bucket, key)
+		Expect(err).To(Equal(nats.ErrKeyNotFound))
+		Expect(getErr).To(Equal(nats.ErrKeyNotFound))
+
+		Expect(calls).To(Equal([]string{"before-put:foo", "after-put:foo", "before-delete", "after-delete"}))
+	})
+})
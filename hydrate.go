@@ -0,0 +1,76 @@
+package natty
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/pkg/errors"
+)
+
+const (
+	// HydratedBucket holds a marker per bucket that Hydrate() has completed
+	// for (keyed by bucket name); its value is the time of hydration
+	// (RFC3339). Markers are kept out of the hydrated buckets so that they do
+	// not show up in Keys(), exports or checksums.
+	HydratedBucket = "natty-hydrated"
+)
+
+// Hydrate pre-loads a bucket (auto-creating it if needed) with data and then
+// marks the bucket as hydrated (see IsHydrated()). Keys are written in sorted
+// order. Hydrating an already hydrated bucket is a no-op: data is not written
+// again, so changes made since the first hydration are kept.
+//
+// NOTE: The marker is not removed when the bucket is deleted; a re-created
+// bucket is still reported as hydrated until the marker is deleted from
+// HydratedBucket.
+func (n *Natty) Hydrate(ctx context.Context, bucket string, data map[string][]byte) error {
+	if n.isClosed() {
+		return ErrConnectionClosed
+	}
+
+	hydrated, err := n.IsHydrated(ctx, bucket)
+	if err != nil {
+		return err
+	}
+
+	if hydrated {
+		return nil
+	}
+
+	keys := make([]string, 0, len(data))
+
+	for key := range data {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if err := n.Put(ctx, bucket, key, data[key]); err != nil {
+			return errors.Wrapf(err, "unable to put key '%s'", key)
+		}
+	}
+
+	// Marker goes last so that a partial hydration is not reported as done
+	if err := n.Put(ctx, HydratedBucket, bucket, []byte(time.Now().UTC().Format(time.RFC3339))); err != nil {
+		return errors.Wrap(err, "unable to mark bucket as hydrated")
+	}
+
+	return nil
+}
+
+// IsHydrated returns true if Hydrate() has completed for the bucket. A missing
+// bucket is not hydrated.
+func (n *Natty) IsHydrated(ctx context.Context, bucket string) (bool, error) {
+	if _, err := n.Get(ctx, HydratedBucket, bucket); err != nil {
+		if err == nats.ErrKeyNotFound {
+			return false, nil
+		}
+
+		return false, errors.Wrap(err, "unable to fetch hydration marker")
+	}
+
+	return true, nil
+}
@@ -0,0 +1,75 @@
+package natty
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Hydrate", func() {
+	var (
+		n   *Natty
+		ctx = context.Background()
+	)
+
+	BeforeEach(func() {
+		var err error
+
+		n, err = New(NewConfig())
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("should load data and mark the bucket as hydrated", func() {
+		bucket, _, _ := NewKVSet()
+
+		data := map[string][]byte{
+			"foo": []byte("bar"),
+			"baz": []byte("qux"),
+		}
+
+		hydrated, err := n.IsHydrated(ctx, bucket)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(hydrated).To(BeFalse())
+
+		Expect(n.Hydrate(ctx, bucket, data)).To(Succeed())
+
+		hydrated, err = n.IsHydrated(ctx, bucket)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(hydrated).To(BeTrue())
+
+		for key, value := range data {
+			got, err := n.Get(ctx, bucket, key)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(got).To(Equal(value))
+		}
+
+		keys, err := n.Keys(ctx, bucket)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(keys).To(ConsistOf("foo", "baz"))
+	})
+
+	It("should not write data again once hydrated", func() {
+		bucket, _, _ := NewKVSet()
+
+		Expect(n.Hydrate(ctx, bucket, map[string][]byte{"foo": []byte("bar")})).To(Succeed())
+		Expect(n.Put(ctx, bucket, "foo", []byte("changed"))).To(Succeed())
+
+		Expect(n.Hydrate(ctx, bucket, map[string][]byte{
+			"foo": []byte("bar"),
+			"baz": []byte("qux"),
+		})).To(Succeed())
+
+		got, err := n.Get(ctx, bucket, "foo")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(got)).To(Equal("changed"))
+
+		keys, err := n.Keys(ctx, bucket)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(keys).To(ConsistOf("foo"))
+
+		hydrated, err := n.IsHydrated(ctx, bucket)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(hydrated).To(BeTrue())
+	})
+})
@@ -0,0 +1,64 @@
+// Package natsserver runs an in-process NATS server for tests. It does not
+// import natty so that natty's own tests can use it (see nattytest for the
+// public helper).
+package natsserver
+
+import (
+	"os"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/pkg/errors"
+)
+
+// ReadyTimeout is how long Start() waits for the server to accept connections
+const ReadyTimeout = 10 * time.Second
+
+// Option modifies the server options used by Start()
+type Option func(*server.Options)
+
+// Start starts a NATS server with JetStream enabled that listens on a random
+// port on localhost; opts are applied on top of these defaults (ie. to disable
+// JetStream or to configure TLS or auth). JetStream data is stored in a
+// temporary directory that is removed by the returned stop func, which also
+// shuts down the server. Connect via server.ClientURL().
+func Start(opts ...Option) (*server.Server, func(), error) {
+	storeDir, err := os.MkdirTemp("", "natty-jetstream-")
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "unable to create JetStream store dir")
+	}
+
+	serverOpts := &server.Options{
+		Host:      "127.0.0.1",
+		Port:      server.RANDOM_PORT,
+		JetStream: true,
+		StoreDir:  storeDir,
+		NoLog:     true,
+		NoSigs:    true,
+	}
+
+	for _, opt := range opts {
+		opt(serverOpts)
+	}
+
+	s, err := server.NewServer(serverOpts)
+	if err != nil {
+		os.RemoveAll(storeDir)
+		return nil, nil, errors.Wrap(err, "unable to create server")
+	}
+
+	stop := func() {
+		s.Shutdown()
+		s.WaitForShutdown()
+		os.RemoveAll(storeDir)
+	}
+
+	go s.Start()
+
+	if !s.ReadyForConnections(ReadyTimeout) {
+		stop()
+		return nil, nil, errors.Errorf("server not ready for connections after %s", ReadyTimeout)
+	}
+
+	return s, stop, nil
+}
@@ -0,0 +1,157 @@
+package natty
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// KeyspaceNotification is an aggregated set of changes to keys in a bucket
+// delivered by WatchKeyspace()
+type KeyspaceNotification struct {
+	Bucket string
+
+	// Changes contains the latest entry for every key that changed, ordered
+	// by revision; deleted keys have Operation set to nats.KeyValueDelete or
+	// nats.KeyValuePurge.
+	Changes []*KVEntry
+}
+
+// KeyspaceOption configures WatchKeyspace()
+type KeyspaceOption func(*keyspaceOptions)
+
+type keyspaceOptions struct {
+	debounce time.Duration
+	maxWait  time.Duration
+}
+
+// WithDebounce delays notifications until no changes have been seen for d;
+// all changes seen in the meantime are delivered in a single notification,
+// with repeated updates to the same key collapsed into the latest entry.
+//
+// A keyspace that keeps changing more often than every d is still notified
+// at most maxWait (default: 10 * d) after the first undelivered change.
+func WithDebounce(d time.Duration, maxWait ...time.Duration) KeyspaceOption {
+	return func(o *keyspaceOptions) {
+		o.debounce = d
+		o.maxWait = 10 * d
+
+		if len(maxWait) > 0 && maxWait[0] > 0 {
+			o.maxWait = maxWait[0]
+		}
+	}
+}
+
+// WatchKeyspace watches key (which may contain wildcards) in bucket via
+// n.WatchWithBackpressure() (so no change is dropped) and delivers changes as
+// KeyspaceNotifications until ctx is cancelled. Without WithDebounce, every change is delivered in its own
+// notification. The returned channel is closed once the watch stops.
+func WatchKeyspace(ctx context.Context, n INatty, bucket, key string, opts ...KeyspaceOption) (<-chan *KeyspaceNotification, error) {
+	o := &keyspaceOptions{}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	entries, err := n.WatchWithBackpressure(ctx, bucket, key)
+	if err != nil {
+		return nil, err
+	}
+
+	notifications := make(chan *KeyspaceNotification)
+
+	go func() {
+		defer close(notifications)
+
+		pending := make(map[string]*KVEntry)
+
+		var (
+			timer   *time.Timer
+			timerCh <-chan time.Time
+
+			// deadline is when pending changes are delivered at the latest
+			deadline time.Time
+		)
+
+		defer func() {
+			if timer != nil {
+				timer.Stop()
+			}
+		}()
+
+		flush := func() bool {
+			changes := make([]*KVEntry, 0, len(pending))
+
+			for _, e := range pending {
+				changes = append(changes, e)
+			}
+
+			sort.Slice(changes, func(i, j int) bool { return changes[i].Revision < changes[j].Revision })
+
+			pending = make(map[string]*KVEntry)
+
+			select {
+			case notifications <- &KeyspaceNotification{Bucket: bucket, Changes: changes}:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case entry, ok := <-entries:
+				if !ok {
+					if len(pending) > 0 {
+						flush()
+					}
+
+					return
+				}
+
+				if len(pending) == 0 {
+					deadline = time.Now().Add(o.maxWait)
+				}
+
+				pending[entry.Key] = entry
+
+				if o.debounce <= 0 {
+					if !flush() {
+						return
+					}
+
+					continue
+				}
+
+				// (Re)start the debounce window, without going past the deadline
+				wait := o.debounce
+
+				if untilDeadline := time.Until(deadline); untilDeadline < wait {
+					wait = untilDeadline
+				}
+
+				if timer == nil {
+					timer = time.NewTimer(wait)
+				} else {
+					if !timer.Stop() && timerCh != nil {
+						<-timer.C
+					}
+
+					timer.Reset(wait)
+				}
+
+				timerCh = timer.C
+			case <-timerCh:
+				timerCh = nil
+
+				if !flush() {
+					return
+				}
+			}
+		}
+	}()
+
+	return notifications, nil
+}
@@ -0,0 +1,144 @@
+package natty
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// watchStub is an INatty whose WatchWithBackpressure() returns entries sent
+// to ch
+type watchStub struct {
+	INatty
+
+	ch chan *KVEntry
+}
+
+func (w *watchStub) WatchWithBackpressure(_ context.Context, _, _ string) (<-chan *KVEntry, error) {
+	return w.ch, nil
+}
+
+var _ = Describe("WatchKeyspace", func() {
+	var (
+		stub   *watchStub
+		ctx    context.Context
+		cancel context.CancelFunc
+	)
+
+	BeforeEach(func() {
+		stub = &watchStub{ch: make(chan *KVEntry, 100)}
+		ctx, cancel = context.WithCancel(context.Background())
+	})
+
+	AfterEach(func() {
+		cancel()
+	})
+
+	put := func(key string, revision int) {
+		stub.ch <- &KVEntry{
+			Bucket:   "bucket",
+			Key:      key,
+			Value:    []byte(strconv.Itoa(revision)),
+			Revision: uint64(revision),
+		}
+	}
+
+	It("should collapse rapid updates to the same key into one notification", func() {
+		notifications, err := WatchKeyspace(ctx, stub, "bucket", ">", WithDebounce(200*time.Millisecond))
+		Expect(err).ToNot(HaveOccurred())
+
+		for i := 1; i <= 10; i++ {
+			put("foo", i)
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		var notification *KeyspaceNotification
+		Eventually(notifications, time.Second).Should(Receive(&notification))
+
+		Expect(notification.Bucket).To(Equal("bucket"))
+		Expect(notification.Changes).To(HaveLen(1))
+		Expect(notification.Changes[0].Key).To(Equal("foo"))
+		Expect(notification.Changes[0].Value).To(Equal([]byte("10")))
+
+		Consistently(notifications, 400*time.Millisecond).ShouldNot(Receive())
+	})
+
+	It("should notify within the max wait while changes keep arriving", func() {
+		notifications, err := WatchKeyspace(ctx, stub, "bucket", ">", WithDebounce(100*time.Millisecond, 300*time.Millisecond))
+		Expect(err).ToNot(HaveOccurred())
+
+		stop := make(chan struct{})
+		stopped := make(chan struct{})
+
+		defer func() {
+			close(stop)
+			<-stopped
+		}()
+
+		// Changes arrive faster than the debounce window until the test ends
+		go func() {
+			defer close(stopped)
+
+			for i := 1; ; i++ {
+				select {
+				case <-stop:
+					return
+				case <-time.After(20 * time.Millisecond):
+					put("key-"+strconv.Itoa(i%5), i)
+				}
+			}
+		}()
+
+		var notification *KeyspaceNotification
+		Eventually(notifications, 600*time.Millisecond).Should(Receive(&notification))
+		Expect(notification.Changes).ToNot(BeEmpty())
+
+		Eventually(notifications, 600*time.Millisecond).Should(Receive(&notification))
+		Expect(len(notification.Changes)).To(BeNumerically("<=", 5))
+	})
+
+	It("should aggregate changes to multiple keys ordered by revision", func() {
+		notifications, err := WatchKeyspace(ctx, stub, "bucket", ">", WithDebounce(100*time.Millisecond))
+		Expect(err).ToNot(HaveOccurred())
+
+		put("b", 1)
+		put("a", 2)
+		put("b", 3)
+
+		var notification *KeyspaceNotification
+		Eventually(notifications, time.Second).Should(Receive(&notification))
+
+		Expect(notification.Changes).To(HaveLen(2))
+		Expect(notification.Changes[0].Key).To(Equal("a"))
+		Expect(notification.Changes[1].Key).To(Equal("b"))
+		Expect(notification.Changes[1].Revision).To(Equal(uint64(3)))
+	})
+
+	It("should deliver every change without debouncing", func() {
+		notifications, err := WatchKeyspace(ctx, stub, "bucket", ">")
+		Expect(err).ToNot(HaveOccurred())
+
+		put("foo", 1)
+		put("foo", 2)
+
+		var notification *KeyspaceNotification
+
+		Eventually(notifications).Should(Receive(&notification))
+		Expect(notification.Changes[0].Revision).To(Equal(uint64(1)))
+
+		Eventually(notifications).Should(Receive(&notification))
+		Expect(notification.Changes[0].Revision).To(Equal(uint64(2)))
+	})
+
+	It("should close the channel when the context is cancelled", func() {
+		notifications, err := WatchKeyspace(ctx, stub, "bucket", ">", WithDebounce(time.Minute))
+		Expect(err).ToNot(HaveOccurred())
+
+		cancel()
+
+		Eventually(notifications).Should(BeClosed())
+	})
+})
@@ -1,126 +1,1175 @@
 package natty
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/nats-io/nats.go"
 	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
 )
 
+// KV operation names used for metrics and tracing
+const (
+	KVOpGet     = "get"
+	KVOpPut     = "put"
+	KVOpCreate  = "create"
+	KVOpDelete  = "delete"
+	KVOpKeys    = "keys"
+	KVOpWatch   = "watch"
+	KVOpCommit  = "commit"
+	KVOpUpdate  = "update"
+	KVOpCompact = "compact"
+)
+
+// Naming used by NATS for the stream (and its subjects) backing a bucket
+const (
+	kvStreamPrefix  = "KV_"
+	kvSubjectPrefix = "$KV."
+)
+
+// kvSubject returns the subject of key (or a key pattern) in the stream
+// backing bucket. Stream subjects are used as-is for purge and consumer
+// filters, regardless of the JetStream API prefix or domain; writes are
+// published to kvPublishSubject() instead.
+func kvSubject(bucket, key string) string {
+	return kvSubjectPrefix + bucket + "." + key
+}
+
+// kvPublishSubject returns the subject that writes to key in bucket are
+// published to; like nats.KeyValue, the JetStream API prefix is prepended if a
+// non-default prefix (or domain) is configured.
+func (n *Natty) kvPublishSubject(bucket, key string) string {
+	if prefix := n.jsAPISubject(""); prefix != defaultJSAPIPrefix {
+		return prefix + kvSubject(bucket, key)
+	}
+
+	return kvSubject(bucket, key)
+}
+
+// WatchBufferSize is the size of the channel returned by Watch()
+const WatchBufferSize = 256
+
+// DefaultWatchMaxQueueBytes is the default Config.WatchMaxQueueBytes
+const DefaultWatchMaxQueueBytes = 64 * 1024 * 1024
+
+// KVEntry contains a value and its metadata; it is a natty-owned copy of
+// the data held in a nats.KeyValueEntry.
+type KVEntry struct {
+	Bucket    string
+	Key       string
+	Value     []byte
+	Revision  uint64
+	Delta     uint64
+	Created   time.Time
+	Operation nats.KeyValueOp
+}
+
+// ReconcileResult contains the number of changes made by Reconcile()
+type ReconcileResult struct {
+	Added   int
+	Updated int
+	Deleted int
+}
+
+// JSONError is returned by GetJSON() and PutJSON() when a value cannot be
+// (un)marshalled; use errors.As() to distinguish it from NATS errors.
+type JSONError struct {
+	Bucket string
+	Key    string
+	Err    error
+}
+
+func (e *JSONError) Error() string {
+	return fmt.Sprintf("unable to (un)marshal JSON for key '%s' in bucket '%s': %s", e.Key, e.Bucket, e.Err)
+}
+
+func (e *JSONError) Unwrap() error {
+	return e.Err
+}
+
+// BatchDeleteError is returned by DeletePrefix() when some keys could not be
+// deleted; Failures maps each failed key to its error.
+type BatchDeleteError struct {
+	Bucket   string
+	Failures map[string]error
+}
+
+func (e *BatchDeleteError) Error() string {
+	keys := make([]string, 0, len(e.Failures))
+
+	for key := range e.Failures {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return fmt.Sprintf("unable to delete %d key(s) in bucket '%s': %s", len(keys), e.Bucket, strings.Join(keys, ", "))
+}
+
 type KeyValueMap struct {
 	rwMutex *sync.RWMutex
 	// Key = bucket name, value = KeyValue
 	kvMap map[string]nats.KeyValue
 }
 
-func (n *Natty) Get(ctx context.Context, bucket string, key string) ([]byte, error) {
+func (n *Natty) Get(ctx context.Context, bucket string, key string) ([]byte, error) {
+	entry, err := n.GetEntry(ctx, bucket, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return entry.Value, nil
+}
+
+// GetEntry fetches the latest entry (value + metadata) for a given key
+func (n *Natty) GetEntry(ctx context.Context, bucket string, key string) (_ *KVEntry, err error) {
+	ctx, done := n.trackKV(ctx, KVOpGet, bucket, key)
+	defer done(&err)
+
+	n.BucketHooks.beforeGet(bucket, key)
+	defer func() { n.BucketHooks.afterGet(bucket, key, err) }()
+
+	if n.isClosed() {
+		return nil, ErrConnectionClosed
+	}
+
+	// NOTE: Context usage for K/V operations is not available in NATS (yet)
+	kv, err := n.getBucket(ctx, bucket, false, 0)
+	if err != nil {
+		if err == nats.ErrBucketNotFound {
+			return nil, nats.ErrKeyNotFound
+		}
+
+		return nil, errors.Wrap(err, "failed to get bucket")
+	}
+
+	var kve nats.KeyValueEntry
+
+	err = n.runKV(ctx, bucket, func() (err error) {
+		kve, err = kv.Get(key)
+		return err
+	})
+	if err != nil {
+		if err == nats.ErrKeyNotFound || err == context.DeadlineExceeded {
+			return nil, err
+		}
+
+		return nil, errors.Wrap(err, "unable to fetch key")
+	}
+
+	return newKVEntry(kve), nil
+}
+
+// GetLatestEntry fetches the newest entry for a given key. Unlike GetEntry(), a
+// deleted or purged key returns its delete/purge marker (with Operation set to
+// nats.KeyValueDelete or nats.KeyValuePurge) instead of nats.ErrKeyNotFound, so
+// the time of the delete is known. nats.ErrKeyNotFound is only returned if the
+// key has no history.
+func (n *Natty) GetLatestEntry(ctx context.Context, bucket string, key string) (_ *KVEntry, err error) {
+	ctx, done := n.trackKV(ctx, KVOpGet, bucket, key)
+	defer done(&err)
+
+	if n.isClosed() {
+		return nil, ErrConnectionClosed
+	}
+
+	kv, err := n.getBucket(ctx, bucket, false, 0)
+	if err != nil {
+		if err == nats.ErrBucketNotFound {
+			return nil, nats.ErrKeyNotFound
+		}
+
+		return nil, errors.Wrap(err, "failed to get bucket")
+	}
+
+	var history []nats.KeyValueEntry
+
+	err = n.runKV(ctx, bucket, func() (err error) {
+		history, err = kv.History(key, nats.Context(ctx))
+		return err
+	})
+	if err != nil {
+		if err == nats.ErrKeyNotFound || err == context.DeadlineExceeded {
+			return nil, err
+		}
+
+		return nil, errors.Wrap(err, "unable to fetch key history")
+	}
+
+	return newKVEntry(history[len(history)-1]), nil
+}
+
+// GetIfNewer fetches the value for a key only if the key's current revision
+// differs from sinceRevision. Returns (nil, sinceRevision, nil) if the key has
+// not been modified; otherwise returns the value and its current revision.
+func (n *Natty) GetIfNewer(ctx context.Context, bucket, key string, sinceRevision uint64) ([]byte, uint64, error) {
+	entry, err := n.GetEntry(ctx, bucket, key)
+	if err != nil {
+		return nil, sinceRevision, err
+	}
+
+	if entry.Revision == sinceRevision {
+		return nil, sinceRevision, nil
+	}
+
+	return entry.Value, entry.Revision, nil
+}
+
+// GetIfModifiedSince fetches the value for a key only if the key's current
+// revision was written after since. Returns (nil, false, nil) if the key has
+// not been modified.
+func (n *Natty) GetIfModifiedSince(ctx context.Context, bucket, key string, since time.Time) ([]byte, bool, error) {
+	entry, err := n.GetEntry(ctx, bucket, key)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if !entry.Created.After(since) {
+		return nil, false, nil
+	}
+
+	return entry.Value, true, nil
+}
+
+// Exists reports whether key exists in bucket; a missing bucket is reported
+// as the key not existing.
+//
+// NOTE: NATS does not expose a head-only KV request, so this fetches the
+// latest entry via GetEntry() and discards the value. A MetaOnly watcher would
+// avoid transferring the value, but creates a consumer per call which costs
+// more than transferring all but very large values.
+func (n *Natty) Exists(ctx context.Context, bucket, key string) (bool, error) {
+	if _, err := n.GetEntry(ctx, bucket, key); err != nil {
+		if err == nats.ErrKeyNotFound {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	return true, nil
+}
+
+// BucketExists reports whether bucket exists
+func (n *Natty) BucketExists(ctx context.Context, bucket string) (bool, error) {
+	if n.isClosed() {
+		return false, ErrConnectionClosed
+	}
+
+	ctx, release := n.guardKV(ctx)
+	defer release()
+
+	if _, err := n.getBucket(ctx, bucket, false, 0); err != nil {
+		if err == nats.ErrBucketNotFound {
+			return false, nil
+		}
+
+		return false, errors.Wrap(err, "unable to fetch bucket")
+	}
+
+	return true, nil
+}
+
+// GetWithTTLHint fetches the value for a key along with the time remaining
+// until it expires (based on the bucket TTL and the time the key was
+// written); remainingTTL is 0 if the bucket has no TTL. If remainingTTL is
+// below Config.TTLWarningThreshold, the value is returned together with an
+// error wrapping ErrKeyExpiringSoon so that callers can refresh it.
+//
+// NOTE: This requires an extra request to fetch the bucket TTL.
+func (n *Natty) GetWithTTLHint(ctx context.Context, bucket, key string) ([]byte, time.Duration, error) {
+	entry, err := n.GetEntry(ctx, bucket, key)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var status nats.KeyValueStatus
+
+	err = n.withBucket(ctx, bucket, false, 0, func(kv nats.KeyValue) (err error) {
+		status, err = kv.Status()
+		return errors.Wrap(err, "unable to fetch bucket status")
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if status.TTL() <= 0 {
+		return entry.Value, 0, nil
+	}
+
+	remaining := time.Until(entry.Created.Add(status.TTL()))
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	if remaining < n.TTLWarningThreshold {
+		return entry.Value, remaining, errors.Wrapf(ErrKeyExpiringSoon, "key '%s' expires in %s", key, remaining)
+	}
+
+	return entry.Value, remaining, nil
+}
+
+// CompareRevisions fetches the current revisions of key1 and key2 and reports
+// whether they are the same.
+//
+// NOTE: Revisions are bucket-wide sequence numbers so two different keys will
+// always be at different revisions, even if they contain the same value.
+func (n *Natty) CompareRevisions(ctx context.Context, bucket, key1, key2 string) (same bool, rev1, rev2 uint64, err error) {
+	entry1, err := n.GetEntry(ctx, bucket, key1)
+	if err != nil {
+		return false, 0, 0, errors.Wrapf(err, "unable to fetch key '%s'", key1)
+	}
+
+	entry2, err := n.GetEntry(ctx, bucket, key2)
+	if err != nil {
+		return false, 0, 0, errors.Wrapf(err, "unable to fetch key '%s'", key2)
+	}
+
+	return entry1.Revision == entry2.Revision, entry1.Revision, entry2.Revision, nil
+}
+
+// GetJSON fetches the value for a key and unmarshals it into out. Returns a
+// *JSONError if the stored value cannot be unmarshalled.
+func (n *Natty) GetJSON(ctx context.Context, bucket, key string, out interface{}) error {
+	data, err := n.Get(ctx, bucket, key)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(data, out); err != nil {
+		return &JSONError{Bucket: bucket, Key: key, Err: err}
+	}
+
+	return nil
+}
+
+// PutJSON marshals v and puts it into a bucket (auto-creating the bucket if it
+// does not exist). Returns a *JSONError if v cannot be marshalled.
+func (n *Natty) PutJSON(ctx context.Context, bucket, key string, v interface{}, keyTTL ...time.Duration) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return &JSONError{Bucket: bucket, Key: key, Err: err}
+	}
+
+	return n.Put(ctx, bucket, key, data, keyTTL...)
+}
+
+// Put puts a key/val into a bucket and will create bucket if it doesn't already
+// exit. TTL is optional - it will only be used if the bucket does not exist &
+// only the first TTL will be used.
+func (n *Natty) Put(ctx context.Context, bucket string, key string, data []byte, keyTTL ...time.Duration) (err error) {
+	ctx, done := n.trackKV(ctx, KVOpPut, bucket, key)
+	defer done(&err)
+
+	n.BucketHooks.beforePut(bucket, key, data)
+	defer func() { n.BucketHooks.afterPut(bucket, key, data, err) }()
+
+	if n.isClosed() {
+		return ErrConnectionClosed
+	}
+
+	// NOTE: Context usage for K/V operations is not available in NATS (yet)
+	var ttl time.Duration
+
+	if len(keyTTL) > 0 {
+		ttl = keyTTL[0]
+	}
+
+	kv, err := n.getBucket(ctx, bucket, true, ttl)
+	if err != nil {
+		return errors.Wrap(err, "unable to fetch bucket")
+	}
+
+	err = n.runKV(ctx, bucket, func() error {
+		_, err := kv.Put(key, data)
+		return err
+	})
+	if err != nil {
+		if err == context.DeadlineExceeded {
+			return err
+		}
+
+		return errors.Wrap(err, "unable to put key")
+	}
+
+	return nil
+}
+
+// BatchPutWithTTL puts all entries into bucket, auto-creating the bucket with
+// the given TTL if it does not exist. Keys are written in sorted order (each
+// waiting for the publish rate limiter, see SetPublishRateLimit()) and the
+// first failure aborts the batch.
+//
+// NOTE: NATS KV TTLs are bucket-wide (the bucket's MaxAge), so if the bucket
+// already exists with a different TTL, ErrBucketTTLMismatch is returned and
+// nothing is written.
+func (n *Natty) BatchPutWithTTL(ctx context.Context, bucket string, entries map[string][]byte, ttl time.Duration) error {
+	if n.isClosed() {
+		return ErrConnectionClosed
+	}
+
+	err := n.withBucket(ctx, bucket, true, ttl, func(kv nats.KeyValue) error {
+		status, err := kv.Status()
+		if err != nil {
+			return errors.Wrap(err, "unable to fetch bucket status")
+		}
+
+		if status.TTL() != ttl {
+			return ErrBucketTTLMismatch
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(entries))
+
+	for key := range entries {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if err := n.publishLimiter.Wait(ctx); err != nil {
+			return errors.Wrap(err, "unable to wait for publish rate limiter")
+		}
+
+		if err := n.Put(ctx, bucket, key, entries[key], ttl); err != nil {
+			return errors.Wrapf(err, "unable to put key '%s'", key)
+		}
+	}
+
+	return nil
+}
+
+// Create will add the key/value pair iff it does not exist; it will create
+// the bucket if it does not already exist. TTL is optional - it will only be
+// used if the bucket does not exist & only the first TTL will be used.
+func (n *Natty) Create(ctx context.Context, bucket string, key string, data []byte, keyTTL ...time.Duration) (err error) {
+	ctx, done := n.trackKV(ctx, KVOpCreate, bucket, key)
+	defer done(&err)
+
+	n.BucketHooks.beforeCreate(bucket, key, data)
+	defer func() { n.BucketHooks.afterCreate(bucket, key, data, err) }()
+
+	if n.isClosed() {
+		return ErrConnectionClosed
+	}
+
+	// NOTE: Context usage for K/V operations is not available in NATS (yet)
+	var ttl time.Duration
+
+	if len(keyTTL) > 0 {
+		ttl = keyTTL[0]
+	}
+
+	kv, err := n.getBucket(ctx, bucket, true, ttl)
+	if err != nil {
+		return errors.Wrap(err, "unable to fetch bucket")
+	}
+
+	err = n.runKV(ctx, bucket, func() error {
+		_, err := kv.Create(key, data)
+		return err
+	})
+	if err != nil {
+		if err == context.DeadlineExceeded {
+			return err
+		}
+
+		if isWrongLastSequence(err) {
+			return errors.Wrapf(ErrKeyExists, "unable to put key: %s", err)
+		}
+
+		return errors.Wrap(err, "unable to put key")
+	}
+
+	return nil
+}
+
+// GetOrCreate returns the value of key if it exists; otherwise it calls
+// factory and stores the result via Create() (auto-creating the bucket with
+// ttl, same as Create()). The returned bool is true if the value was created
+// by this call. If another caller creates the key first, factory's value is
+// discarded and the winner's value is returned instead.
+func (n *Natty) GetOrCreate(ctx context.Context, bucket, key string, ttl time.Duration, factory func() ([]byte, error)) ([]byte, bool, error) {
+	data, err := n.Get(ctx, bucket, key)
+	if err == nil {
+		return data, false, nil
+	}
+
+	if err != nats.ErrKeyNotFound {
+		return nil, false, errors.Wrap(err, "unable to get key")
+	}
+
+	data, err = factory()
+	if err != nil {
+		return nil, false, errors.Wrap(err, "factory error")
+	}
+
+	if err := n.Create(ctx, bucket, key, data, ttl); err != nil {
+		if !errors.Is(err, ErrKeyExists) {
+			return nil, false, err
+		}
+
+		// Lost the race - return the winner's value
+		data, err := n.Get(ctx, bucket, key)
+		if err != nil {
+			return nil, false, errors.Wrap(err, "unable to get key after losing create race")
+		}
+
+		return data, false, nil
+	}
+
+	return data, true, nil
+}
+
+func (n *Natty) Keys(ctx context.Context, bucket string) (_ []string, err error) {
+	ctx, done := n.trackKV(ctx, KVOpKeys, bucket, "")
+	defer done(&err)
+
+	if n.isClosed() {
+		return nil, ErrConnectionClosed
+	}
+
+	kv, err := n.getBucket(ctx, bucket, false, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []string
+
+	err = n.runKV(ctx, bucket, func() (err error) {
+		keys, err = kv.Keys(nats.Context(ctx))
+		return err
+	})
+	if err != nil {
+		if err == nats.ErrNoKeysFound {
+			return make([]string, 0), nil
+		}
+
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// KeysStream streams the keys in a bucket (in stream order) over the returned
+// key channel instead of materializing them like Keys(). At most one error is
+// sent on the error channel (ie. nats.ErrBucketNotFound); both channels are
+// closed once all keys have been sent, an error occurred or ctx is cancelled.
+// Callers that stop reading keys early must cancel ctx.
+func (n *Natty) KeysStream(ctx context.Context, bucket string) (<-chan string, <-chan error) {
+	keys := make(chan string)
+	errs := make(chan error, 1)
+
+	watcher, err := n.keysWatcher(ctx, bucket)
+	if err != nil {
+		errs <- err
+
+		close(keys)
+		close(errs)
+
+		return keys, errs
+	}
+
+	go func() {
+		defer close(errs)
+		defer close(keys)
+		defer watcher.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				errs <- ctx.Err()
+				return
+			case kve, ok := <-watcher.Updates():
+				// nil signals that all keys have been received
+				if !ok || kve == nil {
+					return
+				}
+
+				select {
+				case keys <- kve.Key():
+				case <-ctx.Done():
+					errs <- ctx.Err()
+					return
+				}
+			}
+		}
+	}()
+
+	return keys, errs
+}
+
+// keysWatcher starts the watcher for KeysStream(); like watch(), only the
+// setup is tracked (and held back by GracefulRestart()), not the stream itself
+func (n *Natty) keysWatcher(ctx context.Context, bucket string) (_ nats.KeyWatcher, err error) {
+	// Stream outlives this call so the bucket timeout is not applied to ctx
+	_, done := n.trackKV(ctx, KVOpKeys, bucket, "")
+	defer done(&err)
+
+	if n.isClosed() {
+		return nil, ErrConnectionClosed
+	}
+
+	kv, err := n.getBucket(ctx, bucket, false, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	watcher, err := kv.WatchAll(nats.IgnoreDeletes(), nats.MetaOnly())
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to start watcher")
+	}
+
+	return watcher, nil
+}
+
+// Watch streams changes to key in bucket until ctx is cancelled; key may
+// contain the NATS wildcards '*' and '>' (ie. ">" watches every key in the
+// bucket). The current value(s) are delivered first, followed by updates;
+// deletes are delivered with Operation set to nats.KeyValueDelete or
+// nats.KeyValuePurge. The returned channel is closed once the watch stops.
+//
+// NOTE: The channel is buffered (WatchBufferSize); entries are dropped (and a
+// warning is logged) if the consumer does not keep up.
+func (n *Natty) Watch(ctx context.Context, bucket, key string) (<-chan *KVEntry, error) {
+	return n.watch(ctx, bucket, key, false)
+}
+
+// WatchBucket streams changes to every key in bucket until ctx is cancelled.
+// Like Watch(), the current values are delivered first; unlike Watch(), a nil
+// entry is sent once all current values have been delivered so that callers
+// can tell the initial state apart from new changes.
+func (n *Natty) WatchBucket(ctx context.Context, bucket string) (<-chan *KVEntry, error) {
+	return n.watch(ctx, bucket, nats.AllKeys, true)
+}
+
+// watch implements Watch() and WatchBucket(); if sentinel is true, the nil
+// entry marking the end of the initial values is passed on (and never dropped).
+func (n *Natty) watch(ctx context.Context, bucket, key string, sentinel bool) (_ <-chan *KVEntry, err error) {
+	// Watch outlives this call so the bucket timeout is not applied to ctx
+	_, done := n.trackKV(ctx, KVOpWatch, bucket, key)
+	defer done(&err)
+
+	if n.isClosed() {
+		return nil, ErrConnectionClosed
+	}
+
+	kv, err := n.getBucket(ctx, bucket, false, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	watcher, err := kv.Watch(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to start watcher")
+	}
+
+	entries := make(chan *KVEntry, WatchBufferSize)
+
+	go func() {
+		defer close(entries)
+		defer watcher.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case kve, ok := <-watcher.Updates():
+				if !ok {
+					return
+				}
+
+				// nil signals that all initial values have been received
+				if kve == nil {
+					if !sentinel {
+						continue
+					}
+
+					select {
+					case entries <- nil:
+					case <-ctx.Done():
+						return
+					}
+
+					continue
+				}
+
+				select {
+				case entries <- newKVEntry(kve):
+				default:
+					warnw(n.log, "watch channel is full; dropping revision", "bucket", bucket,
+						"key", kve.Key(), "revision", kve.Revision())
+				}
+			}
+		}
+	}()
+
+	return entries, nil
+}
+
+// WatchWithBackpressure is like Watch() but never drops entries: entries
+// that the consumer has not received yet are queued in memory. Once the queue
+// holds Config.WatchMaxQueueBytes (of keys + values), the watcher stops
+// reading from NATS until the consumer catches up, which in turn slows down
+// delivery from the server via JetStream flow control. The returned channel is
+// closed once ctx is cancelled.
+func (n *Natty) WatchWithBackpressure(ctx context.Context, bucket, key string) (_ <-chan *KVEntry, err error) {
+	// Watch outlives this call so the bucket timeout is not applied to ctx
+	_, done := n.trackKV(ctx, KVOpWatch, bucket, key)
+	defer done(&err)
+
+	if n.isClosed() {
+		return nil, ErrConnectionClosed
+	}
+
+	kv, err := n.getBucket(ctx, bucket, false, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	watcher, err := kv.Watch(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to start watcher")
+	}
+
+	maxBytes := n.WatchMaxQueueBytes
+	if maxBytes <= 0 {
+		maxBytes = DefaultWatchMaxQueueBytes
+	}
+
+	entries := make(chan *KVEntry)
+
+	go func() {
+		defer close(entries)
+		defer watcher.Stop()
+
+		pumpWithBackpressure(ctx, watcher.Updates(), entries, maxBytes)
+	}()
+
+	return entries, nil
+}
+
+// pumpWithBackpressure moves entries from updates to entries via an in-memory
+// queue until ctx is done or updates is closed (and the queue is drained).
+// updates is not read from while the queue holds maxBytes or more.
+func pumpWithBackpressure(ctx context.Context, updates <-chan nats.KeyValueEntry, entries chan<- *KVEntry, maxBytes int) {
+	var (
+		queue       []*KVEntry
+		queuedBytes int
+	)
+
+	for {
+		// nil channels block forever, which disables the select cases below
+		var (
+			in   <-chan nats.KeyValueEntry
+			out  chan<- *KVEntry
+			next *KVEntry
+		)
+
+		if updates != nil && queuedBytes < maxBytes {
+			in = updates
+		}
+
+		if len(queue) > 0 {
+			out = entries
+			next = queue[0]
+		} else if updates == nil {
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case out <- next:
+			queue[0] = nil
+			queue = queue[1:]
+			queuedBytes -= len(next.Key) + len(next.Value)
+		case kve, ok := <-in:
+			if !ok {
+				updates = nil
+				continue
+			}
+
+			// nil signals that all initial values have been received
+			if kve == nil {
+				continue
+			}
+
+			entry := newKVEntry(kve)
+
+			queue = append(queue, entry)
+			queuedBytes += len(entry.Key) + len(entry.Value)
+		}
+	}
+}
+
+func (n *Natty) Delete(ctx context.Context, bucket string, key string) (err error) {
+	ctx, done := n.trackKV(ctx, KVOpDelete, bucket, key)
+	defer done(&err)
+
+	n.BucketHooks.beforeDelete(bucket, key)
+	defer func() { n.BucketHooks.afterDelete(bucket, key, err) }()
+
+	if n.isClosed() {
+		return ErrConnectionClosed
+	}
+
 	// NOTE: Context usage for K/V operations is not available in NATS (yet)
 	kv, err := n.getBucket(ctx, bucket, false, 0)
 	if err != nil {
 		if err == nats.ErrBucketNotFound {
-			return nil, nats.ErrKeyNotFound
+			return nil
 		}
 
-		return nil, errors.Wrap(err, "failed to get bucket")
+		return errors.Wrap(err, "unable to fetch bucket")
 	}
 
-	kve, err := kv.Get(key)
+	return n.runKV(ctx, bucket, func() error {
+		return kv.Purge(key)
+	})
+}
+
+// DeletePrefix deletes every key in bucket that starts with prefix and returns
+// the number of deleted keys. Keys are deleted one at a time (NATS has no
+// multi-key delete), so this is not atomic: on partial failure the number of
+// keys that were deleted is returned along with a *BatchDeleteError. A missing
+// bucket is not an error.
+func (n *Natty) DeletePrefix(ctx context.Context, bucket, prefix string) (int, error) {
+	keys, err := n.Keys(ctx, bucket)
 	if err != nil {
-		if err == nats.ErrKeyNotFound {
-			return nil, nats.ErrKeyNotFound
+		if err == nats.ErrBucketNotFound {
+			return 0, nil
 		}
 
-		return nil, errors.Wrap(err, "unable to fetch key")
+		return 0, errors.Wrap(err, "unable to fetch keys")
+	}
+
+	var (
+		deleted  int
+		failures = make(map[string]error)
+	)
+
+	for _, key := range keys {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+
+		if err := n.Delete(ctx, bucket, key); err != nil {
+			failures[key] = err
+			continue
+		}
+
+		deleted++
 	}
 
-	return kve.Value(), nil
+	if len(failures) > 0 {
+		return deleted, &BatchDeleteError{Bucket: bucket, Failures: failures}
+	}
+
+	return deleted, nil
 }
 
-// Put puts a key/val into a bucket and will create bucket if it doesn't already
-// exit. TTL is optional - it will only be used if the bucket does not exist &
-// only the first TTL will be used.
-func (n *Natty) Put(ctx context.Context, bucket string, key string, data []byte, keyTTL ...time.Duration) error {
-	// NOTE: Context usage for K/V operations is not available in NATS (yet)
-	var ttl time.Duration
+// DeleteByPattern deletes every key in bucket matching keyPattern (a NATS
+// subject pattern, ie. "user.*.profile" or "user.>") with a single purge of
+// the bucket's stream and returns the number of purged messages. Unlike
+// DeletePrefix(), keys do not need to be listed first; the purge is atomic. A
+// missing bucket is not an error.
+//
+// NOTE: The count includes older revisions of matching keys if the bucket
+// keeps history. Purged keys are removed outright (no delete markers are
+// written), so watchers are not notified of the deletes.
+func (n *Natty) DeleteByPattern(ctx context.Context, bucket, keyPattern string) (_ int, err error) {
+	ctx, done := n.trackKV(ctx, KVOpDelete, bucket, keyPattern)
+	defer done(&err)
 
-	if len(keyTTL) > 0 {
-		ttl = keyTTL[0]
+	if n.isClosed() {
+		return 0, ErrConnectionClosed
 	}
 
-	kv, err := n.getBucket(ctx, bucket, true, ttl)
-	if err != nil {
-		return errors.Wrap(err, "unable to fetch bucket")
+	if keyPattern == "" {
+		return 0, errors.New("key pattern cannot be empty")
 	}
 
-	if _, err := kv.Put(key, data); err != nil {
-		return errors.Wrap(err, "unable to put key")
+	if _, err := n.getBucket(ctx, bucket, false, 0); err != nil {
+		if err == nats.ErrBucketNotFound {
+			return 0, nil
+		}
+
+		return 0, errors.Wrap(err, "unable to fetch bucket")
 	}
 
-	return nil
+	purged, err := n.purgeStream(ctx, kvStreamPrefix+bucket, &streamPurgeRequest{
+		Subject: kvSubject(bucket, keyPattern),
+	})
+	if err != nil {
+		return 0, errors.Wrap(err, "unable to purge keys")
+	}
+
+	return int(purged), nil
 }
 
-// Create will add the key/value pair iff it does not exist; it will create
-// the bucket if it does not already exist. TTL is optional - it will only be
-// used if the bucket does not exist & only the first TTL will be used.
-func (n *Natty) Create(ctx context.Context, bucket string, key string, data []byte, keyTTL ...time.Duration) error {
-	// NOTE: Context usage for K/V operations is not available in NATS (yet)
-	var ttl time.Duration
+// CompactHistory trims the history of a key down to the newest keepRevisions
+// values. NATS only supports a bucket-wide history setting, so compaction is
+// done by re-writing the kept values: the oldest kept value is written with a
+// subject rollup (which purges all prior revisions) and the remaining values
+// are written via sequential Update() calls.
+//
+// The rollup and each re-write fire the Put hooks (see BucketHooks).
+//
+// NOTE: Kept values will be assigned new revisions; delete markers are not kept.
+func (n *Natty) CompactHistory(ctx context.Context, bucket, key string, keepRevisions int) (err error) {
+	ctx, done := n.trackKV(ctx, KVOpCompact, bucket, key)
+	defer done(&err)
 
-	if len(keyTTL) > 0 {
-		ttl = keyTTL[0]
+	if n.isClosed() {
+		return ErrConnectionClosed
 	}
 
-	kv, err := n.getBucket(ctx, bucket, true, ttl)
+	if keepRevisions < 1 {
+		return errors.New("keepRevisions must be greater than 0")
+	}
+
+	kv, err := n.getBucket(ctx, bucket, false, 0)
 	if err != nil {
 		return errors.Wrap(err, "unable to fetch bucket")
 	}
 
-	if _, err := kv.Create(key, data); err != nil {
-		return errors.Wrap(err, "unable to put key")
+	var history []nats.KeyValueEntry
+
+	err = n.runKV(ctx, bucket, func() (err error) {
+		history, err = kv.History(key, nats.Context(ctx))
+		return err
+	})
+	if err != nil {
+		if err == nats.ErrKeyNotFound {
+			return nats.ErrKeyNotFound
+		}
+
+		return errors.Wrap(err, "unable to fetch key history")
+	}
+
+	latest := history[len(history)-1]
+
+	if latest.Operation() != nats.KeyValuePut {
+		return nats.ErrKeyNotFound
+	}
+
+	entries := make([]nats.KeyValueEntry, 0)
+
+	for _, e := range history {
+		if e.Operation() == nats.KeyValuePut {
+			entries = append(entries, e)
+		}
+	}
+
+	if len(history) <= keepRevisions && len(entries) == len(history) {
+		// Nothing to compact
+		return nil
+	}
+
+	if len(entries) > keepRevisions {
+		entries = entries[len(entries)-keepRevisions:]
+	}
+
+	// Rollup purges all previous revisions of the key; expected last subject
+	// sequence ensures that we do not clobber a concurrent write.
+	msg := nats.NewMsg(n.kvPublishSubject(bucket, key))
+	msg.Data = entries[0].Value()
+	msg.Header.Set(nats.MsgRollup, nats.MsgRollupSubject)
+	msg.Header.Set(nats.ExpectedLastSubjSeqHdr, strconv.FormatUint(latest.Revision(), 10))
+
+	var revision uint64
+
+	err = n.compactPut(bucket, key, msg.Data, func() error {
+		return n.runKV(ctx, bucket, func() error {
+			ack, err := n.getJS().PublishMsg(msg, nats.Context(ctx))
+			if err != nil {
+				return err
+			}
+
+			revision = ack.Sequence
+
+			return nil
+		})
+	})
+	if err != nil {
+		return errors.Wrap(err, "unable to rollup key history")
+	}
+
+	for _, e := range entries[1:] {
+		value := e.Value()
+
+		err = n.compactPut(bucket, key, value, func() error {
+			return n.runKV(ctx, bucket, func() (err error) {
+				revision, err = kv.Update(key, value, revision)
+				return err
+			})
+		})
+		if err != nil {
+			return errors.Wrap(err, "unable to re-write key revision")
+		}
 	}
 
 	return nil
 }
 
-func (n *Natty) Keys(ctx context.Context, bucket string) ([]string, error) {
-	kv, err := n.getBucket(ctx, bucket, false, 0)
-	if err != nil {
-		return nil, err
+// compactPut runs write (which writes value to key) between the Put hooks
+func (n *Natty) compactPut(bucket, key string, value []byte, write func() error) (err error) {
+	n.BucketHooks.beforePut(bucket, key, value)
+	defer func() { n.BucketHooks.afterPut(bucket, key, value, err) }()
+
+	return write()
+}
+
+// BucketChecksum computes a deterministic SHA256 checksum of the current state
+// of a bucket. Entries are sorted by key and hashed as
+// len(key)||key||revision||len(value)||value (lengths and revision are encoded
+// as 8 byte big-endian) so that different entries cannot produce the same
+// byte stream. Buckets with identical contents (including revisions) will
+// produce identical checksums; keys are read via GetEntry().
+//
+// NOTE: Revisions are assigned by each bucket, so a copy of a bucket (ie. one
+// kept in sync by KeyValueReplicator) only matches its source if every key was
+// written in the same order and no other writes happened in either bucket.
+func (n *Natty) BucketChecksum(ctx context.Context, bucket string) ([]byte, error) {
+	if n.isClosed() {
+		return nil, ErrConnectionClosed
 	}
 
-	keys, err := kv.Keys(nats.Context(ctx))
+	keys, err := n.Keys(ctx, bucket)
 	if err != nil {
-		if err == nats.ErrNoKeysFound {
-			return make([]string, 0), nil
+		return nil, errors.Wrap(err, "unable to fetch keys")
+	}
+
+	sort.Strings(keys)
+
+	h := sha256.New()
+	buf := make([]byte, 8)
+
+	writeUint64 := func(v uint64) {
+		binary.BigEndian.PutUint64(buf, v)
+		h.Write(buf)
+	}
+
+	for _, key := range keys {
+		entry, err := n.GetEntry(ctx, bucket, key)
+		if err != nil {
+			if err == nats.ErrKeyNotFound {
+				// Key was deleted since we listed keys
+				continue
+			}
+
+			return nil, errors.Wrapf(err, "unable to fetch key '%s'", key)
 		}
 
-		return nil, err
+		writeUint64(uint64(len(key)))
+		h.Write([]byte(key))
+		writeUint64(entry.Revision)
+		writeUint64(uint64(len(entry.Value)))
+		h.Write(entry.Value)
 	}
 
-	return keys, nil
+	return h.Sum(nil), nil
 }
 
-func (n *Natty) Delete(ctx context.Context, bucket string, key string) error {
-	// NOTE: Context usage for K/V operations is not available in NATS (yet)
-	kv, err := n.getBucket(ctx, bucket, false, 0)
+// Reconcile brings the contents of a bucket into conformance with the desired
+// state: keys missing from the bucket are added, keys with differing values are
+// updated and keys not present in desired are deleted. Will auto-create the
+// bucket if it does not already exist. Keys are read via GetEntry() and
+// written via Put() and Delete(), so the same retries, timeouts and hooks
+// apply as for individual calls.
+func (n *Natty) Reconcile(ctx context.Context, bucket string, desired map[string][]byte) (ReconcileResult, error) {
+	result := ReconcileResult{}
+
+	if n.isClosed() {
+		return result, ErrConnectionClosed
+	}
+
+	ctx, release := n.guardKV(ctx)
+	defer release()
+
+	if _, err := n.getBucket(ctx, bucket, true, 0); err != nil {
+		return result, errors.Wrap(err, "unable to fetch bucket")
+	}
+
+	keys, err := n.Keys(ctx, bucket)
 	if err != nil {
-		if err == nats.ErrBucketNotFound {
-			return nil
+		return result, errors.Wrap(err, "unable to fetch keys")
+	}
+
+	current := make(map[string][]byte)
+
+	for _, key := range keys {
+		entry, err := n.GetEntry(ctx, bucket, key)
+		if err != nil {
+			if err == nats.ErrKeyNotFound {
+				// Key was deleted since we listed keys
+				continue
+			}
+
+			return result, errors.Wrapf(err, "unable to fetch key '%s'", key)
 		}
 
-		return errors.Wrap(err, "unable to fetch bucket")
+		current[key] = entry.Value
+	}
+
+	for key := range current {
+		if _, ok := desired[key]; ok {
+			continue
+		}
+
+		if err := n.Delete(ctx, bucket, key); err != nil {
+			return result, errors.Wrapf(err, "unable to delete key '%s'", key)
+		}
+
+		result.Deleted++
 	}
 
-	return kv.Purge(key)
+	for key, value := range desired {
+		existing, ok := current[key]
+		if ok && bytes.Equal(existing, value) {
+			continue
+		}
+
+		if err := n.Put(ctx, bucket, key, value); err != nil {
+			return result, errors.Wrapf(err, "unable to put key '%s'", key)
+		}
+
+		if ok {
+			result.Updated++
+		} else {
+			result.Added++
+		}
+	}
+
+	return result, nil
 }
 
 func (n *Natty) DeleteBucket(_ context.Context, bucket string) error {
+	if n.isClosed() {
+		return ErrConnectionClosed
+	}
+
 	// Get rid of it locally (noop if doesn't exist)
 	n.kvMap.Delete(bucket)
 
-	if err := n.js.DeleteKeyValue(bucket); err != nil {
+	if err := n.getJS().DeleteKeyValue(bucket); err != nil {
 		if err == nats.ErrStreamNotFound {
 			return nil
 		}
@@ -134,6 +1183,10 @@ func (n *Natty) DeleteBucket(_ context.Context, bucket string) error {
 // CreateBucket creates a bucket; returns an error if it already exists.
 // Context usage not supported by NATS kv (yet).
 func (n *Natty) CreateBucket(_ context.Context, name string, ttl time.Duration, description ...string) error {
+	if n.isClosed() {
+		return ErrConnectionClosed
+	}
+
 	cfg := &nats.KeyValueConfig{
 		Bucket: name,
 		TTL:    ttl,
@@ -143,7 +1196,7 @@ func (n *Natty) CreateBucket(_ context.Context, name string, ttl time.Duration,
 		cfg.Description = description[0]
 	}
 
-	kv, err := n.js.CreateKeyValue(cfg)
+	kv, err := n.getJS().CreateKeyValue(cfg)
 	if err != nil {
 		return err
 	}
@@ -153,8 +1206,242 @@ func (n *Natty) CreateBucket(_ context.Context, name string, ttl time.Duration,
 	return nil
 }
 
-// getBucket will either fetch a known bucket or create it if it doesn't exist
-func (n *Natty) getBucket(_ context.Context, bucket string, create bool, ttl time.Duration) (nats.KeyValue, error) {
+// UpdateBucketConfig updates the configuration of an existing bucket by
+// translating cfg into the config of the bucket's backing stream. The
+// following fields can be changed after creation: Description, History, TTL,
+// MaxBytes, MaxValueSize and Replicas (clustered servers only). Storage and
+// Placement cannot be changed by NATS and are ignored; cfg.Bucket must either
+// be empty or match bucket. Only fields that are set (non-zero) in cfg are
+// changed; all other settings of the bucket are kept as they are.
+//
+// NOTE: Because zero values mean "unchanged", a TTL cannot be removed and a
+// description cannot be cleared via UpdateBucketConfig().
+func (n *Natty) UpdateBucketConfig(_ context.Context, bucket string, cfg *nats.KeyValueConfig) error {
+	if n.isClosed() {
+		return ErrConnectionClosed
+	}
+
+	if cfg == nil {
+		return nats.ErrKeyValueConfigRequired
+	}
+
+	if cfg.Bucket != "" && cfg.Bucket != bucket {
+		return errors.Errorf("config bucket '%s' does not match bucket '%s'", cfg.Bucket, bucket)
+	}
+
+	if cfg.History > nats.KeyValueMaxHistory {
+		return nats.ErrHistoryToLarge
+	}
+
+	info, err := n.getJS().StreamInfo(kvStreamPrefix + bucket)
+	if err != nil {
+		if err == nats.ErrStreamNotFound {
+			return nats.ErrBucketNotFound
+		}
+
+		return errors.Wrap(err, "unable to fetch bucket stream")
+	}
+
+	scfg := info.Config
+
+	if cfg.Description != "" {
+		scfg.Description = cfg.Description
+	}
+
+	if cfg.History > 0 {
+		scfg.MaxMsgsPerSubject = int64(cfg.History)
+	}
+
+	if cfg.TTL > 0 {
+		scfg.MaxAge = cfg.TTL
+
+		// Same as nats.go on bucket creation: the duplicate window may not
+		// exceed the TTL
+		if scfg.Duplicates > cfg.TTL {
+			scfg.Duplicates = cfg.TTL
+		}
+	}
+
+	if cfg.MaxBytes != 0 {
+		scfg.MaxBytes = cfg.MaxBytes
+	}
+
+	if cfg.MaxValueSize != 0 {
+		scfg.MaxMsgSize = cfg.MaxValueSize
+	}
+
+	if cfg.Replicas != 0 {
+		scfg.Replicas = cfg.Replicas
+	}
+
+	if _, err := n.getJS().UpdateStream(&scfg); err != nil {
+		return errors.Wrap(err, "unable to update bucket stream")
+	}
+
+	// Make sure the next op picks up the new config
+	n.kvMap.Delete(bucket)
+
+	return nil
+}
+
+// CreateBucketWithConfig creates a bucket with full control over its config
+// (ie. replicas, max bytes, storage type, placement); returns an error if the
+// bucket already exists with a different config.
+func (n *Natty) CreateBucketWithConfig(_ context.Context, cfg *nats.KeyValueConfig) (nats.KeyValue, error) {
+	if n.isClosed() {
+		return nil, ErrConnectionClosed
+	}
+
+	if cfg == nil {
+		return nil, nats.ErrKeyValueConfigRequired
+	}
+
+	kv, err := n.getJS().CreateKeyValue(cfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to create bucket")
+	}
+
+	n.kvMap.Put(cfg.Bucket, kv)
+
+	return kv, nil
+}
+
+// TemporaryBucket creates a uniquely named bucket, calls fn with the bucket
+// name and deletes the bucket once fn returns (even if fn returns an error).
+// fn's error takes precedence over any error encountered during bucket deletion.
+func (n *Natty) TemporaryBucket(ctx context.Context, fn func(bucket string) error) (err error) {
+	bucket := uuid.NewV4().String()
+
+	if err := n.CreateBucket(ctx, bucket, 0, "temporary bucket via natty"); err != nil {
+		return errors.Wrap(err, "unable to create temporary bucket")
+	}
+
+	defer func() {
+		if deleteErr := n.DeleteBucket(ctx, bucket); deleteErr != nil {
+			errorw(n.log, "unable to delete temporary bucket", "bucket", bucket, "error", deleteErr)
+
+			if err == nil {
+				err = errors.Wrap(deleteErr, "unable to delete temporary bucket")
+			}
+		}
+	}()
+
+	return fn(bucket)
+}
+
+// TemporaryKey generates a unique key name, calls fn with it and deletes the
+// key from bucket once fn returns (even if fn returns an error). fn is
+// responsible for writing the key. fn's error takes precedence over any error
+// encountered during key deletion.
+func (n *Natty) TemporaryKey(ctx context.Context, bucket string, fn func(key string) error) (err error) {
+	key := uuid.NewV4().String()
+
+	defer func() {
+		if deleteErr := n.Delete(ctx, bucket, key); deleteErr != nil {
+			errorw(n.log, "unable to delete temporary key", "bucket", bucket, "key", key, "error", deleteErr)
+
+			if err == nil {
+				err = errors.Wrap(deleteErr, "unable to delete temporary key")
+			}
+		}
+	}()
+
+	return fn(key)
+}
+
+// CopyKey copies the current value of srcKey in srcBucket to dstKey in
+// dstBucket; the destination bucket is auto-created if it does not exist (same
+// as Put()). Returns nats.ErrKeyNotFound if the source key (or bucket) does
+// not exist. Only the value is copied - revision and timestamps are not.
+func (n *Natty) CopyKey(ctx context.Context, srcBucket, srcKey, dstBucket, dstKey string) error {
+	data, err := n.Get(ctx, srcBucket, srcKey)
+	if err != nil {
+		if err == nats.ErrKeyNotFound {
+			return err
+		}
+
+		return errors.Wrap(err, "unable to fetch source key")
+	}
+
+	if err := n.Put(ctx, dstBucket, dstKey, data); err != nil {
+		return errors.Wrap(err, "unable to put destination key")
+	}
+
+	return nil
+}
+
+// trackKV starts metrics, tracing and debug logging for a KV operation,
+// holds back GracefulRestart() until the operation is done and applies the
+// bucket timeout (if any) to the returned context. The returned
+// func is intended to be deferred with a pointer to the method's named error
+// return:
+//
+//	ctx, done := n.trackKV(ctx, KVOpGet, bucket, key)
+//	defer done(&err)
+func (n *Natty) trackKV(ctx context.Context, operation, bucket, key string) (context.Context, func(err *error)) {
+	start := time.Now()
+	cancel := func() {}
+
+	ctx, release := n.guardKV(ctx)
+
+	if timeout, ok := n.BucketTimeouts[bucket]; ok && timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	}
+
+	metricsDone := n.metrics.StartKV(operation, bucket)
+	ctx, traceDone := n.tracer.StartKV(ctx, operation, bucket, key)
+
+	return ctx, func(err *error) {
+		cancel()
+		release()
+		traceDone(*err)
+		metricsDone(*err)
+
+		debugw(n.log, "kv operation", "operation", operation, "bucket", bucket,
+			"key", key, "latency", time.Since(start), "error", *err)
+	}
+}
+
+// runKV runs fn, retrying transient errors according to Config.Retry, and
+// enforces the bucket timeout (if any) configured via Config.BucketTimeouts.
+// Returns ErrCircuitOpen without calling fn if the circuit breaker is open.
+func (n *Natty) runKV(ctx context.Context, bucket string, fn func() error) error {
+	return n.breaker.do(func() error {
+		return n.Retry.do(ctx, func() error {
+			return n.runWithBucketTimeout(ctx, bucket, fn)
+		})
+	})
+}
+
+// runWithBucketTimeout runs fn; if ctx expires before fn completes, ctx.Err()
+// is returned. NATS K/V operations do not accept a context (yet) so fn is ran
+// in a goroutine; NOTE: fn may still complete after the timeout.
+func (n *Natty) runWithBucketTimeout(ctx context.Context, bucket string, fn func() error) error {
+	if _, ok := n.BucketTimeouts[bucket]; !ok {
+		return fn()
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	errCh := make(chan error, 1)
+
+	go func() {
+		errCh <- fn()
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// getBucket will either fetch a known bucket or create it if it doesn't exist;
+// fetching and creating the bucket are retried according to Config.Retry.
+func (n *Natty) getBucket(ctx context.Context, bucket string, create bool, ttl time.Duration) (nats.KeyValue, error) {
 	// NOTE: Context usage for K/V operations is not available in NATS (yet)
 
 	// Do we have this bucket locally?
@@ -164,7 +1451,10 @@ func (n *Natty) getBucket(_ context.Context, bucket string, create bool, ttl tim
 	}
 
 	// Nope - try to get it from NATS
-	kv, err := n.js.KeyValue(bucket)
+	err := n.Retry.do(ctx, func() (err error) {
+		kv, err = n.getJS().KeyValue(bucket)
+		return err
+	})
 	if err != nil {
 		// Is this a fatal error?
 		if err != nats.ErrBucketNotFound {
@@ -180,13 +1470,28 @@ func (n *Natty) getBucket(_ context.Context, bucket string, create bool, ttl tim
 
 	// Bucket was not found and we want to create
 	if kv == nil && create {
-		kv, err = n.js.CreateKeyValue(&nats.KeyValueConfig{
+		cfg := &nats.KeyValueConfig{
 			Bucket:      bucket,
 			Description: "auto-created bucket via natty",
 			History:     5,
 			TTL:         ttl,
-		})
+		}
 
+		// Use the configured override (if any); TTL given to the caller is
+		// only used if the override does not set one
+		if override, ok := n.BucketConfigs[bucket]; ok && override != nil {
+			cfg = copyKVConfig(override)
+			cfg.Bucket = bucket
+
+			if cfg.TTL == 0 {
+				cfg.TTL = ttl
+			}
+		}
+
+		err = n.Retry.do(ctx, func() (err error) {
+			kv, err = n.getJS().CreateKeyValue(cfg)
+			return err
+		})
 		if err != nil {
 			return nil, errors.Wrap(err, "bucket create error in getBucket()")
 		}
@@ -201,6 +1506,40 @@ func (n *Natty) getBucket(_ context.Context, bucket string, create bool, ttl tim
 	return nil, nats.ErrBucketNotFound
 }
 
+// withBucket calls fn with bucket (see getBucket()) while holding back
+// GracefulRestart(); for operations that do not go through trackKV(). The
+// bucket is looked up on every call as handles fetched before a restart
+// belong to the old connection.
+func (n *Natty) withBucket(ctx context.Context, bucket string, create bool, ttl time.Duration, fn func(kv nats.KeyValue) error) error {
+	ctx, release := n.guardKV(ctx)
+	defer release()
+
+	kv, err := n.getBucket(ctx, bucket, create, ttl)
+	if err != nil {
+		return errors.Wrap(err, "unable to fetch bucket")
+	}
+
+	return fn(kv)
+}
+
+// copyKVConfig returns a shallow copy of cfg (Placement is shared)
+func copyKVConfig(cfg *nats.KeyValueConfig) *nats.KeyValueConfig {
+	c := *cfg
+	return &c
+}
+
+func newKVEntry(kve nats.KeyValueEntry) *KVEntry {
+	return &KVEntry{
+		Bucket:    kve.Bucket(),
+		Key:       kve.Key(),
+		Value:     kve.Value(),
+		Revision:  kve.Revision(),
+		Delta:     kve.Delta(),
+		Created:   kve.Created(),
+		Operation: kve.Operation(),
+	}
+}
+
 func (k *KeyValueMap) Get(key string) (nats.KeyValue, bool) {
 	k.rwMutex.RLock()
 	v, ok := k.kvMap[key]
@@ -215,6 +1554,13 @@ func (k *KeyValueMap) Put(key string, value nats.KeyValue) {
 	k.rwMutex.Unlock()
 }
 
+// reset drops all cached buckets (ie. after the connection was replaced)
+func (k *KeyValueMap) reset() {
+	k.rwMutex.Lock()
+	k.kvMap = make(map[string]nats.KeyValue)
+	k.rwMutex.Unlock()
+}
+
 // Delete functionality is not used because there is no way to list buckets in NATS
 func (k *KeyValueMap) Delete(key string) {
 	k.rwMutex.Lock()
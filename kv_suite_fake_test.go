@@ -0,0 +1,22 @@
+package natty_test
+
+import (
+	. "github.com/onsi/ginkgo"
+
+	"github.com/batchcorp/natty"
+	"github.com/batchcorp/natty/nattytest"
+)
+
+// nattytest imports natty, so FakeNatty can only be used from an external
+// test package
+var _ = Describe("KVTestSuite", func() {
+	Context("FakeNatty", func() {
+		suite := &natty.KVTestSuite{}
+
+		BeforeEach(func() {
+			suite.Subject = nattytest.NewFakeNatty()
+		})
+
+		suite.SharedBehaviors()
+	})
+})
@@ -0,0 +1,184 @@
+package natty
+
+import (
+	"context"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// KVTestSuite contains the KV behaviors that every INatty implementation is
+// expected to share. Subject must be set (ie. in a BeforeEach) before the
+// specs registered via SharedBehaviors() run.
+//
+// The suite is run against Natty and RaceTestNatty (below) and against
+// nattytest.FakeNatty (see kv_suite_fake_test.go); new implementations should
+// be added there as well.
+type KVTestSuite struct {
+	Subject INatty
+}
+
+// SharedBehaviors registers the shared KV specs in the current container
+func (s *KVTestSuite) SharedBehaviors() {
+	ctx := context.Background()
+
+	Describe("Put/Get", func() {
+		It("should round trip a value and auto-create the bucket", func() {
+			bucket, key, value := NewKVSet()
+
+			Expect(s.Subject.Put(ctx, bucket, key, value)).To(Succeed())
+
+			data, err := s.Subject.Get(ctx, bucket, key)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal(value))
+		})
+
+		It("should overwrite an existing value", func() {
+			bucket, key, value := NewKVSet()
+
+			Expect(s.Subject.Put(ctx, bucket, key, value)).To(Succeed())
+			Expect(s.Subject.Put(ctx, bucket, key, []byte("updated"))).To(Succeed())
+
+			entry, err := s.Subject.GetEntry(ctx, bucket, key)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(entry.Value).To(Equal([]byte("updated")))
+			Expect(entry.Revision).To(Equal(uint64(2)))
+		})
+
+		It("should return ErrKeyNotFound for missing bucket or key", func() {
+			bucket, key, value := NewKVSet()
+
+			_, err := s.Subject.Get(ctx, bucket, key)
+			Expect(err).To(Equal(nats.ErrKeyNotFound))
+
+			Expect(s.Subject.Put(ctx, bucket, key, value)).To(Succeed())
+
+			_, err = s.Subject.Get(ctx, bucket, "missing")
+			Expect(err).To(Equal(nats.ErrKeyNotFound))
+		})
+	})
+
+	Describe("Create", func() {
+		It("should only create a key if it does not exist", func() {
+			bucket, key, value := NewKVSet()
+
+			Expect(s.Subject.Create(ctx, bucket, key, value)).To(Succeed())
+			Expect(s.Subject.Create(ctx, bucket, key, value)).ToNot(Succeed())
+
+			data, err := s.Subject.Get(ctx, bucket, key)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal(value))
+		})
+	})
+
+	Describe("Delete", func() {
+		It("should delete a key", func() {
+			bucket, key, value := NewKVSet()
+
+			Expect(s.Subject.Put(ctx, bucket, key, value)).To(Succeed())
+			Expect(s.Subject.Delete(ctx, bucket, key)).To(Succeed())
+
+			_, err := s.Subject.Get(ctx, bucket, key)
+			Expect(err).To(Equal(nats.ErrKeyNotFound))
+		})
+
+		It("should not error on a missing bucket", func() {
+			bucket, key, _ := NewKVSet()
+
+			Expect(s.Subject.Delete(ctx, bucket, key)).To(Succeed())
+		})
+	})
+
+	Describe("Keys", func() {
+		It("should list keys", func() {
+			bucket, key, value := NewKVSet()
+
+			Expect(s.Subject.Put(ctx, bucket, key, value)).To(Succeed())
+			Expect(s.Subject.Put(ctx, bucket, "other", value)).To(Succeed())
+
+			keys, err := s.Subject.Keys(ctx, bucket)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(keys).To(ConsistOf(key, "other"))
+		})
+
+		It("should return no keys for an empty bucket", func() {
+			bucket, _, _ := NewKVSet()
+
+			Expect(s.Subject.CreateBucket(ctx, bucket, time.Minute)).To(Succeed())
+
+			keys, err := s.Subject.Keys(ctx, bucket)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(keys).To(BeEmpty())
+		})
+	})
+
+	Describe("CreateBucket/DeleteBucket", func() {
+		It("should create and delete a bucket", func() {
+			bucket, key, value := NewKVSet()
+
+			Expect(s.Subject.CreateBucket(ctx, bucket, time.Minute, "test bucket")).To(Succeed())
+			Expect(s.Subject.Put(ctx, bucket, key, value)).To(Succeed())
+			Expect(s.Subject.DeleteBucket(ctx, bucket)).To(Succeed())
+
+			_, err := s.Subject.Get(ctx, bucket, key)
+			Expect(err).To(Equal(nats.ErrKeyNotFound))
+		})
+	})
+
+	Describe("JSON", func() {
+		It("should round trip a JSON value", func() {
+			bucket, key, _ := NewKVSet()
+
+			in := map[string]string{"foo": "bar"}
+			out := make(map[string]string)
+
+			Expect(s.Subject.PutJSON(ctx, bucket, key, in)).To(Succeed())
+			Expect(s.Subject.GetJSON(ctx, bucket, key, &out)).To(Succeed())
+			Expect(out).To(Equal(in))
+		})
+	})
+
+	Describe("Increment/Decrement", func() {
+		It("should add and subtract from a counter", func() {
+			bucket, key, _ := NewKVSet()
+
+			value, err := s.Subject.Increment(ctx, bucket, key, 10)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(value).To(Equal(int64(10)))
+
+			value, err = s.Subject.Decrement(ctx, bucket, key, 3)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(value).To(Equal(int64(7)))
+		})
+	})
+}
+
+var _ = Describe("KVTestSuite", func() {
+	Context("Natty", func() {
+		suite := &KVTestSuite{}
+
+		BeforeEach(func() {
+			n, err := New(NewConfig())
+			Expect(err).ToNot(HaveOccurred())
+
+			suite.Subject = n
+		})
+
+		suite.SharedBehaviors()
+	})
+
+	Context("RaceTestNatty", func() {
+		suite := &KVTestSuite{}
+
+		BeforeEach(func() {
+			n, err := New(NewConfig())
+			Expect(err).ToNot(HaveOccurred())
+
+			suite.Subject = NewRaceTestNatty(n, nil)
+		})
+
+		suite.SharedBehaviors()
+	})
+})
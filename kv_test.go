@@ -1,9 +1,11 @@
-// NOTE: These tests require NATS to be available on "localhost"
 package natty
 
 import (
 	"context"
+	"errors"
 	"math/rand"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/nats-io/nats.go"
@@ -61,6 +63,310 @@ var _ = Describe("KV", func() {
 		})
 	})
 
+	Describe("GetEntry", func() {
+		It("should return the value and metadata for a key", func() {
+			bucket, key, value := NewKVSet()
+
+			Expect(n.Put(context.Background(), bucket, key, []byte("first"))).To(Succeed())
+			Expect(n.Put(context.Background(), bucket, key, value)).To(Succeed())
+
+			entry, err := n.GetEntry(context.Background(), bucket, key)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(entry).ToNot(BeNil())
+
+			Expect(entry.Bucket).To(Equal(bucket))
+			Expect(entry.Key).To(Equal(key))
+			Expect(entry.Value).To(Equal(value))
+			Expect(entry.Revision).To(Equal(uint64(2)))
+			Expect(entry.Operation).To(Equal(nats.KeyValuePut))
+			Expect(entry.Created).To(BeTemporally("~", time.Now(), 5*time.Second))
+		})
+
+		It("should return ErrKeyNotFound for missing key", func() {
+			bucket, key, value := NewKVSet()
+
+			Expect(n.Put(context.Background(), bucket, "other", value)).To(Succeed())
+
+			entry, err := n.GetEntry(context.Background(), bucket, key)
+			Expect(err).To(Equal(nats.ErrKeyNotFound))
+			Expect(entry).To(BeNil())
+		})
+	})
+
+	Describe("GetIfNewer", func() {
+		It("should return the value only if modified since revision", func() {
+			bucket, key, value := NewKVSet()
+
+			Expect(n.Put(context.Background(), bucket, key, value)).To(Succeed())
+
+			// Modified
+			data, revision, err := n.GetIfNewer(context.Background(), bucket, key, 0)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal(value))
+			Expect(revision).To(Equal(uint64(1)))
+
+			// Not modified
+			data, revision, err = n.GetIfNewer(context.Background(), bucket, key, revision)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(BeNil())
+			Expect(revision).To(Equal(uint64(1)))
+		})
+
+		It("should return ErrKeyNotFound for missing key", func() {
+			bucket, key, _ := NewKVSet()
+
+			_, revision, err := n.GetIfNewer(context.Background(), bucket, key, 5)
+			Expect(err).To(Equal(nats.ErrKeyNotFound))
+			Expect(revision).To(Equal(uint64(5)))
+		})
+	})
+
+	Describe("GetIfModifiedSince", func() {
+		It("should return the value only if modified since timestamp", func() {
+			bucket, key, value := NewKVSet()
+
+			before := time.Now()
+
+			time.Sleep(100 * time.Millisecond)
+
+			Expect(n.Put(context.Background(), bucket, key, value)).To(Succeed())
+
+			time.Sleep(100 * time.Millisecond)
+
+			after := time.Now()
+
+			data, modified, err := n.GetIfModifiedSince(context.Background(), bucket, key, before)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(modified).To(BeTrue())
+			Expect(data).To(Equal(value))
+
+			data, modified, err = n.GetIfModifiedSince(context.Background(), bucket, key, after)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(modified).To(BeFalse())
+			Expect(data).To(BeNil())
+		})
+	})
+
+	Describe("GetWithTTLHint", func() {
+		It("should return the remaining TTL", func() {
+			bucket, key, value := NewKVSet()
+
+			Expect(n.Put(context.Background(), bucket, key, value, 5*time.Second)).To(Succeed())
+
+			data, remaining, err := n.GetWithTTLHint(context.Background(), bucket, key)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal(value))
+			Expect(remaining).To(BeNumerically(">", 4*time.Second))
+			Expect(remaining).To(BeNumerically("<=", 5*time.Second))
+		})
+
+		It("should return ErrKeyExpiringSoon below the warning threshold", func() {
+			bucket, key, value := NewKVSet()
+
+			cfg := NewConfig()
+			cfg.TTLWarningThreshold = time.Minute
+
+			warning, err := New(cfg)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(warning.Put(context.Background(), bucket, key, value, 5*time.Second)).To(Succeed())
+
+			data, remaining, err := warning.GetWithTTLHint(context.Background(), bucket, key)
+			Expect(errors.Is(err, ErrKeyExpiringSoon)).To(BeTrue())
+			Expect(data).To(Equal(value))
+			Expect(remaining).To(BeNumerically(">", 0))
+		})
+
+		It("should return 0 for buckets without a TTL", func() {
+			bucket, key, value := NewKVSet()
+
+			Expect(n.Put(context.Background(), bucket, key, value)).To(Succeed())
+
+			_, remaining, err := n.GetWithTTLHint(context.Background(), bucket, key)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(remaining).To(BeZero())
+		})
+	})
+
+	Describe("CompareRevisions", func() {
+		It("should report different revisions for keys with the same value", func() {
+			bucket, key, value := NewKVSet()
+
+			Expect(n.Put(context.Background(), bucket, key, value)).To(Succeed())
+			Expect(n.Put(context.Background(), bucket, "other", value)).To(Succeed())
+
+			same, rev1, rev2, err := n.CompareRevisions(context.Background(), bucket, key, "other")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(same).To(BeFalse())
+			Expect(rev1).To(Equal(uint64(1)))
+			Expect(rev2).To(Equal(uint64(2)))
+		})
+
+		It("should report the same revision when comparing a key to itself", func() {
+			bucket, key, value := NewKVSet()
+
+			Expect(n.Put(context.Background(), bucket, key, value)).To(Succeed())
+
+			same, rev1, rev2, err := n.CompareRevisions(context.Background(), bucket, key, key)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(same).To(BeTrue())
+			Expect(rev1).To(Equal(rev2))
+		})
+
+		It("should error if a key does not exist", func() {
+			bucket, key, value := NewKVSet()
+
+			Expect(n.Put(context.Background(), bucket, key, value)).To(Succeed())
+
+			_, _, _, err := n.CompareRevisions(context.Background(), bucket, key, "missing")
+			Expect(err).To(HaveOccurred())
+			Expect(errors.Is(err, nats.ErrKeyNotFound)).To(BeTrue())
+		})
+	})
+
+	Describe("Watch", func() {
+		It("should deliver the current value followed by updates", func() {
+			bucket, key, value := NewKVSet()
+
+			Expect(n.Put(context.Background(), bucket, key, value)).To(Succeed())
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			entries, err := n.Watch(ctx, bucket, ">")
+			Expect(err).ToNot(HaveOccurred())
+
+			var entry *KVEntry
+			Eventually(entries).Should(Receive(&entry))
+			Expect(entry.Key).To(Equal(key))
+			Expect(entry.Value).To(Equal(value))
+
+			Expect(n.Put(context.Background(), bucket, "other", []byte("updated"))).To(Succeed())
+
+			Eventually(entries).Should(Receive(&entry))
+			Expect(entry.Key).To(Equal("other"))
+			Expect(entry.Value).To(Equal([]byte("updated")))
+			Expect(entry.Operation).To(Equal(nats.KeyValuePut))
+
+			Expect(n.Delete(context.Background(), bucket, key)).To(Succeed())
+
+			Eventually(entries).Should(Receive(&entry))
+			Expect(entry.Key).To(Equal(key))
+			Expect(entry.Operation).To(Equal(nats.KeyValuePurge))
+
+			cancel()
+
+			Eventually(entries).Should(BeClosed())
+		})
+
+		It("should error for a missing bucket", func() {
+			_, err := n.Watch(context.Background(), GetRandomName("test", 1), ">")
+			Expect(err).To(Equal(nats.ErrBucketNotFound))
+		})
+	})
+
+	Describe("WatchBucket", func() {
+		It("should deliver the current values, a nil sentinel and then updates", func() {
+			bucket, _, _ := NewKVSet()
+
+			for _, key := range []string{"a", "b", "c"} {
+				Expect(n.Put(context.Background(), bucket, key, []byte(key))).To(Succeed())
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			entries, err := n.WatchBucket(ctx, bucket)
+			Expect(err).ToNot(HaveOccurred())
+
+			keys := make([]string, 0)
+
+			for entry := range entries {
+				if entry == nil {
+					break
+				}
+
+				keys = append(keys, entry.Key)
+			}
+
+			Expect(keys).To(Equal([]string{"a", "b", "c"}))
+
+			Expect(n.Put(context.Background(), bucket, "d", []byte("d"))).To(Succeed())
+
+			var entry *KVEntry
+			Eventually(entries).Should(Receive(&entry))
+			Expect(entry).ToNot(BeNil())
+			Expect(entry.Key).To(Equal("d"))
+			Expect(entry.Value).To(Equal([]byte("d")))
+		})
+
+		It("should send the sentinel for an empty bucket", func() {
+			bucket, _, _ := NewKVSet()
+
+			Expect(n.CreateBucket(context.Background(), bucket, 0)).To(Succeed())
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			entries, err := n.WatchBucket(ctx, bucket)
+			Expect(err).ToNot(HaveOccurred())
+
+			Eventually(entries).Should(Receive(BeNil()))
+		})
+	})
+
+	Describe("WatchWithBackpressure", func() {
+		It("should not drop entries for a slow consumer", func() {
+			const numEvents = 10000
+
+			bucket, _, _ := NewKVSet()
+
+			cfg.WatchMaxQueueBytes = 1024
+
+			slow, err := New(cfg)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(slow.CreateBucket(context.Background(), bucket, 0)).To(Succeed())
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			entries, err := slow.WatchWithBackpressure(ctx, bucket, ">")
+			Expect(err).ToNot(HaveOccurred())
+
+			kv, err := n.js.KeyValue(bucket)
+			Expect(err).ToNot(HaveOccurred())
+
+			go func() {
+				defer GinkgoRecover()
+
+				for i := 0; i < numEvents; i++ {
+					_, err := kv.Put("key."+strconv.Itoa(i), []byte(strconv.Itoa(i)))
+					Expect(err).ToNot(HaveOccurred())
+				}
+			}()
+
+			// Fall behind before consuming anything
+			time.Sleep(time.Second)
+
+			for i := 0; i < numEvents; i++ {
+				var entry *KVEntry
+				Eventually(entries, 10*time.Second).Should(Receive(&entry))
+				Expect(entry.Revision).To(Equal(uint64(i + 1)))
+				Expect(entry.Value).To(Equal([]byte(strconv.Itoa(i))))
+			}
+
+			cancel()
+
+			Eventually(entries).Should(BeClosed())
+		})
+
+		It("should error for a missing bucket", func() {
+			_, err := n.WatchWithBackpressure(context.Background(), GetRandomName("test", 1), ">")
+			Expect(err).To(Equal(nats.ErrBucketNotFound))
+		})
+	})
+
 	Describe("Create", func() {
 		It("should auto-create bucket + create kv entry", func() {
 			bucket, key, value := NewKVSet()
@@ -132,6 +438,7 @@ var _ = Describe("KV", func() {
 			err = n.Create(nil, bucket, key, value)
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("wrong last sequence"))
+			Expect(errors.Is(err, ErrKeyExists)).To(BeTrue())
 		})
 
 		It("should use TTL", func() {
@@ -156,6 +463,96 @@ var _ = Describe("KV", func() {
 		})
 	})
 
+	Describe("GetOrCreate", func() {
+		It("should create a missing key and return existing keys", func() {
+			bucket, key, value := NewKVSet()
+
+			calls := 0
+
+			factory := func() ([]byte, error) {
+				calls++
+				return value, nil
+			}
+
+			data, created, err := n.GetOrCreate(context.Background(), bucket, key, 0, factory)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(created).To(BeTrue())
+			Expect(data).To(Equal(value))
+
+			data, created, err = n.GetOrCreate(context.Background(), bucket, key, 0, factory)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(created).To(BeFalse())
+			Expect(data).To(Equal(value))
+
+			Expect(calls).To(Equal(1))
+		})
+
+		It("should return factory errors without creating the key", func() {
+			bucket, key, _ := NewKVSet()
+
+			_, _, err := n.GetOrCreate(context.Background(), bucket, key, 0, func() ([]byte, error) {
+				return nil, errors.New("boom")
+			})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("boom"))
+
+			_, err = n.Get(context.Background(), bucket, key)
+			Expect(err).To(Equal(nats.ErrKeyNotFound))
+		})
+
+		It("should return the winner's value to concurrent callers", func() {
+			bucket, key, _ := NewKVSet()
+
+			// Make sure the bucket exists so callers race on the key only
+			Expect(n.CreateBucket(context.Background(), bucket, 0)).To(Succeed())
+
+			const numCallers = 10
+
+			var (
+				wg      sync.WaitGroup
+				start   = make(chan struct{})
+				results = make([][]byte, numCallers)
+				created = make([]bool, numCallers)
+				errs    = make([]error, numCallers)
+			)
+
+			for i := 0; i < numCallers; i++ {
+				wg.Add(1)
+
+				go func(i int) {
+					defer GinkgoRecover()
+					defer wg.Done()
+
+					<-start
+
+					results[i], created[i], errs[i] = n.GetOrCreate(context.Background(), bucket, key, 0, func() ([]byte, error) {
+						return []byte("caller-" + strconv.Itoa(i)), nil
+					})
+				}(i)
+			}
+
+			close(start)
+			wg.Wait()
+
+			winners := 0
+
+			for i := 0; i < numCallers; i++ {
+				Expect(errs[i]).ToNot(HaveOccurred())
+				Expect(results[i]).To(Equal(results[0]))
+
+				if created[i] {
+					winners++
+				}
+			}
+
+			Expect(winners).To(Equal(1))
+
+			stored, err := n.Get(context.Background(), bucket, key)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(stored).To(Equal(results[0]))
+		})
+	})
+
 	Describe("Put", func() {
 		It("should set the value for a key (and auto-create the bucket)", func() {
 			bucket, key, value := NewKVSet()
@@ -177,6 +574,18 @@ var _ = Describe("KV", func() {
 			Expect(kve.Value()).To(Equal(value))
 		})
 
+		It("should store a value of a specific size", func() {
+			bucket, key, value := NewKVSetWithSize(64 * 1024)
+			Expect(len(value)).To(Equal(64 * 1024))
+
+			putErr := n.Put(nil, bucket, key, value)
+			Expect(putErr).ToNot(HaveOccurred())
+
+			data, err := n.Get(nil, bucket, key)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal(value))
+		})
+
 		It("a key with a TTL will get auto expired", func() {
 			bucket, key, value := NewKVSet()
 
@@ -200,6 +609,138 @@ var _ = Describe("KV", func() {
 		})
 	})
 
+	Describe("BatchPutWithTTL", func() {
+		It("should put all entries", func() {
+			bucket, _, _ := NewKVSet()
+
+			entries := map[string][]byte{
+				"a": []byte("1"),
+				"b": []byte("2"),
+				"c": []byte("3"),
+			}
+
+			Expect(n.BatchPutWithTTL(context.Background(), bucket, entries, time.Minute)).To(Succeed())
+
+			for key, value := range entries {
+				data, err := n.Get(context.Background(), bucket, key)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(data).To(Equal(value))
+			}
+		})
+
+		It("all keys will get auto expired", func() {
+			bucket, _, _ := NewKVSet()
+
+			entries := map[string][]byte{
+				"session-1": []byte("token-1"),
+				"session-2": []byte("token-2"),
+				"session-3": []byte("token-3"),
+			}
+
+			Expect(n.BatchPutWithTTL(context.Background(), bucket, entries, 1*time.Second)).To(Succeed())
+
+			// Bucket should've been created with the TTL
+			kv, err := n.js.KeyValue(bucket)
+			Expect(err).ToNot(HaveOccurred())
+
+			status, err := kv.Status()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(status.TTL()).To(Equal(1 * time.Second))
+
+			// Wait a couple sec
+			time.Sleep(2 * time.Second)
+
+			for key := range entries {
+				_, err := kv.Get(key)
+				Expect(err).To(Equal(nats.ErrKeyNotFound))
+			}
+		})
+
+		It("should error if the bucket exists with a different TTL", func() {
+			bucket, key, value := NewKVSet()
+
+			Expect(n.Put(context.Background(), bucket, key, value, time.Minute)).To(Succeed())
+
+			entries := map[string][]byte{
+				"a": []byte("1"),
+			}
+
+			err := n.BatchPutWithTTL(context.Background(), bucket, entries, time.Hour)
+			Expect(err).To(Equal(ErrBucketTTLMismatch))
+
+			_, err = n.Get(context.Background(), bucket, "a")
+			Expect(err).To(Equal(nats.ErrKeyNotFound))
+		})
+	})
+
+	Describe("GetJSON/PutJSON", func() {
+		type nested struct {
+			Tags  []string          `json:"tags"`
+			Attrs map[string]string `json:"attrs"`
+		}
+
+		type record struct {
+			ID      string    `json:"id"`
+			Count   int       `json:"count"`
+			Created time.Time `json:"created"`
+			Nested  *nested   `json:"nested"`
+		}
+
+		It("should round-trip a struct", func() {
+			bucket, key, _ := NewKVSet()
+
+			in := &record{
+				ID:      uuid.NewV4().String(),
+				Count:   42,
+				Created: time.Now().UTC().Truncate(time.Second),
+				Nested: &nested{
+					Tags:  []string{"foo", "bar"},
+					Attrs: map[string]string{"baz": "qux"},
+				},
+			}
+
+			err := n.PutJSON(context.Background(), bucket, key, in)
+			Expect(err).ToNot(HaveOccurred())
+
+			out := &record{}
+
+			err = n.GetJSON(context.Background(), bucket, key, out)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(out).To(Equal(in))
+		})
+
+		It("should return a JSONError for malformed values", func() {
+			bucket, key, _ := NewKVSet()
+
+			err := n.Put(context.Background(), bucket, key, []byte("{not json"))
+			Expect(err).ToNot(HaveOccurred())
+
+			err = n.GetJSON(context.Background(), bucket, key, &record{})
+			Expect(err).To(HaveOccurred())
+
+			var jsonErr *JSONError
+			Expect(errors.As(err, &jsonErr)).To(BeTrue())
+			Expect(jsonErr.Key).To(Equal(key))
+		})
+
+		It("should return a JSONError for unmarshalable values", func() {
+			bucket, key, _ := NewKVSet()
+
+			err := n.PutJSON(context.Background(), bucket, key, make(chan int))
+			Expect(err).To(HaveOccurred())
+
+			var jsonErr *JSONError
+			Expect(errors.As(err, &jsonErr)).To(BeTrue())
+		})
+
+		It("should return NATS errors as-is", func() {
+			bucket, key, _ := NewKVSet()
+
+			err := n.GetJSON(context.Background(), bucket, key, &record{})
+			Expect(err).To(Equal(nats.ErrKeyNotFound))
+		})
+	})
+
 	Describe("Delete", func() {
 		It("should delete the value for a key", func() {
 			bucket, key, value := NewKVSet()
@@ -226,9 +767,583 @@ var _ = Describe("KV", func() {
 		})
 	})
 
-	Describe("Keys", func() {
-		It("should return all keys in bucket", func() {
-			// Create bucket, add a bunch of keys into it
+	Describe("Exists/BucketExists", func() {
+		It("should report a missing bucket", func() {
+			bucket, key, _ := NewKVSet()
+
+			ok, err := n.BucketExists(context.Background(), bucket)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ok).To(BeFalse())
+
+			ok, err = n.Exists(context.Background(), bucket, key)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ok).To(BeFalse())
+		})
+
+		It("should report a missing key in an existing bucket", func() {
+			bucket, key, _ := NewKVSet()
+
+			Expect(n.CreateBucket(context.Background(), bucket, time.Minute)).To(Succeed())
+
+			ok, err := n.BucketExists(context.Background(), bucket)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ok).To(BeTrue())
+
+			ok, err = n.Exists(context.Background(), bucket, key)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ok).To(BeFalse())
+		})
+
+		It("should report an existing key", func() {
+			bucket, key, value := NewKVSet()
+
+			Expect(n.Put(context.Background(), bucket, key, value)).To(Succeed())
+
+			ok, err := n.BucketExists(context.Background(), bucket)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ok).To(BeTrue())
+
+			ok, err = n.Exists(context.Background(), bucket, key)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ok).To(BeTrue())
+		})
+
+		It("should report a deleted key as missing", func() {
+			bucket, key, value := NewKVSet()
+
+			Expect(n.Put(context.Background(), bucket, key, value)).To(Succeed())
+			Expect(n.Delete(context.Background(), bucket, key)).To(Succeed())
+
+			ok, err := n.Exists(context.Background(), bucket, key)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ok).To(BeFalse())
+		})
+	})
+
+	Describe("DeletePrefix", func() {
+		It("should only delete keys with the prefix", func() {
+			bucket, _, _ := NewKVSet()
+
+			for i := 0; i < 10; i++ {
+				Expect(n.Put(context.Background(), bucket, "a."+strconv.Itoa(i), []byte("a"))).To(Succeed())
+			}
+
+			remaining := make([]string, 0)
+
+			for i := 0; i < 5; i++ {
+				key := "b." + strconv.Itoa(i)
+
+				Expect(n.Put(context.Background(), bucket, key, []byte("b"))).To(Succeed())
+
+				remaining = append(remaining, key)
+			}
+
+			deleted, err := n.DeletePrefix(context.Background(), bucket, "a.")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(deleted).To(Equal(10))
+
+			keys, err := n.Keys(context.Background(), bucket)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(keys).To(ConsistOf(remaining))
+		})
+
+		It("should not error on a missing bucket", func() {
+			bucket, _, _ := NewKVSet()
+
+			deleted, err := n.DeletePrefix(context.Background(), bucket, "a.")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(deleted).To(BeZero())
+		})
+	})
+
+	Describe("DeleteByPattern", func() {
+		It("should only delete keys matching the pattern", func() {
+			bucket, _, _ := NewKVSet()
+
+			for i := 0; i < 100; i++ {
+				Expect(n.Put(context.Background(), bucket, "user."+strconv.Itoa(i)+".profile", []byte("a"))).To(Succeed())
+			}
+
+			remaining := make([]string, 0)
+
+			for i := 0; i < 10; i++ {
+				key := "admin." + strconv.Itoa(i) + ".profile"
+
+				Expect(n.Put(context.Background(), bucket, key, []byte("b"))).To(Succeed())
+
+				remaining = append(remaining, key)
+			}
+
+			deleted, err := n.DeleteByPattern(context.Background(), bucket, "user.*.*")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(deleted).To(Equal(100))
+
+			keys, err := n.Keys(context.Background(), bucket)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(keys).To(ConsistOf(remaining))
+		})
+
+		It("should not error on a missing bucket", func() {
+			bucket, _, _ := NewKVSet()
+
+			deleted, err := n.DeleteByPattern(context.Background(), bucket, "user.>")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(deleted).To(BeZero())
+		})
+	})
+
+	Describe("CopyKey", func() {
+		It("should copy a key to another bucket", func() {
+			srcBucket, key, value := NewKVSet()
+			dstBucket, _, _ := NewKVSet()
+
+			Expect(n.Put(context.Background(), srcBucket, key, value)).To(Succeed())
+
+			err := n.CopyKey(context.Background(), srcBucket, key, dstBucket, "copy")
+			Expect(err).ToNot(HaveOccurred())
+
+			data, err := n.Get(context.Background(), dstBucket, "copy")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal(value))
+
+			// Source should be untouched
+			data, err = n.Get(context.Background(), srcBucket, key)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal(value))
+		})
+
+		It("should return ErrKeyNotFound for a missing source key", func() {
+			srcBucket, key, _ := NewKVSet()
+			dstBucket, _, _ := NewKVSet()
+
+			err := n.CopyKey(context.Background(), srcBucket, key, dstBucket, key)
+			Expect(err).To(Equal(nats.ErrKeyNotFound))
+		})
+	})
+
+	Describe("UpdateBucketConfig", func() {
+		It("should update the TTL of an existing bucket", func() {
+			bucket, key, value := NewKVSet()
+
+			Expect(n.CreateBucket(context.Background(), bucket, 0)).To(Succeed())
+			Expect(n.Put(context.Background(), bucket, key, value)).To(Succeed())
+
+			err := n.UpdateBucketConfig(context.Background(), bucket, &nats.KeyValueConfig{
+				TTL:     time.Minute,
+				History: 5,
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			kv, err := n.js.KeyValue(bucket)
+			Expect(err).ToNot(HaveOccurred())
+
+			status, err := kv.Status()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(status.TTL()).To(Equal(time.Minute))
+			Expect(status.History()).To(Equal(int64(5)))
+
+			// Existing data should be untouched
+			data, err := n.Get(context.Background(), bucket, key)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal(value))
+		})
+
+		It("should keep settings that are not set in the update", func() {
+			bucket, _, _ := NewKVSet()
+
+			_, err := n.CreateBucketWithConfig(context.Background(), &nats.KeyValueConfig{
+				Bucket:       bucket,
+				Description:  "original",
+				History:      3,
+				TTL:          time.Hour,
+				MaxBytes:     1024 * 1024,
+				MaxValueSize: 1024,
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			err = n.UpdateBucketConfig(context.Background(), bucket, &nats.KeyValueConfig{TTL: time.Minute})
+			Expect(err).ToNot(HaveOccurred())
+
+			info, err := n.js.StreamInfo(kvStreamPrefix + bucket)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(info.Config.MaxAge).To(Equal(time.Minute))
+			Expect(info.Config.Description).To(Equal("original"))
+			Expect(info.Config.MaxMsgsPerSubject).To(Equal(int64(3)))
+			Expect(info.Config.MaxBytes).To(Equal(int64(1024 * 1024)))
+			Expect(info.Config.MaxMsgSize).To(Equal(int32(1024)))
+			Expect(info.Config.Replicas).To(Equal(1))
+
+			// Updating another field must not reset the TTL
+			err = n.UpdateBucketConfig(context.Background(), bucket, &nats.KeyValueConfig{History: 5})
+			Expect(err).ToNot(HaveOccurred())
+
+			info, err = n.js.StreamInfo(kvStreamPrefix + bucket)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(info.Config.MaxAge).To(Equal(time.Minute))
+			Expect(info.Config.MaxMsgsPerSubject).To(Equal(int64(5)))
+		})
+
+		It("should return ErrBucketNotFound for a missing bucket", func() {
+			bucket, _, _ := NewKVSet()
+
+			err := n.UpdateBucketConfig(context.Background(), bucket, &nats.KeyValueConfig{TTL: time.Minute})
+			Expect(err).To(Equal(nats.ErrBucketNotFound))
+		})
+
+		It("should reject a mismatched bucket name", func() {
+			bucket, _, _ := NewKVSet()
+
+			err := n.UpdateBucketConfig(context.Background(), bucket, &nats.KeyValueConfig{Bucket: "other"})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("does not match"))
+		})
+	})
+
+	Describe("CreateBucketWithConfig", func() {
+		// BackingStore() is always "JetStream" in nats.go, so check the
+		// storage type of the backing stream instead
+		storageOf := func(bucket string) nats.StorageType {
+			kv, err := n.js.KeyValue(bucket)
+			Expect(err).ToNot(HaveOccurred())
+
+			status, err := kv.Status()
+			Expect(err).ToNot(HaveOccurred())
+
+			return status.(*nats.KeyValueBucketStatus).StreamInfo().Config.Storage
+		}
+
+		It("should create a bucket with the given config", func() {
+			bucket, key, value := NewKVSet()
+
+			kv, err := n.CreateBucketWithConfig(context.Background(), &nats.KeyValueConfig{
+				Bucket:  bucket,
+				History: 10,
+				Storage: nats.MemoryStorage,
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(kv).ToNot(BeNil())
+
+			Expect(storageOf(bucket)).To(Equal(nats.MemoryStorage))
+
+			Expect(n.Put(context.Background(), bucket, key, value)).To(Succeed())
+
+			data, err := n.Get(context.Background(), bucket, key)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal(value))
+		})
+
+		It("should use configured bucket configs when auto-creating", func() {
+			bucket, key, value := NewKVSet()
+
+			cfg := NewConfig()
+			cfg.BucketConfigs = map[string]*nats.KeyValueConfig{
+				bucket: {Storage: nats.MemoryStorage, History: 2},
+			}
+
+			withConfigs, err := New(cfg)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(withConfigs.Put(context.Background(), bucket, key, value, time.Minute)).To(Succeed())
+
+			Expect(storageOf(bucket)).To(Equal(nats.MemoryStorage))
+
+			kv, err := n.js.KeyValue(bucket)
+			Expect(err).ToNot(HaveOccurred())
+
+			status, err := kv.Status()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(status.History()).To(Equal(int64(2)))
+			Expect(status.TTL()).To(Equal(time.Minute))
+		})
+	})
+
+	Describe("CompactHistory", func() {
+		It("should only keep the newest revisions", func() {
+			bucket, key, _ := NewKVSet()
+
+			kv, err := n.js.CreateKeyValue(&nats.KeyValueConfig{
+				Bucket:  bucket,
+				History: 10,
+			})
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(kv).ToNot(BeNil())
+
+			for i := 0; i < 10; i++ {
+				_, err := kv.Put(key, []byte(strconv.Itoa(i)))
+				Expect(err).ToNot(HaveOccurred())
+			}
+
+			history, err := kv.History(key)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(len(history)).To(Equal(10))
+
+			err = n.CompactHistory(context.Background(), bucket, key, 3)
+			Expect(err).ToNot(HaveOccurred())
+
+			history, err = kv.History(key)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(len(history)).To(Equal(3))
+
+			Expect(history[0].Value()).To(Equal([]byte("7")))
+			Expect(history[1].Value()).To(Equal([]byte("8")))
+			Expect(history[2].Value()).To(Equal([]byte("9")))
+		})
+
+		It("should error if key does not exist", func() {
+			bucket, key, value := NewKVSet()
+
+			err := n.Put(context.Background(), bucket, "other-key", value)
+			Expect(err).ToNot(HaveOccurred())
+
+			err = n.CompactHistory(context.Background(), bucket, key, 3)
+			Expect(err).To(Equal(nats.ErrKeyNotFound))
+		})
+
+		It("should record metrics and fire Put hooks for re-written values", func() {
+			bucket, key, _ := NewKVSet()
+
+			kv, err := n.js.CreateKeyValue(&nats.KeyValueConfig{
+				Bucket:  bucket,
+				History: 10,
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			for i := 0; i < 5; i++ {
+				_, err := kv.Put(key, []byte(strconv.Itoa(i)))
+				Expect(err).ToNot(HaveOccurred())
+			}
+
+			metrics := &recordingMetrics{mutex: &sync.Mutex{}}
+			written := make([]string, 0)
+
+			compactor, err := New(NewConfig().WithMetrics(metrics).WithBucketHooks(BucketHooks{
+				AfterPut: func(_, _ string, value []byte, err error) {
+					Expect(err).ToNot(HaveOccurred())
+					written = append(written, string(value))
+				},
+			}))
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(compactor.CompactHistory(context.Background(), bucket, key, 2)).To(Succeed())
+
+			Expect(metrics.ops).To(Equal([]recordedOp{{operation: KVOpCompact, bucket: bucket}}))
+			Expect(written).To(Equal([]string{"3", "4"}))
+		})
+	})
+
+	Describe("BucketChecksum", func() {
+		It("should match for buckets with identical contents", func() {
+			bucketA, _, _ := NewKVSet()
+			bucketB, _, _ := NewKVSet()
+
+			for i := 0; i < 10; i++ {
+				key := "key-" + strconv.Itoa(i)
+				value := []byte("value-" + strconv.Itoa(i))
+
+				Expect(n.Put(context.Background(), bucketA, key, value)).To(Succeed())
+				Expect(n.Put(context.Background(), bucketB, key, value)).To(Succeed())
+			}
+
+			checksumA, err := n.BucketChecksum(context.Background(), bucketA)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(checksumA).To(HaveLen(32))
+
+			checksumB, err := n.BucketChecksum(context.Background(), bucketB)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(checksumB).To(Equal(checksumA))
+
+			// Modify one of the buckets - checksums should no longer match
+			Expect(n.Put(context.Background(), bucketB, "key-0", []byte("modified"))).To(Succeed())
+
+			checksumB, err = n.BucketChecksum(context.Background(), bucketB)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(checksumB).ToNot(Equal(checksumA))
+		})
+
+		It("should not match if keys and values are split differently", func() {
+			bucketA, _, _ := NewKVSet()
+			bucketB, _, _ := NewKVSet()
+
+			Expect(n.Put(context.Background(), bucketA, "a", []byte("bc"))).To(Succeed())
+			Expect(n.Put(context.Background(), bucketA, "d", []byte("value"))).To(Succeed())
+
+			Expect(n.Put(context.Background(), bucketB, "a", []byte("b"))).To(Succeed())
+			Expect(n.Put(context.Background(), bucketB, "cd", []byte("value"))).To(Succeed())
+
+			checksumA, err := n.BucketChecksum(context.Background(), bucketA)
+			Expect(err).ToNot(HaveOccurred())
+
+			checksumB, err := n.BucketChecksum(context.Background(), bucketB)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(checksumB).ToNot(Equal(checksumA))
+		})
+
+		It("should error if bucket does not exist", func() {
+			bucket, _, _ := NewKVSet()
+
+			checksum, err := n.BucketChecksum(context.Background(), bucket)
+			Expect(err).To(HaveOccurred())
+			Expect(checksum).To(BeNil())
+		})
+	})
+
+	Describe("Reconcile", func() {
+		It("should bring bucket into desired state", func() {
+			bucket, _, _ := NewKVSet()
+
+			Expect(n.Put(context.Background(), bucket, "unchanged", []byte("same"))).To(Succeed())
+			Expect(n.Put(context.Background(), bucket, "updated", []byte("old"))).To(Succeed())
+			Expect(n.Put(context.Background(), bucket, "deleted", []byte("gone"))).To(Succeed())
+
+			desired := map[string][]byte{
+				"unchanged": []byte("same"),
+				"updated":   []byte("new"),
+				"added-1":   []byte("added"),
+				"added-2":   []byte("added"),
+			}
+
+			result, err := n.Reconcile(context.Background(), bucket, desired)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(Equal(ReconcileResult{
+				Added:   2,
+				Updated: 1,
+				Deleted: 1,
+			}))
+
+			keys, err := n.Keys(context.Background(), bucket)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(len(keys)).To(Equal(len(desired)))
+
+			for key, value := range desired {
+				data, err := n.Get(context.Background(), bucket, key)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(data).To(Equal(value))
+			}
+
+			// Second reconcile should be a no-op
+			result, err = n.Reconcile(context.Background(), bucket, desired)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(Equal(ReconcileResult{}))
+		})
+
+		It("should write via Put() and Delete()", func() {
+			calls := make([]string, 0)
+
+			hooked, err := New(NewConfig().WithBucketHooks(BucketHooks{
+				AfterPut: func(bucket, key string, value []byte, err error) {
+					calls = append(calls, "put:"+key)
+				},
+				AfterDelete: func(bucket, key string, err error) {
+					calls = append(calls, "delete:"+key)
+				},
+			}))
+			Expect(err).ToNot(HaveOccurred())
+
+			bucket, _, _ := NewKVSet()
+
+			Expect(hooked.Put(context.Background(), bucket, "deleted", []byte("gone"))).To(Succeed())
+
+			calls = calls[:0]
+
+			_, err = hooked.Reconcile(context.Background(), bucket, map[string][]byte{"added": []byte("added")})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(calls).To(Equal([]string{"delete:deleted", "put:added"}))
+		})
+	})
+
+	Describe("TemporaryBucket", func() {
+		It("should delete the bucket after fn returns", func() {
+			var tmpBucket string
+
+			err := n.TemporaryBucket(context.Background(), func(bucket string) error {
+				tmpBucket = bucket
+
+				// Bucket should exist while fn is running
+				_, err := n.js.KeyValue(bucket)
+				Expect(err).ToNot(HaveOccurred())
+
+				return n.Put(context.Background(), bucket, "foo", []byte("bar"))
+			})
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tmpBucket).ToNot(BeEmpty())
+
+			_, err = n.js.KeyValue(tmpBucket)
+			Expect(err).To(Equal(nats.ErrBucketNotFound))
+		})
+
+		It("should propagate fn error and still delete the bucket", func() {
+			var tmpBucket string
+
+			fnErr := errors.New("fn failed")
+
+			err := n.TemporaryBucket(context.Background(), func(bucket string) error {
+				tmpBucket = bucket
+				return fnErr
+			})
+
+			Expect(err).To(Equal(fnErr))
+
+			_, err = n.js.KeyValue(tmpBucket)
+			Expect(err).To(Equal(nats.ErrBucketNotFound))
+		})
+	})
+
+	Describe("TemporaryKey", func() {
+		It("should delete the key after fn returns", func() {
+			bucket, _, value := NewKVSet()
+
+			var tmpKey string
+
+			err := n.TemporaryKey(context.Background(), bucket, func(key string) error {
+				tmpKey = key
+
+				if err := n.Put(context.Background(), bucket, key, value); err != nil {
+					return err
+				}
+
+				data, err := n.Get(context.Background(), bucket, key)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(data).To(Equal(value))
+
+				return nil
+			})
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tmpKey).ToNot(BeEmpty())
+
+			_, err = n.Get(context.Background(), bucket, tmpKey)
+			Expect(err).To(Equal(nats.ErrKeyNotFound))
+		})
+
+		It("should propagate fn error and still delete the key", func() {
+			bucket, _, value := NewKVSet()
+
+			var tmpKey string
+
+			fnErr := errors.New("fn failed")
+
+			err := n.TemporaryKey(context.Background(), bucket, func(key string) error {
+				tmpKey = key
+
+				Expect(n.Put(context.Background(), bucket, key, value)).To(Succeed())
+
+				return fnErr
+			})
+
+			Expect(err).To(Equal(fnErr))
+
+			_, err = n.Get(context.Background(), bucket, tmpKey)
+			Expect(err).To(Equal(nats.ErrKeyNotFound))
+		})
+	})
+
+	Describe("Keys", func() {
+		It("should return all keys in bucket", func() {
+			// Create bucket, add a bunch of keys into it
 			bucket, _, _ := NewKVSet()
 
 			kv, err := n.js.CreateKeyValue(&nats.KeyValueConfig{
@@ -275,8 +1390,194 @@ var _ = Describe("KV", func() {
 			Expect(keys).To(BeNil())
 		})
 	})
+
+	Describe("KeysStream", func() {
+		collect := func(ctx context.Context, bucket string) ([]string, error) {
+			keyCh, errCh := n.KeysStream(ctx, bucket)
+
+			keys := make([]string, 0)
+
+			for key := range keyCh {
+				keys = append(keys, key)
+			}
+
+			return keys, <-errCh
+		}
+
+		It("should stream all keys in a stable order", func() {
+			bucket, _, _ := NewKVSet()
+
+			expected := make([]string, 0)
+
+			for i := 0; i < 10; i++ {
+				key := "key-" + strconv.Itoa(i)
+
+				Expect(n.Put(context.Background(), bucket, key, []byte("test"))).To(Succeed())
+
+				expected = append(expected, key)
+			}
+
+			keys, err := collect(context.Background(), bucket)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(keys).To(Equal(expected))
+
+			again, err := collect(context.Background(), bucket)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(again).To(Equal(keys))
+		})
+
+		It("should stop and close the channels when the context is cancelled", func() {
+			bucket, _, _ := NewKVSet()
+
+			for i := 0; i < 10; i++ {
+				Expect(n.Put(context.Background(), bucket, "key-"+strconv.Itoa(i), []byte("test"))).To(Succeed())
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			keyCh, errCh := n.KeysStream(ctx, bucket)
+
+			Eventually(keyCh).Should(Receive())
+
+			cancel()
+
+			Eventually(keyCh).Should(BeClosed())
+			Eventually(errCh).Should(Receive(Equal(context.Canceled)))
+			Eventually(errCh).Should(BeClosed())
+		})
+
+		It("should send an error if the bucket does not exist", func() {
+			keys, err := collect(context.Background(), uuid.NewV4().String())
+			Expect(err).To(Equal(nats.ErrBucketNotFound))
+			Expect(keys).To(BeEmpty())
+		})
+	})
 })
 
+var _ = Describe("pumpWithBackpressure", func() {
+	It("should stop reading updates once the queue is full", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		updates := make(chan nats.KeyValueEntry)
+		entries := make(chan *KVEntry)
+		stopped := make(chan struct{})
+
+		go func() {
+			defer close(stopped)
+			pumpWithBackpressure(ctx, updates, entries, 8)
+		}()
+
+		// Each entry is 4 bytes; the queue is full after two
+		updates <- &fakeKVEntry{key: "a", value: []byte("111")}
+		updates <- &fakeKVEntry{key: "b", value: []byte("222")}
+		Consistently(updates).ShouldNot(BeSent(&fakeKVEntry{key: "c", value: []byte("333")}))
+
+		var entry *KVEntry
+		Eventually(entries).Should(Receive(&entry))
+		Expect(entry.Key).To(Equal("a"))
+
+		Eventually(updates).Should(BeSent(&fakeKVEntry{key: "c", value: []byte("333")}))
+		close(updates)
+
+		// Queued entries are delivered after updates is closed
+		Eventually(entries).Should(Receive(&entry))
+		Expect(entry.Key).To(Equal("b"))
+		Eventually(entries).Should(Receive(&entry))
+		Expect(entry.Key).To(Equal("c"))
+
+		Eventually(stopped).Should(BeClosed())
+	})
+})
+
+var _ = Describe("BatchDeleteError", func() {
+	It("should list the failed keys", func() {
+		err := &BatchDeleteError{
+			Bucket: "bucket",
+			Failures: map[string]error{
+				"b": errors.New("boom"),
+				"a": errors.New("boom"),
+			},
+		}
+
+		Expect(err.Error()).To(Equal("unable to delete 2 key(s) in bucket 'bucket': a, b"))
+	})
+})
+
+// partialDeleteKV lists keys and fails Purge() for the keys in failures
+type partialDeleteKV struct {
+	nats.KeyValue
+
+	keys     []string
+	failures map[string]bool
+	purged   []string
+}
+
+func (kv *partialDeleteKV) Keys(opts ...nats.WatchOpt) ([]string, error) {
+	return kv.keys, nil
+}
+
+func (kv *partialDeleteKV) Purge(key string, opts ...nats.DeleteOpt) error {
+	if kv.failures[key] {
+		return nats.ErrTimeout
+	}
+
+	kv.purged = append(kv.purged, key)
+
+	return nil
+}
+
+var _ = Describe("DeletePrefix partial failure", func() {
+	It("should return the deleted count along with the failed keys", func() {
+		kv := &partialDeleteKV{
+			keys:     []string{"a.1", "a.2", "a.3", "a.4", "b.1"},
+			failures: map[string]bool{"a.2": true, "a.4": true},
+		}
+
+		n := &Natty{
+			Config: &Config{},
+			kvMap: &KeyValueMap{
+				rwMutex: &sync.RWMutex{},
+				kvMap:   map[string]nats.KeyValue{"bucket": kv},
+			},
+			closedMutex:  &sync.RWMutex{},
+			restartMutex: &restartLock{},
+			connMutex:    &sync.RWMutex{},
+			log:          &NoOpLogger{},
+			metrics:      &NoOpMetrics{},
+			tracer:       &NoOpTracer{},
+		}
+
+		deleted, err := n.DeletePrefix(context.Background(), "bucket", "a.")
+		Expect(deleted).To(Equal(2))
+		Expect(kv.purged).To(ConsistOf("a.1", "a.3"))
+
+		var batchErr *BatchDeleteError
+
+		Expect(errors.As(err, &batchErr)).To(BeTrue())
+		Expect(batchErr.Bucket).To(Equal("bucket"))
+		Expect(batchErr.Failures).To(HaveLen(2))
+		Expect(batchErr.Failures).To(HaveKey("a.2"))
+		Expect(batchErr.Failures).To(HaveKey("a.4"))
+		Expect(errors.Is(batchErr.Failures["a.2"], nats.ErrTimeout)).To(BeTrue())
+	})
+})
+
+// fakeKVEntry is a nats.KeyValueEntry for tests that do not need NATS
+type fakeKVEntry struct {
+	key   string
+	value []byte
+}
+
+func (f *fakeKVEntry) Bucket() string             { return "" }
+func (f *fakeKVEntry) Key() string                { return f.key }
+func (f *fakeKVEntry) Value() []byte              { return f.value }
+func (f *fakeKVEntry) Revision() uint64           { return 0 }
+func (f *fakeKVEntry) Created() time.Time         { return time.Time{} }
+func (f *fakeKVEntry) Delta() uint64              { return 0 }
+func (f *fakeKVEntry) Operation() nats.KeyValueOp { return nats.KeyValuePut }
+
 func NewKVSet() (bucket string, key string, value []byte) {
 	bucket = uuid.NewV4().String()
 	key = uuid.NewV4().String()
@@ -286,3 +1587,15 @@ func NewKVSet() (bucket string, key string, value []byte) {
 
 	return
 }
+
+func NewKVSetWithSize(size int) (bucket string, key string, value []byte) {
+	bucket = uuid.NewV4().String()
+	key = uuid.NewV4().String()
+	value = make([]byte, size)
+
+	rand.Read(value)
+
+	testBuckets = append(testBuckets, bucket)
+
+	return
+}
@@ -53,9 +53,9 @@ func (n *Natty) AsLeader(ctx context.Context, cfg *AsLeaderConfig, f func() erro
 	// Attempt to create bucket; if bucket exists, verify that TTL matches
 	if err := n.CreateBucket(ctx, cfg.Bucket, cfg.BucketTTL, cfg.Description); err != nil {
 		if strings.Contains(err.Error(), "stream name already in use") {
-			n.log.Debug("bucket exists, checking if ttl matches")
+			debugw(n.log, "bucket exists, checking if ttl matches", "bucket", cfg.Bucket)
 
-			kv, err := n.js.KeyValue(cfg.Bucket)
+			kv, err := n.getJS().KeyValue(cfg.Bucket)
 			if err != nil {
 				return errors.Wrap(err, "unable to fetch existing bucket")
 			}
@@ -65,10 +65,10 @@ func (n *Natty) AsLeader(ctx context.Context, cfg *AsLeaderConfig, f func() erro
 				return errors.Wrap(err, "unable to fetch existing bucket status")
 			}
 
-			n.log.Debugf("ttl on bucket: %v desired ttl: %v", s.TTL(), cfg.BucketTTL)
+			debugw(n.log, "checking bucket ttl", "bucket", cfg.Bucket, "ttl", s.TTL(), "desired_ttl", cfg.BucketTTL)
 
 			if s.TTL().Seconds() != cfg.BucketTTL.Seconds() {
-				n.log.Error("bucket ttls do not match")
+				errorw(n.log, "bucket ttls do not match", "bucket", cfg.Bucket, "ttl", s.TTL(), "desired_ttl", cfg.BucketTTL)
 				return ErrBucketTTLMismatch
 			}
 		} else {
@@ -78,20 +78,20 @@ func (n *Natty) AsLeader(ctx context.Context, cfg *AsLeaderConfig, f func() erro
 
 	errCh := make(chan error, 1)
 
-	n.log.Debugf("%s: starting leader election goroutine", cfg.NodeName)
+	debugw(n.log, "starting leader election goroutine", "node", cfg.NodeName)
 
 	// Launch leader election in goroutine (leader election goroutine should quit when AsLeader is cancelled or exits)
 	go func() {
 		err := n.runLeaderElection(ctx, cfg)
 		if err != nil {
-			n.log.Errorf("%s: unable to run leader election: %v", cfg.NodeName, err)
+			errorw(n.log, "unable to run leader election", "node", cfg.NodeName, "error", err)
 			errCh <- err
 
 			return
 		}
 	}()
 
-	n.log.Debugf("%s: waiting for goroutine to not error", cfg.NodeName)
+	debugw(n.log, "waiting for goroutine to not error", "node", cfg.NodeName)
 
 	select {
 	case err := <-errCh:
@@ -104,19 +104,19 @@ func (n *Natty) AsLeader(ctx context.Context, cfg *AsLeaderConfig, f func() erro
 	}
 
 	// Leader election goroutine started; run main loop
-	n.log.Debugf("%s: leader election goroutine started; running main loop", cfg.NodeName)
+	debugw(n.log, "leader election goroutine started; running main loop", "node", cfg.NodeName)
 
 	cfg.Looper.Loop(func() error {
 		if !cfg.haveLeader {
-			n.log.Debugf("%s: AsLeader: not leader", cfg.NodeName)
+			debugw(n.log, "AsLeader: not leader", "node", cfg.NodeName)
 			return nil
 		}
 
-		n.log.Debugf("%s: AsLeader: running func", cfg.NodeName)
+		debugw(n.log, "AsLeader: running func", "node", cfg.NodeName)
 
 		// Have leader, exec func
 		if err := f(); err != nil {
-			n.log.Errorf("%s: error during func execution: %v", cfg.NodeName, err)
+			errorw(n.log, "error during func execution", "node", cfg.NodeName, "error", err)
 			return nil
 		}
 
@@ -139,7 +139,7 @@ func (n *Natty) runLeaderElection(ctx context.Context, cfg *AsLeaderConfig) erro
 		// NATS K/V client does not support ctx yet so we do it here instead
 		select {
 		case <-ctx.Done():
-			n.log.Debugf("%s: context cancelled, exiting leader election", cfg.NodeName)
+			debugw(n.log, "context cancelled, exiting leader election", "node", cfg.NodeName)
 			quit = true
 			cfg.ElectionLooper.Quit()
 
@@ -151,29 +151,29 @@ func (n *Natty) runLeaderElection(ctx context.Context, cfg *AsLeaderConfig) erro
 		// Have leader - attempt to update key to increase TTL
 		if cfg.haveLeader {
 			if err := n.Put(ctx, cfg.Bucket, "leader", []byte(cfg.NodeName)); err != nil {
-				n.log.Errorf("%s: unable to update leader key: %v", cfg.NodeName, err)
+				errorw(n.log, "unable to update leader key", "node", cfg.NodeName, "error", err)
 				cfg.haveLeader = false
 
 				return nil
 			}
 
-			n.log.Debugf("%s: updated leader key", cfg.NodeName)
+			debugw(n.log, "updated leader key", "node", cfg.NodeName)
 
 			return nil
 		}
 
 		if err := n.Create(ctx, cfg.Bucket, "leader", []byte(cfg.NodeName)); err != nil {
 			if strings.Contains(err.Error(), "wrong last sequence") {
-				n.log.Debugf("%s: leader key already exists, ignoring", cfg.NodeName)
+				debugw(n.log, "leader key already exists, ignoring", "node", cfg.NodeName)
 				return nil
 			}
 
-			n.log.Errorf("%s: unable to create leader key: %v", cfg.NodeName, err)
+			errorw(n.log, "unable to create leader key", "node", cfg.NodeName, "error", err)
 
 			return nil
 		}
 
-		n.log.Debugf("%s: leader key created", cfg.NodeName)
+		debugw(n.log, "leader key created", "node", cfg.NodeName)
 
 		// Have leader
 		cfg.haveLeader = true
@@ -181,7 +181,7 @@ func (n *Natty) runLeaderElection(ctx context.Context, cfg *AsLeaderConfig) erro
 		return nil
 	})
 
-	n.log.Debugf("%s: leader election goroutine exiting", cfg.NodeName)
+	debugw(n.log, "leader election goroutine exiting", "node", cfg.NodeName)
 
 	return nil
 }
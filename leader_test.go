@@ -1,4 +1,3 @@
-// NOTE: These tests require NATS to be available on "localhost"
 package natty
 
 import (
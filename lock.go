@@ -0,0 +1,264 @@
+package natty
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+)
+
+const (
+	DefaultLockPollInterval = time.Millisecond * 100
+
+	DefaultKeyLockBucket = "natty-key-locks"
+	DefaultKeyLockTTL    = time.Second * 30
+)
+
+var (
+	ErrLockNotHeld = errors.New("lock is no longer held")
+)
+
+// Lock acquires a distributed lock on lockKey in bucket. The lock is claimed
+// via Create() (which fails if the key already exists) and stores a unique
+// owner ID as the value. While the lock is held, a background goroutine
+// refreshes the key every ttl/2 to prevent it from expiring. Every refresh
+// looks up the bucket again, so the lock is kept across GracefulRestart().
+//
+// Lock relies on bucket-level TTL to expire locks held by crashed processes:
+// the bucket will be auto-created with the given ttl; if the bucket already
+// exists with a different TTL, ErrBucketTTLMismatch is returned.
+//
+// Lock blocks until the lock is acquired or ctx is done (in which case the
+// context error is returned). The returned unlock func stops the refresh
+// goroutine and deletes the key; it returns ErrLockNotHeld if the lock was
+// lost in the meantime (or if unlock has already been called).
+func (n *Natty) Lock(ctx context.Context, bucket, lockKey string, ttl time.Duration) (func() error, error) {
+	if n.isClosed() {
+		return nil, ErrConnectionClosed
+	}
+
+	if ttl <= 0 {
+		return nil, errors.New("ttl must be greater than 0")
+	}
+
+	err := n.withBucket(ctx, bucket, true, ttl, func(kv nats.KeyValue) error {
+		status, err := kv.Status()
+		if err != nil {
+			return errors.Wrap(err, "unable to fetch bucket status")
+		}
+
+		if status.TTL() != ttl {
+			return ErrBucketTTLMismatch
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	owner := []byte(uuid.NewV4().String())
+
+	revision, err := n.acquireLock(ctx, bucket, lockKey, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshCtx, cancel := context.WithCancel(context.Background())
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+
+		ticker := time.NewTicker(ttl / 2)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-refreshCtx.Done():
+				return
+			case <-ticker.C:
+				err := n.withBucket(refreshCtx, bucket, false, 0, func(kv nats.KeyValue) (err error) {
+					revision, err = kv.Update(lockKey, owner, revision)
+					return err
+				})
+				if err != nil {
+					errorw(n.log, "unable to refresh lock", "bucket", bucket, "key", lockKey, "error", err)
+					revision = 0
+
+					return
+				}
+			}
+		}
+	}()
+
+	once := &sync.Once{}
+
+	unlock := func() error {
+		err := ErrLockNotHeld
+
+		once.Do(func() {
+			cancel()
+			wg.Wait()
+
+			if revision == 0 {
+				// Refresh failed - lock was lost
+				return
+			}
+
+			purgeErr := n.withBucket(context.Background(), bucket, false, 0, func(kv nats.KeyValue) error {
+				return kv.Purge(lockKey, nats.LastRevision(revision))
+			})
+			if purgeErr != nil {
+				if !isWrongLastSequence(purgeErr) {
+					err = errors.Wrap(purgeErr, "unable to delete lock key")
+				}
+
+				return
+			}
+
+			err = nil
+		})
+
+		return err
+	}
+
+	return unlock, nil
+}
+
+// acquireLock attempts to Create() lockKey until it succeeds or ctx is done
+func (n *Natty) acquireLock(ctx context.Context, bucket, lockKey string, owner []byte) (uint64, error) {
+	ticker := time.NewTicker(DefaultLockPollInterval)
+	defer ticker.Stop()
+
+	for {
+		var revision uint64
+
+		err := n.withBucket(ctx, bucket, false, 0, func(kv nats.KeyValue) (err error) {
+			revision, err = kv.Create(lockKey, owner)
+			return err
+		})
+		if err == nil {
+			return revision, nil
+		}
+
+		if !isWrongLastSequence(err) {
+			return 0, errors.Wrap(err, "unable to create lock key")
+		}
+
+		// Lock is held by someone else - wait and try again
+		select {
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// KeyLock provides exclusive locks on individual keys of any bucket (ie. to
+// serialize read-modify-write cycles on a key across processes) without
+// locking the whole bucket. Locks for all buckets are kept in a single lock
+// bucket (keyed "<bucket>.<key>") and are acquired via Lock(), so the same
+// CAS and refresh semantics apply.
+type KeyLock struct {
+	n      INatty
+	bucket string
+	ttl    time.Duration
+}
+
+// NewKeyLock creates a KeyLock that keeps its locks in lockBucket with the given
+// ttl (see Lock() on how ttl is used); if lockBucket is empty,
+// DefaultKeyLockBucket is used and if ttl is 0, DefaultKeyLockTTL is used.
+//
+// NOTE: All processes locking the same keys must use the same lockBucket and
+// ttl.
+func NewKeyLock(n INatty, lockBucket string, ttl time.Duration) *KeyLock {
+	if lockBucket == "" {
+		lockBucket = DefaultKeyLockBucket
+	}
+
+	if ttl == 0 {
+		ttl = DefaultKeyLockTTL
+	}
+
+	return &KeyLock{
+		n:      n,
+		bucket: lockBucket,
+		ttl:    ttl,
+	}
+}
+
+// Lock blocks until the lock on key in bucket is acquired or ctx is done; the
+// returned unlock func releases it. Locks on other keys are not affected.
+func (k *KeyLock) Lock(ctx context.Context, bucket, key string) (unlock func() error, err error) {
+	if bucket == "" {
+		return nil, errors.New("bucket cannot be empty")
+	}
+
+	if key == "" {
+		return nil, errors.New("key cannot be empty")
+	}
+
+	// Bucket names cannot contain '.', so lock keys cannot collide
+	return k.n.Lock(ctx, k.bucket, bucket+"."+key, k.ttl)
+}
+
+// MultiKeyLock locks all keys in bucket. Keys are locked one at a time in
+// sorted order (duplicates are ignored), so concurrent callers with
+// overlapping key sets cannot deadlock. Blocks until all locks are acquired
+// or ctx is done; on failure, locks acquired so far are released (errors while
+// releasing them are ignored, such locks expire after the lock TTL). The returned
+// unlock func releases all locks and returns the first error encountered.
+func (k *KeyLock) MultiKeyLock(ctx context.Context, bucket string, keys []string) (unlock func() error, err error) {
+	if len(keys) == 0 {
+		return nil, errors.New("keys cannot be empty")
+	}
+
+	sorted := make([]string, 0, len(keys))
+	seen := make(map[string]struct{}, len(keys))
+
+	for _, key := range keys {
+		if _, ok := seen[key]; ok {
+			continue
+		}
+
+		seen[key] = struct{}{}
+		sorted = append(sorted, key)
+	}
+
+	sort.Strings(sorted)
+
+	unlocks := make([]func() error, 0, len(sorted))
+
+	unlockAll := func() error {
+		var firstErr error
+
+		// Release in reverse acquisition order
+		for i := len(unlocks) - 1; i >= 0; i-- {
+			if err := unlocks[i](); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+
+		return firstErr
+	}
+
+	for _, key := range sorted {
+		unlockKey, err := k.Lock(ctx, bucket, key)
+		if err != nil {
+			// Locks that cannot be released expire after the lock TTL
+			_ = unlockAll()
+
+			return nil, errors.Wrapf(err, "unable to lock key '%s'", key)
+		}
+
+		unlocks = append(unlocks, unlockKey)
+	}
+
+	return unlockAll, nil
+}
@@ -0,0 +1,308 @@
+package natty
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+)
+
+var _ = Describe("Lock", func() {
+	var (
+		cfg *Config
+		n   *Natty
+	)
+
+	BeforeEach(func() {
+		var err error
+
+		cfg = NewConfig()
+
+		n, err = New(cfg)
+
+		Expect(err).To(BeNil())
+		Expect(n).NotTo(BeNil())
+	})
+
+	It("should provide mutual exclusion", func() {
+		bucket, key, _ := NewKVSet()
+
+		var holders int32
+		var violations int32
+
+		wg := &sync.WaitGroup{}
+
+		for i := 0; i < 2; i++ {
+			wg.Add(1)
+
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+
+				for j := 0; j < 5; j++ {
+					ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+
+					unlock, err := n.Lock(ctx, bucket, key, 5*time.Second)
+					Expect(err).ToNot(HaveOccurred())
+
+					if atomic.AddInt32(&holders, 1) > 1 {
+						atomic.AddInt32(&violations, 1)
+					}
+
+					time.Sleep(50 * time.Millisecond)
+
+					atomic.AddInt32(&holders, -1)
+
+					Expect(unlock()).To(Succeed())
+					cancel()
+				}
+			}()
+		}
+
+		wg.Wait()
+
+		Expect(atomic.LoadInt32(&violations)).To(Equal(int32(0)))
+	})
+
+	It("should return context error if lock cannot be acquired", func() {
+		bucket, key, _ := NewKVSet()
+
+		unlock, err := n.Lock(context.Background(), bucket, key, 5*time.Second)
+		Expect(err).ToNot(HaveOccurred())
+
+		defer unlock()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+		defer cancel()
+
+		_, err = n.Lock(ctx, bucket, key, 5*time.Second)
+		Expect(err).To(Equal(context.DeadlineExceeded))
+	})
+
+	It("should keep the lock across a GracefulRestart", func() {
+		bucket, key, _ := NewKVSet()
+
+		unlock, err := n.Lock(context.Background(), bucket, key, time.Second)
+		Expect(err).ToNot(HaveOccurred())
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		Expect(n.GracefulRestart(ctx)).To(Succeed())
+
+		// Wait past the TTL - lock must have been refreshed on the new connection
+		time.Sleep(2 * time.Second)
+
+		lockCtx, lockCancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+		defer lockCancel()
+
+		_, err = n.Lock(lockCtx, bucket, key, time.Second)
+		Expect(err).To(Equal(context.DeadlineExceeded))
+
+		Expect(unlock()).To(Succeed())
+	})
+
+	It("should refresh the lock while it is held", func() {
+		bucket, key, _ := NewKVSet()
+
+		unlock, err := n.Lock(context.Background(), bucket, key, time.Second)
+		Expect(err).ToNot(HaveOccurred())
+
+		// Wait past the TTL - lock should still be held
+		time.Sleep(2 * time.Second)
+
+		_, err = n.Get(context.Background(), bucket, key)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(unlock()).To(Succeed())
+		Expect(unlock()).To(Equal(ErrLockNotHeld))
+	})
+})
+
+var _ = Describe("KeyLock", func() {
+	var (
+		n  *Natty
+		kl *KeyLock
+	)
+
+	BeforeEach(func() {
+		var err error
+
+		n, err = New(NewConfig())
+		Expect(err).ToNot(HaveOccurred())
+
+		lockBucket, _, _ := NewKVSet()
+
+		kl = NewKeyLock(n, lockBucket, 5*time.Second)
+	})
+
+	It("should provide mutual exclusion per key", func() {
+		bucket, key, _ := NewKVSet()
+
+		var holders int32
+		var violations int32
+
+		wg := &sync.WaitGroup{}
+
+		for i := 0; i < 3; i++ {
+			wg.Add(1)
+
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+
+				for j := 0; j < 5; j++ {
+					ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+
+					unlock, err := kl.Lock(ctx, bucket, key)
+					Expect(err).ToNot(HaveOccurred())
+
+					if atomic.AddInt32(&holders, 1) > 1 {
+						atomic.AddInt32(&violations, 1)
+					}
+
+					time.Sleep(20 * time.Millisecond)
+
+					atomic.AddInt32(&holders, -1)
+
+					Expect(unlock()).To(Succeed())
+					cancel()
+				}
+			}()
+		}
+
+		wg.Wait()
+
+		Expect(atomic.LoadInt32(&violations)).To(Equal(int32(0)))
+	})
+
+	It("should allow concurrent locks on different keys", func() {
+		bucket, key, _ := NewKVSet()
+
+		unlock, err := kl.Lock(context.Background(), bucket, key)
+		Expect(err).ToNot(HaveOccurred())
+
+		defer unlock()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+		defer cancel()
+
+		// Same key is held
+		_, err = kl.Lock(ctx, bucket, key)
+		Expect(err).To(Equal(context.DeadlineExceeded))
+
+		ctx, cancel = context.WithTimeout(context.Background(), 500*time.Millisecond)
+		defer cancel()
+
+		// Other key in the same bucket and same key in another bucket are not
+		otherUnlock, err := kl.Lock(ctx, bucket, "other")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(otherUnlock()).To(Succeed())
+
+		otherBucket, _, _ := NewKVSet()
+
+		otherUnlock, err = kl.Lock(ctx, otherBucket, key)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(otherUnlock()).To(Succeed())
+	})
+
+	It("should error on an empty key", func() {
+		_, err := kl.Lock(context.Background(), "bucket", "")
+		Expect(err).To(HaveOccurred())
+	})
+
+	Describe("MultiKeyLock", func() {
+		It("should not deadlock on overlapping key sets", func() {
+			bucket, _, _ := NewKVSet()
+
+			keySets := [][]string{
+				{"a", "b", "c"},
+				{"c", "b", "a"},
+				{"b", "c"},
+				{"c", "a", "a"},
+			}
+
+			holders := map[string]*int32{"a": new(int32), "b": new(int32), "c": new(int32)}
+
+			var violations int32
+
+			wg := &sync.WaitGroup{}
+
+			for _, keys := range keySets {
+				wg.Add(1)
+
+				go func(keys []string) {
+					defer GinkgoRecover()
+					defer wg.Done()
+
+					// MultiKeyLock ignores duplicate keys
+					unique := make(map[string]struct{})
+
+					for _, key := range keys {
+						unique[key] = struct{}{}
+					}
+
+					for j := 0; j < 3; j++ {
+						ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+
+						unlock, err := kl.MultiKeyLock(ctx, bucket, keys)
+						Expect(err).ToNot(HaveOccurred())
+
+						for key := range unique {
+							if atomic.AddInt32(holders[key], 1) > 1 {
+								atomic.AddInt32(&violations, 1)
+							}
+						}
+
+						time.Sleep(10 * time.Millisecond)
+
+						for key := range unique {
+							atomic.AddInt32(holders[key], -1)
+						}
+
+						Expect(unlock()).To(Succeed())
+						cancel()
+					}
+				}(keys)
+			}
+
+			done := make(chan struct{})
+
+			go func() {
+				wg.Wait()
+				close(done)
+			}()
+
+			Eventually(done, 30*time.Second).Should(BeClosed())
+
+			Expect(atomic.LoadInt32(&violations)).To(Equal(int32(0)))
+		})
+
+		It("should release acquired locks on failure", func() {
+			bucket, _, _ := NewKVSet()
+
+			unlockB, err := kl.Lock(context.Background(), bucket, "b")
+			Expect(err).ToNot(HaveOccurred())
+
+			defer unlockB()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+			defer cancel()
+
+			_, err = kl.MultiKeyLock(ctx, bucket, []string{"b", "a"})
+			Expect(errors.Is(err, context.DeadlineExceeded)).To(BeTrue())
+
+			// "a" was locked before "b" and must have been released
+			ctx, cancel = context.WithTimeout(context.Background(), 500*time.Millisecond)
+			defer cancel()
+
+			unlockA, err := kl.Lock(ctx, bucket, "a")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(unlockA()).To(Succeed())
+		})
+	})
+})
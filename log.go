@@ -1,5 +1,10 @@
 package natty
 
+import (
+	"fmt"
+	"strings"
+)
+
 // Logger is the common interface for user-provided loggers.
 type Logger interface {
 	// Debug sends out a debug message with the given arguments to the logger.
@@ -20,6 +25,74 @@ type Logger interface {
 	Errorf(format string, args ...interface{})
 }
 
+// StructuredLogger may optionally be implemented by a Logger to receive log
+// messages with structured key/value fields (ie. "bucket", "foo") rather than
+// pre-formatted strings. See SlogLogger.
+type StructuredLogger interface {
+	// Debugw sends out a debug message with the given key/value fields.
+	Debugw(msg string, keysAndValues ...interface{})
+	// Infow sends out an informational message with the given key/value fields.
+	Infow(msg string, keysAndValues ...interface{})
+	// Warnw sends out a warning message with the given key/value fields.
+	Warnw(msg string, keysAndValues ...interface{})
+	// Errorw sends out an error message with the given key/value fields.
+	Errorw(msg string, keysAndValues ...interface{})
+}
+
+// debugw logs a debug message with key/value fields; fields are appended to
+// the message as key=value if the logger is not a StructuredLogger.
+func debugw(l Logger, msg string, keysAndValues ...interface{}) {
+	if _, ok := l.(*NoOpLogger); ok {
+		// Avoid formatting fields for nothing
+		return
+	}
+
+	if sl, ok := l.(StructuredLogger); ok {
+		sl.Debugw(msg, keysAndValues...)
+		return
+	}
+
+	l.Debug(formatFields(msg, keysAndValues))
+}
+
+// errorw logs an error message with key/value fields; fields are appended to
+// the message as key=value if the logger is not a StructuredLogger.
+func errorw(l Logger, msg string, keysAndValues ...interface{}) {
+	if sl, ok := l.(StructuredLogger); ok {
+		sl.Errorw(msg, keysAndValues...)
+		return
+	}
+
+	l.Error(formatFields(msg, keysAndValues))
+}
+
+// warnw logs a warning message with key/value fields; fields are appended to
+// the message as key=value if the logger is not a StructuredLogger.
+func warnw(l Logger, msg string, keysAndValues ...interface{}) {
+	if sl, ok := l.(StructuredLogger); ok {
+		sl.Warnw(msg, keysAndValues...)
+		return
+	}
+
+	l.Warn(formatFields(msg, keysAndValues))
+}
+
+func formatFields(msg string, keysAndValues []interface{}) string {
+	var sb strings.Builder
+
+	sb.WriteString(msg)
+
+	for i := 0; i < len(keysAndValues); i += 2 {
+		if i+1 < len(keysAndValues) {
+			sb.WriteString(fmt.Sprintf(" %v=%v", keysAndValues[i], keysAndValues[i+1]))
+		} else {
+			sb.WriteString(fmt.Sprintf(" %v", keysAndValues[i]))
+		}
+	}
+
+	return sb.String()
+}
+
 // NoOpLogger is a do-nothing logger; it is used internally
 // as the default Logger when none is provided in the Options.
 type NoOpLogger struct {
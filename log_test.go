@@ -0,0 +1,19 @@
+package natty
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Log", func() {
+	Describe("formatFields", func() {
+		It("should append fields as key=value", func() {
+			Expect(formatFields("kv operation", []interface{}{"bucket", "foo", "key", "bar"})).
+				To(Equal("kv operation bucket=foo key=bar"))
+		})
+
+		It("should handle a dangling key", func() {
+			Expect(formatFields("msg", []interface{}{"bucket"})).To(Equal("msg bucket"))
+		})
+	})
+})
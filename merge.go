@@ -0,0 +1,32 @@
+package natty
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+)
+
+// Merge resolves diverged values for key into a single value and stores it.
+// resolver is called with the current value of the key (if it exists) followed
+// by values, and must return the merged value. The merged value is written
+// with a compare-and-set on the revision that was read; if the key changes in
+// the meantime, the current value is re-read and resolver is called again
+// until the write succeeds or the context is cancelled. The bucket will be
+// auto-created if it does not exist.
+func (n *Natty) Merge(ctx context.Context, bucket, key string, values [][]byte, resolver func(values [][]byte) []byte) error {
+	if resolver == nil {
+		return errors.New("resolver cannot be nil")
+	}
+
+	return n.updateCAS(ctx, bucket, key, func(current []byte, exists bool) ([]byte, error) {
+		if !exists {
+			return resolver(values), nil
+		}
+
+		candidates := make([][]byte, 0, len(values)+1)
+		candidates = append(candidates, current)
+		candidates = append(candidates, values...)
+
+		return resolver(candidates), nil
+	})
+}
@@ -0,0 +1,114 @@
+package natty
+
+import (
+	"bytes"
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Merge", func() {
+	var n *Natty
+
+	BeforeEach(func() {
+		var err error
+
+		n, err = New(NewConfig())
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	// longest picks the longest value
+	longest := func(values [][]byte) []byte {
+		var result []byte
+
+		for _, v := range values {
+			if len(v) > len(result) {
+				result = v
+			}
+		}
+
+		return result
+	}
+
+	It("should store the resolver's output for conflicting values", func() {
+		bucket, key, _ := NewKVSet()
+
+		var seen [][]byte
+
+		err := n.Merge(context.Background(), bucket, key, [][]byte{[]byte("us-east"), []byte("eu-west-1")}, func(values [][]byte) []byte {
+			seen = values
+			return longest(values)
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(seen).To(HaveLen(2))
+
+		data, err := n.Get(context.Background(), bucket, key)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(Equal([]byte("eu-west-1")))
+	})
+
+	It("should include the current value when resolving", func() {
+		bucket, key, _ := NewKVSet()
+
+		Expect(n.Put(context.Background(), bucket, key, []byte("ap-southeast-2"))).To(Succeed())
+
+		var seen [][]byte
+
+		err := n.Merge(context.Background(), bucket, key, [][]byte{[]byte("us-east"), []byte("eu-west-1")}, func(values [][]byte) []byte {
+			seen = values
+			return bytes.Join(values, []byte(","))
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(seen).To(HaveLen(3))
+
+		data, err := n.Get(context.Background(), bucket, key)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(Equal([]byte("ap-southeast-2,us-east,eu-west-1")))
+	})
+
+	It("should not lose values when merging concurrently", func() {
+		bucket, key, _ := NewKVSet()
+
+		const numMergers = 20
+
+		wg := &sync.WaitGroup{}
+
+		for i := 0; i < numMergers; i++ {
+			wg.Add(1)
+
+			go func(i int) {
+				defer GinkgoRecover()
+				defer wg.Done()
+
+				value := []byte(strconv.Itoa(i))
+
+				err := n.Merge(context.Background(), bucket, key, [][]byte{value}, func(values [][]byte) []byte {
+					return bytes.Join(values, []byte(","))
+				})
+				Expect(err).ToNot(HaveOccurred())
+			}(i)
+		}
+
+		wg.Wait()
+
+		data, err := n.Get(context.Background(), bucket, key)
+		Expect(err).ToNot(HaveOccurred())
+
+		merged := strings.Split(string(data), ",")
+		Expect(merged).To(HaveLen(numMergers))
+
+		for i := 0; i < numMergers; i++ {
+			Expect(merged).To(ContainElement(strconv.Itoa(i)))
+		}
+	})
+
+	It("should error on a nil resolver", func() {
+		bucket, key, _ := NewKVSet()
+
+		Expect(n.Merge(context.Background(), bucket, key, nil, nil)).ToNot(Succeed())
+	})
+})
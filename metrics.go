@@ -0,0 +1,21 @@
+package natty
+
+// Metrics is the common interface for user-provided metrics collectors. A
+// Prometheus implementation lives in the "metrics" sub-package so that users
+// who do not need metrics do not have to import Prometheus.
+type Metrics interface {
+	// StartKV is called at the beginning of a KV operation (one of the KVOp*
+	// constants); the returned func is called once the operation completes
+	// with the resulting error (nil on success).
+	StartKV(operation, bucket string) func(err error)
+}
+
+// NoOpMetrics is a do-nothing metrics collector; it is used internally as the
+// default Metrics when none is provided in the Config.
+type NoOpMetrics struct {
+}
+
+// StartKV is no-op implementation of Metrics' StartKV.
+func (m *NoOpMetrics) StartKV(operation, bucket string) func(err error) {
+	return func(err error) {}
+}
@@ -0,0 +1,98 @@
+// Package metrics provides a Prometheus implementation of natty.Metrics.
+//
+// Usage:
+//
+//	mc := metrics.NewMetricsCollector(prometheus.DefaultRegisterer)
+//	n, err := natty.New(cfg.WithMetrics(mc))
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	Namespace = "natty"
+
+	StatusSuccess = "success"
+	StatusError   = "error"
+)
+
+// MetricsCollector records latency, error and in-flight metrics for natty KV
+// operations, labeled by operation and bucket.
+type MetricsCollector struct {
+	operations *prometheus.CounterVec
+	duration   *prometheus.HistogramVec
+	inFlight   *prometheus.GaugeVec
+}
+
+// NewMetricsCollector creates a collector and registers its metrics with the
+// given registerer; if registerer is nil, prometheus.DefaultRegisterer is used.
+// Metrics that are already registered (ie. by a previous collector) are reused.
+func NewMetricsCollector(registerer prometheus.Registerer) *MetricsCollector {
+	if registerer == nil {
+		registerer = prometheus.DefaultRegisterer
+	}
+
+	mc := &MetricsCollector{
+		operations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Subsystem: "kv",
+			Name:      "operations_total",
+			Help:      "Number of KV operations by operation, bucket and status",
+		}, []string{"operation", "bucket", "status"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: Namespace,
+			Subsystem: "kv",
+			Name:      "operation_duration_seconds",
+			Help:      "Duration of KV operations by operation and bucket",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"operation", "bucket"}),
+		inFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: Namespace,
+			Subsystem: "kv",
+			Name:      "operations_in_flight",
+			Help:      "Number of KV operations currently in progress",
+		}, []string{"operation", "bucket"}),
+	}
+
+	mc.operations = register(registerer, mc.operations).(*prometheus.CounterVec)
+	mc.duration = register(registerer, mc.duration).(*prometheus.HistogramVec)
+	mc.inFlight = register(registerer, mc.inFlight).(*prometheus.GaugeVec)
+
+	return mc
+}
+
+// StartKV implements natty.Metrics
+func (mc *MetricsCollector) StartKV(operation, bucket string) func(err error) {
+	start := time.Now()
+
+	inFlight := mc.inFlight.WithLabelValues(operation, bucket)
+	inFlight.Inc()
+
+	return func(err error) {
+		inFlight.Dec()
+
+		status := StatusSuccess
+
+		if err != nil {
+			status = StatusError
+		}
+
+		mc.duration.WithLabelValues(operation, bucket).Observe(time.Since(start).Seconds())
+		mc.operations.WithLabelValues(operation, bucket, status).Inc()
+	}
+}
+
+func register(registerer prometheus.Registerer, c prometheus.Collector) prometheus.Collector {
+	if err := registerer.Register(c); err != nil {
+		if are, ok := err.(prometheus.AlreadyRegisteredError); ok {
+			return are.ExistingCollector
+		}
+
+		panic(err)
+	}
+
+	return c
+}
@@ -0,0 +1,97 @@
+package metrics
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var _ = Describe("MetricsCollector", func() {
+	var (
+		registry *prometheus.Registry
+		mc       *MetricsCollector
+	)
+
+	BeforeEach(func() {
+		registry = prometheus.NewRegistry()
+		mc = NewMetricsCollector(registry)
+	})
+
+	// counterValue returns the value of the natty_kv_operations_total counter
+	// with the given labels (or -1 if not found)
+	counterValue := func(operation, bucket, status string) float64 {
+		families, err := registry.Gather()
+		Expect(err).ToNot(HaveOccurred())
+
+		for _, f := range families {
+			if f.GetName() != "natty_kv_operations_total" {
+				continue
+			}
+
+			for _, m := range f.GetMetric() {
+				labels := make(map[string]string)
+
+				for _, l := range m.GetLabel() {
+					labels[l.GetName()] = l.GetValue()
+				}
+
+				if labels["operation"] == operation && labels["bucket"] == bucket && labels["status"] == status {
+					return m.GetCounter().GetValue()
+				}
+			}
+		}
+
+		return -1
+	}
+
+	It("records success and error by operation and bucket", func() {
+		mc.StartKV("get", "foo")(nil)
+		mc.StartKV("get", "foo")(nil)
+		mc.StartKV("put", "foo")(errors.New("boom"))
+
+		Expect(counterValue("get", "foo", StatusSuccess)).To(Equal(float64(2)))
+		Expect(counterValue("put", "foo", StatusError)).To(Equal(float64(1)))
+		Expect(counterValue("put", "foo", StatusSuccess)).To(Equal(float64(-1)))
+	})
+
+	It("tracks in-flight operations", func() {
+		done := mc.StartKV("keys", "bar")
+
+		families, err := registry.Gather()
+		Expect(err).ToNot(HaveOccurred())
+
+		var inFlight float64
+
+		for _, f := range families {
+			if f.GetName() == "natty_kv_operations_in_flight" {
+				inFlight = f.GetMetric()[0].GetGauge().GetValue()
+			}
+		}
+
+		Expect(inFlight).To(Equal(float64(1)))
+
+		done(nil)
+
+		families, err = registry.Gather()
+		Expect(err).ToNot(HaveOccurred())
+
+		for _, f := range families {
+			if f.GetName() == "natty_kv_operations_in_flight" {
+				inFlight = f.GetMetric()[0].GetGauge().GetValue()
+			}
+		}
+
+		Expect(inFlight).To(Equal(float64(0)))
+	})
+
+	It("reuses metrics that are already registered", func() {
+		Expect(func() { NewMetricsCollector(registry) }).ToNot(Panic())
+
+		other := NewMetricsCollector(registry)
+		other.StartKV("delete", "baz")(nil)
+
+		Expect(counterValue("delete", "baz", StatusSuccess)).To(Equal(float64(1)))
+	})
+})
@@ -0,0 +1,81 @@
+package natty
+
+import (
+	"context"
+	"sync"
+
+	"github.com/nats-io/nats.go"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type recordedOp struct {
+	operation string
+	bucket    string
+	err       error
+}
+
+type recordingMetrics struct {
+	mutex *sync.Mutex
+	ops   []recordedOp
+}
+
+func (m *recordingMetrics) StartKV(operation, bucket string) func(err error) {
+	return func(err error) {
+		m.mutex.Lock()
+		defer m.mutex.Unlock()
+
+		m.ops = append(m.ops, recordedOp{operation: operation, bucket: bucket, err: err})
+	}
+}
+
+var _ = Describe("Metrics", func() {
+	var (
+		n       *Natty
+		metrics *recordingMetrics
+	)
+
+	BeforeEach(func() {
+		var err error
+
+		metrics = &recordingMetrics{mutex: &sync.Mutex{}}
+
+		n, err = New(NewConfig().WithMetrics(metrics))
+
+		Expect(err).To(BeNil())
+		Expect(n).NotTo(BeNil())
+	})
+
+	It("should record KV operations by operation and bucket", func() {
+		bucket, key, value := NewKVSet()
+
+		Expect(n.Put(context.Background(), bucket, key, value)).To(Succeed())
+
+		_, err := n.Get(context.Background(), bucket, key)
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = n.Keys(context.Background(), bucket)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(n.Delete(context.Background(), bucket, key)).To(Succeed())
+
+		_, err = n.Get(context.Background(), bucket, key)
+		Expect(err).To(Equal(nats.ErrKeyNotFound))
+
+		Expect(metrics.ops).To(Equal([]recordedOp{
+			{operation: KVOpPut, bucket: bucket},
+			{operation: KVOpGet, bucket: bucket},
+			{operation: KVOpKeys, bucket: bucket},
+			{operation: KVOpDelete, bucket: bucket},
+			{operation: KVOpGet, bucket: bucket, err: nats.ErrKeyNotFound},
+		}))
+
+		Expect(n.DeleteBucket(context.Background(), bucket)).To(Succeed())
+	})
+
+	It("should default to NoOpMetrics", func() {
+		n, err := New(NewConfig())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(n.metrics).To(BeAssignableToTypeOf(&NoOpMetrics{}))
+	})
+})
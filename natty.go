@@ -2,16 +2,24 @@ package natty
 
 import (
 	"context"
+	"crypto/ed25519"
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"math/rand"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nkeys"
 	"github.com/pkg/errors"
 	"github.com/relistan/go-director"
+	uuid "github.com/satori/go.uuid"
+	"golang.org/x/time/rate"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
 )
 
@@ -23,12 +31,21 @@ const (
 	DefaultSubBatchSize      = 256
 	DefaultWorkerIdleTimeout = time.Minute
 	DefaultPublishTimeout    = time.Second * 5 // TODO: figure out a good value for this
+	DefaultMaxReconnects     = -1              // Reconnect forever
+	NoReconnect              = -2              // Config.MaxReconnects value that disables reconnects
+	DefaultReconnectWait     = time.Second * 2
+	MaxReconnectBackoff      = time.Minute
+	PingSubject              = "_PING.natty"
+	DefaultFlushTimeout      = time.Second * 5
 )
 
 var (
 	ErrEmptyStreamName   = errors.New("StreamName cannot be empty")
 	ErrEmptyConsumerName = errors.New("ConsumerName cannot be empty")
 	ErrEmptySubject      = errors.New("Subject cannot be empty")
+	ErrConnectionClosed  = errors.New("connection has been closed")
+	ErrKeyExists         = errors.New("key already exists")
+	ErrKeyExpiringSoon   = errors.New("key is expiring soon")
 )
 
 type Mode int
@@ -40,25 +57,103 @@ type INatty interface {
 	// This is a blocking call; cancellation should be performed via the context.
 	Consume(ctx context.Context, cfg *ConsumerConfig, cb func(ctx context.Context, msg *nats.Msg) error) error
 
+	// SubscribeWithHeaderFilter subscribes to subject and calls handler only for
+	// messages with a matching header. Filtering is performed client-side: all
+	// messages are still delivered to the subscription.
+	SubscribeWithHeaderFilter(ctx context.Context, stream, subject, durable string, filterHeader, filterValue string, handler func(*nats.Msg) error) (func() error, error)
+
+	// Subscribe creates (or re-attaches to) a durable push consumer and calls
+	// handler for every message; delivery resumes after the last ACK'd message
+	// on reconnect.
+	Subscribe(ctx context.Context, stream, subject string, opts SubOptions, handler func(*nats.Msg) error) (func() error, error)
+
+	// FetchWithOptions performs a single pull request against a consumer and
+	// returns up to opts.MaxMessages messages (fewer if the request expires or
+	// opts.NoWait is set).
+	FetchWithOptions(ctx context.Context, stream, consumer string, opts FetchOptions) ([]*nats.Msg, error)
+
+	// InProgress resets the ack wait timer of a consumed message
+	InProgress(msg *nats.Msg) error
+
+	// AutoInProgress periodically resets the ack wait timer of a consumed message
+	// until the returned stop func is called
+	AutoInProgress(ctx context.Context, msg *nats.Msg, interval time.Duration) func()
+
 	// Publish publishes a single message with the given subject; this method
-	// will perform automatic batching as configured during `natty.New(..)`
+	// will perform automatic batching as configured during `natty.New(..)`.
+	// Messages that cannot be queued are logged and sent to
+	// Config.PublishErrorCh (if set); use TryPublish to get the error instead.
 	Publish(ctx context.Context, subject string, data []byte)
 
+	// TryPublish is like Publish but returns an error if the message could
+	// not be queued. Blocks if Config.PublishRateLimit is exceeded. Returns
+	// ErrConnectionClosed if Drain() or Close() has been called,
+	// ErrCircuitOpen if the circuit breaker (if configured) is open and
+	// *ErrMessageTooLarge if data exceeds Config.MaxMsgSize.
+	TryPublish(ctx context.Context, subject string, data []byte) error
+
+	// PublishWithRetry synchronously publishes a message, retrying "no
+	// responders" errors (ie. the stream does not exist yet) per retryPolicy
+	PublishWithRetry(ctx context.Context, subject string, data []byte, retryPolicy RetryPolicy) (*nats.PubAck, error)
+
+	// PublishAsyncBatch publishes messages asynchronously, waits for all acks
+	// and returns a result (sequence or error) per message.
+	PublishAsyncBatch(ctx context.Context, messages []*nats.Msg) ([]PublishResult, error)
+
 	// DeletePublisher shuts down a publisher and deletes it from the internal publisherMap
 	DeletePublisher(ctx context.Context, id string) bool
 
 	// CreateStream creates a new stream if it does not exist
 	CreateStream(ctx context.Context, name string, subjects []string) error
 
+	// CreateStreamWithConfig creates a stream from a full config (ie. with
+	// Sources or a Mirror)
+	CreateStreamWithConfig(ctx context.Context, cfg *nats.StreamConfig) (*nats.StreamInfo, error)
+
+	// CreateMirrorStream creates a stream (named cfg.Name) that mirrors the
+	// sourceName stream
+	CreateMirrorStream(ctx context.Context, sourceName string, cfg *nats.StreamConfig) (*nats.StreamInfo, error)
+
 	// DeleteStream deletes an existing stream
 	DeleteStream(ctx context.Context, name string) error
 
+	// PurgeStreamSubject removes all messages on subject from stream, keeping
+	// messages on other subjects
+	PurgeStreamSubject(ctx context.Context, stream, subject string) error
+
+	// PurgeStreamBefore removes all messages with a sequence lower than seq
+	PurgeStreamBefore(ctx context.Context, stream string, seq uint64) error
+
+	// SnapshotStream writes a snapshot of the stream (messages and consumers)
+	// to w
+	SnapshotStream(ctx context.Context, name string, w io.Writer) error
+
+	// RestoreStream creates a stream from a snapshot created via
+	// SnapshotStream()
+	RestoreStream(ctx context.Context, cfg *nats.StreamConfig, r io.Reader) (*nats.StreamInfo, error)
+
 	// CreateConsumer creates a new consumer if it does not exist
 	CreateConsumer(ctx context.Context, streamName, consumerName string, filterSubject ...string) error
 
+	// UpdateConsumer updates the config (ie. the filter subject) of an existing
+	// consumer without resetting its ack floor
+	UpdateConsumer(ctx context.Context, stream string, cfg *nats.ConsumerConfig) (*nats.ConsumerInfo, error)
+
 	// DeleteConsumer deletes an existing consumer
 	DeleteConsumer(ctx context.Context, consumerName, streamName string) error
 
+	// ListStreams returns info for every stream in the account
+	ListStreams(ctx context.Context) ([]*nats.StreamInfo, error)
+
+	// ListConsumers returns info for every consumer on a stream
+	ListConsumers(ctx context.Context, stream string) ([]*nats.ConsumerInfo, error)
+
+	// AccountInfo returns the JetStream usage and limits of the account
+	AccountInfo(ctx context.Context) (*nats.AccountInfo, error)
+
+	// WaitForStream polls until the stream exists or ctx is done
+	WaitForStream(ctx context.Context, name string, pollInterval time.Duration) error
+
 	// NATS key/value Get/Put/Delete/Update functionality operates on "buckets"
 	// that are exposed via a 'KeyValue' instance. To simplify our interface,
 	// our Put method will automatically create the bucket if it does not already
@@ -71,41 +166,261 @@ type INatty interface {
 	// bucket if it does not exist.
 	Get(ctx context.Context, bucket string, key string) ([]byte, error)
 
-	// Create will attempt to create a key in KV. It will return an error if
-	// the key already exists. Will auto-create the bucket if it does not
-	// already exist.
+	// GetEntry will fetch the value + metadata (revision, creation time, etc.)
+	// for a given bucket and key. Will NOT auto-create bucket if it does not
+	// exist.
+	GetEntry(ctx context.Context, bucket string, key string) (*KVEntry, error)
+
+	// GetLatestEntry fetches the newest entry for a given key, including
+	// delete and purge markers (see KVEntry.Operation)
+	GetLatestEntry(ctx context.Context, bucket string, key string) (*KVEntry, error)
+
+	// Exists reports whether a key exists; a missing bucket is reported as the
+	// key not existing
+	Exists(ctx context.Context, bucket, key string) (bool, error)
+
+	// BucketExists reports whether a bucket exists
+	BucketExists(ctx context.Context, bucket string) (bool, error)
+
+	// GetIfNewer will fetch the value for a key only if its revision differs
+	// from sinceRevision; returns (nil, sinceRevision, nil) if not modified.
+	GetIfNewer(ctx context.Context, bucket, key string, sinceRevision uint64) ([]byte, uint64, error)
+
+	// GetIfModifiedSince will fetch the value for a key only if it was written
+	// after since; returns (nil, false, nil) if not modified.
+	GetIfModifiedSince(ctx context.Context, bucket, key string, since time.Time) ([]byte, bool, error)
+
+	// GetWithTTLHint fetches the value for a key along with the time remaining
+	// until it expires; returns a wrapped ErrKeyExpiringSoon if it expires within
+	// Config.TTLWarningThreshold
+	GetWithTTLHint(ctx context.Context, bucket, key string) ([]byte, time.Duration, error)
+
+	// CompareRevisions will fetch the current revisions of two keys in the same
+	// bucket and report whether they are equal.
+	CompareRevisions(ctx context.Context, bucket, key1, key2 string) (same bool, rev1, rev2 uint64, err error)
+
+	// Create will attempt to create a key in KV. It will return an error
+	// wrapping ErrKeyExists if the key already exists. Will auto-create the
+	// bucket if it does not already exist.
 	Create(ctx context.Context, bucket string, key string, data []byte, keyTTL ...time.Duration) error
 
+	// GetOrCreate returns the value of a key, creating it via factory if it does
+	// not exist; the bool is true if the value was created by this call
+	GetOrCreate(ctx context.Context, bucket, key string, ttl time.Duration, factory func() ([]byte, error)) ([]byte, bool, error)
+
 	// Put will put a new value for a given bucket and key. Will auto-create
 	// the bucket if it does not already exist.
 	Put(ctx context.Context, bucket string, key string, data []byte, ttl ...time.Duration) error
 
+	// BatchPutWithTTL puts all entries, auto-creating the bucket with the given
+	// TTL if it does not exist; returns ErrBucketTTLMismatch if the bucket
+	// exists with a different TTL
+	BatchPutWithTTL(ctx context.Context, bucket string, entries map[string][]byte, ttl time.Duration) error
+
+	// GetJSON will fetch the value for a given bucket and key and unmarshal
+	// it into out. Returns a *JSONError if unmarshalling fails.
+	GetJSON(ctx context.Context, bucket, key string, out interface{}) error
+
+	// PutJSON will marshal v and put it into the given bucket and key. Will
+	// auto-create the bucket if it does not already exist. Returns a
+	// *JSONError if marshalling fails.
+	PutJSON(ctx context.Context, bucket, key string, v interface{}, ttl ...time.Duration) error
+
 	// Delete will delete a key from a given bucket. Will no-op if the bucket
 	// or key does not exist.
 	Delete(ctx context.Context, bucket string, key string) error
 
+	// DeletePrefix deletes every key in a bucket that starts with prefix and
+	// returns the number of deleted keys
+	DeletePrefix(ctx context.Context, bucket, prefix string) (int, error)
+
+	// DeleteByPattern deletes every key in a bucket matching keyPattern (a NATS
+	// subject pattern, ie. "user.*.profile") with a single purge request
+	DeleteByPattern(ctx context.Context, bucket, keyPattern string) (int, error)
+
+	// CopyKey copies the value of a key to another key (and/or bucket)
+	CopyKey(ctx context.Context, srcBucket, srcKey, dstBucket, dstKey string) error
+
 	// CreateBucket will attempt to create a new bucket. Will return an error if
 	// bucket already exists.
 	CreateBucket(ctx context.Context, bucket string, ttl time.Duration, description ...string) error
 
+	// CreateBucketWithConfig creates a bucket with full control over its config
+	// (ie. replicas, max bytes, storage type)
+	CreateBucketWithConfig(ctx context.Context, cfg *nats.KeyValueConfig) (nats.KeyValue, error)
+
+	// UpdateBucketConfig updates the configuration (ie. TTL, history, replicas)
+	// of an existing bucket; fields that are not set in cfg are kept
+	UpdateBucketConfig(ctx context.Context, bucket string, cfg *nats.KeyValueConfig) error
+
 	// DeleteBucket will delete the specified bucket
 	DeleteBucket(ctx context.Context, bucket string) error
 
+	// Increment will atomically add delta to the integer counter stored at
+	// key and return the new value. Will auto-create the bucket and counter.
+	Increment(ctx context.Context, bucket, key string, delta int64) (int64, error)
+
+	// Decrement will atomically subtract delta from the integer counter
+	// stored at key and return the new value. Will auto-create the bucket and
+	// counter.
+	Decrement(ctx context.Context, bucket, key string, delta int64) (int64, error)
+
+	// Merge resolves diverged values (and the current value) of a key via
+	// resolver and stores the result using compare-and-set
+	Merge(ctx context.Context, bucket, key string, values [][]byte, resolver func(values [][]byte) []byte) error
+
+	// TemporaryBucket will create a uniquely named bucket, call fn with its
+	// name and delete the bucket after fn returns (even if fn errors).
+	TemporaryBucket(ctx context.Context, fn func(bucket string) error) error
+
+	// TemporaryKey will generate a unique key name, call fn with it and
+	// delete the key from bucket after fn returns (even if fn errors).
+	TemporaryKey(ctx context.Context, bucket string, fn func(key string) error) error
+
 	// Keys will return all of the keys in a bucket (empty slice if none found)
 	Keys(ctx context.Context, bucket string) ([]string, error)
 
+	// KeysStream streams the keys in a bucket over a channel; at most one error
+	// is sent on the error channel before both channels are closed
+	KeysStream(ctx context.Context, bucket string) (<-chan string, <-chan error)
+
+	// Watch streams changes to key (which may contain wildcards) in bucket until
+	// ctx is cancelled
+	Watch(ctx context.Context, bucket, key string) (<-chan *KVEntry, error)
+
+	// WatchBucket streams changes to every key in a bucket; a nil entry is sent
+	// once all current values have been delivered
+	WatchBucket(ctx context.Context, bucket string) (<-chan *KVEntry, error)
+
+	// WatchWithBackpressure is like Watch() but queues entries in memory instead
+	// of dropping them; once Config.WatchMaxQueueBytes are queued, it stops
+	// reading from NATS until the consumer catches up
+	WatchWithBackpressure(ctx context.Context, bucket, key string) (<-chan *KVEntry, error)
+
+	// WatchCheckpointed streams changes to key into ch, storing the revision of
+	// every delivered entry in checkpointKey so that a later call resumes where
+	// this one left off. This is a blocking call.
+	WatchCheckpointed(ctx context.Context, bucket, key, checkpointKey string, ch chan<- *KVEntry) error
+
+	// CompactHistory will trim the history of a key down to the newest
+	// keepRevisions values. Will NOT auto-create bucket if it does not exist.
+	CompactHistory(ctx context.Context, bucket, key string, keepRevisions int) error
+
+	// BucketChecksum will compute a deterministic SHA256 checksum of the
+	// current contents of a bucket. Will NOT auto-create bucket.
+	BucketChecksum(ctx context.Context, bucket string) ([]byte, error)
+
+	// Snapshot copies the current contents of a bucket into a KVSnapshot
+	Snapshot(ctx context.Context, bucket string) (*KVSnapshot, error)
+
+	// Reconcile will add, update and delete keys in a bucket so that it
+	// matches the desired state. Will auto-create the bucket if it does not
+	// already exist.
+	Reconcile(ctx context.Context, bucket string, desired map[string][]byte) (ReconcileResult, error)
+
+	// Hydrate pre-loads a bucket with data and marks it as hydrated
+	Hydrate(ctx context.Context, bucket string, data map[string][]byte) error
+
+	// IsHydrated returns true if Hydrate() has completed for a bucket
+	IsHydrated(ctx context.Context, bucket string) (bool, error)
+
+	// ExportToCSV will write the contents of a bucket to w in CSV format
+	ExportToCSV(ctx context.Context, bucket string, w io.Writer) error
+
+	// ImportFromCSV will Put() every row of CSV data (as written by
+	// ExportToCSV) into bucket. Will auto-create the bucket if it does not
+	// already exist.
+	ImportFromCSV(ctx context.Context, bucket string, r io.Reader) error
+
+	// PutObject will store the contents of r as an object in an object store
+	// bucket. Will auto-create bucket if it does not exist.
+	PutObject(ctx context.Context, bucket, name string, r io.Reader) (*nats.ObjectInfo, error)
+
+	// GetObject will return a reader for an object; the reader must be closed
+	// by the caller.
+	GetObject(ctx context.Context, bucket, name string) (io.ReadCloser, error)
+
+	// DeleteObject will delete an object from an object store bucket
+	DeleteObject(ctx context.Context, bucket, name string) error
+
+	// ListObjects will return info for all objects in an object store bucket
+	ListObjects(ctx context.Context, bucket string) ([]*nats.ObjectInfo, error)
+
+	// Lock acquires a distributed lock on lockKey in bucket (auto-created with
+	// the given TTL). Blocks until the lock is acquired or ctx is done; the
+	// returned func releases the lock.
+	Lock(ctx context.Context, bucket, lockKey string, ttl time.Duration) (unlock func() error, err error)
+
 	// AsLeader enables simple leader election by using NATS k/v functionality.
 	//
 	// AsLeader will execute opts.Func if and only if the node executing AsLeader
 	// acquires leader role. It will continue executing opts.Func until it loses
 	// leadership and another node becomes leader.
 	AsLeader(ctx context.Context, opts *AsLeaderConfig, f func() error) error
+
+	// Drain will wait for all publisher queues to be flushed, drain the NATS
+	// connection and block until the connection is closed or the context
+	// expires. Any subsequent KV or publish calls will return ErrConnectionClosed.
+	Drain(ctx context.Context) error
+
+	// Flush blocks until all publisher queues have been flushed and all
+	// buffered NATS messages have been sent to (and processed by) the server
+	// or ctx expires; unlike Drain(), the connection stays open
+	Flush(ctx context.Context) error
+
+	// GracefulRestart replaces the NATS connection: new KV operations are held
+	// back while in-flight ones complete, then a new connection is swapped in and
+	// subscriptions are re-created.
+	GracefulRestart(ctx context.Context) error
+
+	// Close will immediately close the NATS connection. Any subsequent KV or
+	// publish calls will return ErrConnectionClosed.
+	Close() error
+
+	// IsConnected returns true if the underlying NATS connection is connected
+	IsConnected() bool
+
+	// Status returns the status of the underlying NATS connection
+	Status() nats.Status
+
+	// JetStream returns the underlying JetStream context (nil once closed);
+	// calls made through it bypass natty's retries, metrics and tracing
+	JetStream() nats.JetStreamContext
+
+	// Conn returns the underlying NATS connection (nil once closed); calls made
+	// through it bypass natty's retries, metrics and tracing
+	Conn() *nats.Conn
+
+	// ServerInfo returns information (ie. version) about the connected NATS
+	// server
+	ServerInfo(ctx context.Context) (*ServerInfo, error)
+
+	// ConnectedServerName returns the name of the connected NATS server
+	ConnectedServerName() string
+
+	// ConnectedClusterName returns the cluster name of the connected NATS server
+	ConnectedClusterName() string
+
+	// Healthcheck returns nil if the connection is up and JetStream responds;
+	// used by KVProbe.
+	Healthcheck(ctx context.Context) error
+
+	// Ping performs a round-trip core NATS publish + receive; useful for
+	// liveness/readiness probes.
+	Ping(ctx context.Context) error
 }
 
+// Client is the interface implemented by Natty; depend on it (rather than on
+// *Natty) to be able to inject a test double such as nattytest.MockClient.
+type Client = INatty
+
+var _ Client = &Natty{}
+
 type Config struct {
-	// NatsURL defines the NATS urls the library will attempt to connect to. Iff
-	// first URL fails, we will try to connect to the next one. Only fail if all
-	// URLs fail.
+	// NatsURL defines the NATS urls the library will attempt to connect to.
+	// URLs are passed to the NATS client as a cluster seed list - the client
+	// will connect to the first reachable server and fail over to the others
+	// on reconnect. Only fail if all URLs fail.
 	NatsURL []string
 
 	// MaxMsgs defines the maximum number of messages a stream will contain.
@@ -124,9 +439,21 @@ type Config struct {
 	// the consumer has seen.
 	DeliverPolicy nats.DeliverPolicy
 
-	// Logger allows you to inject a logger into the library. Optional.
+	// Logger allows you to inject a logger into the library. Optional;
+	// defaults to NoOpLogger (see WithLogger to use a *slog.Logger).
 	Logger Logger
 
+	// Metrics allows you to inject a metrics collector into the library;
+	// see the "metrics" sub-package for a Prometheus collector. Optional.
+	Metrics Metrics
+
+	// Tracer allows you to inject a distributed tracer into the library; see
+	// the "tracing" sub-package for an OpenTelemetry tracer. Optional.
+	Tracer Tracer
+
+	// BucketHooks are optional callbacks fired before and after KV operations
+	BucketHooks BucketHooks
+
 	// Whether to use TLS
 	UseTLS bool
 
@@ -142,6 +469,35 @@ type Config struct {
 	// Do not perform server certificate checks
 	TLSSkipVerify bool
 
+	// TLSConfig is used as-is instead of generating a TLS config from the
+	// TLS* file fields above; implies UseTLS.
+	TLSConfig *tls.Config
+
+	// The following fields configure authentication. Only one auth method
+	// (CredsFile, NKeyFile, NKeySeed, Username/Password or Token) may be
+	// specified; New() will return an error otherwise.
+
+	// CredsFile is the path to a chained credentials file (JWT + NKey seed)
+	CredsFile string
+
+	// NKeyFile is the path to a file containing an NKey user seed
+	NKeyFile string
+
+	// NKeySeed is the NKey user seed itself
+	NKeySeed string
+
+	// UserJWT is the user JWT used for operator-mode auth; requires NKeySeed
+	UserJWT string
+
+	// Username is used for username/password auth (optional)
+	Username string
+
+	// Password is used for username/password auth (optional)
+	Password string
+
+	// Token is used for token auth (optional)
+	Token string
+
 	// PublishBatchSize is how many messages to async publish at once
 	// Default: 256
 	PublishBatchSize int
@@ -159,8 +515,143 @@ type Config struct {
 	// PublishTimeout is how long to wait for a batch of async publish calls to be ACK'd
 	PublishTimeout time.Duration
 
+	// BucketTimeouts optionally overrides the timeout for KV operations on
+	// specific buckets (key = bucket name). Operations that exceed the timeout
+	// return context.DeadlineExceeded.
+	BucketTimeouts map[string]time.Duration
+
+	// BucketConfigs optionally overrides the config used when a bucket is
+	// auto-created (ie. by Put() or Create()) for specific buckets (key =
+	// bucket name); the Bucket field is ignored. A TTL passed to Put() or
+	// Create() is only used if the override does not set one.
+	BucketConfigs map[string]*nats.KeyValueConfig
+
+	// TTLWarningThreshold causes GetWithTTLHint() to return (a wrapped)
+	// ErrKeyExpiringSoon when a key expires in less than the threshold.
+	// Disabled (0) by default.
+	TTLWarningThreshold time.Duration
+
+	// WatchMaxQueueBytes is the size (keys + values) of the queue at which
+	// WatchWithBackpressure() stops reading from NATS until the consumer
+	// catches up. Defaults to DefaultWatchMaxQueueBytes.
+	WatchMaxQueueBytes int
+
+	// Retry configures retries of KV operations that fail with a transient
+	// error (ie. during a rolling upgrade). Fetching (or auto-creating) the
+	// bucket is retried for every KV method; the operation itself is retried
+	// by Get, GetEntry, Put, Create, Keys, Delete, Increment, Merge and
+	// KVTx.Commit (and methods built on them, ie. GetJSON or PutJSON).
+	// Disabled by default.
+	Retry RetryPolicy
+
+	// CircuitBreaker configures a circuit breaker around KV and publish calls
+	// which fails calls fast (with ErrCircuitOpen) after Threshold consecutive
+	// errors. Disabled by default.
+	CircuitBreaker CircuitBreakerConfig
+
+	// PublishRateLimit limits the rate (messages per second) at which Publish()
+	// accepts messages; calls block until allowed or the context is done.
+	// Default: 0 (unlimited). See also Natty.SetPublishRateLimit().
+	PublishRateLimit rate.Limit
+
+	// PublishBurst is the max number of messages Publish() will accept at once
+	// when PublishRateLimit is set. Default: 1
+	PublishBurst int
+
+	// MaxMsgSize is the max payload size (in bytes) accepted by Publish(),
+	// PublishWithRetry() and PublishAsyncBatch(); larger messages are rejected
+	// with an *ErrMessageTooLarge before being sent. Set it to (at most) the
+	// streams' MaxMsgSize to avoid server-side rejections. Message headers are
+	// not counted. Default: 0 (no limit)
+	MaxMsgSize int32
+
+	// SigningKey is used by SignedKV (see NewSignedKV()) to sign values; they
+	// are verified with its public key. Optional.
+	SigningKey ed25519.PrivateKey
+
 	// PublishErrorCh will receive any
 	PublishErrorCh chan *PublishError
+
+	// MaxReconnects is the max number of reconnect attempts before giving up.
+	// 0 is treated as unset (ie. the default); use NoReconnect to never
+	// reconnect. Default: -1 (reconnect forever)
+	MaxReconnects int
+
+	// ReconnectWait is the base delay between reconnect attempts; the delay is
+	// doubled after every failed attempt (capped at MaxReconnectBackoff).
+	// Default: 2s
+	ReconnectWait time.Duration
+
+	// ReconnectJitter is the max amount of random jitter added to every
+	// reconnect delay. Optional.
+	ReconnectJitter time.Duration
+
+	// ReconnectHandler is called after a successful reconnect. Optional.
+	ReconnectHandler func(nc *nats.Conn)
+
+	// DisconnectErrHandler is called when the connection is lost (err will
+	// be nil if the disconnect was explicit, ie. via Close()). Optional.
+	DisconnectErrHandler func(nc *nats.Conn, err error)
+
+	// ErrorHandler is called on async errors such as slow consumers. Optional.
+	ErrorHandler func(nc *nats.Conn, sub *nats.Subscription, err error)
+
+	// ClosedHandler is called when the connection is permanently closed. Optional.
+	ClosedHandler func(nc *nats.Conn)
+
+	// NatsOptions are passed as-is to nats.Connect(); they are applied after
+	// all options configured via Config (and thus can override them). Optional.
+	NatsOptions []nats.Option
+
+	// JetStreamOptions are passed as-is when creating the JetStream contexts
+	// used by natty. Set the JetStream domain or API prefix via
+	// JetStreamDomain or JetStreamAPIPrefix instead of nats.Domain() or
+	// nats.APIPrefix(): natty makes some JetStream API requests itself and
+	// cannot see the prefix set by a nats.JSOpt. Optional.
+	JetStreamOptions []nats.JSOpt
+
+	// JetStreamDomain is the JetStream domain to use (see nats.Domain());
+	// cannot be combined with JetStreamAPIPrefix. Optional.
+	JetStreamDomain string
+
+	// JetStreamAPIPrefix is the JetStream API prefix to use (see
+	// nats.APIPrefix()); cannot be combined with JetStreamDomain. Optional.
+	JetStreamAPIPrefix string
+}
+
+// WithNatsOptions is an escape hatch for passing arbitrary nats.Option's that
+// are not (yet) exposed via Config; returns cfg for chaining.
+func (cfg *Config) WithNatsOptions(opts ...nats.Option) *Config {
+	cfg.NatsOptions = append(cfg.NatsOptions, opts...)
+	return cfg
+}
+
+// WithJetStreamOpts is an escape hatch for passing arbitrary nats.JSOpt's
+// to the JetStream context; returns cfg for chaining.
+func (cfg *Config) WithJetStreamOpts(opts ...nats.JSOpt) *Config {
+	cfg.JetStreamOptions = append(cfg.JetStreamOptions, opts...)
+	return cfg
+}
+
+// WithMetrics sets the metrics collector used to instrument KV operations;
+// returns cfg for chaining.
+func (cfg *Config) WithMetrics(m Metrics) *Config {
+	cfg.Metrics = m
+	return cfg
+}
+
+// WithTracer sets the tracer used to instrument KV operations and to propagate
+// trace context via message headers; returns cfg for chaining.
+func (cfg *Config) WithTracer(t Tracer) *Config {
+	cfg.Tracer = t
+	return cfg
+}
+
+// WithBucketHooks sets the callbacks fired before and after KV operations;
+// returns cfg for chaining.
+func (cfg *Config) WithBucketHooks(h BucketHooks) *Config {
+	cfg.BucketHooks = h
+	return cfg
 }
 
 // ConsumerConfig is used to pass configuration options to Consume()
@@ -191,6 +682,9 @@ type Publisher struct {
 	IdleTimeout time.Duration
 	looper      director.Looper
 
+	// sending is true while a batch taken off the Queue is being written
+	sending bool
+
 	// ErrorCh is optional. It will receive async publish errors if specified
 	// Otherwise errors will only be logged
 	ErrorCh chan *PublishError
@@ -211,6 +705,7 @@ type Publisher struct {
 type message struct {
 	Subject string
 	Value   []byte
+	Header  nats.Header
 }
 
 // PublishError is a wrapper struct used to return errors to code that occur during async batch publishes
@@ -223,86 +718,573 @@ type Natty struct {
 	*Config
 	nc             *nats.Conn
 	js             nats.JetStreamContext
+	asyncJS        nats.JetStreamContext
+	jsPrefix       string
 	consumerLooper director.Looper
 	kvMap          *KeyValueMap
 	kvMutex        *sync.RWMutex
 	publisherMutex *sync.RWMutex
 	publisherMap   map[string]*Publisher
+	closed         bool
+	closedMutex    *sync.RWMutex
 	log            Logger
+	metrics        Metrics
+	tracer         Tracer
+	breaker        *circuitBreaker
+	publishLimiter *rate.Limiter
+	restartMutex   *restartLock
+	connMutex      *sync.RWMutex
+	subsMutex      *sync.Mutex
+	subs           map[*managedSub]struct{}
+}
+
+// New creates a new Natty instance; opts (if any) are applied to cfg before
+// it is validated.
+func New(cfg *Config, opts ...Option) (*Natty, error) {
+	if cfg != nil {
+		for _, opt := range opts {
+			opt(cfg)
+		}
+	}
+
+	if err := validateConfig(cfg); err != nil {
+		return nil, errors.Wrap(err, "invalid config")
+	}
+
+	natsOpts, err := buildNatsOptions(cfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to build NATS options")
+	}
+
+	// NATS client handles failover between multiple URLs internally
+	nc, err := nats.Connect(strings.Join(cfg.NatsURL, ","), natsOpts...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to connect to NATS")
+	}
+
+	// Create js contexts
+	js, asyncJS, err := newJetStreamContexts(nc, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	n := &Natty{
+		nc:       nc,
+		js:       js,
+		asyncJS:  asyncJS,
+		jsPrefix: jsAPIPrefix(cfg),
+		Config:   cfg,
+		kvMap: &KeyValueMap{
+			rwMutex: &sync.RWMutex{},
+			kvMap:   make(map[string]nats.KeyValue),
+		},
+		publisherMutex: &sync.RWMutex{},
+		publisherMap:   make(map[string]*Publisher),
+		closedMutex:    &sync.RWMutex{},
+		restartMutex:   &restartLock{},
+		connMutex:      &sync.RWMutex{},
+		subsMutex:      &sync.Mutex{},
+		subs:           make(map[*managedSub]struct{}),
+	}
+
+	// Inject logger (if provided)
+	n.log = cfg.Logger
+
+	if n.log == nil {
+		n.log = &NoOpLogger{}
+	}
+
+	// Inject metrics collector (if provided)
+	n.metrics = cfg.Metrics
+
+	if n.metrics == nil {
+		n.metrics = &NoOpMetrics{}
+	}
+
+	// Inject tracer (if provided)
+	n.tracer = cfg.Tracer
+
+	if n.tracer == nil {
+		n.tracer = &NoOpTracer{}
+	}
+
+	n.breaker = newCircuitBreaker(cfg.CircuitBreaker)
+
+	n.publishLimiter = rate.NewLimiter(rate.Inf, 0)
+	n.SetPublishRateLimit(cfg.PublishRateLimit, cfg.PublishBurst)
+
+	return n, nil
+}
+
+// Drain marks the connection as closed, waits for all publisher queues to be
+// flushed and then drains the NATS connection. Blocks until the connection is
+// closed or the context expires.
+func (n *Natty) Drain(ctx context.Context) error {
+	if !n.setClosed() {
+		return ErrConnectionClosed
+	}
+
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+
+	// Give publishers a chance to flush their queues
+	for n.pendingPublishes() > 0 {
+		select {
+		case <-ctx.Done():
+			return errors.Wrap(ctx.Err(), "timed out waiting for publisher queues to flush")
+		case <-ticker.C:
+		}
+	}
+
+	nc := n.getConn()
+
+	if err := nc.Drain(); err != nil {
+		return errors.Wrap(err, "unable to drain connection")
+	}
+
+	for !nc.IsClosed() {
+		select {
+		case <-ctx.Done():
+			return errors.Wrap(ctx.Err(), "timed out waiting for connection to drain")
+		case <-ticker.C:
+		}
+	}
+
+	return nil
+}
+
+// Close marks the connection as closed and immediately closes the NATS connection
+func (n *Natty) Close() error {
+	if !n.setClosed() {
+		return ErrConnectionClosed
+	}
+
+	n.getConn().Close()
+
+	return nil
+}
+
+// IsConnected is a cheap check that returns true if the underlying NATS
+// connection is currently connected.
+func (n *Natty) IsConnected() bool {
+	return n.getConn().IsConnected()
+}
+
+// Status returns the raw status of the underlying NATS connection.
+func (n *Natty) Status() nats.Status {
+	return n.getConn().Status()
+}
+
+// Flush waits for all publisher queues (see Publish()) to be flushed, then
+// sends all messages buffered by the NATS client and waits for the server to
+// acknowledge them (via a PING/PONG round trip), so that messages published
+// before Flush() are guaranteed to have been processed by the server when it
+// returns. If ctx has no deadline, DefaultFlushTimeout is used.
+//
+// NOTE: Unlike Drain(), Flush() does not close the connection or wait for
+// subscriptions.
+func (n *Natty) Flush(ctx context.Context) error {
+	if n.isClosed() {
+		return ErrConnectionClosed
+	}
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithTimeout(ctx, DefaultFlushTimeout)
+		defer cancel()
+	}
+
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+
+	// Publishers need the (read) guard to flush, so flush before locking
+	for n.pendingPublishes() > 0 {
+		select {
+		case <-ctx.Done():
+			return errors.Wrap(ctx.Err(), "timed out waiting for publisher queues to flush")
+		case <-ticker.C:
+		}
+	}
+
+	n.restartMutex.RLock()
+	defer n.restartMutex.RUnlock()
+
+	if err := n.getConn().FlushWithContext(ctx); err != nil {
+		return errors.Wrap(err, "unable to flush connection")
+	}
+
+	return nil
+}
+
+// JetStream returns the underlying JetStream context as an escape hatch for
+// JetStream features that natty does not (yet) expose. Calls made through it
+// bypass natty's retries, circuit breaker, metrics, tracing and hooks.
+// Returns nil once Close() or Drain() has been called; the context is
+// replaced (and must be fetched again) after GracefulRestart().
+func (n *Natty) JetStream() nats.JetStreamContext {
+	if n.isClosed() {
+		return nil
+	}
+
+	return n.getJS()
+}
+
+// Conn returns the underlying NATS connection as an escape hatch; the same
+// caveats as for JetStream() apply. Returns nil once Close() or Drain() has
+// been called.
+func (n *Natty) Conn() *nats.Conn {
+	if n.isClosed() {
+		return nil
+	}
+
+	return n.getConn()
+}
+
+// getConn returns the current NATS connection; every access to the connection
+// must go through it (or getJS()) as GracefulRestart() replaces it.
+//
+// NOTE: connMutex is used rather than restartMutex as KV operations already
+// hold the latter; a nested RLock() deadlocks once GracefulRestart() waits.
+func (n *Natty) getConn() *nats.Conn {
+	n.connMutex.RLock()
+	defer n.connMutex.RUnlock()
+
+	return n.nc
+}
+
+// getJS returns the current JetStream context (see getConn())
+func (n *Natty) getJS() nats.JetStreamContext {
+	n.connMutex.RLock()
+	defer n.connMutex.RUnlock()
+
+	return n.js
+}
+
+// getAsyncJS returns the current JetStream context used for async publishes
+// (see getConn())
+func (n *Natty) getAsyncJS() nats.JetStreamContext {
+	n.connMutex.RLock()
+	defer n.connMutex.RUnlock()
+
+	return n.asyncJS
+}
+
+// newJetStreamContexts creates the JetStream context used for everything but
+// PublishAsyncBatch() and the (shared) one used by PublishAsyncBatch().
+func newJetStreamContexts(nc *nats.Conn, cfg *Config) (nats.JetStreamContext, nats.JetStreamContext, error) {
+	js, err := nc.JetStream(jetStreamOptions(cfg)...)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to create jetstream context")
+	}
+
+	asyncJS, err := nc.JetStream(jetStreamOptions(cfg, nats.PublishAsyncMaxPending(cfg.PublishBatchSize))...)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to create async jetstream context")
+	}
+
+	return js, asyncJS, nil
+}
+
+// defaultJSAPIPrefix is the JetStream API prefix used by nats.go unless a
+// domain or API prefix is given
+const defaultJSAPIPrefix = "$JS.API."
+
+// jetStreamOptions returns the options for JetStream contexts created by natty:
+// Config.JetStreamOptions, the configured domain or API prefix and extra
+func jetStreamOptions(cfg *Config, extra ...nats.JSOpt) []nats.JSOpt {
+	opts := make([]nats.JSOpt, 0, len(cfg.JetStreamOptions)+len(extra)+1)
+	opts = append(opts, cfg.JetStreamOptions...)
+
+	switch {
+	case cfg.JetStreamDomain != "":
+		opts = append(opts, nats.Domain(cfg.JetStreamDomain))
+	case cfg.JetStreamAPIPrefix != "":
+		opts = append(opts, nats.APIPrefix(cfg.JetStreamAPIPrefix))
+	}
+
+	return append(opts, extra...)
+}
+
+// jsAPIPrefix returns the JetStream API prefix (including the trailing '.')
+// for the domain or API prefix set in cfg; same as nats.Domain() and
+// nats.APIPrefix() use.
+func jsAPIPrefix(cfg *Config) string {
+	switch {
+	case cfg.JetStreamDomain != "":
+		return "$JS." + cfg.JetStreamDomain + ".API."
+	case cfg.JetStreamAPIPrefix != "":
+		return strings.TrimSuffix(cfg.JetStreamAPIPrefix, ".") + "."
+	default:
+		return defaultJSAPIPrefix
+	}
+}
+
+// jsAPISubject returns the subject of the JetStream API endpoint (ie.
+// "STREAM.LIST") under the configured API prefix
+func (n *Natty) jsAPISubject(endpoint string) string {
+	if n.jsPrefix == "" {
+		return defaultJSAPIPrefix + endpoint
+	}
+
+	return n.jsPrefix + endpoint
+}
+
+// ServerInfo describes the NATS server that Natty is currently connected to
+type ServerInfo struct {
+	ID      string
+	Name    string
+	Version string
+	Cluster string
+	URL     string
+}
+
+// ServerInfo returns information about the currently connected NATS server;
+// useful for guarding feature usage behind server version checks. The info is
+// cached by the NATS client (and refreshed on reconnect) so no request is made
+// to the server.
+func (n *Natty) ServerInfo(ctx context.Context) (*ServerInfo, error) {
+	if n.isClosed() {
+		return nil, ErrConnectionClosed
+	}
+
+	if ctx != nil {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	nc := n.getConn()
+
+	if !nc.IsConnected() {
+		return nil, errors.New("not connected to a NATS server")
+	}
+
+	return &ServerInfo{
+		ID:      nc.ConnectedServerId(),
+		Name:    nc.ConnectedServerName(),
+		Version: nc.ConnectedServerVersion(),
+		Cluster: nc.ConnectedClusterName(),
+		URL:     nc.ConnectedUrlRedacted(),
+	}, nil
+}
+
+// ConnectedServerName returns the name of the connected NATS server (empty if
+// not connected)
+func (n *Natty) ConnectedServerName() string {
+	return n.getConn().ConnectedServerName()
+}
+
+// ConnectedClusterName returns the name of the connected NATS server's
+// cluster (empty if not connected or not clustered)
+func (n *Natty) ConnectedClusterName() string {
+	return n.getConn().ConnectedClusterName()
+}
+
+// Ping publishes a small core NATS message to a unique subject under
+// PingSubject and waits for it to be echoed back to us. This provides a
+// stronger health signal than IsConnected() as it verifies a full round-trip
+// to the server. Ping will block until the echo is received or ctx expires.
+func (n *Natty) Ping(ctx context.Context) error {
+	if n.isClosed() {
+		return ErrConnectionClosed
+	}
+
+	subject := PingSubject + "." + uuid.NewV4().String()
+
+	nc := n.getConn()
+
+	sub, err := nc.SubscribeSync(subject)
+	if err != nil {
+		return errors.Wrap(err, "unable to subscribe to ping subject")
+	}
+
+	defer sub.Unsubscribe()
+
+	if err := nc.Publish(subject, []byte("ping")); err != nil {
+		return errors.Wrap(err, "unable to publish ping")
+	}
+
+	if _, err := sub.NextMsgWithContext(ctx); err != nil {
+		return errors.Wrap(err, "did not receive ping echo")
+	}
+
+	return nil
+}
+
+// Healthcheck returns nil if natty is usable for KV operations: the
+// connection is open and connected, and JetStream responds to an account info
+// request. Unlike Ping(), this also detects a server with JetStream disabled
+// or unavailable.
+func (n *Natty) Healthcheck(ctx context.Context) error {
+	if n.isClosed() {
+		return ErrConnectionClosed
+	}
+
+	nc := n.getConn()
+
+	if !nc.IsConnected() {
+		return errors.Errorf("not connected to a NATS server (status: %s)", nc.Status())
+	}
+
+	if _, err := n.getJS().AccountInfo(nats.Context(ctx)); err != nil {
+		return errors.Wrap(err, "JetStream is unavailable")
+	}
+
+	return nil
+}
+
+// setClosed marks natty as closed; returns false if already closed
+func (n *Natty) setClosed() bool {
+	n.closedMutex.Lock()
+	defer n.closedMutex.Unlock()
+
+	if n.closed {
+		return false
+	}
+
+	n.closed = true
+
+	return true
+}
+
+func (n *Natty) isClosed() bool {
+	n.closedMutex.RLock()
+	defer n.closedMutex.RUnlock()
+
+	return n.closed
 }
 
-func New(cfg *Config) (*Natty, error) {
-	if err := validateConfig(cfg); err != nil {
-		return nil, errors.Wrap(err, "invalid config")
+// buildNatsOptions translates Config into options for nats.Connect()
+func buildNatsOptions(cfg *Config) ([]nats.Option, error) {
+	opts := []nats.Option{
+		nats.ReconnectWait(cfg.ReconnectWait),
+		nats.CustomReconnectDelay(newReconnectDelayHandler(cfg.ReconnectWait, cfg.ReconnectJitter)),
+	}
+
+	if cfg.MaxReconnects == NoReconnect {
+		opts = append(opts, nats.NoReconnect())
+	} else {
+		opts = append(opts, nats.MaxReconnects(cfg.MaxReconnects))
+	}
+
+	if cfg.ReconnectHandler != nil {
+		opts = append(opts, nats.ReconnectHandler(cfg.ReconnectHandler))
+	}
+
+	if cfg.DisconnectErrHandler != nil {
+		opts = append(opts, nats.DisconnectErrHandler(cfg.DisconnectErrHandler))
+	}
+
+	if cfg.ErrorHandler != nil {
+		opts = append(opts, nats.ErrorHandler(cfg.ErrorHandler))
 	}
 
-	var connected bool
-	var nc *nats.Conn
-	var err error
-	var tlsConfig *tls.Config
+	if cfg.ClosedHandler != nil {
+		opts = append(opts, nats.ClosedHandler(cfg.ClosedHandler))
+	}
 
-	if cfg.UseTLS {
-		tlsConfig, err = GenerateTLSConfig(cfg.TLSCACertFile, cfg.TLSClientCertFile, cfg.TLSClientKeyFile, cfg.TLSSkipVerify)
+	if cfg.TLSConfig != nil {
+		opts = append(opts, nats.Secure(cfg.TLSConfig))
+	} else if cfg.UseTLS {
+		tlsConfig, err := GenerateTLSConfig(cfg.TLSCACertFile, cfg.TLSClientCertFile, cfg.TLSClientKeyFile, cfg.TLSSkipVerify)
 		if err != nil {
 			return nil, errors.Wrap(err, "failed to create TLS config")
 		}
+
+		opts = append(opts, nats.Secure(tlsConfig))
 	}
 
-	// Attempt to connect
-	for _, address := range cfg.NatsURL {
-		if cfg.UseTLS {
-			nc, err = nats.Connect(address, nats.Secure(tlsConfig))
-		} else {
-			nc, err = nats.Connect(address)
+	switch {
+	case cfg.CredsFile != "":
+		opts = append(opts, nats.UserCredentials(cfg.CredsFile))
+	case cfg.NKeyFile != "":
+		opt, err := nats.NkeyOptionFromSeed(cfg.NKeyFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to load nkey seed file")
 		}
 
+		opts = append(opts, opt)
+	case cfg.NKeySeed != "":
+		opt, err := nkeyOptionFromSeed(cfg.NKeySeed, cfg.UserJWT)
 		if err != nil {
-			fmt.Printf("unable to connect to '%s': %s\n", address, err)
-
-			continue
+			return nil, errors.Wrap(err, "unable to parse nkey seed")
 		}
 
-		connected = true
-		break
+		opts = append(opts, opt)
 	}
 
-	if !connected {
-		return nil, errors.Wrap(err, "failed to connect to NATS")
+	if cfg.Username != "" || cfg.Password != "" {
+		opts = append(opts, nats.UserInfo(cfg.Username, cfg.Password))
 	}
 
-	// Create js context
-	js, err := nc.JetStream()
+	if cfg.Token != "" {
+		opts = append(opts, nats.Token(cfg.Token))
+	}
+
+	// User provided options go last so they can override anything above
+	opts = append(opts, cfg.NatsOptions...)
+
+	return opts, nil
+}
+
+// newReconnectDelayHandler returns a reconnect delay func that performs
+// exponential backoff (capped at MaxReconnectBackoff) with optional jitter
+func newReconnectDelayHandler(wait, jitter time.Duration) nats.ReconnectDelayHandler {
+	return func(attempts int) time.Duration {
+		delay := wait
+
+		for i := 1; i < attempts && delay < MaxReconnectBackoff; i++ {
+			delay *= 2
+		}
+
+		if delay > MaxReconnectBackoff {
+			delay = MaxReconnectBackoff
+		}
+
+		if jitter > 0 {
+			delay += time.Duration(rand.Int63n(int64(jitter)))
+		}
+
+		return delay
+	}
+}
+
+// nkeyOptionFromSeed creates an NKey (or JWT, if userJWT is set) auth option
+// from an in-memory seed
+func nkeyOptionFromSeed(seed, userJWT string) (nats.Option, error) {
+	kp, err := nkeys.FromSeed([]byte(seed))
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to create jetstream context")
+		return nil, err
 	}
 
-	n := &Natty{
-		nc:     nc,
-		js:     js,
-		Config: cfg,
-		kvMap: &KeyValueMap{
-			rwMutex: &sync.RWMutex{},
-			kvMap:   make(map[string]nats.KeyValue),
-		},
-		publisherMutex: &sync.RWMutex{},
-		publisherMap:   make(map[string]*Publisher),
+	sigCB := func(nonce []byte) ([]byte, error) {
+		return kp.Sign(nonce)
 	}
 
-	// Inject logger (if provided)
-	n.log = cfg.Logger
+	if userJWT != "" {
+		return nats.UserJWT(func() (string, error) {
+			return userJWT, nil
+		}, sigCB), nil
+	}
 
-	if n.log == nil {
-		n.log = &NoOpLogger{}
+	pub, err := kp.PublicKey()
+	if err != nil {
+		return nil, err
 	}
 
-	return n, nil
+	if !nkeys.IsValidPublicUserKey(pub) {
+		return nil, errors.New("not a valid nkey user seed")
+	}
+
+	return nats.Nkey(pub, sigCB), nil
 }
 
 func (n *Natty) DeleteStream(ctx context.Context, name string) error {
 	span, _ := tracer.StartSpanFromContext(ctx, "natty.DeleteStream")
 	defer span.Finish()
 
-	if err := n.js.DeleteStream(name); err != nil {
+	if err := n.getJS().DeleteStream(name); err != nil {
 		err = errors.Wrap(err, "unable to delete stream")
 		span.SetTag("error", err)
 		return err
@@ -311,12 +1293,129 @@ func (n *Natty) DeleteStream(ctx context.Context, name string) error {
 	return nil
 }
 
+// PurgeStreamSubject removes all messages on subject (which may contain
+// wildcards) from stream; messages on other subjects are kept.
+func (n *Natty) PurgeStreamSubject(ctx context.Context, stream, subject string) error {
+	span, ctx := tracer.StartSpanFromContext(ctx, "natty.PurgeStreamSubject")
+	defer span.Finish()
+
+	if subject == "" {
+		return ErrEmptySubject
+	}
+
+	if _, err := n.purgeStream(ctx, stream, &streamPurgeRequest{Subject: subject}); err != nil {
+		span.SetTag("error", err)
+		return err
+	}
+
+	return nil
+}
+
+// PurgeStreamBefore removes all messages with a sequence lower than seq from
+// stream.
+func (n *Natty) PurgeStreamBefore(ctx context.Context, stream string, seq uint64) error {
+	span, ctx := tracer.StartSpanFromContext(ctx, "natty.PurgeStreamBefore")
+	defer span.Finish()
+
+	if seq == 0 {
+		return errors.New("seq must be greater than 0")
+	}
+
+	if _, err := n.purgeStream(ctx, stream, &streamPurgeRequest{Sequence: seq}); err != nil {
+		span.SetTag("error", err)
+		return err
+	}
+
+	return nil
+}
+
+// streamPurgeRequest mirrors the (unexported) request of the JetStream API
+type streamPurgeRequest struct {
+	// Purge up to but not including sequence
+	Sequence uint64 `json:"seq,omitempty"`
+
+	// Only purge messages matching subject
+	Subject string `json:"filter,omitempty"`
+}
+
+// jsAPIResponse is embedded in responses of JetStream API requests that are
+// made without the NATS client (see purgeStream())
+type jsAPIResponse struct {
+	Error *struct {
+		Code        int    `json:"code"`
+		Description string `json:"description"`
+	} `json:"error,omitempty"`
+}
+
+// err returns the API error (if any); a 404 is returned as
+// nats.ErrStreamNotFound
+func (r *jsAPIResponse) err() error {
+	if r.Error == nil {
+		return nil
+	}
+
+	if r.Error.Code == 404 {
+		return nats.ErrStreamNotFound
+	}
+
+	return errors.Errorf("%s (code %d)", r.Error.Description, r.Error.Code)
+}
+
+// purgeStream sends a purge request straight to the JetStream API and returns
+// the number of purged messages.
+//
+// NOTE: The vendored nats.go JetStreamContext.PurgeStream() ignores its
+// options, so subject/sequence scoped purges cannot go through it.
+func (n *Natty) purgeStream(ctx context.Context, stream string, req *streamPurgeRequest) (uint64, error) {
+	if n.isClosed() {
+		return 0, ErrConnectionClosed
+	}
+
+	if stream == "" {
+		return 0, ErrEmptyStreamName
+	}
+
+	data, err := json.Marshal(req)
+	if err != nil {
+		return 0, errors.Wrap(err, "unable to marshal purge request")
+	}
+
+	msg, err := n.getConn().RequestWithContext(ctx, n.jsAPISubject("STREAM.PURGE."+stream), data)
+	if err != nil {
+		return 0, errors.Wrap(err, "unable to send purge request")
+	}
+
+	var resp struct {
+		jsAPIResponse
+		Success bool   `json:"success"`
+		Purged  uint64 `json:"purged"`
+	}
+
+	if err := json.Unmarshal(msg.Data, &resp); err != nil {
+		return 0, errors.Wrap(err, "unable to unmarshal purge response")
+	}
+
+	if err := resp.err(); err != nil {
+		if err == nats.ErrStreamNotFound {
+			return 0, err
+		}
+
+		return 0, errors.Wrap(err, "unable to purge stream")
+	}
+
+	if !resp.Success {
+		return 0, errors.New("unable to purge stream: request was not successful")
+	}
+
+	return resp.Purged, nil
+}
+
 func (n *Natty) CreateStream(ctx context.Context, name string, subjects []string) error {
 	span, _ := tracer.StartSpanFromContext(ctx, "natty.CreateStream")
 	defer span.Finish()
 
 	// Check if stream exists
-	_, err := n.js.StreamInfo(name)
+	_, err := n.getJS().StreamInfo(name)
 	if err == nil {
 		// We have a stream already, nothing else to do
 		return nil
@@ -326,7 +1425,7 @@ func (n *Natty) CreateStream(ctx context.Context, name string, subjects []string
 		return err
 	}
 
-	_, err = n.js.AddStream(&nats.StreamConfig{
+	_, err = n.getJS().AddStream(&nats.StreamConfig{
 		Name:      name,
 		Subjects:  subjects,
 		Retention: nats.LimitsPolicy,   // Limit to age
@@ -343,6 +1442,55 @@ func (n *Natty) CreateStream(ctx context.Context, name string, subjects []string
 	return nil
 }
 
+// CreateStreamWithConfig creates a stream from cfg; unlike CreateStream(), all
+// settings (ie. Sources, Mirror, Replicas) are up to the caller. Creating a
+// stream that already exists with the same config is not an error; if the
+// config differs, the server rejects the request.
+func (n *Natty) CreateStreamWithConfig(ctx context.Context, cfg *nats.StreamConfig) (*nats.StreamInfo, error) {
+	span, ctx := tracer.StartSpanFromContext(ctx, "natty.CreateStreamWithConfig")
+	defer span.Finish()
+
+	if n.isClosed() {
+		return nil, ErrConnectionClosed
+	}
+
+	if cfg == nil {
+		return nil, errors.New("stream config cannot be nil")
+	}
+
+	if cfg.Name == "" {
+		return nil, ErrEmptyStreamName
+	}
+
+	info, err := n.getJS().AddStream(cfg, nats.Context(ctx))
+	if err != nil {
+		err = errors.Wrap(err, "unable to create stream")
+		span.SetTag("error", err)
+		return nil, err
+	}
+
+	return info, nil
+}
+
+// CreateMirrorStream creates a stream named cfg.Name that mirrors all messages
+// of the sourceName stream. cfg is copied and its Mirror field set; a mirror
+// cannot have Subjects or Sources of its own. To mirror a stream in another
+// domain or account, use CreateStreamWithConfig() and set Mirror.External.
+func (n *Natty) CreateMirrorStream(ctx context.Context, sourceName string, cfg *nats.StreamConfig) (*nats.StreamInfo, error) {
+	if sourceName == "" {
+		return nil, ErrEmptyStreamName
+	}
+
+	if cfg == nil {
+		return nil, errors.New("stream config cannot be nil")
+	}
+
+	mirrorCfg := *cfg
+	mirrorCfg.Mirror = &nats.StreamSource{Name: sourceName}
+
+	return n.CreateStreamWithConfig(ctx, &mirrorCfg)
+}
+
 func GenerateTLSConfig(caCertFile, clientKeyFile, clientCertFile string, tlsSkipVerify bool) (*tls.Config, error) {
 	if caCertFile == "" && clientKeyFile == "" && clientCertFile == "" {
 		return &tls.Config{
@@ -400,7 +1548,7 @@ func (n *Natty) CreateConsumer(ctx context.Context, streamName, consumerName str
 		filter = filterSubject[0]
 	}
 
-	if _, err := n.js.AddConsumer(streamName, &nats.ConsumerConfig{
+	if _, err := n.getJS().AddConsumer(streamName, &nats.ConsumerConfig{
 		Durable:       consumerName,
 		AckPolicy:     nats.AckExplicitPolicy,
 		FilterSubject: filter,
@@ -413,11 +1561,46 @@ func (n *Natty) CreateConsumer(ctx context.Context, streamName, consumerName str
 	return nil
 }
 
+// UpdateConsumer updates an existing durable consumer in place; unlike
+// deleting and re-creating it, the consumer keeps its delivered and ack floor
+// sequences. Only some fields can be changed (ie. FilterSubject, AckWait,
+// MaxDeliver, MaxAckPending) and changing the filter subject requires NATS
+// server 2.9+; the server rejects other changes.
+func (n *Natty) UpdateConsumer(ctx context.Context, stream string, cfg *nats.ConsumerConfig) (*nats.ConsumerInfo, error) {
+	span, ctx := tracer.StartSpanFromContext(ctx, "natty.UpdateConsumer")
+	defer span.Finish()
+
+	if n.isClosed() {
+		return nil, ErrConnectionClosed
+	}
+
+	if stream == "" {
+		return nil, ErrEmptyStreamName
+	}
+
+	if cfg == nil {
+		return nil, errors.New("consumer config cannot be nil")
+	}
+
+	if cfg.Durable == "" {
+		return nil, ErrEmptyConsumerName
+	}
+
+	info, err := n.getJS().UpdateConsumer(stream, cfg, nats.Context(ctx))
+	if err != nil {
+		err = errors.Wrap(err, "unable to update consumer")
+		span.SetTag("error", err)
+		return nil, err
+	}
+
+	return info, nil
+}
+
 func (n *Natty) DeleteConsumer(ctx context.Context, consumerName, streamName string) error {
 	span, _ := tracer.StartSpanFromContext(ctx, "natty.CreateConsumer")
 	defer span.Finish()
 
-	if err := n.js.DeleteConsumer(streamName, consumerName); err != nil {
+	if err := n.getJS().DeleteConsumer(streamName, consumerName); err != nil {
 		err = errors.Wrap(err, "unable to delete consumer")
 		span.SetTag("error", err)
 		return err
@@ -426,21 +1609,216 @@ func (n *Natty) DeleteConsumer(ctx context.Context, consumerName, streamName str
 	return nil
 }
 
+// ListStreams returns info for every stream in the account. ctx bounds the
+// entire listing (all pages); if any page cannot be fetched, an error is
+// returned instead of a partial list.
+func (n *Natty) ListStreams(ctx context.Context) ([]*nats.StreamInfo, error) {
+	span, ctx := tracer.StartSpanFromContext(ctx, "natty.ListStreams")
+	defer span.Finish()
+
+	if n.isClosed() {
+		return nil, ErrConnectionClosed
+	}
+
+	streams := make([]*nats.StreamInfo, 0)
+
+	err := n.listJSAPI(ctx, n.jsAPISubject("STREAM.LIST"), func(data []byte) (int, int, error) {
+		var resp struct {
+			jsAPIResponse
+			jsAPIPaged
+			Streams []*nats.StreamInfo `json:"streams"`
+		}
+
+		if err := json.Unmarshal(data, &resp); err != nil {
+			return 0, 0, errors.Wrap(err, "unable to unmarshal stream list response")
+		}
+
+		if err := resp.err(); err != nil {
+			return 0, 0, err
+		}
+
+		streams = append(streams, resp.Streams...)
+
+		return len(resp.Streams), resp.Total, nil
+	})
+	if err != nil {
+		err = errors.Wrap(err, "unable to list streams")
+		span.SetTag("error", err)
+		return nil, err
+	}
+
+	return streams, nil
+}
+
+// ListConsumers returns info for every consumer on the given stream. ctx
+// bounds the entire listing (all pages); if any page cannot be fetched, an
+// error is returned instead of a partial list. Returns nats.ErrStreamNotFound
+// (wrapped) if the stream does not exist.
+func (n *Natty) ListConsumers(ctx context.Context, stream string) ([]*nats.ConsumerInfo, error) {
+	span, ctx := tracer.StartSpanFromContext(ctx, "natty.ListConsumers")
+	defer span.Finish()
+
+	if n.isClosed() {
+		return nil, ErrConnectionClosed
+	}
+
+	if stream == "" {
+		return nil, ErrEmptyStreamName
+	}
+
+	consumers := make([]*nats.ConsumerInfo, 0)
+
+	err := n.listJSAPI(ctx, n.jsAPISubject("CONSUMER.LIST."+stream), func(data []byte) (int, int, error) {
+		var resp struct {
+			jsAPIResponse
+			jsAPIPaged
+			Consumers []*nats.ConsumerInfo `json:"consumers"`
+		}
+
+		if err := json.Unmarshal(data, &resp); err != nil {
+			return 0, 0, errors.Wrap(err, "unable to unmarshal consumer list response")
+		}
+
+		if err := resp.err(); err != nil {
+			return 0, 0, err
+		}
+
+		consumers = append(consumers, resp.Consumers...)
+
+		return len(resp.Consumers), resp.Total, nil
+	})
+	if err != nil {
+		err = errors.Wrapf(err, "unable to list consumers for stream '%s'", stream)
+		span.SetTag("error", err)
+		return nil, err
+	}
+
+	return consumers, nil
+}
+
+// jsAPIPaged is embedded in responses of paged JetStream API list requests
+type jsAPIPaged struct {
+	Total  int `json:"total"`
+	Offset int `json:"offset"`
+	Limit  int `json:"limit"`
+}
+
+// jsAPIPagedRequest requests the page starting at Offset
+type jsAPIPagedRequest struct {
+	Offset int `json:"offset"`
+}
+
+// listJSAPI requests every page of a JetStream API list endpoint. decode is
+// called with each response; it must keep the page's items and return their
+// count along with the total number of items.
+//
+// NOTE: The nats.go listers (ie. StreamsInfo()) close their channel on errors
+// without reporting them, hence the raw requests.
+func (n *Natty) listJSAPI(ctx context.Context, subject string, decode func(data []byte) (int, int, error)) error {
+	offset := 0
+
+	for {
+		req, err := json.Marshal(&jsAPIPagedRequest{Offset: offset})
+		if err != nil {
+			return errors.Wrap(err, "unable to marshal list request")
+		}
+
+		msg, err := n.getConn().RequestWithContext(ctx, subject, req)
+		if err != nil {
+			return errors.Wrap(err, "unable to send list request")
+		}
+
+		count, total, err := decode(msg.Data)
+		if err != nil {
+			return err
+		}
+
+		offset += count
+
+		if count == 0 || offset >= total {
+			return nil
+		}
+	}
+}
+
+// AccountInfo returns the JetStream usage (memory, storage, stream and consumer
+// counts, API stats) and limits of the connected account.
+func (n *Natty) AccountInfo(ctx context.Context) (*nats.AccountInfo, error) {
+	span, ctx := tracer.StartSpanFromContext(ctx, "natty.AccountInfo")
+	defer span.Finish()
+
+	if n.isClosed() {
+		return nil, ErrConnectionClosed
+	}
+
+	info, err := n.getJS().AccountInfo(nats.Context(ctx))
+	if err != nil {
+		err = errors.Wrap(err, "unable to fetch account info")
+		span.SetTag("error", err)
+		return nil, err
+	}
+
+	return info, nil
+}
+
+// WaitForStream polls StreamInfo every pollInterval until the stream exists;
+// returns ctx.Err() (wrapped) if ctx is done first.
+func (n *Natty) WaitForStream(ctx context.Context, name string, pollInterval time.Duration) error {
+	span, ctx := tracer.StartSpanFromContext(ctx, "natty.WaitForStream")
+	defer span.Finish()
+
+	if n.isClosed() {
+		return ErrConnectionClosed
+	}
+
+	if name == "" {
+		return ErrEmptyStreamName
+	}
+
+	if pollInterval <= 0 {
+		return errors.New("poll interval must be greater than 0")
+	}
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		_, err := n.getJS().StreamInfo(name, nats.Context(ctx))
+		if err == nil {
+			return nil
+		}
+
+		if err != nats.ErrStreamNotFound && ctx.Err() == nil {
+			err = errors.Wrap(err, "unable to fetch stream info")
+			span.SetTag("error", err)
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			err := errors.Wrapf(ctx.Err(), "stream '%s' did not appear", name)
+			span.SetTag("error", err)
+			return err
+		case <-ticker.C:
+		}
+	}
+}
+
 // Consume will create a durable consumer and consume messages from the configured stream
 func (n *Natty) Consume(ctx context.Context, cfg *ConsumerConfig, f func(ctx context.Context, msg *nats.Msg) error) error {
 	if err := validateConsumerConfig(cfg); err != nil {
 		return errors.Wrap(err, "invalid consumer config")
 	}
 
-	sub, err := n.js.PullSubscribe(cfg.Subject, cfg.ConsumerName)
+	sub, err := n.getJS().PullSubscribe(cfg.Subject, cfg.ConsumerName)
 	if err != nil {
 		return errors.Wrap(err, "unable to create subscription")
 	}
 
 	defer func() {
 		if err := sub.Unsubscribe(); err != nil {
-			n.log.Errorf("unable to unsubscribe from (stream: '%s', subj: '%s'): %s",
-				cfg.StreamName, cfg.Subject, err)
+			errorw(n.log, "unable to unsubscribe",
+				"stream", cfg.StreamName, "subject", cfg.Subject, "error", err)
 		}
 	}()
 
@@ -457,8 +1835,7 @@ func (n *Natty) Consume(ctx context.Context, cfg *ConsumerConfig, f func(ctx con
 		msgs, err := sub.Fetch(n.FetchSize, nats.Context(ctx))
 		if err != nil {
 			if err == context.Canceled {
-				n.log.Debugf("context canceled (stream: %s, subj: %s)",
-					cfg.StreamName, cfg.Subject)
+				debugw(n.log, "context canceled", "stream", cfg.StreamName, "subject", cfg.Subject)
 
 				cfg.Looper.Quit()
 				quit = true
@@ -478,7 +1855,7 @@ func (n *Natty) Consume(ctx context.Context, cfg *ConsumerConfig, f func(ctx con
 		}
 
 		for _, m := range msgs {
-			if err := f(ctx, m); err != nil {
+			if err := f(n.tracer.Extract(ctx, m), m); err != nil {
 				n.report(cfg.ErrorCh, fmt.Errorf("callback func failed during message processing (stream: '%s', subj: '%s', msg: '%s'): %s",
 					cfg.StreamName, cfg.Subject, m.Data, err))
 			}
@@ -487,13 +1864,13 @@ func (n *Natty) Consume(ctx context.Context, cfg *ConsumerConfig, f func(ctx con
 		return nil
 	})
 
-	n.log.Debugf("consumer exiting (stream: %s, subj: %s)", cfg.StreamName, cfg.Subject)
+	debugw(n.log, "consumer exiting", "stream", cfg.StreamName, "subject", cfg.Subject)
 
 	return nil
 }
 
 func (n *Natty) report(errorCh chan error, err error) {
-	n.log.Error(err)
+	errorw(n.log, "consumer error", "error", err)
 
 	if errorCh != nil {
 		// Write the err in a goroutine to avoid block in case chan is full
@@ -501,12 +1878,12 @@ func (n *Natty) report(errorCh chan error, err error) {
 			select {
 			case errorCh <- err:
 			default:
-				n.log.Warnf("consumer error channel is full; discarding error")
+				warnw(n.log, "consumer error channel is full; discarding error", "error", err)
 			}
 		}()
 	}
 
-	n.log.Error(err)
+	errorw(n.log, "consumer error", "error", err)
 }
 
 func validateConfig(cfg *Config) error {
@@ -518,6 +1895,20 @@ func validateConfig(cfg *Config) error {
 		return errors.New("NatsURL cannot be empty")
 	}
 
+	for _, url := range cfg.NatsURL {
+		if strings.TrimSpace(url) == "" {
+			return errors.New("NatsURL cannot contain empty URLs")
+		}
+	}
+
+	if err := validateAuthConfig(cfg); err != nil {
+		return err
+	}
+
+	if cfg.JetStreamDomain != "" && cfg.JetStreamAPIPrefix != "" {
+		return errors.New("JetStreamDomain cannot be used together with JetStreamAPIPrefix")
+	}
+
 	if cfg.MaxMsgs == 0 {
 		cfg.MaxMsgs = DefaultMaxMsgs
 	}
@@ -545,9 +1936,42 @@ func validateConfig(cfg *Config) error {
 		cfg.PublishTimeout = DefaultPublishTimeout
 	}
 
+	if cfg.MaxReconnects == 0 {
+		cfg.MaxReconnects = DefaultMaxReconnects
+	}
+
+	if cfg.ReconnectWait == 0 {
+		cfg.ReconnectWait = DefaultReconnectWait
+	}
+
 	if cfg.ServiceShutdownContext == nil {
-		ctx, _ := context.WithCancel(context.Background())
-		cfg.ServiceShutdownContext = ctx
+		cfg.ServiceShutdownContext = context.Background()
+	}
+
+	return nil
+}
+
+func validateAuthConfig(cfg *Config) error {
+	var methods int
+
+	for _, set := range []bool{
+		cfg.CredsFile != "",
+		cfg.NKeyFile != "",
+		cfg.NKeySeed != "",
+		cfg.Username != "" || cfg.Password != "",
+		cfg.Token != "",
+	} {
+		if set {
+			methods++
+		}
+	}
+
+	if methods > 1 {
+		return errors.New("only one of CredsFile, NKeyFile, NKeySeed, Username/Password or Token can be specified")
+	}
+
+	if cfg.UserJWT != "" && cfg.NKeySeed == "" {
+		return errors.New("UserJWT requires NKeySeed to be specified")
 	}
 
 	return nil
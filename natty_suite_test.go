@@ -3,11 +3,47 @@ package natty
 import (
 	"testing"
 
+	"github.com/nats-io/nats-server/v2/server"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+
+	"github.com/batchcorp/natty/internal/natsserver"
 )
 
 func TestNattySuite(t *testing.T) {
+	NatsURL = startServer(t, func(opts *server.Options) {
+		tlsConfig, err := server.GenTLSConfig(&server.TLSConfigOpts{
+			CertFile: "assets/server.pem",
+			KeyFile:  "assets/server-key.pem",
+		})
+		if err != nil {
+			t.Fatalf("unable to load TLS config: %s", err)
+		}
+
+		opts.TLSConfig = tlsConfig
+		opts.JetStreamMaxMemory = 1 << 30
+		opts.JetStreamMaxStore = 5 << 30
+	})
+
+	NatsAuthURL = startServer(t, func(opts *server.Options) {
+		opts.JetStream = false
+		opts.Username = "natty"
+		opts.Password = "natty"
+	})
+
 	RegisterFailHandler(Fail)
 	RunSpecs(t, "Natty Suite")
 }
+
+// startServer starts an embedded NATS server that is stopped once the suite
+// completes and returns its URL
+func startServer(t *testing.T, opt natsserver.Option) string {
+	s, stop, err := natsserver.Start(opt)
+	if err != nil {
+		t.Fatalf("unable to start NATS server: %s", err)
+	}
+
+	t.Cleanup(stop)
+
+	return s.ClientURL()
+}
@@ -1,12 +1,13 @@
-// NOTE: These tests require NATS to be available on "localhost"
 package natty
 
 import (
 	"context"
 	"crypto/tls"
 	"math/rand"
+	"net"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/nats-io/nats.go"
@@ -16,11 +17,14 @@ import (
 	uuid "github.com/satori/go.uuid"
 )
 
-const (
-	NatsURL = "tls://localhost:4222"
-)
-
 var (
+	// NatsURL points to a TLS NATS server with JetStream enabled; both servers
+	// are started (in-process) by TestNattySuite
+	NatsURL string
+
+	// NatsAuthURL points to a non-TLS NATS server that requires user/pass auth
+	NatsAuthURL string
+
 	testStreams []string
 	testBuckets []string
 
@@ -57,6 +61,168 @@ var _ = Describe("Natty", func() {
 			n, err := New(cfg)
 			Expect(err).ToNot(HaveOccurred())
 			Expect(n).ToNot(BeNil())
+			Expect(n.nc.IsConnected()).To(BeTrue())
+			Expect(n.nc.Servers()).To(HaveLen(2))
+		})
+
+		It("should default to reconnecting forever", func() {
+			n, err := New(NewConfig())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(n).ToNot(BeNil())
+
+			Expect(n.nc.Opts.MaxReconnect).To(Equal(DefaultMaxReconnects))
+			Expect(n.nc.Opts.ReconnectWait).To(Equal(DefaultReconnectWait))
+			Expect(n.nc.Opts.CustomReconnectDelayCB).ToNot(BeNil())
+		})
+
+		It("should use reconnect settings", func() {
+			cfg := NewConfig()
+			cfg.MaxReconnects = 5
+			cfg.ReconnectWait = time.Second
+			cfg.ReconnectHandler = func(_ *nats.Conn) {}
+
+			n, err := New(cfg)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(n).ToNot(BeNil())
+
+			Expect(n.nc.Opts.MaxReconnect).To(Equal(5))
+			Expect(n.nc.Opts.ReconnectWait).To(Equal(time.Second))
+			Expect(n.nc.Opts.ReconnectedCB).ToNot(BeNil())
+		})
+
+		It("should call disconnect and closed handlers", func() {
+			disconnectCh := make(chan struct{}, 1)
+			closedCh := make(chan struct{}, 1)
+
+			cfg := NewConfig()
+			cfg.DisconnectErrHandler = func(_ *nats.Conn, _ error) {
+				disconnectCh <- struct{}{}
+			}
+			cfg.ClosedHandler = func(_ *nats.Conn) {
+				closedCh <- struct{}{}
+			}
+
+			n, err := New(cfg)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(n).ToNot(BeNil())
+
+			Expect(n.Close()).To(Succeed())
+
+			Eventually(disconnectCh).Should(Receive())
+			Eventually(closedCh).Should(Receive())
+		})
+
+		It("should call disconnect and reconnect handlers when the connection drops", func() {
+			disconnectCh := make(chan struct{}, 1)
+			reconnectCh := make(chan struct{}, 1)
+
+			dialer := &closingDialer{}
+
+			cfg := NewConfig().WithNatsOptions(nats.SetCustomDialer(dialer))
+			cfg.ReconnectWait = 10 * time.Millisecond
+			cfg.DisconnectErrHandler = func(_ *nats.Conn, _ error) {
+				select {
+				case disconnectCh <- struct{}{}:
+				default:
+				}
+			}
+			cfg.ReconnectHandler = func(_ *nats.Conn) {
+				select {
+				case reconnectCh <- struct{}{}:
+				default:
+				}
+			}
+
+			n, err := New(cfg)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(n).ToNot(BeNil())
+
+			defer n.Close()
+
+			// Drop the connection underneath the NATS client
+			dialer.closeAll()
+
+			Eventually(disconnectCh).Should(Receive())
+			Eventually(reconnectCh, 5*time.Second).Should(Receive())
+			Expect(n.nc.IsConnected()).To(BeTrue())
+		})
+
+		It("should call error handler on async errors", func() {
+			errCh := make(chan error, 1)
+
+			cfg := NewConfig()
+			cfg.ErrorHandler = func(_ *nats.Conn, _ *nats.Subscription, err error) {
+				select {
+				case errCh <- err:
+				default:
+				}
+			}
+
+			n, err := New(cfg)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(n).ToNot(BeNil())
+
+			// Trigger a slow consumer error
+			subject := uuid.NewV4().String()
+
+			sub, err := n.nc.SubscribeSync(subject)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sub.SetPendingLimits(1, 1024)).To(Succeed())
+
+			for i := 0; i < 10; i++ {
+				Expect(n.nc.Publish(subject, []byte("foo"))).To(Succeed())
+			}
+
+			Expect(n.nc.Flush()).To(Succeed())
+
+			Eventually(errCh).Should(Receive(Equal(nats.ErrSlowConsumer)))
+		})
+
+		It("should pass through custom nats options", func() {
+			cfg := NewConfig().WithNatsOptions(nats.Name("custom-name"))
+
+			n, err := New(cfg)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(n).ToNot(BeNil())
+			Expect(n.nc.Opts.Name).To(Equal("custom-name"))
+		})
+
+		It("should pass through custom jetstream options", func() {
+			cfg := NewConfig().WithJetStreamOpts(nats.PublishAsyncMaxPending(5))
+
+			n, err := New(cfg)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(n).ToNot(BeNil())
+
+			// Subscriber that never responds ensures acks never arrive
+			subject := uuid.NewV4().String()
+
+			_, err = n.nc.Subscribe(subject, func(_ *nats.Msg) {})
+			Expect(err).ToNot(HaveOccurred())
+
+			// The message being published counts towards the limit, so the 5th
+			// publish already stalls
+			for i := 0; i < 4; i++ {
+				_, err := n.js.PublishAsync(subject, []byte("foo"))
+				Expect(err).ToNot(HaveOccurred())
+			}
+
+			_, err = n.js.PublishAsync(subject, []byte("foo"))
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("too many outstanding async published messages"))
+		})
+
+		It("should fail when all NatsURLs are bad", func() {
+			cfg := NewConfig()
+			cfg.NatsURL = []string{
+				"nats://localhost:22",
+				"nats://localhost:23",
+			}
+
+			n, err := New(cfg)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("failed to connect to NATS"))
+			Expect(n).To(BeNil())
 		})
 
 		It("should fail with bad config options", func() {
@@ -88,6 +254,58 @@ var _ = Describe("Natty", func() {
 					shouldError:   true,
 					errorContains: "NatsURL cannot be empty",
 				},
+
+				{
+					cfg: &Config{
+						NatsURL: []string{NatsURL, ""},
+					},
+					description:   "should fail with an empty nats url",
+					shouldError:   true,
+					errorContains: "NatsURL cannot contain empty URLs",
+				},
+
+				{
+					cfg: &Config{
+						NatsURL:   []string{NatsURL},
+						CredsFile: "/tmp/user.creds",
+						NKeySeed:  "SUAMK2FG4MI6UE3ACF3FK3OIQBCEIEZV7NSWFFEW63UXMRLFM2XLAXK4GY",
+					},
+					description:   "should fail with multiple auth methods",
+					shouldError:   true,
+					errorContains: "only one of CredsFile, NKeyFile, NKeySeed, Username/Password or Token",
+				},
+
+				{
+					cfg: &Config{
+						NatsURL: []string{NatsURL},
+						UserJWT: "eyJ0eXAiOiJKV1QiLCJhbGciOiJlZDI1NTE5In0",
+					},
+					description:   "should fail with UserJWT but no NKeySeed",
+					shouldError:   true,
+					errorContains: "UserJWT requires NKeySeed",
+				},
+
+				{
+					cfg: &Config{
+						NatsURL:  []string{NatsURL},
+						NKeySeed: "not-a-seed",
+					},
+					description:   "should fail with invalid NKeySeed",
+					shouldError:   true,
+					errorContains: "unable to parse nkey seed",
+				},
+
+				{
+					cfg: &Config{
+						NatsURL:  []string{NatsURL},
+						Username: "natty",
+						Password: "natty",
+						Token:    "natty",
+					},
+					description:   "should fail with both token and username/password",
+					shouldError:   true,
+					errorContains: "only one of CredsFile, NKeyFile, NKeySeed, Username/Password or Token",
+				},
 			}
 
 			for _, v := range configs {
@@ -104,6 +322,31 @@ var _ = Describe("Natty", func() {
 			}
 		})
 
+		It("should connect with username and password", func() {
+			cfg := NewConfig()
+			cfg.NatsURL = []string{NatsAuthURL}
+			cfg.UseTLS = false
+			cfg.Username = "natty"
+			cfg.Password = "natty"
+
+			n, err := New(cfg)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(n).ToNot(BeNil())
+		})
+
+		It("should fail to connect with bad username and password", func() {
+			cfg := NewConfig()
+			cfg.NatsURL = []string{NatsAuthURL}
+			cfg.UseTLS = false
+			cfg.Username = "natty"
+			cfg.Password = "wrong"
+
+			n, err := New(cfg)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Authorization Violation"))
+			Expect(n).To(BeNil())
+		})
+
 		It("adding an existing stream and consumer should not error", func() {
 			cfg := NewConfig()
 
@@ -118,6 +361,119 @@ var _ = Describe("Natty", func() {
 		})
 	})
 
+	Describe("validateAuthConfig", func() {
+		methods := map[string]func(cfg *Config){
+			"CredsFile": func(cfg *Config) { cfg.CredsFile = "/tmp/user.creds" },
+			"NKeyFile":  func(cfg *Config) { cfg.NKeyFile = "/tmp/user.nk" },
+			"NKeySeed":  func(cfg *Config) { cfg.NKeySeed = "SUAMK2FG4MI6UE3ACF3FK3OIQBCEIEZV7NSWFFEW63UXMRLFM2XLAXK4GY" },
+			"Username":  func(cfg *Config) { cfg.Username = "natty" },
+			"Password":  func(cfg *Config) { cfg.Password = "natty" },
+			"Token":     func(cfg *Config) { cfg.Token = "natty" },
+		}
+
+		It("should allow a single auth method", func() {
+			scenarios := [][]string{
+				{},
+				{"CredsFile"},
+				{"NKeyFile"},
+				{"NKeySeed"},
+				{"Username", "Password"},
+				{"Username"},
+				{"Password"},
+				{"Token"},
+			}
+
+			for _, fields := range scenarios {
+				cfg := &Config{}
+
+				for _, field := range fields {
+					methods[field](cfg)
+				}
+
+				Expect(validateAuthConfig(cfg)).To(Succeed(), "%v", fields)
+			}
+		})
+
+		It("should reject every combination of auth methods", func() {
+			scenarios := [][]string{
+				{"CredsFile", "NKeyFile"},
+				{"CredsFile", "NKeySeed"},
+				{"CredsFile", "Username", "Password"},
+				{"CredsFile", "Token"},
+				{"NKeyFile", "NKeySeed"},
+				{"NKeyFile", "Username", "Password"},
+				{"NKeyFile", "Token"},
+				{"NKeySeed", "Username", "Password"},
+				{"NKeySeed", "Token"},
+				{"Username", "Password", "Token"},
+				{"Username", "Token"},
+				{"Password", "Token"},
+				{"CredsFile", "NKeyFile", "NKeySeed", "Username", "Password", "Token"},
+			}
+
+			for _, fields := range scenarios {
+				cfg := &Config{}
+
+				for _, field := range fields {
+					methods[field](cfg)
+				}
+
+				err := validateAuthConfig(cfg)
+				Expect(err).To(HaveOccurred(), "%v", fields)
+				Expect(err.Error()).To(ContainSubstring("only one of CredsFile, NKeyFile, NKeySeed, Username/Password or Token"))
+			}
+		})
+	})
+
+	Describe("jsAPIPrefix", func() {
+		It("should return the prefix for the configured domain or API prefix", func() {
+			Expect(jsAPIPrefix(&Config{})).To(Equal("$JS.API."))
+			Expect(jsAPIPrefix(&Config{JetStreamAPIPrefix: "$JS.hub.API"})).To(Equal("$JS.hub.API."))
+			Expect(jsAPIPrefix(&Config{JetStreamAPIPrefix: "$JS.hub.API."})).To(Equal("$JS.hub.API."))
+			Expect(jsAPIPrefix(&Config{JetStreamDomain: "hub"})).To(Equal("$JS.hub.API."))
+		})
+
+		It("should prefix KV writes only for non-default prefixes", func() {
+			n := &Natty{Config: NewConfig(), jsPrefix: jsAPIPrefix(NewConfig())}
+			Expect(n.kvPublishSubject("bucket", "key")).To(Equal("$KV.bucket.key"))
+
+			n = &Natty{Config: NewConfig(), jsPrefix: jsAPIPrefix(&Config{JetStreamDomain: "hub"})}
+			Expect(n.kvPublishSubject("bucket", "key")).To(Equal("$JS.hub.API.$KV.bucket.key"))
+			Expect(n.jsAPISubject("STREAM.LIST")).To(Equal("$JS.hub.API.STREAM.LIST"))
+		})
+
+		It("should not allow both a domain and an API prefix", func() {
+			cfg := NewConfig()
+			cfg.JetStreamDomain = "hub"
+			cfg.JetStreamAPIPrefix = "$JS.hub.API"
+
+			_, err := New(cfg)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("JetStreamDomain cannot be used together with JetStreamAPIPrefix"))
+		})
+	})
+
+	Describe("newReconnectDelayHandler", func() {
+		It("should back off exponentially", func() {
+			delay := newReconnectDelayHandler(time.Second, 0)
+
+			Expect(delay(1)).To(Equal(time.Second))
+			Expect(delay(2)).To(Equal(2 * time.Second))
+			Expect(delay(3)).To(Equal(4 * time.Second))
+			Expect(delay(100)).To(Equal(MaxReconnectBackoff))
+		})
+
+		It("should add jitter", func() {
+			delay := newReconnectDelayHandler(time.Second, time.Second)
+
+			for i := 0; i < 10; i++ {
+				d := delay(1)
+				Expect(d).To(BeNumerically(">=", time.Second))
+				Expect(d).To(BeNumerically("<", 2*time.Second))
+			}
+		})
+	})
+
 	Describe("Consume", func() {
 		var (
 			cfg *Config
@@ -230,268 +586,1138 @@ var _ = Describe("Natty", func() {
 				n.Publish(ctx, badFilter, []byte("bad"))
 			}
 
-			// Publish is async - give it a second to actually get written
-			time.Sleep(time.Second)
+			// Publish is async - give it a second to actually get written
+			time.Sleep(time.Second)
+
+			doneCh := make(chan struct{}, 0)
+			consumed := make([]*nats.Msg, 0)
+
+			handler := func(ctx context.Context, msg *nats.Msg) error {
+				consumed = append(consumed, msg)
+				Expect(msg.Ack()).ToNot(HaveOccurred())
+
+				if len(consumed) == 100 {
+					doneCh <- struct{}{}
+				}
+
+				return nil
+			}
+
+			// Consumer should only receive for streamName.foo
+			go func() {
+				err = n.Consume(ctx, &ConsumerConfig{
+					StreamName:   streamName,
+					Subject:      goodFilter,
+					ConsumerName: consumerName,
+				}, handler)
+
+				Expect(err).ToNot(HaveOccurred())
+			}()
+
+			// Should receive data within 5 seconds
+			select {
+			case <-time.After(5 * time.Second):
+				Fail("consumer timed out waiting for message(s)")
+			case <-doneCh:
+				break
+			}
+
+			Expect(len(consumed)).To(Equal(publishNumMessages))
+
+			for _, m := range consumed {
+				Expect(m.Subject).To(Equal(goodFilter))
+			}
+		})
+
+		It("error watch channel is used", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+
+			consumed := make([]string, 0)
+
+			streamName := strings.ToUpper(GetRandomName("test", 1))
+			consumerName := GetRandomName("test", 1)
+
+			err := n.CreateStream(ctx, streamName, []string{streamName + ".*"})
+			Expect(err).ToNot(HaveOccurred())
+
+			err = n.CreateConsumer(ctx, streamName, consumerName)
+			Expect(err).ToNot(HaveOccurred())
+
+			subj := streamName + ".foo"
+
+			var exit bool
+
+			errChan := make(chan error, 1000)
+
+			// Launch consumer in a goroutine
+			go func() {
+				consumerConfig := &ConsumerConfig{
+					Subject:      subj,
+					StreamName:   streamName,
+					ConsumerName: consumerName,
+					ErrorCh:      errChan,
+				}
+
+				err := n.Consume(ctx, consumerConfig, func(ctx context.Context, msg *nats.Msg) error {
+					consumed = append(consumed, string(msg.Data))
+					return errors.New("stuff broke")
+				})
+
+				Expect(err).ToNot(HaveOccurred())
+				exit = true
+			}()
+
+			chanErrors := make([]error, 0)
+
+			// Expect watcher to get 5 errors
+			go func() {
+				for {
+					select {
+					case <-ctx.Done():
+						return
+					case err := <-errChan:
+						chanErrors = append(chanErrors, err)
+					}
+				}
+			}()
+
+			// Produce 5 events
+			err = Publish(cfg, 5, subj, uuid.NewV4().String())
+			Expect(err).ToNot(HaveOccurred())
+
+			// Give Consume() enough time to consume
+			time.Sleep(1 * time.Second)
+
+			cancel()
+
+			// Give Consume() enough time to exit
+			time.Sleep(1 * time.Second)
+
+			Expect(exit).To(BeTrue())
+			Expect(len(consumed)).To(Equal(5))
+			Expect(len(chanErrors)).To(Equal(5))
+		})
+	})
+
+	Describe("Publish", func() {
+		var (
+			cfg *Config
+			n   *Natty
+		)
+
+		BeforeEach(func() {
+			var err error
+
+			cfg = NewConfig()
+
+			n, err = New(cfg)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(n).ToNot(BeNil())
+		})
+
+		It("should publish", func() {
+			const MessagesToPublish = 50
+			defer GinkgoRecover()
+			// Publish stuff
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			payload := uuid.NewV4().String()
+
+			cfg := NewConfig()
+
+			streamName := strings.ToUpper(GetRandomName("test", 1))
+
+			err := n.CreateStream(context.Background(), streamName, []string{streamName + ".*"})
+			Expect(err).ToNot(HaveOccurred())
+
+			var hit int
+
+			// Create a consumer - expect to see a msg
+			go func() {
+				testStreams = append(testStreams, streamName)
+
+				natsClient, err := nats.Connect(cfg.NatsURL[0], nats.Secure(tlsConfig))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(natsClient).ToNot(BeNil())
+
+				js, err := natsClient.JetStream()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(js).ToNot(BeNil())
+
+				_, err = js.Subscribe(streamName+".foo", func(msg *nats.Msg) {
+					hit += 1
+					Expect(string(msg.Data)).To(Equal(payload))
+					msg.Ack()
+				})
+				Expect(err).ToNot(HaveOccurred())
+			}()
+
+			for i := 0; i < MessagesToPublish; i++ {
+				n.Publish(ctx, streamName+".foo", []byte(payload))
+			}
+
+			time.Sleep(5 * time.Second)
+
+			Expect(hit).To(Equal(MessagesToPublish))
+		})
+
+		It("should reject messages larger than MaxMsgSize", func() {
+			n, err := New(NewConfig(), WithMaxMsgSize(1024))
+			Expect(err).ToNot(HaveOccurred())
+
+			streamName := strings.ToUpper(GetRandomName("test", 1))
+			testStreams = append(testStreams, streamName)
+
+			err = n.CreateStream(context.Background(), streamName, []string{streamName + ".*"})
+			Expect(err).ToNot(HaveOccurred())
+
+			large := make([]byte, 2048)
+
+			err = n.TryPublish(context.Background(), streamName+".foo", large)
+			Expect(err).To(HaveOccurred())
+
+			var tooLarge *ErrMessageTooLarge
+
+			Expect(errors.As(err, &tooLarge)).To(BeTrue())
+			Expect(tooLarge.Limit).To(Equal(1024))
+			Expect(tooLarge.Actual).To(Equal(2048))
+
+			_, err = n.PublishWithRetry(context.Background(), streamName+".foo", large, RetryPolicy{})
+			Expect(errors.As(err, &tooLarge)).To(BeTrue())
+
+			results, err := n.PublishAsyncBatch(context.Background(), []*nats.Msg{
+				{Subject: streamName + ".foo", Data: large},
+				{Subject: streamName + ".foo", Data: make([]byte, 1024)},
+			})
+			Expect(err).To(HaveOccurred())
+			Expect(errors.As(results[0].Err, &tooLarge)).To(BeTrue())
+			Expect(results[1].Err).ToNot(HaveOccurred())
+		})
+
+		It("should publish a batch asynchronously and return results", func() {
+			streamName := strings.ToUpper(GetRandomName("test", 1))
+			testStreams = append(testStreams, streamName)
+
+			err := n.CreateStream(context.Background(), streamName, []string{streamName + ".*"})
+			Expect(err).ToNot(HaveOccurred())
+
+			messages := make([]*nats.Msg, 0)
+
+			for i := 0; i < 1000; i++ {
+				msg := nats.NewMsg(streamName + ".foo")
+				msg.Data = []byte(strconv.Itoa(i))
+
+				messages = append(messages, msg)
+			}
+
+			results, err := n.PublishAsyncBatch(context.Background(), messages)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(results).To(HaveLen(1000))
+
+			for i, r := range results {
+				Expect(r.Err).ToNot(HaveOccurred())
+				Expect(r.Subject).To(Equal(streamName + ".foo"))
+				Expect(r.Sequence).To(Equal(uint64(i + 1)))
+			}
+		})
+
+		It("should return the results of concurrent batches", func() {
+			streamName := strings.ToUpper(GetRandomName("test", 1))
+			testStreams = append(testStreams, streamName)
+
+			err := n.CreateStream(context.Background(), streamName, []string{streamName + ".*"})
+			Expect(err).ToNot(HaveOccurred())
+
+			wg := &sync.WaitGroup{}
+
+			for b := 0; b < 5; b++ {
+				wg.Add(1)
+
+				go func(b int) {
+					defer GinkgoRecover()
+					defer wg.Done()
+
+					subject := streamName + "." + strconv.Itoa(b)
+					messages := make([]*nats.Msg, 0)
+
+					for i := 0; i < 200; i++ {
+						messages = append(messages, &nats.Msg{Subject: subject, Data: []byte(strconv.Itoa(i))})
+					}
+
+					results, err := n.PublishAsyncBatch(context.Background(), messages)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(results).To(HaveLen(200))
+
+					for _, r := range results {
+						Expect(r.Subject).To(Equal(subject))
+						Expect(r.Sequence).ToNot(BeZero())
+					}
+				}(b)
+			}
+
+			wg.Wait()
+		})
+
+		It("should aggregate per-message publish errors", func() {
+			streamName := strings.ToUpper(GetRandomName("test", 1))
+			testStreams = append(testStreams, streamName)
+
+			err := n.CreateStream(context.Background(), streamName, []string{streamName + ".*"})
+			Expect(err).ToNot(HaveOccurred())
+
+			good := nats.NewMsg(streamName + ".foo")
+			good.Data = []byte("foo")
+
+			// No stream is bound to this subject
+			bad := nats.NewMsg(GetRandomName("test", 1))
+			bad.Data = []byte("bar")
+
+			results, err := n.PublishAsyncBatch(context.Background(), []*nats.Msg{good, bad})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("1 of 2 messages failed"))
+			Expect(results).To(HaveLen(2))
+			Expect(results[0].Err).ToNot(HaveOccurred())
+			Expect(results[0].Sequence).To(Equal(uint64(1)))
+			Expect(results[1].Err).To(HaveOccurred())
+		})
+
+		It("should open the circuit breaker after failed batches", func() {
+			breaker, err := New(NewConfig(), WithCircuitBreaker(CircuitBreakerConfig{Threshold: 2, OpenDuration: time.Minute}))
+			Expect(err).ToNot(HaveOccurred())
+
+			// No stream is bound to this subject
+			bad := nats.NewMsg(GetRandomName("test", 1))
+			bad.Data = []byte("bar")
+
+			for i := 0; i < 2; i++ {
+				_, err := breaker.PublishAsyncBatch(context.Background(), []*nats.Msg{bad})
+				Expect(err).To(HaveOccurred())
+				Expect(err).ToNot(Equal(ErrCircuitOpen))
+			}
+
+			_, err = breaker.PublishAsyncBatch(context.Background(), []*nats.Msg{bad})
+			Expect(err).To(Equal(ErrCircuitOpen))
+		})
+
+		It("should honor the publish rate limit", func() {
+			// 10 msgs/sec with a burst of 1: 11 messages take at least 1s
+			n.SetPublishRateLimit(10, 1)
+
+			subject := GetRandomName("test", 1)
+			start := time.Now()
+
+			wg := &sync.WaitGroup{}
+
+			for i := 0; i < 11; i++ {
+				wg.Add(1)
+
+				go func() {
+					defer GinkgoRecover()
+					defer wg.Done()
+
+					Expect(n.TryPublish(context.Background(), subject, []byte("foo"))).To(Succeed())
+				}()
+			}
+
+			wg.Wait()
+
+			Expect(time.Since(start)).To(BeNumerically(">=", 950*time.Millisecond))
+
+			// BatchPutWithTTL() waits for the limiter once per key
+			bucket, _, _ := NewKVSet()
+			entries := make(map[string][]byte)
+
+			for i := 0; i < 11; i++ {
+				entries["key-"+strconv.Itoa(i)] = []byte("foo")
+			}
+
+			start = time.Now()
+
+			Expect(n.BatchPutWithTTL(context.Background(), bucket, entries, time.Minute)).To(Succeed())
+			Expect(time.Since(start)).To(BeNumerically(">=", 950*time.Millisecond))
+		})
+
+		It("should return an error if the context is done while rate limited", func() {
+			n.SetPublishRateLimit(0.1, 1)
+
+			Expect(n.TryPublish(context.Background(), "foo", []byte("bar"))).To(Succeed())
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+
+			Expect(n.TryPublish(ctx, "foo", []byte("bar"))).ToNot(Succeed())
+		})
+
+		It("should disable rate limiting with a limit of 0", func() {
+			n.SetPublishRateLimit(0, 0)
+
+			start := time.Now()
+
+			for i := 0; i < 100; i++ {
+				Expect(n.TryPublish(context.Background(), "foo", []byte("bar"))).To(Succeed())
+			}
+
+			Expect(time.Since(start)).To(BeNumerically("<", time.Second))
+		})
+	})
+
+	Describe("CreateStream", func() {
+		It("should create a stream", func() {
+			cfg := &Config{
+				NatsURL:       []string{NatsURL},
+				UseTLS:        true,
+				TLSSkipVerify: true,
+			}
+
+			n, err := New(cfg)
+			Expect(err).ToNot(HaveOccurred())
+
+			name := "ingest-4beb5666-2e30-4b28-a87e-6222731601ec"
+			err = n.CreateStream(context.Background(), name, []string{name})
+			Expect(err).ToNot(HaveOccurred())
+
+			CleanupStreams([]string{name})
+		})
+	})
+
+	Describe("DeleteStream", func() {
+		It("should delete a stream", func() {
+			cfg := &Config{
+				NatsURL:       []string{NatsURL},
+				UseTLS:        true,
+				TLSSkipVerify: true,
+			}
+
+			n, err := New(cfg)
+			Expect(err).ToNot(HaveOccurred())
+
+			name := "ingest-4beb5666-2e30-4b28-a87e-6222731601ec"
+			err = n.CreateStream(context.Background(), name, []string{name})
+			Expect(err).ToNot(HaveOccurred())
+
+			err = n.DeleteStream(context.Background(), name)
+			Expect(err).ToNot(HaveOccurred())
+		})
+	})
+
+	Describe("PurgeStreamSubject/PurgeStreamBefore", func() {
+		var (
+			n          *Natty
+			streamName string
+		)
+
+		BeforeEach(func() {
+			var err error
+
+			n, err = New(NewConfig())
+			Expect(err).ToNot(HaveOccurred())
+
+			streamName = "ingest-" + uuid.NewV4().String()
+			testStreams = append(testStreams, streamName)
+
+			Expect(n.CreateStream(context.Background(), streamName, []string{streamName + ".*"})).To(Succeed())
+
+			for i := 0; i < 3; i++ {
+				for _, subject := range []string{"purged", "kept"} {
+					_, err := n.js.Publish(streamName+"."+subject, []byte(subject))
+					Expect(err).ToNot(HaveOccurred())
+				}
+			}
+		})
+
+		It("should only purge messages on the subject", func() {
+			Expect(n.PurgeStreamSubject(context.Background(), streamName, streamName+".purged")).To(Succeed())
+
+			info, err := n.js.StreamInfo(streamName)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(info.State.Msgs).To(Equal(uint64(3)))
+
+			// Odd sequences were published on "purged", even ones on "kept"
+			for seq := uint64(1); seq <= 6; seq++ {
+				msg, err := n.js.GetMsg(streamName, seq)
+
+				if seq%2 == 1 {
+					Expect(err).To(HaveOccurred())
+					continue
+				}
+
+				Expect(err).ToNot(HaveOccurred())
+				Expect(msg.Data).To(Equal([]byte("kept")))
+			}
+		})
+
+		It("should purge messages before a sequence", func() {
+			Expect(n.PurgeStreamBefore(context.Background(), streamName, 5)).To(Succeed())
+
+			info, err := n.js.StreamInfo(streamName)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(info.State.Msgs).To(Equal(uint64(2)))
+			Expect(info.State.FirstSeq).To(Equal(uint64(5)))
+		})
+
+		It("should return ErrStreamNotFound for a missing stream", func() {
+			err := n.PurgeStreamSubject(context.Background(), "missing-"+uuid.NewV4().String(), "foo")
+			Expect(err).To(Equal(nats.ErrStreamNotFound))
+		})
+	})
+
+	Describe("CreateConsumer", func() {
+		It("should create a consumer", func() {
+			cfg := &Config{
+				NatsURL:       []string{NatsURL},
+				UseTLS:        true,
+				TLSSkipVerify: true,
+			}
+
+			n, err := New(cfg)
+			Expect(err).ToNot(HaveOccurred())
+
+			name := "ingest-" + uuid.NewV4().String()
+			err = n.CreateStream(context.Background(), name, []string{name})
+			Expect(err).ToNot(HaveOccurred())
+
+			err = n.CreateConsumer(context.Background(), name, name+"-consumer")
+			Expect(err).ToNot(HaveOccurred())
+
+			CleanupStreams([]string{name})
+		})
+	})
+
+	Describe("CreateMirrorStream", func() {
+		It("should mirror messages from the source stream", func() {
+			n, err := New(NewConfig())
+			Expect(err).ToNot(HaveOccurred())
+
+			sourceName := "source-" + uuid.NewV4().String()
+			mirrorName := sourceName + "-mirror"
+
+			defer CleanupStreams([]string{sourceName, mirrorName})
+
+			err = n.CreateStream(context.Background(), sourceName, []string{sourceName})
+			Expect(err).ToNot(HaveOccurred())
+
+			for i := 0; i < 10; i++ {
+				_, err := n.js.Publish(sourceName, []byte("foo"))
+				Expect(err).ToNot(HaveOccurred())
+			}
+
+			cfg := &nats.StreamConfig{Name: mirrorName, Storage: nats.MemoryStorage}
+
+			info, err := n.CreateMirrorStream(context.Background(), sourceName, cfg)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(info.Config.Mirror).ToNot(BeNil())
+			Expect(info.Config.Mirror.Name).To(Equal(sourceName))
+
+			// Caller's config is not modified
+			Expect(cfg.Mirror).To(BeNil())
+
+			Eventually(func() uint64 {
+				info, err := n.js.StreamInfo(mirrorName)
+				Expect(err).ToNot(HaveOccurred())
+
+				return info.State.Msgs
+			}, 5*time.Second).Should(Equal(uint64(10)))
+		})
+
+		It("should error on an empty source name", func() {
+			n, err := New(NewConfig())
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = n.CreateMirrorStream(context.Background(), "", &nats.StreamConfig{Name: "mirror"})
+			Expect(err).To(Equal(ErrEmptyStreamName))
+		})
+	})
+
+	Describe("CreateStreamWithConfig", func() {
+		It("should create a stream with sources", func() {
+			n, err := New(NewConfig())
+			Expect(err).ToNot(HaveOccurred())
+
+			sourceName := "source-" + uuid.NewV4().String()
+			aggregateName := sourceName + "-aggregate"
+
+			defer CleanupStreams([]string{sourceName, aggregateName})
+
+			err = n.CreateStream(context.Background(), sourceName, []string{sourceName})
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = n.js.Publish(sourceName, []byte("foo"))
+			Expect(err).ToNot(HaveOccurred())
+
+			info, err := n.CreateStreamWithConfig(context.Background(), &nats.StreamConfig{
+				Name:    aggregateName,
+				Storage: nats.MemoryStorage,
+				Sources: []*nats.StreamSource{{Name: sourceName}},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(info.Config.Sources).To(HaveLen(1))
+
+			Eventually(func() uint64 {
+				info, err := n.js.StreamInfo(aggregateName)
+				Expect(err).ToNot(HaveOccurred())
+
+				return info.State.Msgs
+			}, 5*time.Second).Should(Equal(uint64(1)))
+		})
+	})
+
+	Describe("UpdateConsumer", func() {
+		// NOTE: Changing FilterSubject requires NATS server 2.9+; the suite runs
+		// against nats-server 2.8 (see go.mod)
+		It("should update the config of a consumer", func() {
+			n, err := New(NewConfig())
+			Expect(err).ToNot(HaveOccurred())
+
+			streamName := "orders-" + uuid.NewV4().String()
+			consumerName := streamName + "-consumer"
+
+			err = n.CreateStream(context.Background(), streamName, []string{"orders.>"})
+			Expect(err).ToNot(HaveOccurred())
+
+			defer CleanupStreams([]string{streamName})
+
+			err = n.CreateConsumer(context.Background(), streamName, consumerName, "orders.>")
+			Expect(err).ToNot(HaveOccurred())
+
+			info, err := n.js.ConsumerInfo(streamName, consumerName)
+			Expect(err).ToNot(HaveOccurred())
+
+			cfg := info.Config
+			cfg.AckWait = 42 * time.Second
+			cfg.MaxDeliver = 7
+
+			info, err = n.UpdateConsumer(context.Background(), streamName, &cfg)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(info.Config.AckWait).To(Equal(42 * time.Second))
+			Expect(info.Config.MaxDeliver).To(Equal(7))
+
+			info, err = n.js.ConsumerInfo(streamName, consumerName)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(info.Config.AckWait).To(Equal(42 * time.Second))
+			Expect(info.Config.MaxDeliver).To(Equal(7))
+		})
+
+		It("should error if the server does not allow changing the filter subject", func() {
+			n, err := New(NewConfig())
+			Expect(err).ToNot(HaveOccurred())
+
+			streamName := "orders-" + uuid.NewV4().String()
+			consumerName := streamName + "-consumer"
+
+			err = n.CreateStream(context.Background(), streamName, []string{"orders.>"})
+			Expect(err).ToNot(HaveOccurred())
+
+			defer CleanupStreams([]string{streamName})
+
+			err = n.CreateConsumer(context.Background(), streamName, consumerName, "orders.>")
+			Expect(err).ToNot(HaveOccurred())
+
+			info, err := n.js.ConsumerInfo(streamName, consumerName)
+			Expect(err).ToNot(HaveOccurred())
+
+			cfg := info.Config
+			cfg.FilterSubject = "orders.pending.>"
+
+			_, err = n.UpdateConsumer(context.Background(), streamName, &cfg)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("unable to update consumer"))
+			Expect(err.Error()).To(ContainSubstring("filter subject"))
+
+			info, err = n.js.ConsumerInfo(streamName, consumerName)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(info.Config.FilterSubject).To(Equal("orders.>"))
+		})
+
+		It("should error on a missing durable name", func() {
+			n, err := New(NewConfig())
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = n.UpdateConsumer(context.Background(), "stream", &nats.ConsumerConfig{})
+			Expect(err).To(Equal(ErrEmptyConsumerName))
+		})
+	})
+
+	Describe("DeleteConsumer", func() {
+		It("should delete a consumer", func() {
+			cfg := &Config{
+				NatsURL:       []string{NatsURL},
+				UseTLS:        true,
+				TLSSkipVerify: true,
+			}
+
+			n, err := New(cfg)
+			Expect(err).ToNot(HaveOccurred())
+
+			streamName := "ingest-" + uuid.NewV4().String()
+			consumerName := streamName + "-consumer"
+
+			err = n.CreateStream(context.Background(), streamName, []string{streamName})
+			Expect(err).ToNot(HaveOccurred())
+
+			err = n.CreateConsumer(context.Background(), streamName, consumerName)
+			Expect(err).ToNot(HaveOccurred())
+
+			err = n.DeleteConsumer(context.Background(), consumerName, streamName)
+			Expect(err).ToNot(HaveOccurred())
+
+			CleanupStreams([]string{streamName})
+		})
+	})
+
+	Describe("ListStreams", func() {
+		It("should list streams", func() {
+			n, err := New(NewConfig())
+			Expect(err).ToNot(HaveOccurred())
+
+			names := make([]string, 0)
+
+			for i := 0; i < 3; i++ {
+				name := "ingest-" + uuid.NewV4().String()
+
+				err = n.CreateStream(context.Background(), name, []string{name})
+				Expect(err).ToNot(HaveOccurred())
+
+				names = append(names, name)
+			}
+
+			defer CleanupStreams(names)
+
+			streams, err := n.ListStreams(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+
+			found := make([]string, 0)
+
+			for _, s := range streams {
+				found = append(found, s.Config.Name)
+			}
+
+			Expect(found).To(ContainElements(names))
+		})
+
+		It("should use the configured JetStream API prefix", func() {
+			// No JetStream domain by that name exists
+			n, err := New(NewConfig(), WithJetStreamDomain("natty-missing"))
+			Expect(err).ToNot(HaveOccurred())
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			_, err = n.ListStreams(ctx)
+			Expect(err).To(HaveOccurred())
+
+			n, err = New(NewConfig(), WithJetStreamAPIPrefix("$JS.API"))
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = n.ListStreams(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("should return an error instead of a partial list on API failures", func() {
+			// The auth server does not have JetStream enabled
+			cfg := NewConfig()
+			cfg.NatsURL = []string{NatsAuthURL}
+			cfg.UseTLS = false
+			cfg.Username = "natty"
+			cfg.Password = "natty"
+
+			n, err := New(cfg)
+			Expect(err).ToNot(HaveOccurred())
+
+			streams, err := n.ListStreams(context.Background())
+			Expect(err).To(HaveOccurred())
+			Expect(streams).To(BeNil())
+
+			consumers, err := n.ListConsumers(context.Background(), "stream")
+			Expect(err).To(HaveOccurred())
+			Expect(consumers).To(BeNil())
+		})
+	})
+
+	Describe("ListConsumers", func() {
+		It("should list consumers on a stream", func() {
+			n, err := New(NewConfig())
+			Expect(err).ToNot(HaveOccurred())
+
+			streamName := "ingest-" + uuid.NewV4().String()
+
+			err = n.CreateStream(context.Background(), streamName, []string{streamName})
+			Expect(err).ToNot(HaveOccurred())
+
+			defer CleanupStreams([]string{streamName})
+
+			names := make([]string, 0)
+
+			for i := 0; i < 3; i++ {
+				name := streamName + "-consumer-" + strconv.Itoa(i)
+
+				err = n.CreateConsumer(context.Background(), streamName, name)
+				Expect(err).ToNot(HaveOccurred())
+
+				names = append(names, name)
+			}
+
+			consumers, err := n.ListConsumers(context.Background(), streamName)
+			Expect(err).ToNot(HaveOccurred())
+
+			found := make([]string, 0)
+
+			for _, c := range consumers {
+				found = append(found, c.Name)
+			}
+
+			Expect(found).To(ConsistOf(names))
+		})
+
+		It("should error on a missing stream", func() {
+			n, err := New(NewConfig())
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = n.ListConsumers(context.Background(), "missing-"+uuid.NewV4().String())
+			Expect(errors.Is(err, nats.ErrStreamNotFound)).To(BeTrue())
+		})
+	})
+
+	Describe("AccountInfo", func() {
+		It("should report stream and consumer usage", func() {
+			n, err := New(NewConfig())
+			Expect(err).ToNot(HaveOccurred())
+
+			before, err := n.AccountInfo(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(before).ToNot(BeNil())
+
+			streamName := "ingest-" + uuid.NewV4().String()
+
+			err = n.CreateStream(context.Background(), streamName, []string{streamName})
+			Expect(err).ToNot(HaveOccurred())
+
+			defer CleanupStreams([]string{streamName})
+
+			err = n.CreateConsumer(context.Background(), streamName, streamName+"-consumer")
+			Expect(err).ToNot(HaveOccurred())
+
+			after, err := n.AccountInfo(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(after.Streams).To(BeNumerically(">", before.Streams))
+			Expect(after.Consumers).To(BeNumerically(">", before.Consumers))
+		})
+	})
+
+	Describe("PublishWithRetry", func() {
+		var n *Natty
+
+		BeforeEach(func() {
+			var err error
+
+			n, err = New(NewConfig())
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("should retry until the stream exists", func() {
+			streamName := "ingest-" + uuid.NewV4().String()
+
+			defer CleanupStreams([]string{streamName})
+
+			go func() {
+				defer GinkgoRecover()
+
+				time.Sleep(500 * time.Millisecond)
+				Expect(n.CreateStream(context.Background(), streamName, []string{streamName})).To(Succeed())
+			}()
+
+			ack, err := n.PublishWithRetry(context.Background(), streamName, []byte("foo"), RetryPolicy{
+				MaxAttempts:  20,
+				InitialDelay: 100 * time.Millisecond,
+				MaxDelay:     100 * time.Millisecond,
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ack.Stream).To(Equal(streamName))
+			Expect(ack.Sequence).To(Equal(uint64(1)))
+		})
+
+		It("should give up after MaxAttempts", func() {
+			_, err := n.PublishWithRetry(context.Background(), "missing-"+uuid.NewV4().String(), []byte("foo"), RetryPolicy{
+				MaxAttempts:  2,
+				InitialDelay: 10 * time.Millisecond,
+			})
+			Expect(err).To(HaveOccurred())
+			Expect(errors.Is(err, nats.ErrNoStreamResponse)).To(BeTrue())
+		})
+	})
+
+	Describe("WaitForStream", func() {
+		var n *Natty
 
-			doneCh := make(chan struct{}, 0)
-			consumed := make([]*nats.Msg, 0)
+		BeforeEach(func() {
+			var err error
 
-			handler := func(ctx context.Context, msg *nats.Msg) error {
-				consumed = append(consumed, msg)
-				Expect(msg.Ack()).ToNot(HaveOccurred())
+			n, err = New(NewConfig())
+			Expect(err).ToNot(HaveOccurred())
+		})
 
-				if len(consumed) == 100 {
-					doneCh <- struct{}{}
-				}
+		It("should return once the stream exists", func() {
+			streamName := "ingest-" + uuid.NewV4().String()
 
-				return nil
-			}
+			defer CleanupStreams([]string{streamName})
 
-			// Consumer should only receive for streamName.foo
 			go func() {
-				err = n.Consume(ctx, &ConsumerConfig{
-					StreamName:   streamName,
-					Subject:      goodFilter,
-					ConsumerName: consumerName,
-				}, handler)
+				defer GinkgoRecover()
 
-				Expect(err).ToNot(HaveOccurred())
+				time.Sleep(300 * time.Millisecond)
+				Expect(n.CreateStream(context.Background(), streamName, []string{streamName})).To(Succeed())
 			}()
 
-			// Should receive data within 5 seconds
-			select {
-			case <-time.After(5 * time.Second):
-				Fail("consumer timed out waiting for message(s)")
-			case <-doneCh:
-				break
-			}
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
 
-			Expect(len(consumed)).To(Equal(publishNumMessages))
+			Expect(n.WaitForStream(ctx, streamName, 50*time.Millisecond)).To(Succeed())
+		})
 
-			for _, m := range consumed {
-				Expect(m.Subject).To(Equal(goodFilter))
-			}
+		It("should error once the context is done", func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+			defer cancel()
+
+			err := n.WaitForStream(ctx, "missing-"+uuid.NewV4().String(), 50*time.Millisecond)
+			Expect(errors.Is(err, context.DeadlineExceeded)).To(BeTrue())
 		})
+	})
 
-		It("error watch channel is used", func() {
-			ctx, cancel := context.WithCancel(context.Background())
+	Describe("Drain", func() {
+		It("should flush publisher queues before closing", func() {
+			n, err := New(NewConfig())
+			Expect(err).ToNot(HaveOccurred())
 
-			consumed := make([]string, 0)
+			streamName := "ingest-" + uuid.NewV4().String()
+			testStreams = append(testStreams, streamName)
 
-			streamName := strings.ToUpper(GetRandomName("test", 1))
-			consumerName := GetRandomName("test", 1)
+			err = n.CreateStream(context.Background(), streamName, []string{streamName + ".>"})
+			Expect(err).ToNot(HaveOccurred())
 
-			err := n.CreateStream(ctx, streamName, []string{streamName + ".*"})
+			for i := 0; i < 10; i++ {
+				err := n.TryPublish(context.Background(), streamName+".foo", []byte("drain"))
+				Expect(err).ToNot(HaveOccurred())
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			err = n.Drain(ctx)
 			Expect(err).ToNot(HaveOccurred())
+			Expect(n.nc.IsClosed()).To(BeTrue())
 
-			err = n.CreateConsumer(ctx, streamName, consumerName)
+			// Verify via separate connection that messages made it to the stream
+			nc, err := nats.Connect(NatsURL, nats.Secure(tlsConfig))
 			Expect(err).ToNot(HaveOccurred())
 
-			subj := streamName + ".foo"
+			js, err := nc.JetStream()
+			Expect(err).ToNot(HaveOccurred())
 
-			var exit bool
+			info, err := js.StreamInfo(streamName)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(info.State.Msgs).To(Equal(uint64(10)))
+		})
 
-			errChan := make(chan error, 1000)
+		It("should return ErrConnectionClosed on publish after drain", func() {
+			n, err := New(NewConfig())
+			Expect(err).ToNot(HaveOccurred())
 
-			// Launch consumer in a goroutine
-			go func() {
-				consumerConfig := &ConsumerConfig{
-					Subject:      subj,
-					StreamName:   streamName,
-					ConsumerName: consumerName,
-					ErrorCh:      errChan,
-				}
+			err = n.Drain(context.Background())
+			Expect(err).ToNot(HaveOccurred())
 
-				err := n.Consume(ctx, consumerConfig, func(ctx context.Context, msg *nats.Msg) error {
-					consumed = append(consumed, string(msg.Data))
-					return errors.New("stuff broke")
-				})
+			err = n.TryPublish(context.Background(), "foo", []byte("bar"))
+			Expect(err).To(Equal(ErrConnectionClosed))
 
-				Expect(err).ToNot(HaveOccurred())
-				exit = true
-			}()
+			_, err = n.Get(context.Background(), "foo", "bar")
+			Expect(err).To(Equal(ErrConnectionClosed))
 
-			chanErrors := make([]error, 0)
+			// Draining again should error
+			err = n.Drain(context.Background())
+			Expect(err).To(Equal(ErrConnectionClosed))
+		})
 
-			// Expect watcher to get 5 errors
-			go func() {
-				for {
-					select {
-					case <-ctx.Done():
-						return
-					case err := <-errChan:
-						chanErrors = append(chanErrors, err)
-					}
-				}
-			}()
+		It("should send rejected Publish messages to PublishErrorCh after drain", func() {
+			cfg := NewConfig()
+			cfg.PublishErrorCh = make(chan *PublishError, 1)
 
-			// Produce 5 events
-			err = Publish(cfg, 5, subj, uuid.NewV4().String())
+			n, err := New(cfg)
 			Expect(err).ToNot(HaveOccurred())
 
-			// Give Consume() enough time to consume
-			time.Sleep(1 * time.Second)
+			err = n.Drain(context.Background())
+			Expect(err).ToNot(HaveOccurred())
 
-			cancel()
+			n.Publish(context.Background(), "foo", []byte("bar"))
 
-			// Give Consume() enough time to exit
-			time.Sleep(1 * time.Second)
+			var publishErr *PublishError
 
-			Expect(exit).To(BeTrue())
-			Expect(len(consumed)).To(Equal(5))
-			Expect(len(chanErrors)).To(Equal(5))
+			Eventually(cfg.PublishErrorCh).Should(Receive(&publishErr))
+			Expect(publishErr.Subject).To(Equal("foo"))
+			Expect(publishErr.Message).To(Equal(ErrConnectionClosed))
 		})
 	})
 
-	Describe("Publish", func() {
-		var (
-			cfg *Config
-			n   *Natty
-		)
-
-		BeforeEach(func() {
-			var err error
+	Describe("IsConnected", func() {
+		It("should reflect connection state", func() {
+			n, err := New(NewConfig())
+			Expect(err).ToNot(HaveOccurred())
 
-			cfg = NewConfig()
+			Expect(n.IsConnected()).To(BeTrue())
+			Expect(n.Status()).To(Equal(nats.CONNECTED))
 
-			n, err = New(cfg)
+			err = n.Close()
 			Expect(err).ToNot(HaveOccurred())
-			Expect(n).ToNot(BeNil())
-		})
 
-		It("should publish", func() {
-			const MessagesToPublish = 50
-			defer GinkgoRecover()
-			// Publish stuff
-			ctx, cancel := context.WithCancel(context.Background())
-			defer cancel()
+			Expect(n.IsConnected()).To(BeFalse())
+			Expect(n.Status()).To(Equal(nats.CLOSED))
+		})
+	})
 
-			payload := uuid.NewV4().String()
+	Describe("JetStream/Conn", func() {
+		It("should expose the underlying connection until closed", func() {
+			n, err := New(NewConfig())
+			Expect(err).ToNot(HaveOccurred())
 
-			cfg := NewConfig()
+			Expect(n.Conn()).ToNot(BeNil())
+			Expect(n.Conn().IsConnected()).To(BeTrue())
 
-			streamName := strings.ToUpper(GetRandomName("test", 1))
+			js := n.JetStream()
+			Expect(js).ToNot(BeNil())
 
-			err := n.CreateStream(context.Background(), streamName, []string{streamName + ".*"})
+			_, err = js.AccountInfo()
 			Expect(err).ToNot(HaveOccurred())
 
-			var hit int
-
-			// Create a consumer - expect to see a msg
-			go func() {
-				testStreams = append(testStreams, streamName)
+			Expect(n.Close()).To(Succeed())
 
-				natsClient, err := nats.Connect(cfg.NatsURL[0], nats.Secure(tlsConfig))
-				Expect(err).ToNot(HaveOccurred())
-				Expect(natsClient).ToNot(BeNil())
+			Expect(n.Conn()).To(BeNil())
+			Expect(n.JetStream()).To(BeNil())
+		})
+	})
 
-				js, err := natsClient.JetStream()
-				Expect(err).ToNot(HaveOccurred())
-				Expect(js).ToNot(BeNil())
+	Describe("ServerInfo", func() {
+		It("should return info about the connected server", func() {
+			n, err := New(NewConfig())
+			Expect(err).ToNot(HaveOccurred())
 
-				_, err = js.Subscribe(streamName+".foo", func(msg *nats.Msg) {
-					hit += 1
-					Expect(string(msg.Data)).To(Equal(payload))
-					msg.Ack()
-				})
-				Expect(err).ToNot(HaveOccurred())
-			}()
+			info, err := n.ServerInfo(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(info).ToNot(BeNil())
+			Expect(info.Version).ToNot(BeEmpty())
+			Expect(info.ID).ToNot(BeEmpty())
+			Expect(info.Name).To(Equal(n.ConnectedServerName()))
+			Expect(info.Cluster).To(Equal(n.ConnectedClusterName()))
+		})
 
-			for i := 0; i < MessagesToPublish; i++ {
-				n.Publish(ctx, streamName+".foo", []byte(payload))
-			}
+		It("should error after Close", func() {
+			n, err := New(NewConfig())
+			Expect(err).ToNot(HaveOccurred())
 
-			time.Sleep(5 * time.Second)
+			Expect(n.Close()).To(Succeed())
 
-			Expect(hit).To(Equal(MessagesToPublish))
+			_, err = n.ServerInfo(context.Background())
+			Expect(err).To(Equal(ErrConnectionClosed))
 		})
 	})
 
-	Describe("CreateStream", func() {
-		It("should create a stream", func() {
-			cfg := &Config{
-				NatsURL:       []string{NatsURL},
-				UseTLS:        true,
-				TLSSkipVerify: true,
-			}
-
-			n, err := New(cfg)
+	Describe("Flush", func() {
+		It("should send buffered messages before returning", func() {
+			n, err := New(NewConfig())
 			Expect(err).ToNot(HaveOccurred())
 
-			name := "ingest-4beb5666-2e30-4b28-a87e-6222731601ec"
-			err = n.CreateStream(context.Background(), name, []string{name})
+			subject := "flush." + uuid.NewV4().String()
+
+			sub, err := n.Conn().SubscribeSync(subject)
 			Expect(err).ToNot(HaveOccurred())
 
-			CleanupStreams([]string{name})
-		})
-	})
+			defer sub.Unsubscribe()
 
-	Describe("DeleteStream", func() {
-		It("should delete a stream", func() {
-			cfg := &Config{
-				NatsURL:       []string{NatsURL},
-				UseTLS:        true,
-				TLSSkipVerify: true,
-			}
+			Expect(n.Conn().Publish(subject, []byte("foo"))).To(Succeed())
 
-			n, err := New(cfg)
-			Expect(err).ToNot(HaveOccurred())
+			Expect(n.Flush(context.Background())).To(Succeed())
 
-			name := "ingest-4beb5666-2e30-4b28-a87e-6222731601ec"
-			err = n.CreateStream(context.Background(), name, []string{name})
+			// The server sends the message before the PONG that Flush() waits for
+			msgs, _, err := sub.Pending()
 			Expect(err).ToNot(HaveOccurred())
+			Expect(msgs).To(Equal(1))
 
-			err = n.DeleteStream(context.Background(), name)
+			msg, err := sub.NextMsg(time.Second)
 			Expect(err).ToNot(HaveOccurred())
+			Expect(msg.Data).To(Equal([]byte("foo")))
 		})
-	})
 
-	Describe("CreateConsumer", func() {
-		It("should create a consumer", func() {
-			cfg := &Config{
-				NatsURL:       []string{NatsURL},
-				UseTLS:        true,
-				TLSSkipVerify: true,
-			}
+		It("should flush publisher queues", func() {
+			n, err := New(NewConfig())
+			Expect(err).ToNot(HaveOccurred())
 
-			n, err := New(cfg)
+			streamName := "flush-" + uuid.NewV4().String()
+			testStreams = append(testStreams, streamName)
+
+			err = n.CreateStream(context.Background(), streamName, []string{streamName + ".>"})
 			Expect(err).ToNot(HaveOccurred())
 
-			name := "ingest-" + uuid.NewV4().String()
-			err = n.CreateStream(context.Background(), name, []string{name})
+			for i := 0; i < 10; i++ {
+				err := n.TryPublish(context.Background(), streamName+".foo", []byte("flush"))
+				Expect(err).ToNot(HaveOccurred())
+			}
+
+			Expect(n.Flush(context.Background())).To(Succeed())
+			Expect(n.pendingPublishes()).To(BeZero())
+
+			info, err := n.js.StreamInfo(streamName)
 			Expect(err).ToNot(HaveOccurred())
+			Expect(info.State.Msgs).To(Equal(uint64(10)))
 
-			err = n.CreateConsumer(context.Background(), name, name+"-consumer")
+			// The connection stays open
+			Expect(n.IsConnected()).To(BeTrue())
+		})
+
+		It("should error once closed", func() {
+			n, err := New(NewConfig())
 			Expect(err).ToNot(HaveOccurred())
 
-			CleanupStreams([]string{name})
+			Expect(n.Close()).To(Succeed())
+
+			Expect(n.Flush(context.Background())).To(Equal(ErrConnectionClosed))
 		})
 	})
 
-	Describe("DeleteConsumer", func() {
-		It("should delete a consumer", func() {
-			cfg := &Config{
-				NatsURL:       []string{NatsURL},
-				UseTLS:        true,
-				TLSSkipVerify: true,
-			}
+	Describe("Ping", func() {
+		It("should receive ping echo", func() {
+			n, err := New(NewConfig())
+			Expect(err).ToNot(HaveOccurred())
 
-			n, err := New(cfg)
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			err = n.Ping(ctx)
 			Expect(err).ToNot(HaveOccurred())
+		})
 
-			streamName := "ingest-" + uuid.NewV4().String()
-			consumerName := streamName + "-consumer"
+		It("should error after close", func() {
+			n, err := New(NewConfig())
+			Expect(err).ToNot(HaveOccurred())
 
-			err = n.CreateStream(context.Background(), streamName, []string{streamName})
+			err = n.Close()
 			Expect(err).ToNot(HaveOccurred())
 
-			err = n.CreateConsumer(context.Background(), streamName, consumerName)
+			err = n.Ping(context.Background())
+			Expect(err).To(Equal(ErrConnectionClosed))
+		})
+	})
+
+	Describe("Close", func() {
+		It("should return ErrConnectionClosed after close", func() {
+			n, err := New(NewConfig())
 			Expect(err).ToNot(HaveOccurred())
 
-			err = n.DeleteConsumer(context.Background(), consumerName, streamName)
+			err = n.Close()
 			Expect(err).ToNot(HaveOccurred())
+			Expect(n.nc.IsClosed()).To(BeTrue())
 
-			CleanupStreams([]string{streamName})
+			err = n.Put(context.Background(), "foo", "bar", []byte("baz"))
+			Expect(err).To(Equal(ErrConnectionClosed))
+
+			err = n.TryPublish(context.Background(), "foo", []byte("bar"))
+			Expect(err).To(Equal(ErrConnectionClosed))
 		})
 	})
 })
@@ -1416,3 +2642,34 @@ begin:
 	}
 	return name
 }
+
+// closingDialer dials TCP connections and can close all of them at once to
+// force the NATS client to reconnect
+type closingDialer struct {
+	mtx   sync.Mutex
+	conns []net.Conn
+}
+
+func (d *closingDialer) Dial(network, address string) (net.Conn, error) {
+	conn, err := net.Dial(network, address)
+	if err != nil {
+		return nil, err
+	}
+
+	d.mtx.Lock()
+	d.conns = append(d.conns, conn)
+	d.mtx.Unlock()
+
+	return conn, nil
+}
+
+func (d *closingDialer) closeAll() {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	for _, conn := range d.conns {
+		conn.Close()
+	}
+
+	d.conns = nil
+}
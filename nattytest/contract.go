@@ -0,0 +1,150 @@
+// Package nattytest contains helpers for testing code that uses natty.
+package nattytest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nats-io/nats.go"
+	uuid "github.com/satori/go.uuid"
+
+	"github.com/batchcorp/natty"
+)
+
+// RunKVContractTests runs a standard battery of KV contract tests against the
+// INatty returned by factory; factory is called once per test. Every test uses
+// a uniquely named bucket which is deleted once the test completes.
+func RunKVContractTests(t *testing.T, factory func() natty.INatty) {
+	t.Helper()
+
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, n natty.INatty, bucket string)
+	}{
+		{"CreateThenGet", testCreateThenGet},
+		{"CreateExisting", testCreateExisting},
+		{"PutOverwrites", testPutOverwrites},
+		{"DeleteThenGet", testDeleteThenGet},
+		{"KeysAfterDelete", testKeysAfterDelete},
+		{"GetMissing", testGetMissing},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			n := factory()
+			bucket := "nattytest-" + uuid.NewV4().String()
+
+			defer func() {
+				if err := n.DeleteBucket(context.Background(), bucket); err != nil {
+					t.Errorf("unable to delete bucket '%s': %s", bucket, err)
+				}
+			}()
+
+			tt.fn(t, n, bucket)
+		})
+	}
+}
+
+func testCreateThenGet(t *testing.T, n natty.INatty, bucket string) {
+	ctx := context.Background()
+
+	if err := n.Create(ctx, bucket, "foo", []byte("bar")); err != nil {
+		t.Fatalf("unexpected error on Create: %s", err)
+	}
+
+	mustGet(t, n, bucket, "foo", "bar")
+}
+
+func testCreateExisting(t *testing.T, n natty.INatty, bucket string) {
+	ctx := context.Background()
+
+	if err := n.Create(ctx, bucket, "foo", []byte("bar")); err != nil {
+		t.Fatalf("unexpected error on Create: %s", err)
+	}
+
+	if err := n.Create(ctx, bucket, "foo", []byte("baz")); err == nil {
+		t.Fatal("expected error on Create of existing key")
+	}
+
+	mustGet(t, n, bucket, "foo", "bar")
+}
+
+func testPutOverwrites(t *testing.T, n natty.INatty, bucket string) {
+	ctx := context.Background()
+
+	if err := n.Put(ctx, bucket, "foo", []byte("bar")); err != nil {
+		t.Fatalf("unexpected error on Put: %s", err)
+	}
+
+	if err := n.Put(ctx, bucket, "foo", []byte("baz")); err != nil {
+		t.Fatalf("unexpected error on second Put: %s", err)
+	}
+
+	mustGet(t, n, bucket, "foo", "baz")
+}
+
+func testDeleteThenGet(t *testing.T, n natty.INatty, bucket string) {
+	ctx := context.Background()
+
+	if err := n.Put(ctx, bucket, "foo", []byte("bar")); err != nil {
+		t.Fatalf("unexpected error on Put: %s", err)
+	}
+
+	if err := n.Delete(ctx, bucket, "foo"); err != nil {
+		t.Fatalf("unexpected error on Delete: %s", err)
+	}
+
+	if _, err := n.Get(ctx, bucket, "foo"); err != nats.ErrKeyNotFound {
+		t.Fatalf("expected ErrKeyNotFound after Delete, got: %v", err)
+	}
+}
+
+func testKeysAfterDelete(t *testing.T, n natty.INatty, bucket string) {
+	ctx := context.Background()
+
+	for _, key := range []string{"a", "b", "c"} {
+		if err := n.Put(ctx, bucket, key, []byte(key)); err != nil {
+			t.Fatalf("unexpected error on Put: %s", err)
+		}
+	}
+
+	if err := n.Delete(ctx, bucket, "b"); err != nil {
+		t.Fatalf("unexpected error on Delete: %s", err)
+	}
+
+	keys, err := n.Keys(ctx, bucket)
+	if err != nil {
+		t.Fatalf("unexpected error on Keys: %s", err)
+	}
+
+	found := make(map[string]bool)
+
+	for _, key := range keys {
+		found[key] = true
+	}
+
+	if len(keys) != 2 || !found["a"] || !found["c"] {
+		t.Fatalf("expected keys [a c], got: %v", keys)
+	}
+}
+
+func testGetMissing(t *testing.T, n natty.INatty, bucket string) {
+	if _, err := n.Get(context.Background(), bucket, "missing"); err != nats.ErrKeyNotFound {
+		t.Fatalf("expected ErrKeyNotFound for missing bucket, got: %v", err)
+	}
+}
+
+func mustGet(t *testing.T, n natty.INatty, bucket, key, want string) {
+	t.Helper()
+
+	data, err := n.Get(context.Background(), bucket, key)
+	if err != nil {
+		t.Fatalf("unexpected error on Get: %s", err)
+	}
+
+	if string(data) != want {
+		t.Fatalf("expected value '%s', got '%s'", want, data)
+	}
+}
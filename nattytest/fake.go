@@ -0,0 +1,428 @@
+package nattytest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/batchcorp/natty"
+)
+
+var (
+	_ natty.INatty = &FakeNatty{}
+)
+
+type fakeBucket struct {
+	revision uint64
+	entries  map[string]*natty.KVEntry
+}
+
+type fakeWatcher struct {
+	bucket  string
+	key     string
+	entries chan *natty.KVEntry
+
+	// Set for WatchWithBackpressure(): entries are queued (without limit)
+	// instead of dropped and sent to entries by a separate goroutine
+	queued     bool
+	queue      []*natty.KVEntry
+	queueMutex *sync.Mutex
+	wake       chan struct{}
+}
+
+// FakeNatty is an in-memory implementation of the natty.INatty KV methods
+// intended for use in unit tests. Only the KV methods (Get, GetEntry, Put,
+// Create, Delete, Keys, Watch, WatchBucket, WatchWithBackpressure,
+// CreateBucket, DeleteBucket, GetJSON, PutJSON, Increment and Decrement) are
+// implemented; calling any other INatty
+// method will panic. TTLs are accepted but ignored.
+type FakeNatty struct {
+	// Non-KV methods are not implemented
+	natty.INatty
+
+	mutex    *sync.RWMutex
+	buckets  map[string]*fakeBucket
+	watchers map[*fakeWatcher]struct{}
+}
+
+// NewFakeNatty returns an empty FakeNatty
+func NewFakeNatty() *FakeNatty {
+	return &FakeNatty{
+		mutex:    &sync.RWMutex{},
+		buckets:  make(map[string]*fakeBucket),
+		watchers: make(map[*fakeWatcher]struct{}),
+	}
+}
+
+func (f *FakeNatty) Get(ctx context.Context, bucket string, key string) ([]byte, error) {
+	entry, err := f.GetEntry(ctx, bucket, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return entry.Value, nil
+}
+
+func (f *FakeNatty) GetEntry(_ context.Context, bucket string, key string) (*natty.KVEntry, error) {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+
+	b, ok := f.buckets[bucket]
+	if !ok {
+		return nil, nats.ErrKeyNotFound
+	}
+
+	entry, ok := b.entries[key]
+	if !ok {
+		return nil, nats.ErrKeyNotFound
+	}
+
+	// Return a copy so that callers cannot modify stored data
+	e := *entry
+	e.Value = append([]byte(nil), entry.Value...)
+
+	return &e, nil
+}
+
+func (f *FakeNatty) Put(_ context.Context, bucket string, key string, data []byte, _ ...time.Duration) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.put(f.getBucket(bucket), bucket, key, data)
+
+	return nil
+}
+
+func (f *FakeNatty) Create(_ context.Context, bucket string, key string, data []byte, _ ...time.Duration) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	b := f.getBucket(bucket)
+
+	if _, ok := b.entries[key]; ok {
+		return natty.ErrKeyExists
+	}
+
+	f.put(b, bucket, key, data)
+
+	return nil
+}
+
+func (f *FakeNatty) Delete(_ context.Context, bucket string, key string) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if b, ok := f.buckets[bucket]; ok {
+		if _, ok := b.entries[key]; ok {
+			delete(b.entries, key)
+
+			b.revision++
+
+			f.notify(&natty.KVEntry{
+				Bucket:    bucket,
+				Key:       key,
+				Revision:  b.revision,
+				Created:   time.Now().UTC(),
+				Operation: nats.KeyValuePurge,
+			})
+		}
+	}
+
+	return nil
+}
+
+func (f *FakeNatty) Keys(_ context.Context, bucket string) ([]string, error) {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+
+	b, ok := f.buckets[bucket]
+	if !ok {
+		return nil, nats.ErrBucketNotFound
+	}
+
+	keys := make([]string, 0, len(b.entries))
+
+	for k := range b.entries {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys, nil
+}
+
+func (f *FakeNatty) GetJSON(ctx context.Context, bucket, key string, out interface{}) error {
+	data, err := f.Get(ctx, bucket, key)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(data, out); err != nil {
+		return &natty.JSONError{Bucket: bucket, Key: key, Err: err}
+	}
+
+	return nil
+}
+
+func (f *FakeNatty) PutJSON(ctx context.Context, bucket, key string, v interface{}, keyTTL ...time.Duration) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return &natty.JSONError{Bucket: bucket, Key: key, Err: err}
+	}
+
+	return f.Put(ctx, bucket, key, data, keyTTL...)
+}
+
+// Increment adds delta to the counter stored at key; like natty.Natty,
+// counters are stored as decimal ASCII strings.
+func (f *FakeNatty) Increment(_ context.Context, bucket, key string, delta int64) (int64, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	b := f.getBucket(bucket)
+
+	value := delta
+
+	if entry, ok := b.entries[key]; ok {
+		counter, err := strconv.ParseInt(string(entry.Value), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("existing value is not a valid counter: %s", err)
+		}
+
+		value += counter
+	}
+
+	f.put(b, bucket, key, []byte(strconv.FormatInt(value, 10)))
+
+	return value, nil
+}
+
+func (f *FakeNatty) Decrement(ctx context.Context, bucket, key string, delta int64) (int64, error) {
+	return f.Increment(ctx, bucket, key, -delta)
+}
+
+// Watch delivers the current values matching key followed by any updates until
+// ctx is cancelled. Like natty.Natty, entries are dropped if the channel buffer
+// is full.
+func (f *FakeNatty) Watch(ctx context.Context, bucket, key string) (<-chan *natty.KVEntry, error) {
+	return f.watch(ctx, bucket, key, false, false)
+}
+
+// WatchBucket is Watch() for all keys, with a nil entry sent once the current
+// values have been delivered (same as natty.Natty).
+func (f *FakeNatty) WatchBucket(ctx context.Context, bucket string) (<-chan *natty.KVEntry, error) {
+	return f.watch(ctx, bucket, nats.AllKeys, true, false)
+}
+
+// WatchWithBackpressure is Watch() without dropping entries: entries that have
+// not been received yet are queued in memory (without limit).
+func (f *FakeNatty) WatchWithBackpressure(ctx context.Context, bucket, key string) (<-chan *natty.KVEntry, error) {
+	return f.watch(ctx, bucket, key, false, true)
+}
+
+func (f *FakeNatty) watch(ctx context.Context, bucket, key string, sentinel, queued bool) (<-chan *natty.KVEntry, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	b, ok := f.buckets[bucket]
+	if !ok {
+		return nil, nats.ErrBucketNotFound
+	}
+
+	current := make([]*natty.KVEntry, 0)
+
+	for k, e := range b.entries {
+		if matchKey(key, k) {
+			current = append(current, e)
+		}
+	}
+
+	sort.Slice(current, func(i, j int) bool { return current[i].Revision < current[j].Revision })
+
+	size := natty.WatchBufferSize
+
+	// Make sure the sentinel always fits
+	if sentinel && len(current) >= size {
+		size = len(current) + 1
+	}
+
+	w := &fakeWatcher{
+		bucket:  bucket,
+		key:     key,
+		entries: make(chan *natty.KVEntry, size),
+	}
+
+	if queued {
+		w.queued = true
+		w.entries = make(chan *natty.KVEntry)
+		w.queueMutex = &sync.Mutex{}
+		w.wake = make(chan struct{}, 1)
+
+		go w.drain(ctx)
+	}
+
+	for _, e := range current {
+		w.send(e)
+	}
+
+	if sentinel {
+		w.entries <- nil
+	}
+
+	f.watchers[w] = struct{}{}
+
+	go func() {
+		<-ctx.Done()
+
+		f.mutex.Lock()
+		defer f.mutex.Unlock()
+
+		delete(f.watchers, w)
+
+		// drain() closes the channel of queued watchers
+		if !w.queued {
+			close(w.entries)
+		}
+	}()
+
+	return w.entries, nil
+}
+
+func (f *FakeNatty) CreateBucket(_ context.Context, bucket string, _ time.Duration, _ ...string) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if _, ok := f.buckets[bucket]; ok {
+		return nats.ErrStreamNameAlreadyInUse
+	}
+
+	f.getBucket(bucket)
+
+	return nil
+}
+
+func (f *FakeNatty) DeleteBucket(_ context.Context, bucket string) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	delete(f.buckets, bucket)
+
+	return nil
+}
+
+// getBucket returns (and creates if necessary) a bucket; caller must hold the
+// write lock.
+func (f *FakeNatty) getBucket(bucket string) *fakeBucket {
+	b, ok := f.buckets[bucket]
+	if !ok {
+		b = &fakeBucket{entries: make(map[string]*natty.KVEntry)}
+		f.buckets[bucket] = b
+	}
+
+	return b
+}
+
+// put stores a new revision of key; caller must hold the write lock.
+func (f *FakeNatty) put(b *fakeBucket, bucket, key string, data []byte) {
+	b.revision++
+
+	b.entries[key] = &natty.KVEntry{
+		Bucket:    bucket,
+		Key:       key,
+		Value:     append([]byte(nil), data...),
+		Revision:  b.revision,
+		Created:   time.Now().UTC(),
+		Operation: nats.KeyValuePut,
+	}
+
+	f.notify(b.entries[key])
+}
+
+// notify sends entry to all matching watchers; caller must hold the write lock.
+func (f *FakeNatty) notify(entry *natty.KVEntry) {
+	for w := range f.watchers {
+		if w.bucket == entry.Bucket && matchKey(w.key, entry.Key) {
+			w.send(entry)
+		}
+	}
+}
+
+func (w *fakeWatcher) send(entry *natty.KVEntry) {
+	e := *entry
+	e.Value = append([]byte(nil), entry.Value...)
+
+	if w.queued {
+		w.queueMutex.Lock()
+		w.queue = append(w.queue, &e)
+		w.queueMutex.Unlock()
+
+		select {
+		case w.wake <- struct{}{}:
+		default:
+		}
+
+		return
+	}
+
+	select {
+	case w.entries <- &e:
+	default:
+	}
+}
+
+// drain sends queued entries to the entries channel (in order) until ctx is
+// done; the channel is closed on return.
+func (w *fakeWatcher) drain(ctx context.Context) {
+	defer close(w.entries)
+
+	for {
+		w.queueMutex.Lock()
+
+		if len(w.queue) == 0 {
+			w.queueMutex.Unlock()
+
+			select {
+			case <-w.wake:
+				continue
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		entry := w.queue[0]
+		w.queue = w.queue[1:]
+		w.queueMutex.Unlock()
+
+		select {
+		case w.entries <- entry:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// matchKey reports whether key matches pattern using NATS subject wildcard
+// rules ('*' matches a single token, '>' matches one or more tokens).
+func matchKey(pattern, key string) bool {
+	pTokens := strings.Split(pattern, ".")
+	kTokens := strings.Split(key, ".")
+
+	for i, p := range pTokens {
+		if p == ">" {
+			return len(kTokens) > i
+		}
+
+		if i >= len(kTokens) || (p != "*" && p != kTokens[i]) {
+			return false
+		}
+	}
+
+	return len(pTokens) == len(kTokens)
+}
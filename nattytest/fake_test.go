@@ -0,0 +1,88 @@
+package nattytest
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/batchcorp/natty"
+)
+
+func TestFakeNattyKVContract(t *testing.T) {
+	RunKVContractTests(t, func() natty.INatty {
+		return NewFakeNatty()
+	})
+}
+
+func TestFakeNattyWatchBucket(t *testing.T) {
+	f := NewFakeNatty()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	for _, key := range []string{"a", "b"} {
+		if err := f.Put(ctx, "bucket", key, []byte(key)); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	entries, err := f.WatchBucket(ctx, "bucket")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for _, want := range []string{"a", "b"} {
+		if entry := <-entries; entry == nil || entry.Key != want {
+			t.Fatalf("expected entry for key '%s', got %+v", want, entry)
+		}
+	}
+
+	if entry := <-entries; entry != nil {
+		t.Fatalf("expected nil sentinel, got %+v", entry)
+	}
+
+	if err := f.Put(ctx, "bucket", "c", []byte("c")); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if entry := <-entries; entry == nil || entry.Key != "c" {
+		t.Fatalf("expected entry for key 'c', got %+v", entry)
+	}
+}
+
+func TestFakeNattyWatchWithBackpressure(t *testing.T) {
+	f := NewFakeNatty()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := f.CreateBucket(ctx, "bucket", 0); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	entries, err := f.WatchWithBackpressure(ctx, "bucket", "*")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// More than a Watch() would buffer; nothing should be dropped
+	total := natty.WatchBufferSize * 2
+
+	for i := 0; i < total; i++ {
+		if err := f.Put(ctx, "bucket", strconv.Itoa(i), []byte("value")); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	for i := 0; i < total; i++ {
+		if entry := <-entries; entry == nil || entry.Key != strconv.Itoa(i) {
+			t.Fatalf("expected entry for key '%d', got %+v", i, entry)
+		}
+	}
+
+	cancel()
+
+	if _, ok := <-entries; ok {
+		t.Fatal("expected channel to be closed")
+	}
+}
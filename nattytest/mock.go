@@ -0,0 +1,1049 @@
+package nattytest
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/batchcorp/natty"
+)
+
+var (
+	_ natty.Client = &MockClient{}
+)
+
+// Call is a single method call recorded by MockClient
+type Call struct {
+	Method string
+	Args   []interface{}
+}
+
+// MockClient is a natty.Client that records every call and returns results
+// from the configurable <Method>Func fields. If a <Method>Func is nil, zero
+// values are returned (ie. nil error).
+//
+//	m := nattytest.NewMockClient()
+//	m.GetFunc = func(ctx context.Context, bucket, key string) ([]byte, error) {
+//		return []byte("value"), nil
+//	}
+//
+//	// ... exercise code under test ...
+//
+//	calls := m.CallsTo("Get")
+type MockClient struct {
+	ConsumeFunc                   func(ctx context.Context, cfg *natty.ConsumerConfig, cb func(ctx context.Context, msg *nats.Msg) error) error
+	SubscribeWithHeaderFilterFunc func(ctx context.Context, stream, subject, durable string, filterHeader, filterValue string, handler func(*nats.Msg) error) (func() error, error)
+	SubscribeFunc                 func(ctx context.Context, stream, subject string, opts natty.SubOptions, handler func(*nats.Msg) error) (func() error, error)
+	FetchWithOptionsFunc          func(ctx context.Context, stream, consumer string, opts natty.FetchOptions) ([]*nats.Msg, error)
+	InProgressFunc                func(msg *nats.Msg) error
+	AutoInProgressFunc            func(ctx context.Context, msg *nats.Msg, interval time.Duration) func()
+	PublishFunc                   func(ctx context.Context, subject string, data []byte)
+	TryPublishFunc                func(ctx context.Context, subject string, data []byte) error
+	PublishWithRetryFunc          func(ctx context.Context, subject string, data []byte, retryPolicy natty.RetryPolicy) (*nats.PubAck, error)
+	PublishAsyncBatchFunc         func(ctx context.Context, messages []*nats.Msg) ([]natty.PublishResult, error)
+	DeletePublisherFunc           func(ctx context.Context, id string) bool
+	CreateStreamFunc              func(ctx context.Context, name string, subjects []string) error
+	CreateStreamWithConfigFunc    func(ctx context.Context, cfg *nats.StreamConfig) (*nats.StreamInfo, error)
+	CreateMirrorStreamFunc        func(ctx context.Context, sourceName string, cfg *nats.StreamConfig) (*nats.StreamInfo, error)
+	DeleteStreamFunc              func(ctx context.Context, name string) error
+	PurgeStreamSubjectFunc        func(ctx context.Context, stream, subject string) error
+	PurgeStreamBeforeFunc         func(ctx context.Context, stream string, seq uint64) error
+	SnapshotStreamFunc            func(ctx context.Context, name string, w io.Writer) error
+	RestoreStreamFunc             func(ctx context.Context, cfg *nats.StreamConfig, r io.Reader) (*nats.StreamInfo, error)
+	CreateConsumerFunc            func(ctx context.Context, streamName, consumerName string, filterSubject ...string) error
+	UpdateConsumerFunc            func(ctx context.Context, stream string, cfg *nats.ConsumerConfig) (*nats.ConsumerInfo, error)
+	DeleteConsumerFunc            func(ctx context.Context, consumerName, streamName string) error
+	ListStreamsFunc               func(ctx context.Context) ([]*nats.StreamInfo, error)
+	ListConsumersFunc             func(ctx context.Context, stream string) ([]*nats.ConsumerInfo, error)
+	AccountInfoFunc               func(ctx context.Context) (*nats.AccountInfo, error)
+	WaitForStreamFunc             func(ctx context.Context, name string, pollInterval time.Duration) error
+	GetFunc                       func(ctx context.Context, bucket string, key string) ([]byte, error)
+	GetEntryFunc                  func(ctx context.Context, bucket string, key string) (*natty.KVEntry, error)
+	GetLatestEntryFunc            func(ctx context.Context, bucket string, key string) (*natty.KVEntry, error)
+	ExistsFunc                    func(ctx context.Context, bucket, key string) (bool, error)
+	BucketExistsFunc              func(ctx context.Context, bucket string) (bool, error)
+	GetIfNewerFunc                func(ctx context.Context, bucket, key string, sinceRevision uint64) ([]byte, uint64, error)
+	GetIfModifiedSinceFunc        func(ctx context.Context, bucket, key string, since time.Time) ([]byte, bool, error)
+	GetWithTTLHintFunc            func(ctx context.Context, bucket, key string) ([]byte, time.Duration, error)
+	CompareRevisionsFunc          func(ctx context.Context, bucket, key1, key2 string) (same bool, rev1, rev2 uint64, err error)
+	CreateFunc                    func(ctx context.Context, bucket string, key string, data []byte, keyTTL ...time.Duration) error
+	GetOrCreateFunc               func(ctx context.Context, bucket, key string, ttl time.Duration, factory func() ([]byte, error)) ([]byte, bool, error)
+	PutFunc                       func(ctx context.Context, bucket string, key string, data []byte, ttl ...time.Duration) error
+	BatchPutWithTTLFunc           func(ctx context.Context, bucket string, entries map[string][]byte, ttl time.Duration) error
+	GetJSONFunc                   func(ctx context.Context, bucket, key string, out interface{}) error
+	PutJSONFunc                   func(ctx context.Context, bucket, key string, v interface{}, ttl ...time.Duration) error
+	DeleteFunc                    func(ctx context.Context, bucket string, key string) error
+	DeletePrefixFunc              func(ctx context.Context, bucket, prefix string) (int, error)
+	DeleteByPatternFunc           func(ctx context.Context, bucket, keyPattern string) (int, error)
+	CopyKeyFunc                   func(ctx context.Context, srcBucket, srcKey, dstBucket, dstKey string) error
+	CreateBucketFunc              func(ctx context.Context, bucket string, ttl time.Duration, description ...string) error
+	CreateBucketWithConfigFunc    func(ctx context.Context, cfg *nats.KeyValueConfig) (nats.KeyValue, error)
+	UpdateBucketConfigFunc        func(ctx context.Context, bucket string, cfg *nats.KeyValueConfig) error
+	DeleteBucketFunc              func(ctx context.Context, bucket string) error
+	IncrementFunc                 func(ctx context.Context, bucket, key string, delta int64) (int64, error)
+	DecrementFunc                 func(ctx context.Context, bucket, key string, delta int64) (int64, error)
+	MergeFunc                     func(ctx context.Context, bucket, key string, values [][]byte, resolver func(values [][]byte) []byte) error
+	TemporaryBucketFunc           func(ctx context.Context, fn func(bucket string) error) error
+	TemporaryKeyFunc              func(ctx context.Context, bucket string, fn func(key string) error) error
+	KeysFunc                      func(ctx context.Context, bucket string) ([]string, error)
+	KeysStreamFunc                func(ctx context.Context, bucket string) (<-chan string, <-chan error)
+	WatchFunc                     func(ctx context.Context, bucket, key string) (<-chan *natty.KVEntry, error)
+	WatchBucketFunc               func(ctx context.Context, bucket string) (<-chan *natty.KVEntry, error)
+	WatchWithBackpressureFunc     func(ctx context.Context, bucket, key string) (<-chan *natty.KVEntry, error)
+	WatchCheckpointedFunc         func(ctx context.Context, bucket, key, checkpointKey string, ch chan<- *natty.KVEntry) error
+	CompactHistoryFunc            func(ctx context.Context, bucket, key string, keepRevisions int) error
+	BucketChecksumFunc            func(ctx context.Context, bucket string) ([]byte, error)
+	SnapshotFunc                  func(ctx context.Context, bucket string) (*natty.KVSnapshot, error)
+	ReconcileFunc                 func(ctx context.Context, bucket string, desired map[string][]byte) (natty.ReconcileResult, error)
+	HydrateFunc                   func(ctx context.Context, bucket string, data map[string][]byte) error
+	IsHydratedFunc                func(ctx context.Context, bucket string) (bool, error)
+	ExportToCSVFunc               func(ctx context.Context, bucket string, w io.Writer) error
+	ImportFromCSVFunc             func(ctx context.Context, bucket string, r io.Reader) error
+	PutObjectFunc                 func(ctx context.Context, bucket, name string, r io.Reader) (*nats.ObjectInfo, error)
+	GetObjectFunc                 func(ctx context.Context, bucket, name string) (io.ReadCloser, error)
+	DeleteObjectFunc              func(ctx context.Context, bucket, name string) error
+	ListObjectsFunc               func(ctx context.Context, bucket string) ([]*nats.ObjectInfo, error)
+	LockFunc                      func(ctx context.Context, bucket, lockKey string, ttl time.Duration) (func() error, error)
+	AsLeaderFunc                  func(ctx context.Context, opts *natty.AsLeaderConfig, f func() error) error
+	DrainFunc                     func(ctx context.Context) error
+	FlushFunc                     func(ctx context.Context) error
+	GracefulRestartFunc           func(ctx context.Context) error
+	CloseFunc                     func() error
+	IsConnectedFunc               func() bool
+	StatusFunc                    func() nats.Status
+	JetStreamFunc                 func() nats.JetStreamContext
+	ConnFunc                      func() *nats.Conn
+	ServerInfoFunc                func(ctx context.Context) (*natty.ServerInfo, error)
+	ConnectedServerNameFunc       func() string
+	ConnectedClusterNameFunc      func() string
+	HealthcheckFunc               func(ctx context.Context) error
+	PingFunc                      func(ctx context.Context) error
+
+	// Zero value is usable; a MockClient must not be copied after first use
+	mutex sync.Mutex
+	calls []Call
+}
+
+// NewMockClient returns a MockClient with no configured results; the zero
+// value (ie. &MockClient{}) is equivalent.
+func NewMockClient() *MockClient {
+	return &MockClient{
+		calls: make([]Call, 0),
+	}
+}
+
+// Calls returns all recorded calls in the order they were made
+func (m *MockClient) Calls() []Call {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	calls := make([]Call, len(m.calls))
+	copy(calls, m.calls)
+
+	return calls
+}
+
+// CallsTo returns all recorded calls to the given method
+func (m *MockClient) CallsTo(method string) []Call {
+	calls := make([]Call, 0)
+
+	for _, c := range m.Calls() {
+		if c.Method == method {
+			calls = append(calls, c)
+		}
+	}
+
+	return calls
+}
+
+// Reset clears all recorded calls
+func (m *MockClient) Reset() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.calls = make([]Call, 0)
+}
+
+func (m *MockClient) record(method string, args ...interface{}) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.calls = append(m.calls, Call{Method: method, Args: args})
+}
+
+func (m *MockClient) Consume(ctx context.Context, cfg *natty.ConsumerConfig, cb func(ctx context.Context, msg *nats.Msg) error) error {
+	m.record("Consume", ctx, cfg, cb)
+
+	if m.ConsumeFunc != nil {
+		return m.ConsumeFunc(ctx, cfg, cb)
+	}
+
+	return nil
+}
+
+func (m *MockClient) SubscribeWithHeaderFilter(ctx context.Context, stream, subject, durable string, filterHeader, filterValue string, handler func(*nats.Msg) error) (func() error, error) {
+	m.record("SubscribeWithHeaderFilter", ctx, stream, subject, durable, filterHeader, filterValue, handler)
+
+	if m.SubscribeWithHeaderFilterFunc != nil {
+		return m.SubscribeWithHeaderFilterFunc(ctx, stream, subject, durable, filterHeader, filterValue, handler)
+	}
+
+	return nil, nil
+}
+
+func (m *MockClient) Subscribe(ctx context.Context, stream, subject string, opts natty.SubOptions, handler func(*nats.Msg) error) (func() error, error) {
+	m.record("Subscribe", ctx, stream, subject, opts, handler)
+
+	if m.SubscribeFunc != nil {
+		return m.SubscribeFunc(ctx, stream, subject, opts, handler)
+	}
+
+	return nil, nil
+}
+
+func (m *MockClient) FetchWithOptions(ctx context.Context, stream, consumer string, opts natty.FetchOptions) ([]*nats.Msg, error) {
+	m.record("FetchWithOptions", ctx, stream, consumer, opts)
+
+	if m.FetchWithOptionsFunc != nil {
+		return m.FetchWithOptionsFunc(ctx, stream, consumer, opts)
+	}
+
+	return nil, nil
+}
+
+func (m *MockClient) InProgress(msg *nats.Msg) error {
+	m.record("InProgress", msg)
+
+	if m.InProgressFunc != nil {
+		return m.InProgressFunc(msg)
+	}
+
+	return nil
+}
+
+func (m *MockClient) AutoInProgress(ctx context.Context, msg *nats.Msg, interval time.Duration) func() {
+	m.record("AutoInProgress", ctx, msg, interval)
+
+	if m.AutoInProgressFunc != nil {
+		return m.AutoInProgressFunc(ctx, msg, interval)
+	}
+
+	return func() {}
+}
+
+func (m *MockClient) Publish(ctx context.Context, subject string, data []byte) {
+	m.record("Publish", ctx, subject, data)
+
+	if m.PublishFunc != nil {
+		m.PublishFunc(ctx, subject, data)
+	}
+}
+
+func (m *MockClient) TryPublish(ctx context.Context, subject string, data []byte) error {
+	m.record("TryPublish", ctx, subject, data)
+
+	if m.TryPublishFunc != nil {
+		return m.TryPublishFunc(ctx, subject, data)
+	}
+
+	return nil
+}
+
+func (m *MockClient) PublishWithRetry(ctx context.Context, subject string, data []byte, retryPolicy natty.RetryPolicy) (*nats.PubAck, error) {
+	m.record("PublishWithRetry", ctx, subject, data, retryPolicy)
+
+	if m.PublishWithRetryFunc != nil {
+		return m.PublishWithRetryFunc(ctx, subject, data, retryPolicy)
+	}
+
+	return nil, nil
+}
+
+func (m *MockClient) PublishAsyncBatch(ctx context.Context, messages []*nats.Msg) ([]natty.PublishResult, error) {
+	m.record("PublishAsyncBatch", ctx, messages)
+
+	if m.PublishAsyncBatchFunc != nil {
+		return m.PublishAsyncBatchFunc(ctx, messages)
+	}
+
+	return nil, nil
+}
+
+func (m *MockClient) DeletePublisher(ctx context.Context, id string) bool {
+	m.record("DeletePublisher", ctx, id)
+
+	if m.DeletePublisherFunc != nil {
+		return m.DeletePublisherFunc(ctx, id)
+	}
+
+	return false
+}
+
+func (m *MockClient) CreateStream(ctx context.Context, name string, subjects []string) error {
+	m.record("CreateStream", ctx, name, subjects)
+
+	if m.CreateStreamFunc != nil {
+		return m.CreateStreamFunc(ctx, name, subjects)
+	}
+
+	return nil
+}
+
+func (m *MockClient) CreateStreamWithConfig(ctx context.Context, cfg *nats.StreamConfig) (*nats.StreamInfo, error) {
+	m.record("CreateStreamWithConfig", ctx, cfg)
+
+	if m.CreateStreamWithConfigFunc != nil {
+		return m.CreateStreamWithConfigFunc(ctx, cfg)
+	}
+
+	return nil, nil
+}
+
+func (m *MockClient) CreateMirrorStream(ctx context.Context, sourceName string, cfg *nats.StreamConfig) (*nats.StreamInfo, error) {
+	m.record("CreateMirrorStream", ctx, sourceName, cfg)
+
+	if m.CreateMirrorStreamFunc != nil {
+		return m.CreateMirrorStreamFunc(ctx, sourceName, cfg)
+	}
+
+	return nil, nil
+}
+
+func (m *MockClient) DeleteStream(ctx context.Context, name string) error {
+	m.record("DeleteStream", ctx, name)
+
+	if m.DeleteStreamFunc != nil {
+		return m.DeleteStreamFunc(ctx, name)
+	}
+
+	return nil
+}
+
+func (m *MockClient) PurgeStreamSubject(ctx context.Context, stream, subject string) error {
+	m.record("PurgeStreamSubject", ctx, stream, subject)
+
+	if m.PurgeStreamSubjectFunc != nil {
+		return m.PurgeStreamSubjectFunc(ctx, stream, subject)
+	}
+
+	return nil
+}
+
+func (m *MockClient) PurgeStreamBefore(ctx context.Context, stream string, seq uint64) error {
+	m.record("PurgeStreamBefore", ctx, stream, seq)
+
+	if m.PurgeStreamBeforeFunc != nil {
+		return m.PurgeStreamBeforeFunc(ctx, stream, seq)
+	}
+
+	return nil
+}
+
+func (m *MockClient) SnapshotStream(ctx context.Context, name string, w io.Writer) error {
+	m.record("SnapshotStream", ctx, name, w)
+
+	if m.SnapshotStreamFunc != nil {
+		return m.SnapshotStreamFunc(ctx, name, w)
+	}
+
+	return nil
+}
+
+func (m *MockClient) RestoreStream(ctx context.Context, cfg *nats.StreamConfig, r io.Reader) (*nats.StreamInfo, error) {
+	m.record("RestoreStream", ctx, cfg, r)
+
+	if m.RestoreStreamFunc != nil {
+		return m.RestoreStreamFunc(ctx, cfg, r)
+	}
+
+	return nil, nil
+}
+
+func (m *MockClient) CreateConsumer(ctx context.Context, streamName, consumerName string, filterSubject ...string) error {
+	m.record("CreateConsumer", ctx, streamName, consumerName, filterSubject)
+
+	if m.CreateConsumerFunc != nil {
+		return m.CreateConsumerFunc(ctx, streamName, consumerName, filterSubject...)
+	}
+
+	return nil
+}
+
+func (m *MockClient) UpdateConsumer(ctx context.Context, stream string, cfg *nats.ConsumerConfig) (*nats.ConsumerInfo, error) {
+	m.record("UpdateConsumer", ctx, stream, cfg)
+
+	if m.UpdateConsumerFunc != nil {
+		return m.UpdateConsumerFunc(ctx, stream, cfg)
+	}
+
+	return nil, nil
+}
+
+func (m *MockClient) DeleteConsumer(ctx context.Context, consumerName, streamName string) error {
+	m.record("DeleteConsumer", ctx, consumerName, streamName)
+
+	if m.DeleteConsumerFunc != nil {
+		return m.DeleteConsumerFunc(ctx, consumerName, streamName)
+	}
+
+	return nil
+}
+
+func (m *MockClient) ListStreams(ctx context.Context) ([]*nats.StreamInfo, error) {
+	m.record("ListStreams", ctx)
+
+	if m.ListStreamsFunc != nil {
+		return m.ListStreamsFunc(ctx)
+	}
+
+	return nil, nil
+}
+
+func (m *MockClient) ListConsumers(ctx context.Context, stream string) ([]*nats.ConsumerInfo, error) {
+	m.record("ListConsumers", ctx, stream)
+
+	if m.ListConsumersFunc != nil {
+		return m.ListConsumersFunc(ctx, stream)
+	}
+
+	return nil, nil
+}
+
+func (m *MockClient) AccountInfo(ctx context.Context) (*nats.AccountInfo, error) {
+	m.record("AccountInfo", ctx)
+
+	if m.AccountInfoFunc != nil {
+		return m.AccountInfoFunc(ctx)
+	}
+
+	return nil, nil
+}
+
+func (m *MockClient) WaitForStream(ctx context.Context, name string, pollInterval time.Duration) error {
+	m.record("WaitForStream", ctx, name, pollInterval)
+
+	if m.WaitForStreamFunc != nil {
+		return m.WaitForStreamFunc(ctx, name, pollInterval)
+	}
+
+	return nil
+}
+
+func (m *MockClient) Get(ctx context.Context, bucket string, key string) ([]byte, error) {
+	m.record("Get", ctx, bucket, key)
+
+	if m.GetFunc != nil {
+		return m.GetFunc(ctx, bucket, key)
+	}
+
+	return nil, nil
+}
+
+func (m *MockClient) GetEntry(ctx context.Context, bucket string, key string) (*natty.KVEntry, error) {
+	m.record("GetEntry", ctx, bucket, key)
+
+	if m.GetEntryFunc != nil {
+		return m.GetEntryFunc(ctx, bucket, key)
+	}
+
+	return nil, nil
+}
+
+func (m *MockClient) GetLatestEntry(ctx context.Context, bucket string, key string) (*natty.KVEntry, error) {
+	m.record("GetLatestEntry", ctx, bucket, key)
+
+	if m.GetLatestEntryFunc != nil {
+		return m.GetLatestEntryFunc(ctx, bucket, key)
+	}
+
+	return nil, nil
+}
+
+func (m *MockClient) Exists(ctx context.Context, bucket, key string) (bool, error) {
+	m.record("Exists", ctx, bucket, key)
+
+	if m.ExistsFunc != nil {
+		return m.ExistsFunc(ctx, bucket, key)
+	}
+
+	return false, nil
+}
+
+func (m *MockClient) BucketExists(ctx context.Context, bucket string) (bool, error) {
+	m.record("BucketExists", ctx, bucket)
+
+	if m.BucketExistsFunc != nil {
+		return m.BucketExistsFunc(ctx, bucket)
+	}
+
+	return false, nil
+}
+
+func (m *MockClient) GetIfNewer(ctx context.Context, bucket, key string, sinceRevision uint64) ([]byte, uint64, error) {
+	m.record("GetIfNewer", ctx, bucket, key, sinceRevision)
+
+	if m.GetIfNewerFunc != nil {
+		return m.GetIfNewerFunc(ctx, bucket, key, sinceRevision)
+	}
+
+	return nil, 0, nil
+}
+
+func (m *MockClient) GetIfModifiedSince(ctx context.Context, bucket, key string, since time.Time) ([]byte, bool, error) {
+	m.record("GetIfModifiedSince", ctx, bucket, key, since)
+
+	if m.GetIfModifiedSinceFunc != nil {
+		return m.GetIfModifiedSinceFunc(ctx, bucket, key, since)
+	}
+
+	return nil, false, nil
+}
+
+func (m *MockClient) GetWithTTLHint(ctx context.Context, bucket, key string) ([]byte, time.Duration, error) {
+	m.record("GetWithTTLHint", ctx, bucket, key)
+
+	if m.GetWithTTLHintFunc != nil {
+		return m.GetWithTTLHintFunc(ctx, bucket, key)
+	}
+
+	return nil, 0, nil
+}
+
+func (m *MockClient) CompareRevisions(ctx context.Context, bucket, key1, key2 string) (same bool, rev1, rev2 uint64, err error) {
+	m.record("CompareRevisions", ctx, bucket, key1, key2)
+
+	if m.CompareRevisionsFunc != nil {
+		return m.CompareRevisionsFunc(ctx, bucket, key1, key2)
+	}
+
+	return false, 0, 0, nil
+}
+
+func (m *MockClient) Create(ctx context.Context, bucket string, key string, data []byte, keyTTL ...time.Duration) error {
+	m.record("Create", ctx, bucket, key, data, keyTTL)
+
+	if m.CreateFunc != nil {
+		return m.CreateFunc(ctx, bucket, key, data, keyTTL...)
+	}
+
+	return nil
+}
+
+func (m *MockClient) GetOrCreate(ctx context.Context, bucket, key string, ttl time.Duration, factory func() ([]byte, error)) ([]byte, bool, error) {
+	m.record("GetOrCreate", ctx, bucket, key, ttl, factory)
+
+	if m.GetOrCreateFunc != nil {
+		return m.GetOrCreateFunc(ctx, bucket, key, ttl, factory)
+	}
+
+	return nil, false, nil
+}
+
+func (m *MockClient) Put(ctx context.Context, bucket string, key string, data []byte, ttl ...time.Duration) error {
+	m.record("Put", ctx, bucket, key, data, ttl)
+
+	if m.PutFunc != nil {
+		return m.PutFunc(ctx, bucket, key, data, ttl...)
+	}
+
+	return nil
+}
+
+func (m *MockClient) BatchPutWithTTL(ctx context.Context, bucket string, entries map[string][]byte, ttl time.Duration) error {
+	m.record("BatchPutWithTTL", ctx, bucket, entries, ttl)
+
+	if m.BatchPutWithTTLFunc != nil {
+		return m.BatchPutWithTTLFunc(ctx, bucket, entries, ttl)
+	}
+
+	return nil
+}
+
+func (m *MockClient) GetJSON(ctx context.Context, bucket, key string, out interface{}) error {
+	m.record("GetJSON", ctx, bucket, key, out)
+
+	if m.GetJSONFunc != nil {
+		return m.GetJSONFunc(ctx, bucket, key, out)
+	}
+
+	return nil
+}
+
+func (m *MockClient) PutJSON(ctx context.Context, bucket, key string, v interface{}, ttl ...time.Duration) error {
+	m.record("PutJSON", ctx, bucket, key, v, ttl)
+
+	if m.PutJSONFunc != nil {
+		return m.PutJSONFunc(ctx, bucket, key, v, ttl...)
+	}
+
+	return nil
+}
+
+func (m *MockClient) Delete(ctx context.Context, bucket string, key string) error {
+	m.record("Delete", ctx, bucket, key)
+
+	if m.DeleteFunc != nil {
+		return m.DeleteFunc(ctx, bucket, key)
+	}
+
+	return nil
+}
+
+func (m *MockClient) DeletePrefix(ctx context.Context, bucket, prefix string) (int, error) {
+	m.record("DeletePrefix", ctx, bucket, prefix)
+
+	if m.DeletePrefixFunc != nil {
+		return m.DeletePrefixFunc(ctx, bucket, prefix)
+	}
+
+	return 0, nil
+}
+
+func (m *MockClient) DeleteByPattern(ctx context.Context, bucket, keyPattern string) (int, error) {
+	m.record("DeleteByPattern", ctx, bucket, keyPattern)
+
+	if m.DeleteByPatternFunc != nil {
+		return m.DeleteByPatternFunc(ctx, bucket, keyPattern)
+	}
+
+	return 0, nil
+}
+
+func (m *MockClient) CopyKey(ctx context.Context, srcBucket, srcKey, dstBucket, dstKey string) error {
+	m.record("CopyKey", ctx, srcBucket, srcKey, dstBucket, dstKey)
+
+	if m.CopyKeyFunc != nil {
+		return m.CopyKeyFunc(ctx, srcBucket, srcKey, dstBucket, dstKey)
+	}
+
+	return nil
+}
+
+func (m *MockClient) CreateBucket(ctx context.Context, bucket string, ttl time.Duration, description ...string) error {
+	m.record("CreateBucket", ctx, bucket, ttl, description)
+
+	if m.CreateBucketFunc != nil {
+		return m.CreateBucketFunc(ctx, bucket, ttl, description...)
+	}
+
+	return nil
+}
+
+func (m *MockClient) CreateBucketWithConfig(ctx context.Context, cfg *nats.KeyValueConfig) (nats.KeyValue, error) {
+	m.record("CreateBucketWithConfig", ctx, cfg)
+
+	if m.CreateBucketWithConfigFunc != nil {
+		return m.CreateBucketWithConfigFunc(ctx, cfg)
+	}
+
+	return nil, nil
+}
+
+func (m *MockClient) UpdateBucketConfig(ctx context.Context, bucket string, cfg *nats.KeyValueConfig) error {
+	m.record("UpdateBucketConfig", ctx, bucket, cfg)
+
+	if m.UpdateBucketConfigFunc != nil {
+		return m.UpdateBucketConfigFunc(ctx, bucket, cfg)
+	}
+
+	return nil
+}
+
+func (m *MockClient) DeleteBucket(ctx context.Context, bucket string) error {
+	m.record("DeleteBucket", ctx, bucket)
+
+	if m.DeleteBucketFunc != nil {
+		return m.DeleteBucketFunc(ctx, bucket)
+	}
+
+	return nil
+}
+
+func (m *MockClient) Increment(ctx context.Context, bucket, key string, delta int64) (int64, error) {
+	m.record("Increment", ctx, bucket, key, delta)
+
+	if m.IncrementFunc != nil {
+		return m.IncrementFunc(ctx, bucket, key, delta)
+	}
+
+	return 0, nil
+}
+
+func (m *MockClient) Decrement(ctx context.Context, bucket, key string, delta int64) (int64, error) {
+	m.record("Decrement", ctx, bucket, key, delta)
+
+	if m.DecrementFunc != nil {
+		return m.DecrementFunc(ctx, bucket, key, delta)
+	}
+
+	return 0, nil
+}
+
+func (m *MockClient) Merge(ctx context.Context, bucket, key string, values [][]byte, resolver func(values [][]byte) []byte) error {
+	m.record("Merge", ctx, bucket, key, values, resolver)
+
+	if m.MergeFunc != nil {
+		return m.MergeFunc(ctx, bucket, key, values, resolver)
+	}
+
+	return nil
+}
+
+func (m *MockClient) TemporaryBucket(ctx context.Context, fn func(bucket string) error) error {
+	m.record("TemporaryBucket", ctx, fn)
+
+	if m.TemporaryBucketFunc != nil {
+		return m.TemporaryBucketFunc(ctx, fn)
+	}
+
+	return nil
+}
+
+func (m *MockClient) TemporaryKey(ctx context.Context, bucket string, fn func(key string) error) error {
+	m.record("TemporaryKey", ctx, bucket, fn)
+
+	if m.TemporaryKeyFunc != nil {
+		return m.TemporaryKeyFunc(ctx, bucket, fn)
+	}
+
+	return nil
+}
+
+func (m *MockClient) Keys(ctx context.Context, bucket string) ([]string, error) {
+	m.record("Keys", ctx, bucket)
+
+	if m.KeysFunc != nil {
+		return m.KeysFunc(ctx, bucket)
+	}
+
+	return nil, nil
+}
+
+func (m *MockClient) KeysStream(ctx context.Context, bucket string) (<-chan string, <-chan error) {
+	m.record("KeysStream", ctx, bucket)
+
+	if m.KeysStreamFunc != nil {
+		return m.KeysStreamFunc(ctx, bucket)
+	}
+
+	keys := make(chan string)
+	errs := make(chan error)
+
+	close(keys)
+	close(errs)
+
+	return keys, errs
+}
+
+func (m *MockClient) Watch(ctx context.Context, bucket, key string) (<-chan *natty.KVEntry, error) {
+	m.record("Watch", ctx, bucket, key)
+
+	if m.WatchFunc != nil {
+		return m.WatchFunc(ctx, bucket, key)
+	}
+
+	return nil, nil
+}
+
+func (m *MockClient) WatchBucket(ctx context.Context, bucket string) (<-chan *natty.KVEntry, error) {
+	m.record("WatchBucket", ctx, bucket)
+
+	if m.WatchBucketFunc != nil {
+		return m.WatchBucketFunc(ctx, bucket)
+	}
+
+	return nil, nil
+}
+
+func (m *MockClient) WatchWithBackpressure(ctx context.Context, bucket, key string) (<-chan *natty.KVEntry, error) {
+	m.record("WatchWithBackpressure", ctx, bucket, key)
+
+	if m.WatchWithBackpressureFunc != nil {
+		return m.WatchWithBackpressureFunc(ctx, bucket, key)
+	}
+
+	return nil, nil
+}
+
+func (m *MockClient) WatchCheckpointed(ctx context.Context, bucket, key, checkpointKey string, ch chan<- *natty.KVEntry) error {
+	m.record("WatchCheckpointed", ctx, bucket, key, checkpointKey, ch)
+
+	if m.WatchCheckpointedFunc != nil {
+		return m.WatchCheckpointedFunc(ctx, bucket, key, checkpointKey, ch)
+	}
+
+	return nil
+}
+
+func (m *MockClient) CompactHistory(ctx context.Context, bucket, key string, keepRevisions int) error {
+	m.record("CompactHistory", ctx, bucket, key, keepRevisions)
+
+	if m.CompactHistoryFunc != nil {
+		return m.CompactHistoryFunc(ctx, bucket, key, keepRevisions)
+	}
+
+	return nil
+}
+
+func (m *MockClient) BucketChecksum(ctx context.Context, bucket string) ([]byte, error) {
+	m.record("BucketChecksum", ctx, bucket)
+
+	if m.BucketChecksumFunc != nil {
+		return m.BucketChecksumFunc(ctx, bucket)
+	}
+
+	return nil, nil
+}
+
+func (m *MockClient) Snapshot(ctx context.Context, bucket string) (*natty.KVSnapshot, error) {
+	m.record("Snapshot", ctx, bucket)
+
+	if m.SnapshotFunc != nil {
+		return m.SnapshotFunc(ctx, bucket)
+	}
+
+	return nil, nil
+}
+
+func (m *MockClient) Reconcile(ctx context.Context, bucket string, desired map[string][]byte) (natty.ReconcileResult, error) {
+	m.record("Reconcile", ctx, bucket, desired)
+
+	if m.ReconcileFunc != nil {
+		return m.ReconcileFunc(ctx, bucket, desired)
+	}
+
+	return natty.ReconcileResult{}, nil
+}
+
+func (m *MockClient) Hydrate(ctx context.Context, bucket string, data map[string][]byte) error {
+	m.record("Hydrate", ctx, bucket, data)
+
+	if m.HydrateFunc != nil {
+		return m.HydrateFunc(ctx, bucket, data)
+	}
+
+	return nil
+}
+
+func (m *MockClient) IsHydrated(ctx context.Context, bucket string) (bool, error) {
+	m.record("IsHydrated", ctx, bucket)
+
+	if m.IsHydratedFunc != nil {
+		return m.IsHydratedFunc(ctx, bucket)
+	}
+
+	return false, nil
+}
+
+func (m *MockClient) ExportToCSV(ctx context.Context, bucket string, w io.Writer) error {
+	m.record("ExportToCSV", ctx, bucket, w)
+
+	if m.ExportToCSVFunc != nil {
+		return m.ExportToCSVFunc(ctx, bucket, w)
+	}
+
+	return nil
+}
+
+func (m *MockClient) ImportFromCSV(ctx context.Context, bucket string, r io.Reader) error {
+	m.record("ImportFromCSV", ctx, bucket, r)
+
+	if m.ImportFromCSVFunc != nil {
+		return m.ImportFromCSVFunc(ctx, bucket, r)
+	}
+
+	return nil
+}
+
+func (m *MockClient) PutObject(ctx context.Context, bucket, name string, r io.Reader) (*nats.ObjectInfo, error) {
+	m.record("PutObject", ctx, bucket, name, r)
+
+	if m.PutObjectFunc != nil {
+		return m.PutObjectFunc(ctx, bucket, name, r)
+	}
+
+	return nil, nil
+}
+
+func (m *MockClient) GetObject(ctx context.Context, bucket, name string) (io.ReadCloser, error) {
+	m.record("GetObject", ctx, bucket, name)
+
+	if m.GetObjectFunc != nil {
+		return m.GetObjectFunc(ctx, bucket, name)
+	}
+
+	return nil, nil
+}
+
+func (m *MockClient) DeleteObject(ctx context.Context, bucket, name string) error {
+	m.record("DeleteObject", ctx, bucket, name)
+
+	if m.DeleteObjectFunc != nil {
+		return m.DeleteObjectFunc(ctx, bucket, name)
+	}
+
+	return nil
+}
+
+func (m *MockClient) ListObjects(ctx context.Context, bucket string) ([]*nats.ObjectInfo, error) {
+	m.record("ListObjects", ctx, bucket)
+
+	if m.ListObjectsFunc != nil {
+		return m.ListObjectsFunc(ctx, bucket)
+	}
+
+	return nil, nil
+}
+
+func (m *MockClient) Lock(ctx context.Context, bucket, lockKey string, ttl time.Duration) (func() error, error) {
+	m.record("Lock", ctx, bucket, lockKey, ttl)
+
+	if m.LockFunc != nil {
+		return m.LockFunc(ctx, bucket, lockKey, ttl)
+	}
+
+	return nil, nil
+}
+
+func (m *MockClient) AsLeader(ctx context.Context, opts *natty.AsLeaderConfig, f func() error) error {
+	m.record("AsLeader", ctx, opts, f)
+
+	if m.AsLeaderFunc != nil {
+		return m.AsLeaderFunc(ctx, opts, f)
+	}
+
+	return nil
+}
+
+func (m *MockClient) Drain(ctx context.Context) error {
+	m.record("Drain", ctx)
+
+	if m.DrainFunc != nil {
+		return m.DrainFunc(ctx)
+	}
+
+	return nil
+}
+
+func (m *MockClient) Flush(ctx context.Context) error {
+	m.record("Flush", ctx)
+
+	if m.FlushFunc != nil {
+		return m.FlushFunc(ctx)
+	}
+
+	return nil
+}
+
+func (m *MockClient) GracefulRestart(ctx context.Context) error {
+	m.record("GracefulRestart", ctx)
+
+	if m.GracefulRestartFunc != nil {
+		return m.GracefulRestartFunc(ctx)
+	}
+
+	return nil
+}
+
+func (m *MockClient) Close() error {
+	m.record("Close")
+
+	if m.CloseFunc != nil {
+		return m.CloseFunc()
+	}
+
+	return nil
+}
+
+func (m *MockClient) IsConnected() bool {
+	m.record("IsConnected")
+
+	if m.IsConnectedFunc != nil {
+		return m.IsConnectedFunc()
+	}
+
+	return false
+}
+
+func (m *MockClient) Status() nats.Status {
+	m.record("Status")
+
+	if m.StatusFunc != nil {
+		return m.StatusFunc()
+	}
+
+	return nats.DISCONNECTED
+}
+
+func (m *MockClient) JetStream() nats.JetStreamContext {
+	m.record("JetStream")
+
+	if m.JetStreamFunc != nil {
+		return m.JetStreamFunc()
+	}
+
+	return nil
+}
+
+func (m *MockClient) Conn() *nats.Conn {
+	m.record("Conn")
+
+	if m.ConnFunc != nil {
+		return m.ConnFunc()
+	}
+
+	return nil
+}
+
+func (m *MockClient) ServerInfo(ctx context.Context) (*natty.ServerInfo, error) {
+	m.record("ServerInfo", ctx)
+
+	if m.ServerInfoFunc != nil {
+		return m.ServerInfoFunc(ctx)
+	}
+
+	return nil, nil
+}
+
+func (m *MockClient) ConnectedServerName() string {
+	m.record("ConnectedServerName")
+
+	if m.ConnectedServerNameFunc != nil {
+		return m.ConnectedServerNameFunc()
+	}
+
+	return ""
+}
+
+func (m *MockClient) ConnectedClusterName() string {
+	m.record("ConnectedClusterName")
+
+	if m.ConnectedClusterNameFunc != nil {
+		return m.ConnectedClusterNameFunc()
+	}
+
+	return ""
+}
+
+func (m *MockClient) Healthcheck(ctx context.Context) error {
+	m.record("Healthcheck", ctx)
+
+	if m.HealthcheckFunc != nil {
+		return m.HealthcheckFunc(ctx)
+	}
+
+	return nil
+}
+
+func (m *MockClient) Ping(ctx context.Context) error {
+	m.record("Ping", ctx)
+
+	if m.PingFunc != nil {
+		return m.PingFunc(ctx)
+	}
+
+	return nil
+}
@@ -0,0 +1,95 @@
+package nattytest
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/batchcorp/natty"
+)
+
+// getter is an example of code under test that depends on natty.Client
+func getter(c natty.Client, bucket, key string) (string, error) {
+	data, err := c.Get(context.Background(), bucket, key)
+	if err != nil {
+		return "", err
+	}
+
+	return string(data), nil
+}
+
+func TestMockClientRecordsCalls(t *testing.T) {
+	m := NewMockClient()
+	m.GetFunc = func(ctx context.Context, bucket, key string) ([]byte, error) {
+		return []byte("bar"), nil
+	}
+
+	value, err := getter(m, "bucket", "foo")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if value != "bar" {
+		t.Fatalf("expected 'bar', got '%s'", value)
+	}
+
+	calls := m.CallsTo("Get")
+	if len(calls) != 1 {
+		t.Fatalf("expected 1 call to Get, got %d", len(calls))
+	}
+
+	if !reflect.DeepEqual(calls[0].Args[1:], []interface{}{"bucket", "foo"}) {
+		t.Fatalf("unexpected args: %v", calls[0].Args)
+	}
+}
+
+func TestMockClientDefaults(t *testing.T) {
+	m := NewMockClient()
+
+	if err := m.Put(context.Background(), "bucket", "foo", []byte("bar")); err != nil {
+		t.Fatalf("expected nil error, got: %s", err)
+	}
+
+	if status := m.Status(); status != nats.DISCONNECTED {
+		t.Fatalf("expected DISCONNECTED, got: %s", status)
+	}
+
+	m.DeleteFunc = func(ctx context.Context, bucket, key string) error {
+		return errors.New("boom")
+	}
+
+	if err := m.Delete(context.Background(), "bucket", "foo"); err == nil {
+		t.Fatal("expected error from DeleteFunc")
+	}
+
+	if len(m.Calls()) != 3 {
+		t.Fatalf("expected 3 calls, got %d", len(m.Calls()))
+	}
+
+	m.Reset()
+
+	if len(m.Calls()) != 0 {
+		t.Fatalf("expected no calls after Reset, got %d", len(m.Calls()))
+	}
+}
+
+func TestMockClientZeroValue(t *testing.T) {
+	m := &MockClient{}
+
+	if _, err := m.Get(context.Background(), "bucket", "foo"); err != nil {
+		t.Fatalf("expected nil error, got: %s", err)
+	}
+
+	if len(m.Calls()) != 1 {
+		t.Fatalf("expected 1 call, got %d", len(m.Calls()))
+	}
+
+	m.Reset()
+
+	if len(m.Calls()) != 0 {
+		t.Fatalf("expected no calls after Reset, got %d", len(m.Calls()))
+	}
+}
@@ -0,0 +1,80 @@
+package nattytest
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+
+	"github.com/batchcorp/natty/internal/natsserver"
+)
+
+const (
+	// EnvServerURL is the env var used to point tests at a NATS server
+	EnvServerURL = "NATS_URL"
+
+	ServerConnectTimeout = 2 * time.Second
+)
+
+// ServerURL returns the URL of the NATS server (with JetStream enabled) that
+// tests should run against: $NATS_URL if set, nats.DefaultURL otherwise.
+// Calls t.Skip() if the server is unreachable or does not have JetStream
+// enabled so that `go test ./...` does not fail without infrastructure.
+//
+// Use StartEmbeddedServer() for tests that should not depend on an external
+// server at all.
+func ServerURL(t testing.TB, opts ...nats.Option) string {
+	t.Helper()
+
+	url := os.Getenv(EnvServerURL)
+	if url == "" {
+		url = nats.DefaultURL
+	}
+
+	nc, err := nats.Connect(url, append([]nats.Option{nats.Timeout(ServerConnectTimeout)}, opts...)...)
+	if err != nil {
+		t.Skipf("NATS server not available at '%s': %s", url, err)
+	}
+
+	defer nc.Close()
+
+	js, err := nc.JetStream()
+	if err != nil {
+		t.Skipf("unable to create JetStream context for '%s': %s", url, err)
+	}
+
+	if _, err := js.AccountInfo(); err != nil {
+		t.Skipf("JetStream not available at '%s': %s", url, err)
+	}
+
+	return url
+}
+
+// ServerOption modifies the options of the server started by
+// StartEmbeddedServer(); ie. to configure TLS or auth
+type ServerOption func(*server.Options)
+
+// StartEmbeddedServer starts an in-process NATS server with JetStream enabled
+// (set server.Options.JetStream to false to disable it) on a random port and
+// returns its URL. The server and its JetStream data are removed once t (and
+// its subtests) complete; fails t if the server cannot be started.
+func StartEmbeddedServer(t testing.TB, opts ...ServerOption) string {
+	t.Helper()
+
+	serverOpts := make([]natsserver.Option, 0, len(opts))
+
+	for _, opt := range opts {
+		serverOpts = append(serverOpts, natsserver.Option(opt))
+	}
+
+	s, stop, err := natsserver.Start(serverOpts...)
+	if err != nil {
+		t.Fatalf("unable to start embedded NATS server: %s", err)
+	}
+
+	t.Cleanup(stop)
+
+	return s.ClientURL()
+}
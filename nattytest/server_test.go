@@ -0,0 +1,83 @@
+package nattytest
+
+import (
+	"os"
+	"testing"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+)
+
+func TestServerURLSkipsWhenUnavailable(t *testing.T) {
+	orig, ok := os.LookupEnv(EnvServerURL)
+
+	defer func() {
+		if ok {
+			os.Setenv(EnvServerURL, orig)
+		} else {
+			os.Unsetenv(EnvServerURL)
+		}
+	}()
+
+	// Nothing should be listening on port 1
+	os.Setenv(EnvServerURL, "nats://127.0.0.1:1")
+
+	skipped := true
+
+	t.Run("unavailable", func(t *testing.T) {
+		ServerURL(t)
+		skipped = false
+	})
+
+	if !skipped {
+		t.Fatal("expected ServerURL to skip the test")
+	}
+}
+
+func TestStartEmbeddedServer(t *testing.T) {
+	url := StartEmbeddedServer(t)
+
+	nc, err := nats.Connect(url)
+	if err != nil {
+		t.Fatalf("unable to connect to embedded server: %s", err)
+	}
+
+	defer nc.Close()
+
+	js, err := nc.JetStream()
+	if err != nil {
+		t.Fatalf("unable to create JetStream context: %s", err)
+	}
+
+	if _, err := js.AccountInfo(); err != nil {
+		t.Fatalf("expected JetStream to be enabled: %s", err)
+	}
+}
+
+func TestStartEmbeddedServerWithOptions(t *testing.T) {
+	url := StartEmbeddedServer(t, func(opts *server.Options) {
+		opts.JetStream = false
+		opts.Username = "natty"
+		opts.Password = "natty"
+	})
+
+	if _, err := nats.Connect(url); err == nil {
+		t.Fatal("expected connecting without credentials to fail")
+	}
+
+	nc, err := nats.Connect(url, nats.UserInfo("natty", "natty"))
+	if err != nil {
+		t.Fatalf("unable to connect to embedded server: %s", err)
+	}
+
+	defer nc.Close()
+
+	js, err := nc.JetStream()
+	if err != nil {
+		t.Fatalf("unable to create JetStream context: %s", err)
+	}
+
+	if _, err := js.AccountInfo(); err == nil {
+		t.Fatal("expected JetStream to be disabled")
+	}
+}
@@ -0,0 +1,151 @@
+package natty
+
+import (
+	"context"
+	"io"
+
+	"github.com/nats-io/nats.go"
+	"github.com/pkg/errors"
+)
+
+// PutObject stores the contents of r as object name in bucket; the object
+// store bucket is created if it does not exist. Suitable for payloads that
+// exceed the max message size (objects are chunked by NATS).
+func (n *Natty) PutObject(ctx context.Context, bucket, name string, r io.Reader) (*nats.ObjectInfo, error) {
+	if n.isClosed() {
+		return nil, ErrConnectionClosed
+	}
+
+	ctx, release := n.guardKV(ctx)
+	defer release()
+
+	obs, err := n.getObjectStore(ctx, bucket, true)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := obs.Put(&nats.ObjectMeta{Name: name}, r, nats.Context(ctx))
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to put object")
+	}
+
+	return info, nil
+}
+
+// GetObject returns a reader for object name in bucket; the reader must be
+// closed by the caller. The reader is bound to the current connection, it
+// fails if GracefulRestart() replaces the connection before it is read. Returns nats.ErrObjectNotFound if the object or
+// bucket does not exist.
+func (n *Natty) GetObject(ctx context.Context, bucket, name string) (io.ReadCloser, error) {
+	if n.isClosed() {
+		return nil, ErrConnectionClosed
+	}
+
+	ctx, release := n.guardKV(ctx)
+	defer release()
+
+	obs, err := n.getObjectStore(ctx, bucket, false)
+	if err != nil {
+		if err == nats.ErrBucketNotFound {
+			return nil, nats.ErrObjectNotFound
+		}
+
+		return nil, err
+	}
+
+	result, err := obs.Get(name, nats.Context(ctx))
+	if err != nil {
+		if err == nats.ErrObjectNotFound {
+			return nil, nats.ErrObjectNotFound
+		}
+
+		return nil, errors.Wrap(err, "unable to get object")
+	}
+
+	return result, nil
+}
+
+// DeleteObject deletes object name from bucket; does not error if the bucket
+// does not exist.
+func (n *Natty) DeleteObject(ctx context.Context, bucket, name string) error {
+	if n.isClosed() {
+		return ErrConnectionClosed
+	}
+
+	ctx, release := n.guardKV(ctx)
+	defer release()
+
+	obs, err := n.getObjectStore(ctx, bucket, false)
+	if err != nil {
+		if err == nats.ErrBucketNotFound {
+			return nil
+		}
+
+		return err
+	}
+
+	if err := obs.Delete(name); err != nil {
+		if err == nats.ErrObjectNotFound {
+			return nats.ErrObjectNotFound
+		}
+
+		return errors.Wrap(err, "unable to delete object")
+	}
+
+	return nil
+}
+
+// ListObjects returns info for all (non-deleted) objects in bucket (empty
+// slice if none found).
+func (n *Natty) ListObjects(ctx context.Context, bucket string) ([]*nats.ObjectInfo, error) {
+	if n.isClosed() {
+		return nil, ErrConnectionClosed
+	}
+
+	ctx, release := n.guardKV(ctx)
+	defer release()
+
+	obs, err := n.getObjectStore(ctx, bucket, false)
+	if err != nil {
+		return nil, err
+	}
+
+	objects, err := obs.List(nats.Context(ctx))
+	if err != nil {
+		if err == nats.ErrNoObjectsFound {
+			return make([]*nats.ObjectInfo, 0), nil
+		}
+
+		return nil, errors.Wrap(err, "unable to list objects")
+	}
+
+	return objects, nil
+}
+
+// getObjectStore fetches an object store bucket, optionally creating it if it
+// does not exist; returns nats.ErrBucketNotFound if the bucket does not exist
+// and create is false.
+func (n *Natty) getObjectStore(_ context.Context, bucket string, create bool) (nats.ObjectStore, error) {
+	obs, err := n.getJS().ObjectStore(bucket)
+	if err == nil {
+		return obs, nil
+	}
+
+	if err != nats.ErrStreamNotFound {
+		return nil, errors.Wrap(err, "object store fetch error in getObjectStore()")
+	}
+
+	if !create {
+		return nil, nats.ErrBucketNotFound
+	}
+
+	obs, err = n.getJS().CreateObjectStore(&nats.ObjectStoreConfig{
+		Bucket:      bucket,
+		Description: "auto-created object store via natty",
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "object store create error in getObjectStore()")
+	}
+
+	return obs, nil
+}
@@ -0,0 +1,102 @@
+package natty
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"io/ioutil"
+	"math/rand"
+
+	"github.com/nats-io/nats.go"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	uuid "github.com/satori/go.uuid"
+)
+
+var _ = Describe("ObjectStore", func() {
+	var (
+		n      *Natty
+		bucket string
+	)
+
+	BeforeEach(func() {
+		var err error
+
+		n, err = New(NewConfig())
+		Expect(err).ToNot(HaveOccurred())
+
+		bucket = uuid.NewV4().String()
+	})
+
+	AfterEach(func() {
+		// Bucket may not exist if the test did not create it
+		_ = n.js.DeleteObjectStore(bucket)
+	})
+
+	Describe("PutObject/GetObject", func() {
+		It("should round trip a multi-megabyte payload", func() {
+			payload := make([]byte, 5*1024*1024)
+			rand.Read(payload)
+
+			info, err := n.PutObject(context.Background(), bucket, "blob", bytes.NewReader(payload))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(info.Name).To(Equal("blob"))
+			Expect(info.Size).To(Equal(uint64(len(payload))))
+
+			r, err := n.GetObject(context.Background(), bucket, "blob")
+			Expect(err).ToNot(HaveOccurred())
+
+			defer r.Close()
+
+			data, err := ioutil.ReadAll(r)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sha256.Sum256(data)).To(Equal(sha256.Sum256(payload)))
+		})
+
+		It("should return ErrObjectNotFound for a missing bucket or object", func() {
+			_, err := n.GetObject(context.Background(), bucket, "missing")
+			Expect(err).To(Equal(nats.ErrObjectNotFound))
+
+			_, err = n.PutObject(context.Background(), bucket, "blob", bytes.NewReader([]byte("foo")))
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = n.GetObject(context.Background(), bucket, "missing")
+			Expect(err).To(Equal(nats.ErrObjectNotFound))
+		})
+	})
+
+	Describe("ListObjects/DeleteObject", func() {
+		It("should list and delete objects", func() {
+			for _, name := range []string{"a", "b"} {
+				_, err := n.PutObject(context.Background(), bucket, name, bytes.NewReader([]byte(name)))
+				Expect(err).ToNot(HaveOccurred())
+			}
+
+			objects, err := n.ListObjects(context.Background(), bucket)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(objects).To(HaveLen(2))
+
+			Expect(n.DeleteObject(context.Background(), bucket, "a")).To(Succeed())
+
+			objects, err = n.ListObjects(context.Background(), bucket)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(objects).To(HaveLen(1))
+			Expect(objects[0].Name).To(Equal("b"))
+
+			Expect(n.DeleteObject(context.Background(), bucket, "b")).To(Succeed())
+
+			objects, err = n.ListObjects(context.Background(), bucket)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(objects).To(BeEmpty())
+		})
+
+		It("should not error when deleting from a missing bucket", func() {
+			Expect(n.DeleteObject(context.Background(), bucket, "missing")).To(Succeed())
+		})
+
+		It("should return ErrBucketNotFound when listing a missing bucket", func() {
+			_, err := n.ListObjects(context.Background(), bucket)
+			Expect(err).To(Equal(nats.ErrBucketNotFound))
+		})
+	})
+})
@@ -0,0 +1,174 @@
+package natty
+
+import (
+	"crypto/tls"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"golang.org/x/time/rate"
+)
+
+// Option configures a Config; options can be passed to New() (applied on top
+// of the given Config) or NewWithOptions() (applied on top of the defaults).
+type Option func(*Config)
+
+// NewWithOptions creates a new Natty instance from a default Config with
+// given options applied. Connects to nats.DefaultURL unless WithNatsURL() is
+// given; all other defaults are set the same way as for New().
+func NewWithOptions(opts ...Option) (*Natty, error) {
+	return New(&Config{NatsURL: []string{nats.DefaultURL}}, opts...)
+}
+
+// WithNatsURL sets the NATS URLs to connect to (replacing any existing URLs)
+func WithNatsURL(urls ...string) Option {
+	return func(cfg *Config) {
+		cfg.NatsURL = urls
+	}
+}
+
+// WithTLS enables TLS using the given TLS config
+func WithTLS(tlsConfig *tls.Config) Option {
+	return func(cfg *Config) {
+		cfg.UseTLS = true
+		cfg.TLSConfig = tlsConfig
+	}
+}
+
+// WithCredentials sets the chained credentials file (JWT + NKey seed)
+func WithCredentials(file string) Option {
+	return func(cfg *Config) {
+		cfg.CredsFile = file
+	}
+}
+
+// WithUserInfo sets the username and password used for authentication
+func WithUserInfo(username, password string) Option {
+	return func(cfg *Config) {
+		cfg.Username = username
+		cfg.Password = password
+	}
+}
+
+// WithToken sets the token used for authentication
+func WithToken(token string) Option {
+	return func(cfg *Config) {
+		cfg.Token = token
+	}
+}
+
+// WithReconnect sets the maximum number of reconnect attempts (-1 = forever,
+// 0 = never) and the base wait between attempts
+func WithReconnect(max int, wait time.Duration) Option {
+	return func(cfg *Config) {
+		if max == 0 {
+			max = NoReconnect
+		}
+
+		cfg.MaxReconnects = max
+		cfg.ReconnectWait = wait
+	}
+}
+
+// WithMetrics sets the metrics collector used to instrument KV operations
+func WithMetrics(m Metrics) Option {
+	return func(cfg *Config) {
+		cfg.WithMetrics(m)
+	}
+}
+
+// WithTracer sets the tracer used to instrument KV operations
+func WithTracer(t Tracer) Option {
+	return func(cfg *Config) {
+		cfg.WithTracer(t)
+	}
+}
+
+// WithBucketHooks sets the callbacks fired before and after KV operations
+func WithBucketHooks(h BucketHooks) Option {
+	return func(cfg *Config) {
+		cfg.WithBucketHooks(h)
+	}
+}
+
+// WithBucketTimeout sets the timeout for KV operations on a specific bucket
+func WithBucketTimeout(bucket string, timeout time.Duration) Option {
+	return func(cfg *Config) {
+		if cfg.BucketTimeouts == nil {
+			cfg.BucketTimeouts = make(map[string]time.Duration)
+		}
+
+		cfg.BucketTimeouts[bucket] = timeout
+	}
+}
+
+// WithBucketConfig sets the config used when auto-creating the bucket named
+// by cfg.Bucket (see Config.BucketConfigs)
+func WithBucketConfig(cfg *nats.KeyValueConfig) Option {
+	return func(c *Config) {
+		if c.BucketConfigs == nil {
+			c.BucketConfigs = make(map[string]*nats.KeyValueConfig)
+		}
+
+		c.BucketConfigs[cfg.Bucket] = cfg
+	}
+}
+
+// WithRetry sets the retry policy for KV operations
+func WithRetry(p RetryPolicy) Option {
+	return func(cfg *Config) {
+		cfg.Retry = p
+	}
+}
+
+// WithCircuitBreaker enables the circuit breaker for KV and publish calls
+func WithCircuitBreaker(cfg CircuitBreakerConfig) Option {
+	return func(c *Config) {
+		c.CircuitBreaker = cfg
+	}
+}
+
+// WithPublishRateLimit limits the rate (messages per second) and burst at which
+// Publish() accepts messages
+func WithPublishRateLimit(l rate.Limit, burst int) Option {
+	return func(cfg *Config) {
+		cfg.PublishRateLimit = l
+		cfg.PublishBurst = burst
+	}
+}
+
+// WithMaxMsgSize rejects published messages larger than size bytes; see
+// Config.MaxMsgSize
+func WithMaxMsgSize(size int32) Option {
+	return func(cfg *Config) {
+		cfg.MaxMsgSize = size
+	}
+}
+
+// WithNatsOptions appends arbitrary nats.Option's; see Config.NatsOptions
+func WithNatsOptions(opts ...nats.Option) Option {
+	return func(cfg *Config) {
+		cfg.WithNatsOptions(opts...)
+	}
+}
+
+// WithJetStreamDomain sets the JetStream domain; see Config.JetStreamDomain
+func WithJetStreamDomain(domain string) Option {
+	return func(cfg *Config) {
+		cfg.JetStreamDomain = domain
+	}
+}
+
+// WithJetStreamAPIPrefix sets the JetStream API prefix; see
+// Config.JetStreamAPIPrefix
+func WithJetStreamAPIPrefix(prefix string) Option {
+	return func(cfg *Config) {
+		cfg.JetStreamAPIPrefix = prefix
+	}
+}
+
+// WithJetStreamOpts appends arbitrary nats.JSOpt's; see Config.JetStreamOptions
+func WithJetStreamOpts(opts ...nats.JSOpt) Option {
+	return func(cfg *Config) {
+		cfg.WithJetStreamOpts(opts...)
+	}
+}
@@ -0,0 +1,84 @@
+package natty
+
+import (
+	"context"
+	"crypto/tls"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Options", func() {
+	It("should apply options to Config", func() {
+		tlsCfg := &tls.Config{InsecureSkipVerify: true}
+		metrics := &NoOpMetrics{}
+		bucketCfg := &nats.KeyValueConfig{Bucket: "foo", Storage: nats.MemoryStorage}
+
+		cfg := &Config{}
+
+		for _, opt := range []Option{
+			WithNatsURL("nats://a:4222", "nats://b:4222"),
+			WithTLS(tlsCfg),
+			WithCredentials("/creds/user.creds"),
+			WithReconnect(5, time.Second),
+			WithMetrics(metrics),
+			WithBucketTimeout("foo", time.Millisecond),
+			WithBucketTimeout("bar", time.Second),
+			WithRetry(RetryPolicy{MaxAttempts: 3}),
+			WithCircuitBreaker(CircuitBreakerConfig{Threshold: 5}),
+			WithBucketConfig(bucketCfg),
+		} {
+			opt(cfg)
+		}
+
+		Expect(cfg.NatsURL).To(Equal([]string{"nats://a:4222", "nats://b:4222"}))
+		Expect(cfg.UseTLS).To(BeTrue())
+		Expect(cfg.TLSConfig).To(Equal(tlsCfg))
+		Expect(cfg.CredsFile).To(Equal("/creds/user.creds"))
+		Expect(cfg.MaxReconnects).To(Equal(5))
+		Expect(cfg.ReconnectWait).To(Equal(time.Second))
+		Expect(cfg.Metrics).To(Equal(metrics))
+		Expect(cfg.BucketTimeouts).To(Equal(map[string]time.Duration{"foo": time.Millisecond, "bar": time.Second}))
+		Expect(cfg.Retry.MaxAttempts).To(Equal(3))
+		Expect(cfg.CircuitBreaker.Threshold).To(Equal(5))
+		Expect(cfg.BucketConfigs).To(Equal(map[string]*nats.KeyValueConfig{"foo": bucketCfg}))
+	})
+
+	It("should map WithReconnect(0) to NoReconnect", func() {
+		cfg := &Config{}
+		WithReconnect(0, time.Second)(cfg)
+
+		Expect(cfg.MaxReconnects).To(Equal(NoReconnect))
+
+		opts, err := buildNatsOptions(cfg)
+		Expect(err).ToNot(HaveOccurred())
+
+		natsOpts := nats.GetDefaultOptions()
+
+		for _, opt := range opts {
+			Expect(opt(&natsOpts)).To(Succeed())
+		}
+
+		Expect(natsOpts.AllowReconnect).To(BeFalse())
+	})
+
+	It("should not panic on nil config", func() {
+		_, err := New(nil, WithToken("foo"))
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should connect via NewWithOptions", func() {
+		n, err := NewWithOptions(WithNatsURL(NatsURL), WithTLS(tlsConfig))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(n.FetchSize).To(Equal(DefaultFetchSize))
+		Expect(n.Ping(context.Background())).To(Succeed())
+	})
+
+	It("should apply options on top of an existing Config", func() {
+		n, err := New(NewConfig(), WithJetStreamOpts(nats.MaxWait(3*time.Second)))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(n.JetStreamOptions).To(HaveLen(1))
+	})
+})
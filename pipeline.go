@@ -0,0 +1,85 @@
+package natty
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// KVSink is implemented by consumers of KV change events (ie. writers to
+// Redis, Kafka or SQL) that are attached to a KVPipeline.
+type KVSink interface {
+	// Sink is called (sequentially) for every event; returning an error stops
+	// the pipeline.
+	Sink(ctx context.Context, event *KeyValueEvent) error
+}
+
+// KVPipeline watches a bucket and fans every change out to a set of sinks
+type KVPipeline struct {
+	n     INatty
+	sinks []KVSink
+}
+
+// NewKVPipeline creates a pipeline that delivers events to sinks (in order)
+func NewKVPipeline(n INatty, sinks ...KVSink) *KVPipeline {
+	return &KVPipeline{
+		n:     n,
+		sinks: sinks,
+	}
+}
+
+// Run watches every key in bucket (see WatchWithBackpressure()) and passes
+// each change to all sinks until ctx is cancelled (returns nil) or a sink
+// returns an error (returns that error). Current values are delivered first,
+// followed by updates; a slow sink slows down the watch instead of causing
+// changes to be dropped.
+func (p *KVPipeline) Run(ctx context.Context, bucket string) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	entries, err := p.n.WatchWithBackpressure(ctx, bucket, ">")
+	if err != nil {
+		return errors.Wrapf(err, "unable to watch bucket '%s'", bucket)
+	}
+
+	for entry := range entries {
+		event := newKeyValueEvent(entry)
+
+		for i, sink := range p.sinks {
+			if err := sink.Sink(ctx, event); err != nil {
+				return errors.Wrapf(err, "sink %d failed for key '%s' (revision %d)", i, event.Key, event.Revision)
+			}
+		}
+	}
+
+	return nil
+}
+
+// LogSink is a KVSink that writes every event to an io.Writer as a line of
+// JSON.
+type LogSink struct {
+	mtx sync.Mutex
+	enc *json.Encoder
+}
+
+// NewLogSink creates a LogSink writing to w
+func NewLogSink(w io.Writer) *LogSink {
+	return &LogSink{
+		enc: json.NewEncoder(w),
+	}
+}
+
+// Sink implements KVSink
+func (l *LogSink) Sink(_ context.Context, event *KeyValueEvent) error {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	if err := l.enc.Encode(event); err != nil {
+		return errors.Wrap(err, "unable to write event")
+	}
+
+	return nil
+}
@@ -0,0 +1,111 @@
+package natty
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+)
+
+// recordingSink records every event it receives; if err is set, it is
+// returned from Sink()
+type recordingSink struct {
+	mtx    sync.Mutex
+	events []*KeyValueEvent
+	err    error
+}
+
+func (r *recordingSink) Sink(_ context.Context, event *KeyValueEvent) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	r.events = append(r.events, event)
+
+	return r.err
+}
+
+func (r *recordingSink) Events() []*KeyValueEvent {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	return r.events
+}
+
+var _ = Describe("KVPipeline", func() {
+	var (
+		stub   *watchStub
+		ctx    context.Context
+		cancel context.CancelFunc
+	)
+
+	BeforeEach(func() {
+		stub = &watchStub{ch: make(chan *KVEntry, 10)}
+		ctx, cancel = context.WithCancel(context.Background())
+	})
+
+	AfterEach(func() {
+		cancel()
+	})
+
+	It("should deliver every event to every sink", func() {
+		first := &recordingSink{}
+		second := &recordingSink{}
+
+		done := make(chan error, 1)
+
+		go func() {
+			done <- NewKVPipeline(stub, first, second).Run(ctx, "bucket")
+		}()
+
+		stub.ch <- &KVEntry{Bucket: "bucket", Key: "foo", Value: []byte("bar"), Revision: 1}
+		stub.ch <- &KVEntry{Bucket: "bucket", Key: "foo", Revision: 2, Operation: nats.KeyValueDelete}
+
+		for _, sink := range []*recordingSink{first, second} {
+			Eventually(sink.Events).Should(HaveLen(2))
+			Expect(sink.Events()[0].Operation).To(Equal(KVEventOpPut))
+			Expect(sink.Events()[0].Value).To(Equal([]byte("bar")))
+			Expect(sink.Events()[1].Operation).To(Equal(KVEventOpDelete))
+		}
+
+		close(stub.ch)
+
+		Eventually(done).Should(Receive(BeNil()))
+	})
+
+	It("should stop on a sink error", func() {
+		failing := &recordingSink{err: errors.New("boom")}
+		after := &recordingSink{}
+
+		stub.ch <- &KVEntry{Bucket: "bucket", Key: "foo", Revision: 1}
+
+		err := NewKVPipeline(stub, failing, after).Run(ctx, "bucket")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("boom"))
+
+		Expect(after.Events()).To(BeEmpty())
+	})
+})
+
+var _ = Describe("LogSink", func() {
+	It("should write one JSON line per event", func() {
+		buf := &bytes.Buffer{}
+		sink := NewLogSink(buf)
+
+		Expect(sink.Sink(context.Background(), &KeyValueEvent{Key: "foo", Created: time.Now()})).To(Succeed())
+		Expect(sink.Sink(context.Background(), &KeyValueEvent{Key: "bar", Created: time.Now()})).To(Succeed())
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		Expect(lines).To(HaveLen(2))
+
+		event := &KeyValueEvent{}
+		Expect(json.Unmarshal([]byte(lines[1]), event)).To(Succeed())
+		Expect(event.Key).To(Equal("bar"))
+	})
+})
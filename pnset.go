@@ -0,0 +1,136 @@
+package natty
+
+import (
+	"context"
+	"encoding/base64"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/nats-io/nats.go"
+	"github.com/pkg/errors"
+)
+
+const (
+	pnSetAdd    = "add"
+	pnSetRemove = "rm"
+)
+
+// PNSet is a PN-Set (a CRDT) stored in a KV bucket: every element has an add
+// counter ("<name>.add.<element>") and a remove counter ("<name>.rm.<element>")
+// and is a member of the set while it has been added more times than removed.
+// Counters are updated via Increment(), so concurrent adds and removes are
+// never lost. Elements are base64 (URL) encoded in keys and can be any string.
+type PNSet struct {
+	n      INatty
+	bucket string
+	name   string
+}
+
+// NewPNSet creates a PN-Set called name in bucket; the bucket is auto-created
+// on the first Add() or Remove(). name must be a valid KV key token (ie. no
+// '.', '*' or '>').
+func NewPNSet(n INatty, bucket, name string) *PNSet {
+	return &PNSet{
+		n:      n,
+		bucket: bucket,
+		name:   name,
+	}
+}
+
+// Add increments the add counter of element
+func (p *PNSet) Add(ctx context.Context, element string) error {
+	if _, err := p.n.Increment(ctx, p.bucket, p.key(pnSetAdd, element), 1); err != nil {
+		return errors.Wrapf(err, "unable to add element '%s'", element)
+	}
+
+	return nil
+}
+
+// Remove increments the remove counter of element. Removing an element that
+// is not a member has no visible effect, but a later Add() will be cancelled
+// out by it.
+func (p *PNSet) Remove(ctx context.Context, element string) error {
+	if _, err := p.n.Increment(ctx, p.bucket, p.key(pnSetRemove, element), 1); err != nil {
+		return errors.Wrapf(err, "unable to remove element '%s'", element)
+	}
+
+	return nil
+}
+
+// Members returns the (sorted) elements that have been added more times than
+// they have been removed; a missing bucket is an empty set.
+func (p *PNSet) Members(ctx context.Context) ([]string, error) {
+	keys, err := p.n.Keys(ctx, p.bucket)
+	if err != nil {
+		if err == nats.ErrBucketNotFound {
+			return make([]string, 0), nil
+		}
+
+		return nil, errors.Wrap(err, "unable to fetch keys")
+	}
+
+	counts := make(map[string]int64)
+
+	for _, key := range keys {
+		op, element, ok := p.parseKey(key)
+		if !ok {
+			continue
+		}
+
+		data, err := p.n.Get(ctx, p.bucket, key)
+		if err != nil {
+			if err == nats.ErrKeyNotFound {
+				continue
+			}
+
+			return nil, errors.Wrapf(err, "unable to fetch key '%s'", key)
+		}
+
+		value, err := strconv.ParseInt(string(data), 10, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "key '%s' is not a valid counter", key)
+		}
+
+		if op == pnSetRemove {
+			value = -value
+		}
+
+		counts[element] += value
+	}
+
+	members := make([]string, 0)
+
+	for element, count := range counts {
+		if count > 0 {
+			members = append(members, element)
+		}
+	}
+
+	sort.Strings(members)
+
+	return members, nil
+}
+
+func (p *PNSet) key(op, element string) string {
+	return p.name + "." + op + "." + base64.RawURLEncoding.EncodeToString([]byte(element))
+}
+
+// parseKey returns the op and (decoded) element of a key belonging to this set
+func (p *PNSet) parseKey(key string) (string, string, bool) {
+	parts := strings.Split(key, ".")
+	if len(parts) != 3 || parts[0] != p.name {
+		return "", "", false
+	}
+
+	if parts[1] != pnSetAdd && parts[1] != pnSetRemove {
+		return "", "", false
+	}
+
+	element, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", "", false
+	}
+
+	return parts[1], string(element), true
+}
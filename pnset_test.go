@@ -0,0 +1,77 @@
+package natty
+
+import (
+	"context"
+	"strconv"
+	"sync"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PNSet", func() {
+	var n *Natty
+
+	BeforeEach(func() {
+		var err error
+
+		n, err = New(NewConfig())
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("should reflect concurrent adds and removes", func() {
+		bucket, _, _ := NewKVSet()
+
+		set := NewPNSet(n, bucket, "members")
+
+		var wg sync.WaitGroup
+
+		// Every goroutine adds its own element and "shared"; odd goroutines
+		// then remove their own element
+		for i := 0; i < 6; i++ {
+			wg.Add(1)
+
+			go func(i int) {
+				defer GinkgoRecover()
+				defer wg.Done()
+
+				element := "element-" + strconv.Itoa(i)
+
+				Expect(set.Add(context.Background(), element)).To(Succeed())
+				Expect(set.Add(context.Background(), "shared")).To(Succeed())
+
+				if i%2 == 1 {
+					Expect(set.Remove(context.Background(), element)).To(Succeed())
+				}
+			}(i)
+		}
+
+		wg.Wait()
+
+		members, err := set.Members(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(members).To(Equal([]string{"element-0", "element-2", "element-4", "shared"}))
+	})
+
+	It("should support arbitrary element strings", func() {
+		bucket, _, _ := NewKVSet()
+
+		set := NewPNSet(n, bucket, "members")
+
+		Expect(set.Add(context.Background(), "user@example.com")).To(Succeed())
+		Expect(set.Add(context.Background(), "a.b > c")).To(Succeed())
+		Expect(set.Remove(context.Background(), "a.b > c")).To(Succeed())
+
+		members, err := set.Members(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(members).To(Equal([]string{"user@example.com"}))
+	})
+
+	It("should return an empty set for a missing bucket", func() {
+		bucket, _, _ := NewKVSet()
+
+		members, err := NewPNSet(n, bucket, "members").Members(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(members).To(BeEmpty())
+	})
+})
@@ -0,0 +1,72 @@
+package natty
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultProbeTimeout is the default KVProbe.Timeout
+const DefaultProbeTimeout = 2 * time.Second
+
+// KVProbe is an http.Handler for Kubernetes readiness/liveness probes; it
+// responds with 200 if Healthcheck() passes and with 503 (and the error as the
+// body) otherwise.
+type KVProbe struct {
+	// Timeout bounds every Healthcheck() call (default: DefaultProbeTimeout)
+	Timeout time.Duration
+
+	n INatty
+}
+
+// NewKVProbe creates a probe that checks n
+func NewKVProbe(n INatty) *KVProbe {
+	return &KVProbe{
+		Timeout: DefaultProbeTimeout,
+		n:       n,
+	}
+}
+
+// ServeHTTP implements http.Handler
+func (p *KVProbe) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	timeout := p.Timeout
+	if timeout <= 0 {
+		timeout = DefaultProbeTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+
+	if err := p.n.Healthcheck(ctx); err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("ok"))
+}
+
+// StartProbe serves a KVProbe for n on addr (on every path) in the
+// background. It returns once addr is being listened on; the probe is served
+// until the process exits.
+func StartProbe(addr string, n *Natty) error {
+	if n == nil {
+		return errors.New("natty cannot be nil")
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return errors.Wrapf(err, "unable to listen on '%s'", addr)
+	}
+
+	go func() {
+		if err := http.Serve(listener, NewKVProbe(n)); err != nil {
+			errorw(n.log, "probe server stopped", "addr", addr, "error", err)
+		}
+	}()
+
+	return nil
+}
@@ -0,0 +1,78 @@
+package natty
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("KVProbe", func() {
+	get := func(url string) (int, string) {
+		resp, err := http.Get(url)
+		Expect(err).ToNot(HaveOccurred())
+
+		defer resp.Body.Close()
+
+		body, err := ioutil.ReadAll(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+
+		return resp.StatusCode, string(body)
+	}
+
+	It("should respond based on Healthcheck()", func() {
+		stub := &healthcheckStub{}
+
+		server := httptest.NewServer(NewKVProbe(stub))
+		defer server.Close()
+
+		status, body := get(server.URL)
+		Expect(status).To(Equal(http.StatusOK))
+		Expect(body).To(Equal("ok"))
+
+		stub.err = errors.New("jetstream down")
+
+		status, body = get(server.URL)
+		Expect(status).To(Equal(http.StatusServiceUnavailable))
+		Expect(body).To(ContainSubstring("jetstream down"))
+	})
+
+	It("should return 503 once the natty connection is closed", func() {
+		n, err := New(NewConfig())
+		Expect(err).ToNot(HaveOccurred())
+
+		// Grab a free port for StartProbe()
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).ToNot(HaveOccurred())
+
+		addr := listener.Addr().String()
+		Expect(listener.Close()).To(Succeed())
+
+		Expect(StartProbe(addr, n)).To(Succeed())
+
+		status, _ := get("http://" + addr + "/healthz")
+		Expect(status).To(Equal(http.StatusOK))
+
+		Expect(n.Close()).To(Succeed())
+
+		status, body := get("http://" + addr + "/healthz")
+		Expect(status).To(Equal(http.StatusServiceUnavailable))
+		Expect(body).To(ContainSubstring(ErrConnectionClosed.Error()))
+	})
+})
+
+// healthcheckStub is an INatty whose Healthcheck() returns err
+type healthcheckStub struct {
+	INatty
+
+	err error
+}
+
+func (h *healthcheckStub) Healthcheck(_ context.Context) error {
+	return h.err
+}
@@ -9,14 +9,271 @@ import (
 	"github.com/nats-io/nats.go"
 	"github.com/pkg/errors"
 	"github.com/relistan/go-director"
+	"golang.org/x/time/rate"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
 )
 
+// publisherPollInterval is how long an idle publisher goroutine waits before
+// checking its queue again
+const publisherPollInterval = 10 * time.Millisecond
+
+// ErrMessageTooLarge is returned when publishing a message whose payload is
+// larger than Config.MaxMsgSize; the message is not sent.
+type ErrMessageTooLarge struct {
+	Subject string
+	Limit   int
+	Actual  int
+}
+
+func (e *ErrMessageTooLarge) Error() string {
+	return fmt.Sprintf("message for subject '%s' is too large: %d bytes exceeds limit of %d bytes",
+		e.Subject, e.Actual, e.Limit)
+}
+
+// checkMsgSize returns an *ErrMessageTooLarge if data exceeds Config.MaxMsgSize
+func (n *Natty) checkMsgSize(subject string, data []byte) error {
+	if n.MaxMsgSize > 0 && len(data) > int(n.MaxMsgSize) {
+		return &ErrMessageTooLarge{
+			Subject: subject,
+			Limit:   int(n.MaxMsgSize),
+			Actual:  len(data),
+		}
+	}
+
+	return nil
+}
+
+// Publish publishes a single message with the given subject; the message is
+// batched as configured during New(). Messages that cannot be queued (see
+// TryPublish) are logged and sent to Config.PublishErrorCh (if set).
 func (n *Natty) Publish(ctx context.Context, subject string, value []byte) {
+	if err := n.TryPublish(ctx, subject, value); err != nil {
+		writePublishError(n.log, n.PublishErrorCh, subject, err)
+	}
+}
+
+// TryPublish is like Publish but returns an error if the message could not
+// be queued for publishing.
+func (n *Natty) TryPublish(ctx context.Context, subject string, value []byte) error {
 	span, ctx := tracer.StartSpanFromContext(ctx, "natty.Publish")
 	defer span.Finish()
 
-	n.getPublisherBySubject(subject).batch(ctx, subject, value)
+	if n.isClosed() {
+		span.SetTag("error", ErrConnectionClosed)
+		return ErrConnectionClosed
+	}
+
+	if err := n.checkMsgSize(subject, value); err != nil {
+		span.SetTag("error", err)
+		return err
+	}
+
+	if n.breaker.State() == CircuitOpen {
+		span.SetTag("error", ErrCircuitOpen)
+		return ErrCircuitOpen
+	}
+
+	if err := n.publishLimiter.Wait(ctx); err != nil {
+		err = errors.Wrap(err, "unable to wait for publish rate limiter")
+		span.SetTag("error", err)
+
+		return err
+	}
+
+	// Propagate trace context (if any) via message headers
+	msg := nats.NewMsg(subject)
+	n.tracer.Inject(ctx, msg)
+
+	n.getPublisherBySubject(subject).batch(ctx, subject, value, msg.Header)
+
+	return nil
+}
+
+// PublishWithRetry synchronously publishes data to subject and waits for the
+// stream's ACK, retrying according to retryPolicy. It is meant for startup
+// races where the publisher starts before the stream exists: if
+// retryPolicy.RetryableErrors is empty, only "no responders" errors
+// (nats.ErrNoResponders and nats.ErrNoStreamResponse, which is what JetStream
+// returns when no stream listens on subject) are retried. Every attempt is
+// bound by Config.PublishTimeout.
+//
+// NOTE: Unlike Publish(), the batching publisher is bypassed.
+func (n *Natty) PublishWithRetry(ctx context.Context, subject string, data []byte, retryPolicy RetryPolicy) (*nats.PubAck, error) {
+	span, ctx := tracer.StartSpanFromContext(ctx, "natty.PublishWithRetry")
+	defer span.Finish()
+
+	if n.isClosed() {
+		span.SetTag("error", ErrConnectionClosed)
+		return nil, ErrConnectionClosed
+	}
+
+	if err := n.checkMsgSize(subject, data); err != nil {
+		span.SetTag("error", err)
+		return nil, err
+	}
+
+	if len(retryPolicy.RetryableErrors) == 0 {
+		retryPolicy.RetryableErrors = []error{nats.ErrNoResponders, nats.ErrNoStreamResponse}
+	}
+
+	msg := nats.NewMsg(subject)
+	msg.Data = data
+	n.tracer.Inject(ctx, msg)
+
+	var ack *nats.PubAck
+
+	err := retryPolicy.do(ctx, func() error {
+		attemptCtx, cancel := context.WithTimeout(ctx, n.PublishTimeout)
+		defer cancel()
+
+		var err error
+
+		ack, err = n.getJS().PublishMsg(msg, nats.Context(attemptCtx))
+
+		return err
+	})
+	if err != nil {
+		err = errors.Wrap(err, "unable to publish message")
+		span.SetTag("error", err)
+
+		return nil, err
+	}
+
+	return ack, nil
+}
+
+// PublishResult is the outcome of publishing a single message via
+// PublishAsyncBatch(); Sequence is only set if Err is nil.
+type PublishResult struct {
+	Subject  string
+	Sequence uint64
+	Err      error
+}
+
+// PublishAsyncBatch publishes messages asynchronously (bypassing the batching
+// publisher used by Publish()), waits for all outstanding acks and returns a
+// result per message, in the same order as messages. If any message failed to
+// publish, an error summarizing the failures is returned along with the results.
+// messages are not modified; trace headers are added to copies. The batch is a
+// single call for the circuit breaker and is waited for by GracefulRestart().
+func (n *Natty) PublishAsyncBatch(ctx context.Context, messages []*nats.Msg) ([]PublishResult, error) {
+	if n.isClosed() {
+		return nil, ErrConnectionClosed
+	}
+
+	// Hold back GracefulRestart() until all acks are received
+	n.restartMutex.RLock()
+	defer n.restartMutex.RUnlock()
+
+	generation, err := n.breaker.allow()
+	if err != nil {
+		return nil, err
+	}
+
+	// Shared by concurrent calls, so acks are waited for per message rather
+	// than via PublishAsyncComplete()
+	js := n.getAsyncJS()
+
+	results := make([]PublishResult, len(messages))
+	futures := make([]nats.PubAckFuture, len(messages))
+
+	// First publish or ack error; recorded with the circuit breaker (size and
+	// rate limiter errors say nothing about the server's health)
+	var batchErr error
+
+	for i, msg := range messages {
+		results[i].Subject = msg.Subject
+
+		if err := n.checkMsgSize(msg.Subject, msg.Data); err != nil {
+			results[i].Err = err
+			continue
+		}
+
+		if err := n.publishLimiter.Wait(ctx); err != nil {
+			results[i].Err = errors.Wrap(err, "unable to wait for publish rate limiter")
+			continue
+		}
+
+		out := copyMsg(msg)
+
+		n.tracer.Inject(ctx, out)
+
+		future, err := js.PublishMsgAsync(out)
+		if err != nil {
+			results[i].Err = errors.Wrap(err, "unable to publish message")
+
+			if batchErr == nil {
+				batchErr = results[i].Err
+			}
+
+			continue
+		}
+
+		futures[i] = future
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, n.PublishTimeout)
+	defer cancel()
+
+	var failed int
+
+	for i, future := range futures {
+		if future != nil {
+			select {
+			case ack := <-future.Ok():
+				results[i].Sequence = ack.Sequence
+			case err := <-future.Err():
+				results[i].Err = errors.Wrap(err, "unable to publish message")
+			case <-waitCtx.Done():
+				results[i].Err = errors.Wrap(nats.ErrTimeout, "timed out waiting for message acknowledgement")
+			}
+
+			if batchErr == nil {
+				batchErr = results[i].Err
+			}
+		}
+
+		if results[i].Err != nil {
+			failed++
+		}
+	}
+
+	n.breaker.record(generation, batchErr)
+
+	if failed > 0 {
+		return results, errors.Errorf("%d of %d messages failed to publish", failed, len(messages))
+	}
+
+	return results, nil
+}
+
+// copyMsg returns a copy of msg with its own headers (Data is shared)
+func copyMsg(msg *nats.Msg) *nats.Msg {
+	out := nats.NewMsg(msg.Subject)
+	out.Reply = msg.Reply
+	out.Data = msg.Data
+
+	for k, v := range msg.Header {
+		out.Header[k] = append([]string(nil), v...)
+	}
+
+	return out
+}
+
+// SetPublishRateLimit changes the rate (messages per second) and burst at which
+// Publish() accepts messages; it is safe to call at runtime. A limit of 0
+// disables rate limiting; a burst of 0 is treated as 1.
+func (n *Natty) SetPublishRateLimit(l rate.Limit, burst int) {
+	if l <= 0 {
+		l = rate.Inf
+	}
+
+	if burst <= 0 {
+		burst = 1
+	}
+
+	n.publishLimiter.SetBurst(burst)
+	n.publishLimiter.SetLimit(l)
 }
 
 // DeletePublisher will stop the batch publisher goroutine and remove the
@@ -31,11 +288,11 @@ func (n *Natty) DeletePublisher(ctx context.Context, topic string) bool {
 	n.publisherMutex.RUnlock()
 
 	if !ok {
-		n.log.Debugf("publisher for topic '%s' not found", topic)
+		debugw(n.log, "publisher not found", "topic", topic)
 		return false
 	}
 
-	n.log.Debugf("found existing publisher in cache for topic '%s' - closing and removing", topic)
+	debugw(n.log, "found existing publisher in cache - closing and removing", "topic", topic)
 
 	// Stop batch publisher goroutine
 	publisher.PublisherCancel()
@@ -47,13 +304,33 @@ func (n *Natty) DeletePublisher(ctx context.Context, topic string) bool {
 	return true
 }
 
+// pendingPublishes returns the number of messages waiting in publisher queues
+func (n *Natty) pendingPublishes() int {
+	n.publisherMutex.RLock()
+	defer n.publisherMutex.RUnlock()
+
+	var pending int
+
+	for _, p := range n.publisherMap {
+		p.QueueMutex.RLock()
+		pending += len(p.Queue)
+
+		if p.sending {
+			pending++
+		}
+		p.QueueMutex.RUnlock()
+	}
+
+	return pending
+}
+
 func (n *Natty) getPublisherBySubject(subject string) *Publisher {
 	n.publisherMutex.Lock()
 	defer n.publisherMutex.Unlock()
 
 	p, ok := n.publisherMap[subject]
 	if !ok {
-		n.log.Debugf("creating new publisher goroutine for subject '%s'", subject)
+		debugw(n.log, "creating new publisher goroutine", "subject", subject)
 
 		p = n.newPublisher(subject)
 		n.publisherMap[subject] = p
@@ -84,9 +361,14 @@ func (n *Natty) newPublisher(subject string) *Publisher {
 }
 
 func (p *Publisher) writeMessagesBatch(ctx context.Context, msgs []*message) error {
-	p.log.Debugf("creating a batch for %d messages", len(msgs))
+	debugw(p.log, "creating a batch", "subject", p.Subject, "count", len(msgs))
+
+	// Hold back GracefulRestart() until the batch is published
+	p.Natty.restartMutex.RLock()
+	defer p.Natty.restartMutex.RUnlock()
 
-	js, err := p.Natty.nc.JetStream(nats.PublishAsyncMaxPending(p.Natty.PublishBatchSize), nats.Context(ctx))
+	js, err := p.Natty.getConn().JetStream(jetStreamOptions(p.Natty.Config,
+		nats.PublishAsyncMaxPending(p.Natty.PublishBatchSize), nats.Context(ctx))...)
 	if err != nil {
 		return errors.Wrap(err, "unable to create JetStream context")
 	}
@@ -95,20 +377,30 @@ func (p *Publisher) writeMessagesBatch(ctx context.Context, msgs []*message) err
 
 	// TODO: how to handle retry?
 	for _, batch := range batches {
+		generation, err := p.Natty.breaker.allow()
+		if err != nil {
+			p.writeError(errors.Wrapf(err, "unable to publish '%d' messages for '%s'", len(batch), p.Subject))
+			continue
+		}
+
+		var batchErr error
+
 		for _, msg := range batch {
-			if _, err := js.PublishAsync(msg.Subject, msg.Value); err != nil {
-				err = errors.Wrap(err, "unable to publish message")
-				p.writeError(err)
+			if _, err := js.PublishMsgAsync(&nats.Msg{Subject: msg.Subject, Data: msg.Value, Header: msg.Header}); err != nil {
+				batchErr = errors.Wrap(err, "unable to publish message")
+				p.writeError(batchErr)
 			}
 		}
 
 		select {
 		case <-js.PublishAsyncComplete():
-			p.log.Debugf("Successfully published '%d' messages", len(msgs))
+			p.Natty.breaker.record(generation, batchErr)
+			debugw(p.log, "successfully published messages", "subject", p.Subject, "count", len(msgs))
 			return nil
 		case <-time.After(p.Natty.PublishTimeout):
-			msg := fmt.Errorf("timed out waiting for message acknowledgement of '%d' messages for '%s'", len(batch), p.Subject)
-			p.writeError(msg)
+			batchErr = fmt.Errorf("timed out waiting for message acknowledgement of '%d' messages for '%s'", len(batch), p.Subject)
+			p.Natty.breaker.record(generation, batchErr)
+			p.writeError(batchErr)
 		}
 	}
 
@@ -116,32 +408,39 @@ func (p *Publisher) writeMessagesBatch(ctx context.Context, msgs []*message) err
 }
 
 func (p *Publisher) writeError(err error) {
-	p.log.Error(err)
+	writePublishError(p.log, p.ErrorCh, p.Subject, err)
+}
+
+// writePublishError logs err and sends it to errorCh (if not nil) without
+// blocking
+func writePublishError(l Logger, errorCh chan *PublishError, subject string, err error) {
+	errorw(l, "publish error", "subject", subject, "error", err)
 
-	if p.ErrorCh == nil {
+	if errorCh == nil {
 		return
 	}
 
 	go func() {
 		// Writing in goroutine in case channel is blocked
 		select {
-		case p.ErrorCh <- &PublishError{
-			Subject: p.Subject,
+		case errorCh <- &PublishError{
+			Subject: subject,
 			Message: err,
 		}:
 		default:
-			p.log.Warnf("publish error channel is full; discarding error")
+			warnw(l, "publish error channel is full; discarding error", "subject", subject, "error", err)
 		}
 	}()
 }
 
-func (p *Publisher) batch(_ context.Context, subject string, value []byte) {
+func (p *Publisher) batch(_ context.Context, subject string, value []byte, header nats.Header) {
 	p.QueueMutex.Lock()
 	defer p.QueueMutex.Unlock()
 
 	p.Queue = append(p.Queue, &message{
 		Subject: subject,
 		Value:   value,
+		Header:  header,
 	})
 }
 
@@ -178,7 +477,7 @@ func buildBatch(slice []*message, entriesPerBatch int) [][]*message {
 func (p *Publisher) runBatchPublisher(ctx context.Context) {
 	var quit bool
 
-	p.log.Debugf("publisher id '%s' exiting", p.Subject)
+	debugw(p.log, "publisher exiting", "subject", p.Subject)
 
 	lastArrivedAt := time.Now()
 
@@ -200,11 +499,11 @@ func (p *Publisher) runBatchPublisher(ctx context.Context) {
 		// Should we shutdown?
 		select {
 		case <-ctx.Done(): // DeletePublisher context
-			p.log.Debugf("publisher id '%s' received notice to quit", p.Subject)
+			debugw(p.log, "publisher received notice to quit", "subject", p.Subject)
 			quit = true
 
 		case <-p.ServiceShutdownContext.Done():
-			p.log.Debugf("publisher id '%s' received app shutdown signal, waiting for batch to be empty", p.Subject)
+			debugw(p.log, "publisher received app shutdown signal, waiting for batch to be empty", "subject", p.Subject)
 			quit = true
 		default:
 			// NOOP
@@ -212,14 +511,15 @@ func (p *Publisher) runBatchPublisher(ctx context.Context) {
 
 		// No reason to keep goroutines running forever
 		if remaining == 0 && time.Since(lastArrivedAt) > p.IdleTimeout {
-			p.log.Debugf("idle timeout reached (%s); exiting", p.IdleTimeout)
+			debugw(p.log, "idle timeout reached; exiting", "subject", p.Subject, "idle_timeout", p.IdleTimeout)
 
 			p.Natty.DeletePublisher(ctx, p.Subject)
 			return nil
 		}
 
 		if remaining == 0 {
-			// Queue is empty, nothing to do
+			// Queue is empty, nothing to do; avoid spinning on the CPU
+			time.Sleep(publisherPollInterval)
 			return nil
 		}
 
@@ -227,16 +527,21 @@ func (p *Publisher) runBatchPublisher(ctx context.Context) {
 		tmpQueue := make([]*message, len(p.Queue))
 		copy(tmpQueue, p.Queue)
 		p.Queue = make([]*message, 0)
+		p.sending = true
 		p.QueueMutex.Unlock()
 
 		lastArrivedAt = time.Now()
 
 		if err := p.writeMessagesBatch(ctx, tmpQueue); err != nil {
-			p.log.Error(err)
+			errorw(p.log, "unable to write batch", "subject", p.Subject, "error", err)
 		}
 
+		p.QueueMutex.Lock()
+		p.sending = false
+		p.QueueMutex.Unlock()
+
 		return nil
 	})
 
-	p.log.Debugf("publisher id '%s' exiting", p.Subject)
+	debugw(p.log, "publisher exiting", "subject", p.Subject)
 }
@@ -0,0 +1,432 @@
+package natty
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// RaceTestNatty wraps an INatty and logs a warning every time a method is
+// called with a nil context. Nil contexts are valid in Go but usually point to
+// a bug in the calling code; RaceTestNatty is intended to be used in tests
+// (ideally ran with `-race`) to surface these.
+type RaceTestNatty struct {
+	INatty
+
+	log Logger
+}
+
+// NewRaceTestNatty wraps given INatty; if logger is nil, a NoOpLogger is used.
+func NewRaceTestNatty(n INatty, logger Logger) *RaceTestNatty {
+	if logger == nil {
+		logger = &NoOpLogger{}
+	}
+
+	return &RaceTestNatty{
+		INatty: n,
+		log:    logger,
+	}
+}
+
+func (r *RaceTestNatty) Consume(ctx context.Context, cfg *ConsumerConfig, cb func(ctx context.Context, msg *nats.Msg) error) error {
+	r.checkContext(ctx, "Consume")
+	return r.INatty.Consume(ctx, cfg, cb)
+}
+
+func (r *RaceTestNatty) SubscribeWithHeaderFilter(ctx context.Context, stream, subject, durable string, filterHeader, filterValue string, handler func(*nats.Msg) error) (func() error, error) {
+	r.checkContext(ctx, "SubscribeWithHeaderFilter")
+	return r.INatty.SubscribeWithHeaderFilter(ctx, stream, subject, durable, filterHeader, filterValue, handler)
+}
+
+func (r *RaceTestNatty) Subscribe(ctx context.Context, stream, subject string, opts SubOptions, handler func(*nats.Msg) error) (func() error, error) {
+	r.checkContext(ctx, "Subscribe")
+	return r.INatty.Subscribe(ctx, stream, subject, opts, handler)
+}
+
+func (r *RaceTestNatty) FetchWithOptions(ctx context.Context, stream, consumer string, opts FetchOptions) ([]*nats.Msg, error) {
+	r.checkContext(ctx, "FetchWithOptions")
+	return r.INatty.FetchWithOptions(ctx, stream, consumer, opts)
+}
+
+func (r *RaceTestNatty) AutoInProgress(ctx context.Context, msg *nats.Msg, interval time.Duration) func() {
+	r.checkContext(ctx, "AutoInProgress")
+	return r.INatty.AutoInProgress(ctx, msg, interval)
+}
+
+func (r *RaceTestNatty) Publish(ctx context.Context, subject string, data []byte) {
+	r.checkContext(ctx, "Publish")
+	r.INatty.Publish(ctx, subject, data)
+}
+
+func (r *RaceTestNatty) TryPublish(ctx context.Context, subject string, data []byte) error {
+	r.checkContext(ctx, "TryPublish")
+	return r.INatty.TryPublish(ctx, subject, data)
+}
+
+func (r *RaceTestNatty) PublishWithRetry(ctx context.Context, subject string, data []byte, retryPolicy RetryPolicy) (*nats.PubAck, error) {
+	r.checkContext(ctx, "PublishWithRetry")
+	return r.INatty.PublishWithRetry(ctx, subject, data, retryPolicy)
+}
+
+func (r *RaceTestNatty) PublishAsyncBatch(ctx context.Context, messages []*nats.Msg) ([]PublishResult, error) {
+	r.checkContext(ctx, "PublishAsyncBatch")
+	return r.INatty.PublishAsyncBatch(ctx, messages)
+}
+
+func (r *RaceTestNatty) DeletePublisher(ctx context.Context, id string) bool {
+	r.checkContext(ctx, "DeletePublisher")
+	return r.INatty.DeletePublisher(ctx, id)
+}
+
+func (r *RaceTestNatty) CreateStream(ctx context.Context, name string, subjects []string) error {
+	r.checkContext(ctx, "CreateStream")
+	return r.INatty.CreateStream(ctx, name, subjects)
+}
+
+func (r *RaceTestNatty) CreateStreamWithConfig(ctx context.Context, cfg *nats.StreamConfig) (*nats.StreamInfo, error) {
+	r.checkContext(ctx, "CreateStreamWithConfig")
+	return r.INatty.CreateStreamWithConfig(ctx, cfg)
+}
+
+func (r *RaceTestNatty) CreateMirrorStream(ctx context.Context, sourceName string, cfg *nats.StreamConfig) (*nats.StreamInfo, error) {
+	r.checkContext(ctx, "CreateMirrorStream")
+	return r.INatty.CreateMirrorStream(ctx, sourceName, cfg)
+}
+
+func (r *RaceTestNatty) DeleteStream(ctx context.Context, name string) error {
+	r.checkContext(ctx, "DeleteStream")
+	return r.INatty.DeleteStream(ctx, name)
+}
+
+func (r *RaceTestNatty) PurgeStreamSubject(ctx context.Context, stream, subject string) error {
+	r.checkContext(ctx, "PurgeStreamSubject")
+	return r.INatty.PurgeStreamSubject(ctx, stream, subject)
+}
+
+func (r *RaceTestNatty) PurgeStreamBefore(ctx context.Context, stream string, seq uint64) error {
+	r.checkContext(ctx, "PurgeStreamBefore")
+	return r.INatty.PurgeStreamBefore(ctx, stream, seq)
+}
+
+func (r *RaceTestNatty) SnapshotStream(ctx context.Context, name string, w io.Writer) error {
+	r.checkContext(ctx, "SnapshotStream")
+	return r.INatty.SnapshotStream(ctx, name, w)
+}
+
+func (r *RaceTestNatty) RestoreStream(ctx context.Context, cfg *nats.StreamConfig, reader io.Reader) (*nats.StreamInfo, error) {
+	r.checkContext(ctx, "RestoreStream")
+	return r.INatty.RestoreStream(ctx, cfg, reader)
+}
+
+func (r *RaceTestNatty) CreateConsumer(ctx context.Context, streamName, consumerName string, filterSubject ...string) error {
+	r.checkContext(ctx, "CreateConsumer")
+	return r.INatty.CreateConsumer(ctx, streamName, consumerName, filterSubject...)
+}
+
+func (r *RaceTestNatty) UpdateConsumer(ctx context.Context, stream string, cfg *nats.ConsumerConfig) (*nats.ConsumerInfo, error) {
+	r.checkContext(ctx, "UpdateConsumer")
+	return r.INatty.UpdateConsumer(ctx, stream, cfg)
+}
+
+func (r *RaceTestNatty) DeleteConsumer(ctx context.Context, consumerName, streamName string) error {
+	r.checkContext(ctx, "DeleteConsumer")
+	return r.INatty.DeleteConsumer(ctx, consumerName, streamName)
+}
+
+func (r *RaceTestNatty) ListStreams(ctx context.Context) ([]*nats.StreamInfo, error) {
+	r.checkContext(ctx, "ListStreams")
+	return r.INatty.ListStreams(ctx)
+}
+
+func (r *RaceTestNatty) ListConsumers(ctx context.Context, stream string) ([]*nats.ConsumerInfo, error) {
+	r.checkContext(ctx, "ListConsumers")
+	return r.INatty.ListConsumers(ctx, stream)
+}
+
+func (r *RaceTestNatty) AccountInfo(ctx context.Context) (*nats.AccountInfo, error) {
+	r.checkContext(ctx, "AccountInfo")
+	return r.INatty.AccountInfo(ctx)
+}
+
+func (r *RaceTestNatty) WaitForStream(ctx context.Context, name string, pollInterval time.Duration) error {
+	r.checkContext(ctx, "WaitForStream")
+	return r.INatty.WaitForStream(ctx, name, pollInterval)
+}
+
+func (r *RaceTestNatty) Get(ctx context.Context, bucket string, key string) ([]byte, error) {
+	r.checkContext(ctx, "Get")
+	return r.INatty.Get(ctx, bucket, key)
+}
+
+func (r *RaceTestNatty) Create(ctx context.Context, bucket string, key string, data []byte, keyTTL ...time.Duration) error {
+	r.checkContext(ctx, "Create")
+	return r.INatty.Create(ctx, bucket, key, data, keyTTL...)
+}
+
+func (r *RaceTestNatty) GetOrCreate(ctx context.Context, bucket, key string, ttl time.Duration, factory func() ([]byte, error)) ([]byte, bool, error) {
+	r.checkContext(ctx, "GetOrCreate")
+	return r.INatty.GetOrCreate(ctx, bucket, key, ttl, factory)
+}
+
+func (r *RaceTestNatty) Put(ctx context.Context, bucket string, key string, data []byte, ttl ...time.Duration) error {
+	r.checkContext(ctx, "Put")
+	return r.INatty.Put(ctx, bucket, key, data, ttl...)
+}
+
+func (r *RaceTestNatty) BatchPutWithTTL(ctx context.Context, bucket string, entries map[string][]byte, ttl time.Duration) error {
+	r.checkContext(ctx, "BatchPutWithTTL")
+	return r.INatty.BatchPutWithTTL(ctx, bucket, entries, ttl)
+}
+
+func (r *RaceTestNatty) GetEntry(ctx context.Context, bucket string, key string) (*KVEntry, error) {
+	r.checkContext(ctx, "GetEntry")
+	return r.INatty.GetEntry(ctx, bucket, key)
+}
+
+func (r *RaceTestNatty) GetLatestEntry(ctx context.Context, bucket string, key string) (*KVEntry, error) {
+	r.checkContext(ctx, "GetLatestEntry")
+	return r.INatty.GetLatestEntry(ctx, bucket, key)
+}
+
+func (r *RaceTestNatty) Exists(ctx context.Context, bucket, key string) (bool, error) {
+	r.checkContext(ctx, "Exists")
+	return r.INatty.Exists(ctx, bucket, key)
+}
+
+func (r *RaceTestNatty) BucketExists(ctx context.Context, bucket string) (bool, error) {
+	r.checkContext(ctx, "BucketExists")
+	return r.INatty.BucketExists(ctx, bucket)
+}
+
+func (r *RaceTestNatty) GetIfNewer(ctx context.Context, bucket, key string, sinceRevision uint64) ([]byte, uint64, error) {
+	r.checkContext(ctx, "GetIfNewer")
+	return r.INatty.GetIfNewer(ctx, bucket, key, sinceRevision)
+}
+
+func (r *RaceTestNatty) GetIfModifiedSince(ctx context.Context, bucket, key string, since time.Time) ([]byte, bool, error) {
+	r.checkContext(ctx, "GetIfModifiedSince")
+	return r.INatty.GetIfModifiedSince(ctx, bucket, key, since)
+}
+
+func (r *RaceTestNatty) GetWithTTLHint(ctx context.Context, bucket, key string) ([]byte, time.Duration, error) {
+	r.checkContext(ctx, "GetWithTTLHint")
+	return r.INatty.GetWithTTLHint(ctx, bucket, key)
+}
+
+func (r *RaceTestNatty) CompareRevisions(ctx context.Context, bucket, key1, key2 string) (same bool, rev1, rev2 uint64, err error) {
+	r.checkContext(ctx, "CompareRevisions")
+	return r.INatty.CompareRevisions(ctx, bucket, key1, key2)
+}
+
+func (r *RaceTestNatty) GetJSON(ctx context.Context, bucket, key string, out interface{}) error {
+	r.checkContext(ctx, "GetJSON")
+	return r.INatty.GetJSON(ctx, bucket, key, out)
+}
+
+func (r *RaceTestNatty) PutJSON(ctx context.Context, bucket, key string, v interface{}, ttl ...time.Duration) error {
+	r.checkContext(ctx, "PutJSON")
+	return r.INatty.PutJSON(ctx, bucket, key, v, ttl...)
+}
+
+func (r *RaceTestNatty) Delete(ctx context.Context, bucket string, key string) error {
+	r.checkContext(ctx, "Delete")
+	return r.INatty.Delete(ctx, bucket, key)
+}
+
+func (r *RaceTestNatty) DeletePrefix(ctx context.Context, bucket, prefix string) (int, error) {
+	r.checkContext(ctx, "DeletePrefix")
+	return r.INatty.DeletePrefix(ctx, bucket, prefix)
+}
+
+func (r *RaceTestNatty) DeleteByPattern(ctx context.Context, bucket, keyPattern string) (int, error) {
+	r.checkContext(ctx, "DeleteByPattern")
+	return r.INatty.DeleteByPattern(ctx, bucket, keyPattern)
+}
+
+func (r *RaceTestNatty) CopyKey(ctx context.Context, srcBucket, srcKey, dstBucket, dstKey string) error {
+	r.checkContext(ctx, "CopyKey")
+	return r.INatty.CopyKey(ctx, srcBucket, srcKey, dstBucket, dstKey)
+}
+
+func (r *RaceTestNatty) CreateBucket(ctx context.Context, bucket string, ttl time.Duration, description ...string) error {
+	r.checkContext(ctx, "CreateBucket")
+	return r.INatty.CreateBucket(ctx, bucket, ttl, description...)
+}
+
+func (r *RaceTestNatty) CreateBucketWithConfig(ctx context.Context, cfg *nats.KeyValueConfig) (nats.KeyValue, error) {
+	r.checkContext(ctx, "CreateBucketWithConfig")
+	return r.INatty.CreateBucketWithConfig(ctx, cfg)
+}
+
+func (r *RaceTestNatty) UpdateBucketConfig(ctx context.Context, bucket string, cfg *nats.KeyValueConfig) error {
+	r.checkContext(ctx, "UpdateBucketConfig")
+	return r.INatty.UpdateBucketConfig(ctx, bucket, cfg)
+}
+
+func (r *RaceTestNatty) DeleteBucket(ctx context.Context, bucket string) error {
+	r.checkContext(ctx, "DeleteBucket")
+	return r.INatty.DeleteBucket(ctx, bucket)
+}
+
+func (r *RaceTestNatty) Increment(ctx context.Context, bucket, key string, delta int64) (int64, error) {
+	r.checkContext(ctx, "Increment")
+	return r.INatty.Increment(ctx, bucket, key, delta)
+}
+
+func (r *RaceTestNatty) Decrement(ctx context.Context, bucket, key string, delta int64) (int64, error) {
+	r.checkContext(ctx, "Decrement")
+	return r.INatty.Decrement(ctx, bucket, key, delta)
+}
+
+func (r *RaceTestNatty) Merge(ctx context.Context, bucket, key string, values [][]byte, resolver func(values [][]byte) []byte) error {
+	r.checkContext(ctx, "Merge")
+	return r.INatty.Merge(ctx, bucket, key, values, resolver)
+}
+
+func (r *RaceTestNatty) TemporaryBucket(ctx context.Context, fn func(bucket string) error) error {
+	r.checkContext(ctx, "TemporaryBucket")
+	return r.INatty.TemporaryBucket(ctx, fn)
+}
+
+func (r *RaceTestNatty) TemporaryKey(ctx context.Context, bucket string, fn func(key string) error) error {
+	r.checkContext(ctx, "TemporaryKey")
+	return r.INatty.TemporaryKey(ctx, bucket, fn)
+}
+
+func (r *RaceTestNatty) Keys(ctx context.Context, bucket string) ([]string, error) {
+	r.checkContext(ctx, "Keys")
+	return r.INatty.Keys(ctx, bucket)
+}
+
+func (r *RaceTestNatty) KeysStream(ctx context.Context, bucket string) (<-chan string, <-chan error) {
+	r.checkContext(ctx, "KeysStream")
+	return r.INatty.KeysStream(ctx, bucket)
+}
+
+func (r *RaceTestNatty) Watch(ctx context.Context, bucket, key string) (<-chan *KVEntry, error) {
+	r.checkContext(ctx, "Watch")
+	return r.INatty.Watch(ctx, bucket, key)
+}
+
+func (r *RaceTestNatty) WatchBucket(ctx context.Context, bucket string) (<-chan *KVEntry, error) {
+	r.checkContext(ctx, "WatchBucket")
+	return r.INatty.WatchBucket(ctx, bucket)
+}
+
+func (r *RaceTestNatty) WatchWithBackpressure(ctx context.Context, bucket, key string) (<-chan *KVEntry, error) {
+	r.checkContext(ctx, "WatchWithBackpressure")
+	return r.INatty.WatchWithBackpressure(ctx, bucket, key)
+}
+
+func (r *RaceTestNatty) WatchCheckpointed(ctx context.Context, bucket, key, checkpointKey string, ch chan<- *KVEntry) error {
+	r.checkContext(ctx, "WatchCheckpointed")
+	return r.INatty.WatchCheckpointed(ctx, bucket, key, checkpointKey, ch)
+}
+
+func (r *RaceTestNatty) CompactHistory(ctx context.Context, bucket, key string, keepRevisions int) error {
+	r.checkContext(ctx, "CompactHistory")
+	return r.INatty.CompactHistory(ctx, bucket, key, keepRevisions)
+}
+
+func (r *RaceTestNatty) BucketChecksum(ctx context.Context, bucket string) ([]byte, error) {
+	r.checkContext(ctx, "BucketChecksum")
+	return r.INatty.BucketChecksum(ctx, bucket)
+}
+
+func (r *RaceTestNatty) Snapshot(ctx context.Context, bucket string) (*KVSnapshot, error) {
+	r.checkContext(ctx, "Snapshot")
+	return r.INatty.Snapshot(ctx, bucket)
+}
+
+func (r *RaceTestNatty) Reconcile(ctx context.Context, bucket string, desired map[string][]byte) (ReconcileResult, error) {
+	r.checkContext(ctx, "Reconcile")
+	return r.INatty.Reconcile(ctx, bucket, desired)
+}
+
+func (r *RaceTestNatty) Hydrate(ctx context.Context, bucket string, data map[string][]byte) error {
+	r.checkContext(ctx, "Hydrate")
+	return r.INatty.Hydrate(ctx, bucket, data)
+}
+
+func (r *RaceTestNatty) IsHydrated(ctx context.Context, bucket string) (bool, error) {
+	r.checkContext(ctx, "IsHydrated")
+	return r.INatty.IsHydrated(ctx, bucket)
+}
+
+func (r *RaceTestNatty) ExportToCSV(ctx context.Context, bucket string, w io.Writer) error {
+	r.checkContext(ctx, "ExportToCSV")
+	return r.INatty.ExportToCSV(ctx, bucket, w)
+}
+
+func (r *RaceTestNatty) ImportFromCSV(ctx context.Context, bucket string, rd io.Reader) error {
+	r.checkContext(ctx, "ImportFromCSV")
+	return r.INatty.ImportFromCSV(ctx, bucket, rd)
+}
+
+func (r *RaceTestNatty) PutObject(ctx context.Context, bucket, name string, rd io.Reader) (*nats.ObjectInfo, error) {
+	r.checkContext(ctx, "PutObject")
+	return r.INatty.PutObject(ctx, bucket, name, rd)
+}
+
+func (r *RaceTestNatty) GetObject(ctx context.Context, bucket, name string) (io.ReadCloser, error) {
+	r.checkContext(ctx, "GetObject")
+	return r.INatty.GetObject(ctx, bucket, name)
+}
+
+func (r *RaceTestNatty) DeleteObject(ctx context.Context, bucket, name string) error {
+	r.checkContext(ctx, "DeleteObject")
+	return r.INatty.DeleteObject(ctx, bucket, name)
+}
+
+func (r *RaceTestNatty) ListObjects(ctx context.Context, bucket string) ([]*nats.ObjectInfo, error) {
+	r.checkContext(ctx, "ListObjects")
+	return r.INatty.ListObjects(ctx, bucket)
+}
+
+func (r *RaceTestNatty) Lock(ctx context.Context, bucket, lockKey string, ttl time.Duration) (func() error, error) {
+	r.checkContext(ctx, "Lock")
+	return r.INatty.Lock(ctx, bucket, lockKey, ttl)
+}
+
+func (r *RaceTestNatty) AsLeader(ctx context.Context, opts *AsLeaderConfig, f func() error) error {
+	r.checkContext(ctx, "AsLeader")
+	return r.INatty.AsLeader(ctx, opts, f)
+}
+
+func (r *RaceTestNatty) Drain(ctx context.Context) error {
+	r.checkContext(ctx, "Drain")
+	return r.INatty.Drain(ctx)
+}
+
+func (r *RaceTestNatty) Flush(ctx context.Context) error {
+	r.checkContext(ctx, "Flush")
+	return r.INatty.Flush(ctx)
+}
+
+func (r *RaceTestNatty) GracefulRestart(ctx context.Context) error {
+	r.checkContext(ctx, "GracefulRestart")
+	return r.INatty.GracefulRestart(ctx)
+}
+
+func (r *RaceTestNatty) Ping(ctx context.Context) error {
+	r.checkContext(ctx, "Ping")
+	return r.INatty.Ping(ctx)
+}
+
+func (r *RaceTestNatty) ServerInfo(ctx context.Context) (*ServerInfo, error) {
+	r.checkContext(ctx, "ServerInfo")
+	return r.INatty.ServerInfo(ctx)
+}
+
+func (r *RaceTestNatty) Healthcheck(ctx context.Context) error {
+	r.checkContext(ctx, "Healthcheck")
+	return r.INatty.Healthcheck(ctx)
+}
+
+func (r *RaceTestNatty) checkContext(ctx context.Context, method string) {
+	if ctx == nil {
+		warnw(r.log, "called with nil context", "method", method)
+	}
+}
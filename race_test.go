@@ -0,0 +1,96 @@
+package natty
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RaceTestNatty", func() {
+	var (
+		logger *recordingLogger
+		r      *RaceTestNatty
+	)
+
+	BeforeEach(func() {
+		logger = &recordingLogger{}
+		r = NewRaceTestNatty(&stubNatty{}, logger)
+	})
+
+	It("should warn when called with a nil context", func() {
+		_, err := r.Get(nil, "bucket", "key")
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(logger.Warnings()).To(HaveLen(1))
+		Expect(logger.Warnings()[0]).To(ContainSubstring("called with nil context"))
+		Expect(logger.Warnings()[0]).To(ContainSubstring("method=Get"))
+	})
+
+	It("should not warn when called with a context", func() {
+		_, err := r.Get(context.Background(), "bucket", "key")
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(logger.Warnings()).To(BeEmpty())
+	})
+})
+
+// stubNatty is a do-nothing INatty used to test wrappers; it records the
+// names of called methods.
+type stubNatty struct {
+	INatty
+
+	calls []string
+}
+
+func (s *stubNatty) Get(_ context.Context, _ string, _ string) ([]byte, error) {
+	s.calls = append(s.calls, "Get")
+	return nil, nil
+}
+
+func (s *stubNatty) Put(_ context.Context, _ string, _ string, _ []byte, _ ...time.Duration) error {
+	s.calls = append(s.calls, "Put")
+	return nil
+}
+
+func (s *stubNatty) Keys(_ context.Context, _ string) ([]string, error) {
+	s.calls = append(s.calls, "Keys")
+	return nil, nil
+}
+
+func (s *stubNatty) CopyKey(_ context.Context, _, _, _, _ string) error {
+	s.calls = append(s.calls, "CopyKey")
+	return nil
+}
+
+// recordingLogger records warnings; all other levels are discarded
+type recordingLogger struct {
+	NoOpLogger
+
+	mtx      sync.Mutex
+	warnings []string
+}
+
+func (l *recordingLogger) Warn(args ...interface{}) {
+	l.record(fmt.Sprint(args...))
+}
+
+func (l *recordingLogger) Warnf(format string, args ...interface{}) {
+	l.record(fmt.Sprintf(format, args...))
+}
+
+func (l *recordingLogger) Warnings() []string {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	return l.warnings
+}
+
+func (l *recordingLogger) record(msg string) {
+	l.mtx.Lock()
+	l.warnings = append(l.warnings, msg)
+	l.mtx.Unlock()
+}
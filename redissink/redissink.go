@@ -0,0 +1,53 @@
+// Package redissink provides a natty.KVSink that mirrors KV changes to Redis.
+//
+// Usage:
+//
+//	sink := redissink.NewRedisKVSink(redis.NewClient(&redis.Options{}), "")
+//	err := natty.NewKVPipeline(n, sink).Run(ctx, "bucket")
+package redissink
+
+import (
+	"context"
+
+	"github.com/batchcorp/natty"
+	"github.com/go-redis/redis/v8"
+	"github.com/pkg/errors"
+)
+
+// RedisKVSink mirrors KV changes to a Redis hash: puts are written via HSET
+// and deletes/purges are removed via HDEL (field = key, value = value).
+type RedisKVSink struct {
+	client redis.Cmdable
+	hash   string
+}
+
+// NewRedisKVSink creates a sink that writes to the given hash; if hash is
+// empty, every bucket is mirrored to a hash named after the bucket.
+func NewRedisKVSink(client redis.Cmdable, hash string) *RedisKVSink {
+	return &RedisKVSink{
+		client: client,
+		hash:   hash,
+	}
+}
+
+// Sink implements natty.KVSink
+func (r *RedisKVSink) Sink(ctx context.Context, event *natty.KeyValueEvent) error {
+	hash := r.hash
+
+	if hash == "" {
+		hash = event.Bucket
+	}
+
+	switch event.Operation {
+	case natty.KVEventOpDelete, natty.KVEventOpPurge:
+		if err := r.client.HDel(ctx, hash, event.Key).Err(); err != nil {
+			return errors.Wrapf(err, "unable to HDEL key '%s' from hash '%s'", event.Key, hash)
+		}
+	default:
+		if err := r.client.HSet(ctx, hash, event.Key, event.Value).Err(); err != nil {
+			return errors.Wrapf(err, "unable to HSET key '%s' in hash '%s'", event.Key, hash)
+		}
+	}
+
+	return nil
+}
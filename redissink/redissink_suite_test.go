@@ -0,0 +1,13 @@
+package redissink
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestRedisSinkSuite(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "RedisSink Suite")
+}
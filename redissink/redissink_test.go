@@ -0,0 +1,80 @@
+package redissink
+
+import (
+	"context"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/batchcorp/natty"
+	"github.com/batchcorp/natty/nattytest"
+	"github.com/go-redis/redis/v8"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RedisKVSink", func() {
+	var (
+		server *miniredis.Miniredis
+		client *redis.Client
+		fake   *nattytest.FakeNatty
+		ctx    context.Context
+		cancel context.CancelFunc
+	)
+
+	BeforeEach(func() {
+		var err error
+
+		server, err = miniredis.Run()
+		Expect(err).ToNot(HaveOccurred())
+
+		client = redis.NewClient(&redis.Options{Addr: server.Addr()})
+		fake = nattytest.NewFakeNatty()
+
+		ctx, cancel = context.WithCancel(context.Background())
+	})
+
+	AfterEach(func() {
+		cancel()
+		client.Close()
+		server.Close()
+	})
+
+	It("should mirror puts and deletes to a hash", func() {
+		Expect(fake.Put(ctx, "bucket", "existing", []byte("value"))).To(Succeed())
+
+		go natty.NewKVPipeline(fake, NewRedisKVSink(client, "")).Run(ctx, "bucket")
+
+		Eventually(func() string { return server.HGet("bucket", "existing") }).Should(Equal("value"))
+
+		Expect(fake.Put(ctx, "bucket", "foo", []byte("bar"))).To(Succeed())
+		Expect(fake.Put(ctx, "bucket", "foo", []byte("baz"))).To(Succeed())
+		Expect(fake.Delete(ctx, "bucket", "existing")).To(Succeed())
+
+		Eventually(func() []string {
+			keys, _ := server.HKeys("bucket")
+			return keys
+		}).Should(Equal([]string{"foo"}))
+		Expect(server.HGet("bucket", "foo")).To(Equal("baz"))
+	})
+
+	It("should write to the configured hash", func() {
+		sink := NewRedisKVSink(client, "mirror")
+
+		err := sink.Sink(ctx, &natty.KeyValueEvent{
+			Bucket:    "bucket",
+			Key:       "foo",
+			Value:     []byte("bar"),
+			Operation: natty.KVEventOpPut,
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(server.HGet("mirror", "foo")).To(Equal("bar"))
+		Expect(server.Exists("bucket")).To(BeFalse())
+	})
+
+	It("should return redis errors", func() {
+		server.Close()
+
+		err := NewRedisKVSink(client, "").Sink(ctx, &natty.KeyValueEvent{Bucket: "bucket", Key: "foo"})
+		Expect(err).To(HaveOccurred())
+	})
+})
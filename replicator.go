@@ -0,0 +1,168 @@
+package natty
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/nats-io/nats.go"
+	"github.com/pkg/errors"
+)
+
+// Replicator keeps a bucket on a destination INatty (ie. a Natty connected to
+// a NATS cluster in another region) in sync with the same bucket on a source
+// Natty, either one-way (Start()) or both ways (StartBidirectional()). The
+// zero value is ready to use.
+type Replicator struct {
+	// Logger is used to log changes that could not be replicated. Defaults to
+	// the logger of src (Start()) or a (StartBidirectional()).
+	Logger Logger
+}
+
+// Start watches every key in bucket on src (see WatchWithBackpressure()) and
+// applies each change to the same bucket on dst: puts are written via Put()
+// (auto-creating the destination bucket) and deletes/purges via Delete().
+// Current values are replicated first, followed by updates. Start returns once
+// the watch is running; replication stops when ctx is cancelled.
+//
+// NOTE: Replication is one-way and last-writer-wins; changes made directly on
+// dst are overwritten by later changes on src. src and dst must not share a
+// NATS server, otherwise every replicated change is replicated again. Failures
+// to apply a change are logged and the change is skipped.
+func (r *Replicator) Start(ctx context.Context, src *Natty, dst INatty, bucket string) error {
+	if src == nil || dst == nil {
+		return errors.New("src and dst cannot be nil")
+	}
+
+	entries, err := src.WatchWithBackpressure(ctx, bucket, ">")
+	if err != nil {
+		return errors.Wrapf(err, "unable to watch bucket '%s'", bucket)
+	}
+
+	go func() {
+		for entry := range entries {
+			if err := r.apply(ctx, dst, entry); err != nil {
+				r.logger(src.log).Errorf("unable to replicate key '%s' (revision %d) in bucket '%s': %s",
+					entry.Key, entry.Revision, bucket, err)
+			}
+		}
+	}()
+
+	return nil
+}
+
+// StartBidirectional replicates bucket from a to b and from b to a; a change
+// is only applied if it wins over the other side's current entry:
+//
+//   - the entry with the later Created timestamp wins (last-write-wins); a
+//     deleted key is compared by the timestamp of its delete marker
+//   - on a tie, the entry with the (bytewise) larger value wins
+//   - a change whose value is already present on the other side is skipped,
+//     which is also what stops a replicated change from bouncing back
+//
+// Like Start(), current values are replicated first and StartBidirectional
+// returns once both watches are running; replication stops when ctx is
+// cancelled. Failures to apply a change are logged (via a's logger unless
+// Logger is set) and the change is skipped.
+//
+// NOTE: Timestamps are assigned by each side's NATS server, so clock skew
+// between servers skews conflict resolution. The check and the write are not
+// atomic: a change made on one side while a change is being applied to it can
+// be overwritten.
+func (r *Replicator) StartBidirectional(ctx context.Context, a *Natty, b INatty, bucket string) error {
+	if a == nil || b == nil {
+		return errors.New("a and b cannot be nil")
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+
+	aEntries, err := a.WatchWithBackpressure(ctx, bucket, ">")
+	if err != nil {
+		cancel()
+		return errors.Wrapf(err, "unable to watch bucket '%s' on a", bucket)
+	}
+
+	bEntries, err := b.WatchWithBackpressure(ctx, bucket, ">")
+	if err != nil {
+		cancel()
+		return errors.Wrapf(err, "unable to watch bucket '%s' on b", bucket)
+	}
+
+	log := r.logger(a.log)
+
+	merge := func(dst INatty, entries <-chan *KVEntry) {
+		defer cancel()
+
+		for entry := range entries {
+			if err := r.applyIfNewer(ctx, dst, bucket, entry); err != nil {
+				errorw(log, "unable to replicate key", "bucket", bucket,
+					"key", entry.Key, "revision", entry.Revision, "error", err)
+			}
+		}
+	}
+
+	go merge(b, aEntries)
+	go merge(a, bEntries)
+
+	return nil
+}
+
+func (r *Replicator) apply(ctx context.Context, dst INatty, entry *KVEntry) error {
+	switch entry.Operation {
+	case nats.KeyValueDelete, nats.KeyValuePurge:
+		return dst.Delete(ctx, entry.Bucket, entry.Key)
+	default:
+		return dst.Put(ctx, entry.Bucket, entry.Key, entry.Value)
+	}
+}
+
+// applyIfNewer applies entry to bucket on dst if it wins over dst's current
+// entry (see StartBidirectional())
+func (r *Replicator) applyIfNewer(ctx context.Context, dst INatty, bucket string, entry *KVEntry) error {
+	deleted := entry.Operation == nats.KeyValueDelete || entry.Operation == nats.KeyValuePurge
+
+	current, err := dst.GetLatestEntry(ctx, bucket, entry.Key)
+	if err != nil {
+		if err != nats.ErrKeyNotFound {
+			return errors.Wrap(err, "unable to fetch current entry")
+		}
+
+		// Nothing to delete; a key without history is always overwritten by a put
+		if deleted {
+			return nil
+		}
+
+		return dst.Put(ctx, bucket, entry.Key, entry.Value)
+	}
+
+	currentDeleted := current.Operation == nats.KeyValueDelete || current.Operation == nats.KeyValuePurge
+
+	if deleted && currentDeleted {
+		return nil
+	}
+
+	if !deleted && !currentDeleted && bytes.Equal(entry.Value, current.Value) {
+		return nil
+	}
+
+	if current.Created.After(entry.Created) {
+		return nil
+	}
+
+	if current.Created.Equal(entry.Created) && bytes.Compare(entry.Value, current.Value) <= 0 {
+		return nil
+	}
+
+	if deleted {
+		return dst.Delete(ctx, bucket, entry.Key)
+	}
+
+	return dst.Put(ctx, bucket, entry.Key, entry.Value)
+}
+
+func (r *Replicator) logger(fallback Logger) Logger {
+	if r.Logger != nil {
+		return r.Logger
+	}
+
+	return fallback
+}
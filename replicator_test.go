@@ -0,0 +1,250 @@
+package natty
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Replicator", func() {
+	var (
+		src *Natty
+		dst *replicaNatty
+	)
+
+	BeforeEach(func() {
+		var err error
+
+		src, err = New(NewConfig())
+		Expect(err).ToNot(HaveOccurred())
+
+		n, err := New(NewConfig())
+		Expect(err).ToNot(HaveOccurred())
+
+		dst = &replicaNatty{Natty: n}
+	})
+
+	It("should replicate changes from src to dst", func() {
+		bucket, _, _ := NewKVSet()
+
+		Expect(src.Put(context.Background(), bucket, "existing", []byte("value"))).To(Succeed())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		Expect((&Replicator{}).Start(ctx, src, dst, bucket)).To(Succeed())
+
+		Eventually(func() ([]byte, error) {
+			return dst.Get(context.Background(), bucket, "existing")
+		}, time.Second).Should(Equal([]byte("value")))
+
+		for i := 0; i < 10; i++ {
+			Expect(src.Put(context.Background(), bucket, "key"+strconv.Itoa(i), []byte(strconv.Itoa(i)))).To(Succeed())
+		}
+
+		for i := 0; i < 10; i++ {
+			key := "key" + strconv.Itoa(i)
+
+			Eventually(func() ([]byte, error) {
+				return dst.Get(context.Background(), bucket, key)
+			}, time.Second).Should(Equal([]byte(strconv.Itoa(i))))
+		}
+
+		Expect(src.Delete(context.Background(), bucket, "existing")).To(Succeed())
+
+		Eventually(func() error {
+			_, err := dst.Get(context.Background(), bucket, "existing")
+			return err
+		}, time.Second).Should(Equal(nats.ErrKeyNotFound))
+	})
+
+	It("should error for a missing bucket", func() {
+		err := (&Replicator{}).Start(context.Background(), src, dst, GetRandomName("test", 1))
+		Expect(errors.Is(err, nats.ErrBucketNotFound)).To(BeTrue())
+	})
+})
+
+var _ = Describe("Replicator bidirectional", func() {
+	var (
+		a *Natty
+		b *replicaNatty
+	)
+
+	BeforeEach(func() {
+		var err error
+
+		a, err = New(NewConfig())
+		Expect(err).ToNot(HaveOccurred())
+
+		n, err := New(NewConfig())
+		Expect(err).ToNot(HaveOccurred())
+
+		b = &replicaNatty{Natty: n}
+	})
+
+	// snapshot returns all key/values in bucket on n
+	snapshot := func(n INatty, bucket string) map[string]string {
+		keys, err := n.Keys(context.Background(), bucket)
+		Expect(err).ToNot(HaveOccurred())
+
+		data := make(map[string]string)
+
+		for _, key := range keys {
+			value, err := n.Get(context.Background(), bucket, key)
+			if err == nats.ErrKeyNotFound {
+				continue
+			}
+
+			Expect(err).ToNot(HaveOccurred())
+
+			data[key] = string(value)
+		}
+
+		return data
+	}
+
+	It("should converge when both sides are written to simultaneously", func() {
+		bucket, _, _ := NewKVSet()
+
+		Expect(a.CreateBucket(context.Background(), bucket, 0)).To(Succeed())
+		Expect(b.CreateBucket(context.Background(), bucket, 0)).To(Succeed())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		Expect((&Replicator{}).StartBidirectional(ctx, a, b, bucket)).To(Succeed())
+
+		var wg sync.WaitGroup
+
+		for _, side := range []struct {
+			name string
+			n    INatty
+		}{{"a", a}, {"b", b}} {
+			wg.Add(1)
+
+			go func(name string, n INatty) {
+				defer GinkgoRecover()
+				defer wg.Done()
+
+				for i := 0; i < 10; i++ {
+					Expect(n.Put(context.Background(), bucket, name+strconv.Itoa(i), []byte(name))).To(Succeed())
+					Expect(n.Put(context.Background(), bucket, "shared"+strconv.Itoa(i), []byte(name))).To(Succeed())
+				}
+			}(side.name, side.n)
+		}
+
+		wg.Wait()
+
+		Eventually(func() map[string]string {
+			return snapshot(b, bucket)
+		}, 5*time.Second).Should(HaveLen(30))
+
+		Eventually(func() bool {
+			aData, bData := snapshot(a, bucket), snapshot(b, bucket)
+			return len(aData) == 30 && reflect.DeepEqual(aData, bData)
+		}, 5*time.Second).Should(BeTrue())
+
+		// Deletes are replicated as well
+		Expect(b.Delete(context.Background(), bucket, "a0")).To(Succeed())
+
+		Eventually(func() error {
+			_, err := a.Get(context.Background(), bucket, "a0")
+			return err
+		}, time.Second).Should(Equal(nats.ErrKeyNotFound))
+	})
+
+	It("should not resurrect a deleted key with an older put", func() {
+		bucket, key, value := NewKVSet()
+
+		Expect(a.Put(context.Background(), bucket, key, value)).To(Succeed())
+
+		older, err := a.GetEntry(context.Background(), bucket, key)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(a.Delete(context.Background(), bucket, key)).To(Succeed())
+
+		latest, err := a.GetLatestEntry(context.Background(), bucket, key)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(latest.Operation).ToNot(Equal(nats.KeyValuePut))
+
+		r := &Replicator{}
+
+		// A put made before the delete (ie. on the other side) arrives late
+		stale := &KVEntry{
+			Bucket:    bucket,
+			Key:       key,
+			Value:     []byte("stale"),
+			Created:   older.Created,
+			Operation: nats.KeyValuePut,
+		}
+
+		Expect(r.applyIfNewer(context.Background(), a, bucket, stale)).To(Succeed())
+
+		_, err = a.Get(context.Background(), bucket, key)
+		Expect(err).To(Equal(nats.ErrKeyNotFound))
+
+		// A put made after the delete wins
+		newer := &KVEntry{
+			Bucket:    bucket,
+			Key:       key,
+			Value:     []byte("newer"),
+			Created:   latest.Created.Add(time.Second),
+			Operation: nats.KeyValuePut,
+		}
+
+		Expect(r.applyIfNewer(context.Background(), a, bucket, newer)).To(Succeed())
+
+		data, err := a.Get(context.Background(), bucket, key)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(Equal([]byte("newer")))
+	})
+})
+
+// replicaNatty uses "<bucket>-replica" instead of bucket so that both sides of
+// a replication can share the test NATS server without replicating in a loop
+type replicaNatty struct {
+	*Natty
+}
+
+func (r *replicaNatty) Get(ctx context.Context, bucket, key string) ([]byte, error) {
+	return r.Natty.Get(ctx, bucket+"-replica", key)
+}
+
+func (r *replicaNatty) Put(ctx context.Context, bucket, key string, data []byte, keyTTL ...time.Duration) error {
+	return r.Natty.Put(ctx, bucket+"-replica", key, data, keyTTL...)
+}
+
+func (r *replicaNatty) Delete(ctx context.Context, bucket, key string) error {
+	return r.Natty.Delete(ctx, bucket+"-replica", key)
+}
+
+func (r *replicaNatty) GetEntry(ctx context.Context, bucket, key string) (*KVEntry, error) {
+	return r.Natty.GetEntry(ctx, bucket+"-replica", key)
+}
+
+func (r *replicaNatty) GetLatestEntry(ctx context.Context, bucket, key string) (*KVEntry, error) {
+	return r.Natty.GetLatestEntry(ctx, bucket+"-replica", key)
+}
+
+func (r *replicaNatty) Keys(ctx context.Context, bucket string) ([]string, error) {
+	return r.Natty.Keys(ctx, bucket+"-replica")
+}
+
+func (r *replicaNatty) CreateBucket(ctx context.Context, bucket string, ttl time.Duration, description ...string) error {
+	return r.Natty.CreateBucket(ctx, bucket+"-replica", ttl, description...)
+}
+
+func (r *replicaNatty) WatchWithBackpressure(ctx context.Context, bucket, key string) (<-chan *KVEntry, error) {
+	return r.Natty.WatchWithBackpressure(ctx, bucket+"-replica", key)
+}
+
+func (r *replicaNatty) Snapshot(ctx context.Context, bucket string) (*KVSnapshot, error) {
+	return r.Natty.Snapshot(ctx, bucket+"-replica")
+}
@@ -0,0 +1,325 @@
+package natty
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/pkg/errors"
+)
+
+const (
+	resubscribeTimeout       = 5 * time.Second
+	resubscribeRetryInterval = 50 * time.Millisecond
+)
+
+// restartGuardKey marks a context as belonging to a KV operation that already
+// holds the restart guard; nested KV calls made with it do not acquire the
+// guard again (which would deadlock once GracefulRestart() is waiting).
+type restartGuardKey struct{}
+
+// GracefulRestart replaces the NATS connection without failing requests:
+//
+//  1. new KV operations, publish batches and subscriptions are held back
+//     (they block until the restart is done); messages queued by Publish()
+//     stay queued and are sent on the new connection
+//  2. in-flight KV operations and publish batches are waited for
+//  3. a new connection is established and swapped in; the old connection is
+//     closed
+//  4. subscriptions created via Subscribe() and SubscribeWithHeaderFilter()
+//     are re-created on the new connection
+//
+// If ctx expires before the new connection is swapped in, the old connection
+// is kept, held back operations continue and an error is returned.
+//
+// NOTE: Subscription handlers are not waited for (a handler may itself be
+// waiting on a held back KV operation); messages that are not ACK'd before
+// the old connection is closed are redelivered once their AckWait expires.
+// Ephemeral subscriptions (SubscribeWithHeaderFilter() without a durable)
+// restart delivery from the beginning of the stream. Locks acquired via Lock()
+// are kept (they are refreshed on the new connection). Watches (including
+// KeysStream()) and Consume() are not covered: only their setup is held back;
+// they stop with the old connection and must be restarted by the caller. See
+// the README for other calls that are not covered.
+func (n *Natty) GracefulRestart(ctx context.Context) error {
+	if n.isClosed() {
+		return ErrConnectionClosed
+	}
+
+	if err := n.restartMutex.Lock(ctx); err != nil {
+		return errors.Wrap(err, "timed out waiting for in-flight operations")
+	}
+
+	defer n.restartMutex.Unlock()
+
+	natsOpts, err := buildNatsOptions(n.Config)
+	if err != nil {
+		return errors.Wrap(err, "unable to build NATS options")
+	}
+
+	nc, err := nats.Connect(strings.Join(n.NatsURL, ","), natsOpts...)
+	if err != nil {
+		return errors.Wrap(err, "failed to connect to NATS")
+	}
+
+	js, asyncJS, err := newJetStreamContexts(nc, n.Config)
+	if err != nil {
+		nc.Close()
+		return err
+	}
+
+	n.connMutex.Lock()
+
+	old := n.nc
+
+	n.nc = nc
+	n.js = js
+	n.asyncJS = asyncJS
+
+	n.connMutex.Unlock()
+
+	n.kvMap.reset()
+
+	// Closing (rather than draining) keeps consumers that the NATS client
+	// created for subscriptions; draining would delete them
+	old.Close()
+
+	var failed int
+
+	n.subsMutex.Lock()
+	defer n.subsMutex.Unlock()
+
+	for ms := range n.subs {
+		if err := ms.resubscribe(ctx, js); err != nil {
+			errorw(n.log, "unable to re-create subscription", "subject", ms.subject, "error", err)
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return errors.Errorf("unable to re-create %d subscription(s)", failed)
+	}
+
+	return nil
+}
+
+// guardKV holds back KV operations while GracefulRestart() is running; the
+// returned func must be called once the operation is done.
+func (n *Natty) guardKV(ctx context.Context) (context.Context, func()) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if ctx.Value(restartGuardKey{}) != nil {
+		return ctx, func() {}
+	}
+
+	n.restartMutex.RLock()
+
+	return context.WithValue(ctx, restartGuardKey{}, true), n.restartMutex.RUnlock
+}
+
+// restartLock is a reader/writer lock between KV operations, publish batches
+// and subscriptions (readers) and GracefulRestart() (the writer). Unlike
+// sync.RWMutex, a writer that gives up waiting (see Lock()) does not keep
+// holding back new readers. The zero value is an unlocked restartLock.
+type restartLock struct {
+	mtx     sync.Mutex
+	readers int
+
+	// writer is closed once the pending (or active) writer is done
+	writer chan struct{}
+
+	// idle is closed once the last reader is done while a writer waits
+	idle chan struct{}
+}
+
+// RLock blocks while a writer is pending or active
+func (l *restartLock) RLock() {
+	l.mtx.Lock()
+
+	for l.writer != nil {
+		writer := l.writer
+
+		l.mtx.Unlock()
+		<-writer
+		l.mtx.Lock()
+	}
+
+	l.readers++
+
+	l.mtx.Unlock()
+}
+
+func (l *restartLock) RUnlock() {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	l.readers--
+
+	if l.readers == 0 && l.idle != nil {
+		close(l.idle)
+		l.idle = nil
+	}
+}
+
+// Lock holds back new readers and waits for active readers to finish. If ctx
+// is done first, new readers are let through again and ctx.Err() is returned.
+func (l *restartLock) Lock(ctx context.Context) error {
+	l.mtx.Lock()
+
+	// Only one writer at a time
+	for l.writer != nil {
+		writer := l.writer
+
+		l.mtx.Unlock()
+
+		select {
+		case <-writer:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		l.mtx.Lock()
+	}
+
+	l.writer = make(chan struct{})
+
+	if l.readers == 0 {
+		l.mtx.Unlock()
+		return nil
+	}
+
+	idle := make(chan struct{})
+	l.idle = idle
+
+	l.mtx.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		l.Unlock()
+		return ctx.Err()
+	}
+}
+
+// Unlock lets held back readers through; must only be called after a
+// successful Lock()
+func (l *restartLock) Unlock() {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	close(l.writer)
+
+	l.writer = nil
+	l.idle = nil
+}
+
+// managedSub is a subscription that is re-created by GracefulRestart()
+type managedSub struct {
+	mtx       sync.Mutex
+	stream    string
+	durable   string
+	subject   string
+	sub       *nats.Subscription
+	subscribe func(cb nats.MsgHandler) (*nats.Subscription, error)
+	cb        nats.MsgHandler
+	stopped   bool
+}
+
+// subscribeManaged calls subscribe (which must use n.getJS()) with cb and
+// registers the subscription for GracefulRestart(); durable is the name of the
+// consumer bound by subscribe (if any).
+func (n *Natty) subscribeManaged(stream, durable, subject string, subscribe func(cb nats.MsgHandler) (*nats.Subscription, error), cb nats.MsgHandler) (*managedSub, error) {
+	ms := &managedSub{
+		stream:    stream,
+		durable:   durable,
+		subject:   subject,
+		subscribe: subscribe,
+		cb:        cb,
+	}
+
+	n.restartMutex.RLock()
+	defer n.restartMutex.RUnlock()
+
+	sub, err := subscribe(ms.cb)
+	if err != nil {
+		return nil, err
+	}
+
+	ms.sub = sub
+
+	n.subsMutex.Lock()
+	n.subs[ms] = struct{}{}
+	n.subsMutex.Unlock()
+
+	return ms, nil
+}
+
+// unsubscribe stops ms and removes it from the GracefulRestart() registry
+func (n *Natty) unsubscribe(ms *managedSub) error {
+	n.subsMutex.Lock()
+	delete(n.subs, ms)
+	n.subsMutex.Unlock()
+
+	ms.mtx.Lock()
+	defer ms.mtx.Unlock()
+
+	ms.stopped = true
+
+	return ms.sub.Unsubscribe()
+}
+
+// resubscribe re-creates ms on the current connection. The server may not have
+// noticed yet that the old connection is gone, in which case durable consumers
+// are still bound to the old subscription; subscribing waits for the consumer
+// to be unbound until resubscribeTimeout (or ctx) expires.
+func (ms *managedSub) resubscribe(ctx context.Context, js nats.JetStreamContext) error {
+	ms.mtx.Lock()
+	defer ms.mtx.Unlock()
+
+	if ms.stopped {
+		return nil
+	}
+
+	deadline := time.Now().Add(resubscribeTimeout)
+
+	for ms.pushBound(js) {
+		if time.Now().After(deadline) {
+			return errors.Errorf("consumer '%s' is still bound to the old subscription", ms.durable)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(resubscribeRetryInterval):
+		}
+	}
+
+	sub, err := ms.subscribe(ms.cb)
+	if err != nil {
+		return err
+	}
+
+	ms.sub = sub
+
+	return nil
+}
+
+// pushBound reports whether the server still considers ms's durable consumer
+// bound to a subscription
+func (ms *managedSub) pushBound(js nats.JetStreamContext) bool {
+	if ms.durable == "" {
+		return false
+	}
+
+	info, err := js.ConsumerInfo(ms.stream, ms.durable)
+	if err != nil {
+		return false
+	}
+
+	return info.PushBound
+}
@@ -0,0 +1,297 @@
+package natty
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("GracefulRestart", func() {
+	It("should not hold back nested KV operations while waiting", func() {
+		n := &Natty{restartMutex: &restartLock{}}
+
+		ctx, release := n.guardKV(context.Background())
+
+		locked := make(chan struct{})
+
+		go func() {
+			Expect(n.restartMutex.Lock(context.Background())).To(Succeed())
+			close(locked)
+			n.restartMutex.Unlock()
+		}()
+
+		// Give the writer a chance to start waiting
+		time.Sleep(50 * time.Millisecond)
+
+		nested := make(chan struct{})
+
+		go func() {
+			_, nestedRelease := n.guardKV(ctx)
+			nestedRelease()
+			close(nested)
+		}()
+
+		Eventually(nested).Should(BeClosed())
+		Consistently(locked, 100*time.Millisecond).ShouldNot(BeClosed())
+
+		release()
+
+		Eventually(locked).Should(BeClosed())
+	})
+
+	It("should let KV operations through once a restart gives up waiting", func() {
+		n := &Natty{restartMutex: &restartLock{}}
+
+		_, release := n.guardKV(context.Background())
+		defer release()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		Expect(n.restartMutex.Lock(ctx)).To(Equal(context.DeadlineExceeded))
+
+		acquired := make(chan struct{})
+
+		go func() {
+			_, release := n.guardKV(context.Background())
+			release()
+			close(acquired)
+		}()
+
+		Eventually(acquired).Should(BeClosed())
+	})
+
+	Context("with NATS", func() {
+		var n *Natty
+
+		BeforeEach(func() {
+			var err error
+
+			n, err = New(NewConfig())
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("should complete operations started before, during and after a restart", func() {
+			bucket, _, _ := NewKVSet()
+
+			Expect(n.CreateBucket(context.Background(), bucket, 0)).To(Succeed())
+
+			const (
+				numWorkers = 10
+				numOps     = 50
+			)
+
+			var wg sync.WaitGroup
+
+			for w := 0; w < numWorkers; w++ {
+				wg.Add(1)
+
+				go func(worker int) {
+					defer GinkgoRecover()
+					defer wg.Done()
+
+					for i := 0; i < numOps; i++ {
+						ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+
+						key := strconv.Itoa(worker) + "-" + strconv.Itoa(i)
+
+						Expect(n.Put(ctx, bucket, key, []byte(key))).To(Succeed())
+
+						data, err := n.Get(ctx, bucket, key)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(data).To(Equal([]byte(key)))
+
+						cancel()
+					}
+				}(w)
+			}
+
+			time.Sleep(50 * time.Millisecond)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			oldConn := n.nc
+
+			Expect(n.GracefulRestart(ctx)).To(Succeed())
+			Expect(oldConn.IsClosed()).To(BeTrue())
+			Expect(n.IsConnected()).To(BeTrue())
+
+			done := make(chan struct{})
+
+			go func() {
+				wg.Wait()
+				close(done)
+			}()
+
+			Eventually(done, 20*time.Second).Should(BeClosed())
+
+			keys, err := n.Keys(context.Background(), bucket)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(keys).To(HaveLen(numWorkers * numOps))
+		})
+
+		It("should complete publish batches started before and during a restart", func() {
+			streamName := strings.ToUpper(GetRandomName("test", 1))
+			testStreams = append(testStreams, streamName)
+
+			Expect(n.CreateStream(context.Background(), streamName, []string{streamName + ".*"})).To(Succeed())
+
+			var wg sync.WaitGroup
+
+			for w := 0; w < 5; w++ {
+				wg.Add(1)
+
+				go func() {
+					defer GinkgoRecover()
+					defer wg.Done()
+
+					for i := 0; i < 20; i++ {
+						messages := make([]*nats.Msg, 0, 50)
+
+						for j := 0; j < 50; j++ {
+							messages = append(messages, &nats.Msg{Subject: streamName + ".foo", Data: []byte("foo")})
+						}
+
+						_, err := n.PublishAsyncBatch(context.Background(), messages)
+						Expect(err).ToNot(HaveOccurred())
+					}
+				}()
+			}
+
+			time.Sleep(50 * time.Millisecond)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			Expect(n.GracefulRestart(ctx)).To(Succeed())
+
+			wg.Wait()
+		})
+
+		It("should not be held back by an active KeysStream", func() {
+			bucket, _, _ := NewKVSet()
+
+			for i := 0; i < 10; i++ {
+				key := strconv.Itoa(i)
+				Expect(n.Put(context.Background(), bucket, key, []byte(key))).To(Succeed())
+			}
+
+			streamCtx, streamCancel := context.WithCancel(context.Background())
+			defer streamCancel()
+
+			keyCh, _ := n.KeysStream(streamCtx, bucket)
+
+			// Read one key and leave the stream idle during the restart
+			Eventually(keyCh, 5*time.Second).Should(Receive())
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			Expect(n.GracefulRestart(ctx)).To(Succeed())
+
+			// KV operations made while consuming the stream must not deadlock
+			_, err := n.Get(ctx, bucket, "0")
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		// Run with -race to catch unsynchronized access to the connection
+		It("should not race with non-KV operations", func() {
+			streamName := strings.ToUpper(GetRandomName("test", 1))
+			testStreams = append(testStreams, streamName)
+
+			Expect(n.CreateStream(context.Background(), streamName, []string{streamName + ".*"})).To(Succeed())
+
+			stop := make(chan struct{})
+
+			var wg sync.WaitGroup
+
+			ops := []func(ctx context.Context){
+				func(ctx context.Context) { n.Ping(ctx) },
+				func(ctx context.Context) { n.Healthcheck(ctx) },
+				func(ctx context.Context) { n.ServerInfo(ctx) },
+				func(ctx context.Context) { n.ListStreams(ctx) },
+				func(ctx context.Context) { n.Publish(ctx, streamName+".foo", []byte("foo")) },
+				func(ctx context.Context) { n.IsConnected() },
+			}
+
+			for _, op := range ops {
+				wg.Add(1)
+
+				// Errors are expected while the old connection is closed
+				go func(op func(ctx context.Context)) {
+					defer wg.Done()
+
+					for {
+						select {
+						case <-stop:
+							return
+						default:
+						}
+
+						ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+						op(ctx)
+						cancel()
+					}
+				}(op)
+			}
+
+			time.Sleep(50 * time.Millisecond)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			Expect(n.GracefulRestart(ctx)).To(Succeed())
+
+			time.Sleep(50 * time.Millisecond)
+
+			close(stop)
+			wg.Wait()
+
+			Expect(n.Ping(ctx)).To(Succeed())
+			Expect(n.Healthcheck(ctx)).To(Succeed())
+		})
+
+		It("should re-create subscriptions on the new connection", func() {
+			streamName := strings.ToUpper(GetRandomName("test", 1))
+			testStreams = append(testStreams, streamName)
+
+			Expect(n.CreateStream(context.Background(), streamName, []string{streamName + ".*"})).To(Succeed())
+
+			received := make(chan string, 10)
+
+			unsubscribe, err := n.Subscribe(context.Background(), streamName, streamName+".foo",
+				SubOptions{Durable: "restart"}, func(msg *nats.Msg) error {
+					received <- string(msg.Data)
+					return nil
+				})
+			Expect(err).ToNot(HaveOccurred())
+
+			defer unsubscribe()
+
+			_, err = n.js.Publish(streamName+".foo", []byte("before"))
+			Expect(err).ToNot(HaveOccurred())
+
+			Eventually(received, 5*time.Second).Should(Receive(Equal("before")))
+
+			Expect(n.GracefulRestart(context.Background())).To(Succeed())
+
+			_, err = n.js.Publish(streamName+".foo", []byte("after"))
+			Expect(err).ToNot(HaveOccurred())
+
+			Eventually(received, 5*time.Second).Should(Receive(Equal("after")))
+			Consistently(received, time.Second).ShouldNot(Receive())
+		})
+
+		It("should error once closed", func() {
+			Expect(n.Close()).To(Succeed())
+			Expect(n.GracefulRestart(context.Background())).To(Equal(ErrConnectionClosed))
+		})
+	})
+})
@@ -0,0 +1,119 @@
+package natty
+
+import (
+	"context"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/pkg/errors"
+)
+
+const (
+	DefaultRetryInitialDelay = 100 * time.Millisecond
+	DefaultRetryMaxDelay     = 5 * time.Second
+	DefaultRetryMultiplier   = 2.0
+)
+
+// DefaultRetryableErrors are the transient errors that are retried when
+// RetryPolicy.RetryableErrors is empty.
+var DefaultRetryableErrors = []error{
+	nats.ErrTimeout,
+	nats.ErrNoResponders,
+	nats.ErrConnectionReconnecting,
+	nats.ErrJetStreamNotEnabled,
+}
+
+// RetryPolicy configures retries of KV operations that fail with a transient
+// error. Retries are disabled unless MaxAttempts is greater than 1; zero
+// values for the other fields are replaced by the DefaultRetry* values.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts (including the first)
+	MaxAttempts int
+
+	// InitialDelay is the delay before the first retry
+	InitialDelay time.Duration
+
+	// MaxDelay caps the delay between retries
+	MaxDelay time.Duration
+
+	// Multiplier is applied to the delay after every retry
+	Multiplier float64
+
+	// RetryableErrors are the errors (matched via errors.Is) that will be
+	// retried; all other errors (ie. nats.ErrKeyNotFound) are returned as-is.
+	// Default: DefaultRetryableErrors
+	RetryableErrors []error
+}
+
+// do calls fn until it succeeds, returns a non-retryable error, MaxAttempts is
+// reached or ctx is done (in which case ctx.Err() is returned).
+func (p *RetryPolicy) do(ctx context.Context, fn func() error) error {
+	if p.MaxAttempts <= 1 {
+		return fn()
+	}
+
+	var err error
+
+	for attempt := 0; attempt < p.MaxAttempts; attempt++ {
+		if attempt > 0 {
+			timer := time.NewTimer(p.backoff(attempt))
+
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			case <-timer.C:
+			}
+		}
+
+		err = fn()
+		if err == nil || !p.isRetryable(err) {
+			return err
+		}
+	}
+
+	return errors.Wrapf(err, "giving up after %d attempts", p.MaxAttempts)
+}
+
+// backoff returns the delay before given retry attempt (1 = first retry)
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.InitialDelay
+	if delay <= 0 {
+		delay = DefaultRetryInitialDelay
+	}
+
+	maxDelay := p.MaxDelay
+	if maxDelay <= 0 {
+		maxDelay = DefaultRetryMaxDelay
+	}
+
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = DefaultRetryMultiplier
+	}
+
+	for i := 1; i < attempt && delay < maxDelay; i++ {
+		delay = time.Duration(float64(delay) * multiplier)
+	}
+
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+
+	return delay
+}
+
+func (p *RetryPolicy) isRetryable(err error) bool {
+	retryable := p.RetryableErrors
+	if len(retryable) == 0 {
+		retryable = DefaultRetryableErrors
+	}
+
+	for _, e := range retryable {
+		if errors.Is(err, e) {
+			return true
+		}
+	}
+
+	return false
+}
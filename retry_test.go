@@ -0,0 +1,210 @@
+package natty
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+)
+
+var _ = Describe("RetryPolicy", func() {
+	var (
+		policy *RetryPolicy
+	)
+
+	BeforeEach(func() {
+		policy = &RetryPolicy{
+			MaxAttempts:  3,
+			InitialDelay: time.Millisecond,
+			MaxDelay:     5 * time.Millisecond,
+		}
+	})
+
+	It("should retry transient errors until success", func() {
+		var attempts int
+
+		err := policy.do(context.Background(), func() error {
+			attempts++
+
+			if attempts < 3 {
+				return errors.Wrap(nats.ErrTimeout, "unable to fetch key")
+			}
+
+			return nil
+		})
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(attempts).To(Equal(3))
+	})
+
+	It("should not retry non-retryable errors", func() {
+		var attempts int
+
+		err := policy.do(context.Background(), func() error {
+			attempts++
+			return nats.ErrKeyNotFound
+		})
+
+		Expect(err).To(Equal(nats.ErrKeyNotFound))
+		Expect(attempts).To(Equal(1))
+	})
+
+	It("should give up after MaxAttempts", func() {
+		var attempts int
+
+		err := policy.do(context.Background(), func() error {
+			attempts++
+			return nats.ErrNoResponders
+		})
+
+		Expect(errors.Is(err, nats.ErrNoResponders)).To(BeTrue())
+		Expect(attempts).To(Equal(3))
+	})
+
+	It("should only retry configured RetryableErrors", func() {
+		custom := errors.New("custom")
+		policy.RetryableErrors = []error{custom}
+
+		var attempts int
+
+		err := policy.do(context.Background(), func() error {
+			attempts++
+			return nats.ErrTimeout
+		})
+
+		Expect(err).To(Equal(nats.ErrTimeout))
+		Expect(attempts).To(Equal(1))
+	})
+
+	It("should stop retrying when the context is cancelled", func() {
+		policy.InitialDelay = time.Minute
+		policy.MaxDelay = time.Minute
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		var attempts int
+
+		err := policy.do(ctx, func() error {
+			attempts++
+			return nats.ErrTimeout
+		})
+
+		Expect(err).To(Equal(context.DeadlineExceeded))
+		Expect(attempts).To(Equal(1))
+	})
+
+	It("should be disabled by default", func() {
+		var attempts int
+
+		err := (&RetryPolicy{}).do(context.Background(), func() error {
+			attempts++
+			return nats.ErrTimeout
+		})
+
+		Expect(err).To(Equal(nats.ErrTimeout))
+		Expect(attempts).To(Equal(1))
+	})
+
+	It("should back off exponentially up to MaxDelay", func() {
+		policy = &RetryPolicy{InitialDelay: 10 * time.Millisecond, MaxDelay: 50 * time.Millisecond, Multiplier: 2}
+
+		Expect(policy.backoff(1)).To(Equal(10 * time.Millisecond))
+		Expect(policy.backoff(2)).To(Equal(20 * time.Millisecond))
+		Expect(policy.backoff(3)).To(Equal(40 * time.Millisecond))
+		Expect(policy.backoff(4)).To(Equal(50 * time.Millisecond))
+	})
+})
+
+// flakyJS fails KeyValue() with nats.ErrTimeout the first failures times
+type flakyJS struct {
+	nats.JetStreamContext
+
+	failures int
+	calls    int
+	kv       nats.KeyValue
+}
+
+func (js *flakyJS) KeyValue(bucket string) (nats.KeyValue, error) {
+	js.calls++
+
+	if js.calls <= js.failures {
+		return nil, nats.ErrTimeout
+	}
+
+	return js.kv, nil
+}
+
+// flakyKV fails Get() with nats.ErrTimeout the first failures times
+type flakyKV struct {
+	nats.KeyValue
+
+	failures int
+	calls    int
+}
+
+func (kv *flakyKV) Get(key string) (nats.KeyValueEntry, error) {
+	kv.calls++
+
+	if kv.calls <= kv.failures {
+		return nil, nats.ErrTimeout
+	}
+
+	return nil, nats.ErrKeyNotFound
+}
+
+var _ = Describe("KV retries", func() {
+	var (
+		js *flakyJS
+		kv *flakyKV
+		n  *Natty
+	)
+
+	BeforeEach(func() {
+		kv = &flakyKV{failures: 2}
+		js = &flakyJS{failures: 2, kv: kv}
+
+		n = &Natty{
+			js: js,
+			Config: &Config{
+				Retry: RetryPolicy{MaxAttempts: 3, InitialDelay: time.Millisecond},
+			},
+			kvMap: &KeyValueMap{
+				rwMutex: &sync.RWMutex{},
+				kvMap:   make(map[string]nats.KeyValue),
+			},
+			closedMutex:  &sync.RWMutex{},
+			restartMutex: &restartLock{},
+			connMutex:    &sync.RWMutex{},
+			log:          &NoOpLogger{},
+			metrics:      &NoOpMetrics{},
+			tracer:       &NoOpTracer{},
+		}
+	})
+
+	It("should retry fetching the bucket", func() {
+		exists, err := n.BucketExists(context.Background(), "bucket")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(exists).To(BeTrue())
+		Expect(js.calls).To(Equal(3))
+	})
+
+	It("should retry the bucket fetch and the operation", func() {
+		_, err := n.Get(context.Background(), "bucket", "key")
+		Expect(err).To(Equal(nats.ErrKeyNotFound))
+		Expect(js.calls).To(Equal(3))
+		Expect(kv.calls).To(Equal(3))
+	})
+
+	It("should give up after MaxAttempts", func() {
+		js.failures = 3
+
+		_, err := n.BucketExists(context.Background(), "bucket")
+		Expect(errors.Is(err, nats.ErrTimeout)).To(BeTrue())
+		Expect(js.calls).To(Equal(3))
+	})
+})
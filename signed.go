@@ -0,0 +1,98 @@
+package natty
+
+import (
+	"crypto/ed25519"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrInvalidSignature = errors.New("invalid signature")
+)
+
+// SignedKV signs every value written via Put, Create and PutJSON with
+// Config.SigningKey; values read via Get, GetEntry and GetJSON are verified
+// (and the signature stripped) before being returned. Reads of values that
+// are not signed, or were signed by a different key, or were tampered with
+// fail with ErrInvalidSignature.
+//
+// The signature is an ed25519 signature over bucket, key and value (NUL
+// separated, so a value cannot be replayed under a different bucket or key)
+// and is stored appended to the value.
+//
+// NOTE: Only the methods listed above plus Delete and Keys are available;
+// use the wrapped Natty for everything else. Without a SigningKey, values
+// are passed through unchanged.
+type SignedKV struct {
+	*transformKV
+
+	privKey ed25519.PrivateKey
+	pubKey  ed25519.PublicKey
+}
+
+// NewSignedKV wraps given Natty; values are signed with its SigningKey (see
+// WithEd25519Signing())
+func NewSignedKV(n *Natty) *SignedKV {
+	s := &SignedKV{}
+
+	if n.SigningKey != nil {
+		s.privKey = n.SigningKey
+		s.pubKey = n.SigningKey.Public().(ed25519.PublicKey)
+	}
+
+	s.transformKV = &transformKV{n: n, t: s}
+
+	return s
+}
+
+// WithEd25519Signing sets the key used by SignedKV to sign values; see
+// Config.SigningKey
+func WithEd25519Signing(privKey ed25519.PrivateKey) Option {
+	return func(cfg *Config) {
+		cfg.SigningKey = privKey
+	}
+}
+
+func signedMessage(bucket, key string, data []byte) []byte {
+	msg := make([]byte, 0, len(bucket)+len(key)+len(data)+2)
+	msg = append(msg, bucket...)
+	msg = append(msg, 0)
+	msg = append(msg, key...)
+	msg = append(msg, 0)
+
+	return append(msg, data...)
+}
+
+// encode returns data with the signature appended
+func (s *SignedKV) encode(bucket, key string, data []byte) ([]byte, error) {
+	if s.privKey == nil {
+		return data, nil
+	}
+
+	sig := ed25519.Sign(s.privKey, signedMessage(bucket, key, data))
+
+	signed := make([]byte, 0, len(data)+len(sig))
+	signed = append(signed, data...)
+
+	return append(signed, sig...), nil
+}
+
+// decode checks the signature appended to data and returns data without it
+func (s *SignedKV) decode(bucket, key string, data []byte) ([]byte, error) {
+	if s.pubKey == nil {
+		return data, nil
+	}
+
+	if len(data) < ed25519.SignatureSize {
+		return nil, errors.Wrapf(ErrInvalidSignature, "value for key '%s' in bucket '%s' is not signed", key, bucket)
+	}
+
+	value := data[:len(data)-ed25519.SignatureSize]
+	sig := data[len(data)-ed25519.SignatureSize:]
+
+	if !ed25519.Verify(s.pubKey, signedMessage(bucket, key, value), sig) {
+		return nil, errors.Wrapf(ErrInvalidSignature, "value for key '%s' in bucket '%s'", key, bucket)
+	}
+
+	return value, nil
+}
@@ -0,0 +1,126 @@
+package natty
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+)
+
+var _ = Describe("SignedKV", func() {
+	var (
+		ctx = context.Background()
+		n   *Natty
+		s   *SignedKV
+	)
+
+	BeforeEach(func() {
+		var err error
+
+		_, privKey, err := ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+
+		n, err = New(NewConfig(), WithEd25519Signing(privKey))
+		Expect(err).ToNot(HaveOccurred())
+
+		s = NewSignedKV(n)
+	})
+
+	It("should store and retrieve signed values", func() {
+		bucket, key, value := NewKVSet()
+
+		Expect(s.Put(ctx, bucket, key, value)).To(Succeed())
+
+		data, err := s.Get(ctx, bucket, key)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(Equal(value))
+
+		entry, err := s.GetEntry(ctx, bucket, key)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(entry.Value).To(Equal(value))
+
+		raw, err := n.Get(ctx, bucket, key)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(raw).To(HaveLen(len(value) + ed25519.SignatureSize))
+	})
+
+	It("should round trip JSON values", func() {
+		bucket, key, _ := NewKVSet()
+
+		in := map[string]string{"foo": "bar"}
+		out := make(map[string]string)
+
+		Expect(s.PutJSON(ctx, bucket, key, in)).To(Succeed())
+		Expect(s.GetJSON(ctx, bucket, key, &out)).To(Succeed())
+		Expect(out).To(Equal(in))
+	})
+
+	It("should return ErrInvalidSignature for tampered values", func() {
+		bucket, key, value := NewKVSet()
+
+		Expect(s.Put(ctx, bucket, key, value)).To(Succeed())
+
+		raw, err := n.Get(ctx, bucket, key)
+		Expect(err).ToNot(HaveOccurred())
+
+		raw[0] ^= 0xff
+
+		Expect(n.Put(ctx, bucket, key, raw)).To(Succeed())
+
+		_, err = s.Get(ctx, bucket, key)
+		Expect(errors.Is(err, ErrInvalidSignature)).To(BeTrue())
+
+		_, err = s.GetEntry(ctx, bucket, key)
+		Expect(errors.Is(err, ErrInvalidSignature)).To(BeTrue())
+	})
+
+	It("should return ErrInvalidSignature for unsigned or moved values", func() {
+		bucket, key, value := NewKVSet()
+
+		Expect(n.Put(ctx, bucket, key, value)).To(Succeed())
+
+		_, err := s.Get(ctx, bucket, key)
+		Expect(errors.Is(err, ErrInvalidSignature)).To(BeTrue())
+
+		Expect(s.Put(ctx, bucket, key, value)).To(Succeed())
+
+		raw, err := n.Get(ctx, bucket, key)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(n.Put(ctx, bucket, "other", raw)).To(Succeed())
+
+		_, err = s.Get(ctx, bucket, "other")
+		Expect(errors.Is(err, ErrInvalidSignature)).To(BeTrue())
+	})
+
+	It("should reject values signed with a different key", func() {
+		bucket, key, value := NewKVSet()
+
+		_, otherKey, err := ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+
+		other, err := New(NewConfig(), WithEd25519Signing(otherKey))
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(NewSignedKV(other).Put(ctx, bucket, key, value)).To(Succeed())
+
+		_, err = s.Get(ctx, bucket, key)
+		Expect(errors.Is(err, ErrInvalidSignature)).To(BeTrue())
+	})
+
+	It("should pass values through without a signing key", func() {
+		unsigned, err := New(NewConfig())
+		Expect(err).ToNot(HaveOccurred())
+
+		bucket, key, value := NewKVSet()
+
+		Expect(NewSignedKV(unsigned).Put(ctx, bucket, key, value)).To(Succeed())
+
+		raw, err := n.Get(ctx, bucket, key)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(raw).To(Equal(value))
+	})
+})
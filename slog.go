@@ -0,0 +1,100 @@
+//go:build go1.21
+// +build go1.21
+
+package natty
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+)
+
+// WithLogger sets a *slog.Logger as the Logger used by the library
+func WithLogger(l *slog.Logger) Option {
+	return func(cfg *Config) {
+		cfg.Logger = NewSlogLogger(l)
+	}
+}
+
+// SlogLogger adapts a *slog.Logger to the Logger and StructuredLogger
+// interfaces so that natty's internal logs can be routed into an existing
+// structured logging pipeline.
+type SlogLogger struct {
+	logger *slog.Logger
+}
+
+// NewSlogLogger wraps given *slog.Logger; if logger is nil, slog.Default() is
+// used.
+func NewSlogLogger(logger *slog.Logger) *SlogLogger {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	return &SlogLogger{
+		logger: logger,
+	}
+}
+
+// Debug logs at slog.LevelDebug
+func (l *SlogLogger) Debug(args ...interface{}) {
+	l.log(slog.LevelDebug, fmt.Sprint(args...))
+}
+
+// Debugf logs at slog.LevelDebug
+func (l *SlogLogger) Debugf(format string, args ...interface{}) {
+	l.log(slog.LevelDebug, fmt.Sprintf(format, args...))
+}
+
+// Debugw logs at slog.LevelDebug with given key/value fields
+func (l *SlogLogger) Debugw(msg string, keysAndValues ...interface{}) {
+	l.log(slog.LevelDebug, msg, keysAndValues...)
+}
+
+// Info logs at slog.LevelInfo
+func (l *SlogLogger) Info(args ...interface{}) {
+	l.log(slog.LevelInfo, fmt.Sprint(args...))
+}
+
+// Infof logs at slog.LevelInfo
+func (l *SlogLogger) Infof(format string, args ...interface{}) {
+	l.log(slog.LevelInfo, fmt.Sprintf(format, args...))
+}
+
+// Infow logs at slog.LevelInfo with given key/value fields
+func (l *SlogLogger) Infow(msg string, keysAndValues ...interface{}) {
+	l.log(slog.LevelInfo, msg, keysAndValues...)
+}
+
+// Warn logs at slog.LevelWarn
+func (l *SlogLogger) Warn(args ...interface{}) {
+	l.log(slog.LevelWarn, fmt.Sprint(args...))
+}
+
+// Warnf logs at slog.LevelWarn
+func (l *SlogLogger) Warnf(format string, args ...interface{}) {
+	l.log(slog.LevelWarn, fmt.Sprintf(format, args...))
+}
+
+// Warnw logs at slog.LevelWarn with given key/value fields
+func (l *SlogLogger) Warnw(msg string, keysAndValues ...interface{}) {
+	l.log(slog.LevelWarn, msg, keysAndValues...)
+}
+
+// Error logs at slog.LevelError
+func (l *SlogLogger) Error(args ...interface{}) {
+	l.log(slog.LevelError, fmt.Sprint(args...))
+}
+
+// Errorf logs at slog.LevelError
+func (l *SlogLogger) Errorf(format string, args ...interface{}) {
+	l.log(slog.LevelError, fmt.Sprintf(format, args...))
+}
+
+// Errorw logs at slog.LevelError with given key/value fields
+func (l *SlogLogger) Errorw(msg string, keysAndValues ...interface{}) {
+	l.log(slog.LevelError, msg, keysAndValues...)
+}
+
+func (l *SlogLogger) log(level slog.Level, msg string, keysAndValues ...interface{}) {
+	l.logger.Log(context.Background(), level, msg, keysAndValues...)
+}
@@ -0,0 +1,70 @@
+//go:build go1.21
+// +build go1.21
+
+package natty
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SlogLogger", func() {
+	var (
+		buf    *bytes.Buffer
+		logger *SlogLogger
+	)
+
+	BeforeEach(func() {
+		buf = &bytes.Buffer{}
+		logger = NewSlogLogger(slog.New(slog.NewJSONHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	})
+
+	decode := func() map[string]interface{} {
+		out := make(map[string]interface{})
+		Expect(json.Unmarshal(buf.Bytes(), &out)).To(Succeed())
+
+		return out
+	}
+
+	It("should log structured fields", func() {
+		errorw(logger, "unable to fetch key", "bucket", "foo", "key", "bar", "error", errors.New("boom"))
+
+		out := decode()
+		Expect(out["level"]).To(Equal("ERROR"))
+		Expect(out["msg"]).To(Equal("unable to fetch key"))
+		Expect(out["bucket"]).To(Equal("foo"))
+		Expect(out["key"]).To(Equal("bar"))
+		Expect(out["error"]).To(Equal("boom"))
+	})
+
+	It("should log formatted messages", func() {
+		logger.Warnf("publisher '%s' exiting", "foo")
+
+		out := decode()
+		Expect(out["level"]).To(Equal("WARN"))
+		Expect(out["msg"]).To(Equal("publisher 'foo' exiting"))
+	})
+
+	It("should default to slog.Default()", func() {
+		Expect(NewSlogLogger(nil).logger).To(Equal(slog.Default()))
+	})
+
+	It("should only be used when opted into via WithLogger", func() {
+		cfg := NewConfig()
+
+		n, err := New(cfg)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(n.log).To(BeAssignableToTypeOf(&NoOpLogger{}))
+
+		WithLogger(nil)(cfg)
+
+		n, err = New(cfg)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(n.log).To(BeAssignableToTypeOf(&SlogLogger{}))
+	})
+})
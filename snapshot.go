@@ -0,0 +1,110 @@
+package natty
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/pkg/errors"
+)
+
+// KVSnapshot is a point-in-time copy of the contents of a bucket
+type KVSnapshot struct {
+	Bucket string
+	Taken  time.Time
+
+	// Data maps key to value
+	Data map[string][]byte
+}
+
+// KVSnapshotDiff is the delta between two KVSnapshots (see SnapshotDiff())
+type KVSnapshotDiff struct {
+	// Added contains keys only present in the newer snapshot
+	Added map[string][]byte
+
+	// Removed contains keys only present in the older snapshot (with their
+	// last known value)
+	Removed map[string][]byte
+
+	// Changed contains keys present in both snapshots with differing values
+	// (with the value from the newer snapshot)
+	Changed map[string][]byte
+}
+
+// Snapshot copies the current contents of a bucket into a KVSnapshot.
+//
+// NOTE: Keys are read one at a time; writes that happen while the snapshot is
+// being taken may or may not be included.
+func (n *Natty) Snapshot(ctx context.Context, bucket string) (*KVSnapshot, error) {
+	if n.isClosed() {
+		return nil, ErrConnectionClosed
+	}
+
+	keys, err := n.Keys(ctx, bucket)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to fetch keys")
+	}
+
+	snapshot := &KVSnapshot{
+		Bucket: bucket,
+		Taken:  time.Now().UTC(),
+		Data:   make(map[string][]byte, len(keys)),
+	}
+
+	for _, key := range keys {
+		entry, err := n.GetEntry(ctx, bucket, key)
+		if err != nil {
+			if err == nats.ErrKeyNotFound {
+				// Key was deleted since we listed keys
+				continue
+			}
+
+			return nil, errors.Wrapf(err, "unable to fetch key '%s'", key)
+		}
+
+		snapshot.Data[key] = entry.Value
+	}
+
+	return snapshot, nil
+}
+
+// SnapshotDiff computes the changes needed to go from snapshot a to snapshot
+// b. A nil snapshot is treated as empty.
+func SnapshotDiff(a, b *KVSnapshot) *KVSnapshotDiff {
+	diff := &KVSnapshotDiff{
+		Added:   make(map[string][]byte),
+		Removed: make(map[string][]byte),
+		Changed: make(map[string][]byte),
+	}
+
+	var before, after map[string][]byte
+
+	if a != nil {
+		before = a.Data
+	}
+
+	if b != nil {
+		after = b.Data
+	}
+
+	for key, value := range after {
+		old, ok := before[key]
+		if !ok {
+			diff.Added[key] = value
+			continue
+		}
+
+		if !bytes.Equal(old, value) {
+			diff.Changed[key] = value
+		}
+	}
+
+	for key, value := range before {
+		if _, ok := after[key]; !ok {
+			diff.Removed[key] = value
+		}
+	}
+
+	return diff
+}
@@ -0,0 +1,72 @@
+package natty
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Snapshot", func() {
+	var (
+		n   *Natty
+		ctx = context.Background()
+	)
+
+	BeforeEach(func() {
+		var err error
+
+		n, err = New(NewConfig())
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("should capture all changes between two snapshots", func() {
+		bucket, _, _ := NewKVSet()
+
+		Expect(n.Put(ctx, bucket, "unchanged", []byte("same"))).To(Succeed())
+		Expect(n.Put(ctx, bucket, "changed", []byte("old"))).To(Succeed())
+		Expect(n.Put(ctx, bucket, "removed", []byte("gone"))).To(Succeed())
+
+		before, err := n.Snapshot(ctx, bucket)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(before.Bucket).To(Equal(bucket))
+		Expect(before.Data).To(HaveLen(3))
+
+		Expect(n.Put(ctx, bucket, "changed", []byte("new"))).To(Succeed())
+		Expect(n.Delete(ctx, bucket, "removed")).To(Succeed())
+		Expect(n.Put(ctx, bucket, "added", []byte("hello"))).To(Succeed())
+
+		after, err := n.Snapshot(ctx, bucket)
+		Expect(err).ToNot(HaveOccurred())
+
+		diff := SnapshotDiff(before, after)
+		Expect(diff.Added).To(Equal(map[string][]byte{"added": []byte("hello")}))
+		Expect(diff.Removed).To(Equal(map[string][]byte{"removed": []byte("gone")}))
+		Expect(diff.Changed).To(Equal(map[string][]byte{"changed": []byte("new")}))
+	})
+})
+
+var _ = Describe("SnapshotDiff", func() {
+	It("should treat nil snapshots as empty", func() {
+		snapshot := &KVSnapshot{Data: map[string][]byte{"foo": []byte("bar")}}
+
+		diff := SnapshotDiff(nil, snapshot)
+		Expect(diff.Added).To(HaveKey("foo"))
+		Expect(diff.Removed).To(BeEmpty())
+		Expect(diff.Changed).To(BeEmpty())
+
+		diff = SnapshotDiff(snapshot, nil)
+		Expect(diff.Added).To(BeEmpty())
+		Expect(diff.Removed).To(HaveKey("foo"))
+	})
+
+	It("should return an empty diff for identical snapshots", func() {
+		a := &KVSnapshot{Data: map[string][]byte{"foo": []byte("bar")}}
+		b := &KVSnapshot{Data: map[string][]byte{"foo": []byte("bar")}}
+
+		diff := SnapshotDiff(a, b)
+		Expect(diff.Added).To(BeEmpty())
+		Expect(diff.Removed).To(BeEmpty())
+		Expect(diff.Changed).To(BeEmpty())
+	})
+})
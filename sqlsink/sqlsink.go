@@ -0,0 +1,100 @@
+// Package sqlsink provides a natty.KVSink that mirrors KV changes to a SQL
+// table via database/sql; bring your own driver.
+//
+// Usage:
+//
+//	db, err := sql.Open("sqlite3", "file:kv.db")
+//	sink := sqlsink.NewSQLKVSink(db, "kv")
+//	err = natty.NewKVPipeline(n, sink).Run(ctx, "bucket")
+package sqlsink
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/batchcorp/natty"
+	"github.com/pkg/errors"
+)
+
+// SQLKVSink mirrors KV changes to a SQL table with the following columns:
+//
+//	bucket     TEXT      NOT NULL
+//	key        TEXT      NOT NULL
+//	value      BLOB/BYTEA
+//	revision   BIGINT    NOT NULL
+//	updated_at TIMESTAMP NOT NULL
+//	deleted    BOOLEAN   NOT NULL
+//	PRIMARY KEY (bucket, key)
+//
+// Puts upsert the row (clearing deleted); deletes and purges set deleted to
+// true and keep the last value. Events older than the stored revision are
+// ignored, so replaying events is safe. Upserts use "ON CONFLICT" (SQLite
+// 3.24+, PostgreSQL 9.5+).
+type SQLKVSink struct {
+	db     *sql.DB
+	upsert string
+	delete string
+}
+
+// SQLSinkOption configures a SQLKVSink
+type SQLSinkOption func(*sqlSinkOptions)
+
+type sqlSinkOptions struct {
+	placeholder func(n int) string
+}
+
+// WithDollarPlaceholders uses $1, $2, ... placeholders (ie. for PostgreSQL)
+// instead of the default ?
+func WithDollarPlaceholders() SQLSinkOption {
+	return func(o *sqlSinkOptions) {
+		o.placeholder = func(n int) string { return fmt.Sprintf("$%d", n) }
+	}
+}
+
+// NewSQLKVSink creates a sink that writes to table in db; table is used as-is
+// in queries and must not come from untrusted input.
+func NewSQLKVSink(db *sql.DB, table string, opts ...SQLSinkOption) *SQLKVSink {
+	o := &sqlSinkOptions{
+		placeholder: func(_ int) string { return "?" },
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	params := make([]string, 5)
+
+	for i := range params {
+		params[i] = o.placeholder(i + 1)
+	}
+
+	values := strings.Join(params, ", ")
+
+	return &SQLKVSink{
+		db: db,
+		upsert: fmt.Sprintf(`INSERT INTO %[1]s (bucket, key, value, revision, updated_at, deleted) VALUES (%[2]s, FALSE)
+ON CONFLICT (bucket, key) DO UPDATE SET value = excluded.value, revision = excluded.revision,
+updated_at = excluded.updated_at, deleted = FALSE WHERE %[1]s.revision < excluded.revision`, table, values),
+		delete: fmt.Sprintf(`INSERT INTO %[1]s (bucket, key, value, revision, updated_at, deleted) VALUES (%[2]s, TRUE)
+ON CONFLICT (bucket, key) DO UPDATE SET revision = excluded.revision,
+updated_at = excluded.updated_at, deleted = TRUE WHERE %[1]s.revision < excluded.revision`, table, values),
+	}
+}
+
+// Sink implements natty.KVSink
+func (s *SQLKVSink) Sink(ctx context.Context, event *natty.KeyValueEvent) error {
+	switch event.Operation {
+	case natty.KVEventOpDelete, natty.KVEventOpPurge:
+		if _, err := s.db.ExecContext(ctx, s.delete, event.Bucket, event.Key, nil, event.Revision, event.Created); err != nil {
+			return errors.Wrapf(err, "unable to mark key '%s' in bucket '%s' as deleted", event.Key, event.Bucket)
+		}
+	default:
+		if _, err := s.db.ExecContext(ctx, s.upsert, event.Bucket, event.Key, event.Value, event.Revision, event.Created); err != nil {
+			return errors.Wrapf(err, "unable to upsert key '%s' in bucket '%s'", event.Key, event.Bucket)
+		}
+	}
+
+	return nil
+}
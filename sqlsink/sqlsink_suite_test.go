@@ -0,0 +1,13 @@
+package sqlsink
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestSQLSinkSuite(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "SQLSink Suite")
+}
@@ -0,0 +1,129 @@
+package sqlsink
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/batchcorp/natty"
+	"github.com/batchcorp/natty/nattytest"
+	_ "github.com/mattn/go-sqlite3"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+const schema = `CREATE TABLE kv (
+	bucket     TEXT      NOT NULL,
+	key        TEXT      NOT NULL,
+	value      BLOB,
+	revision   BIGINT    NOT NULL,
+	updated_at TIMESTAMP NOT NULL,
+	deleted    BOOLEAN   NOT NULL,
+	PRIMARY KEY (bucket, key)
+)`
+
+type row struct {
+	Value    string
+	Revision uint64
+	Deleted  bool
+}
+
+var _ = Describe("SQLKVSink", func() {
+	var (
+		db     *sql.DB
+		sink   *SQLKVSink
+		ctx    context.Context
+		cancel context.CancelFunc
+	)
+
+	BeforeEach(func() {
+		var err error
+
+		db, err = sql.Open("sqlite3", ":memory:")
+		Expect(err).ToNot(HaveOccurred())
+
+		// Every connection gets its own in-memory database
+		db.SetMaxOpenConns(1)
+
+		_, err = db.Exec(schema)
+		Expect(err).ToNot(HaveOccurred())
+
+		sink = NewSQLKVSink(db, "kv")
+
+		ctx, cancel = context.WithCancel(context.Background())
+	})
+
+	AfterEach(func() {
+		cancel()
+		db.Close()
+	})
+
+	rows := func() map[string]row {
+		result := make(map[string]row)
+
+		r, err := db.Query("SELECT key, COALESCE(value, ''), revision, deleted FROM kv WHERE bucket = ?", "bucket")
+		Expect(err).ToNot(HaveOccurred())
+
+		defer r.Close()
+
+		for r.Next() {
+			var (
+				key   string
+				value []byte
+				entry row
+			)
+
+			Expect(r.Scan(&key, &value, &entry.Revision, &entry.Deleted)).To(Succeed())
+
+			entry.Value = string(value)
+			result[key] = entry
+		}
+
+		Expect(r.Err()).ToNot(HaveOccurred())
+
+		return result
+	}
+
+	It("should mirror the bucket state", func() {
+		fake := nattytest.NewFakeNatty()
+
+		Expect(fake.Put(ctx, "bucket", "existing", []byte("value"))).To(Succeed())
+
+		go natty.NewKVPipeline(fake, sink).Run(ctx, "bucket")
+
+		Eventually(rows).Should(HaveKey("existing"))
+
+		Expect(fake.Put(ctx, "bucket", "foo", []byte("bar"))).To(Succeed())
+		Expect(fake.Put(ctx, "bucket", "foo", []byte("baz"))).To(Succeed())
+		Expect(fake.Delete(ctx, "bucket", "existing")).To(Succeed())
+
+		Eventually(rows).Should(Equal(map[string]row{
+			"existing": {Value: "value", Revision: 4, Deleted: true},
+			"foo":      {Value: "baz", Revision: 3},
+		}))
+	})
+
+	It("should ignore events older than the stored revision", func() {
+		put := func(value string, revision uint64) {
+			err := sink.Sink(ctx, &natty.KeyValueEvent{
+				Bucket:    "bucket",
+				Key:       "foo",
+				Value:     []byte(value),
+				Revision:  revision,
+				Operation: natty.KVEventOpPut,
+				Created:   time.Now(),
+			})
+			Expect(err).ToNot(HaveOccurred())
+		}
+
+		put("new", 2)
+		put("old", 1)
+
+		Expect(rows()).To(Equal(map[string]row{"foo": {Value: "new", Revision: 2}}))
+	})
+
+	It("should return database errors", func() {
+		err := NewSQLKVSink(db, "missing").Sink(ctx, &natty.KeyValueEvent{Bucket: "bucket", Key: "foo"})
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,307 @@
+package natty
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/pkg/errors"
+)
+
+// SubscribeWithHeaderFilter creates a push subscription on subject (bound to
+// stream, using durable as the consumer name if set) and calls handler only for messages whose filterHeader
+// header equals filterValue. Messages are ACK'd if handler returns nil and
+// NAK'd (for redelivery) if it returns an error. The subscription is stopped
+// when ctx is cancelled or the returned unsubscribe func is called.
+//
+// NOTE: Filtering is performed client-side; JetStream cannot filter on
+// headers so ALL messages on subject are still delivered to the subscription.
+// Messages that do not match the filter are ACK'd and dropped.
+func (n *Natty) SubscribeWithHeaderFilter(
+	ctx context.Context,
+	stream, subject, durable string,
+	filterHeader, filterValue string,
+	handler func(*nats.Msg) error,
+) (func() error, error) {
+	if n.isClosed() {
+		return nil, ErrConnectionClosed
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if stream == "" {
+		return nil, ErrEmptyStreamName
+	}
+
+	if subject == "" {
+		return nil, ErrEmptySubject
+	}
+
+	if handler == nil {
+		return nil, errors.New("handler cannot be nil")
+	}
+
+	opts := []nats.SubOpt{nats.BindStream(stream), nats.ManualAck()}
+
+	// An ephemeral consumer is created if durable is empty
+	if durable != "" {
+		opts = append(opts, nats.Durable(durable))
+	}
+
+	ms, err := n.subscribeManaged(stream, durable, subject, func(cb nats.MsgHandler) (*nats.Subscription, error) {
+		return n.getJS().Subscribe(subject, cb, opts...)
+	}, func(msg *nats.Msg) {
+		if msg.Header.Get(filterHeader) != filterValue {
+			if err := msg.Ack(); err != nil {
+				errorw(n.log, "unable to ack filtered message", "subject", msg.Subject, "error", err)
+			}
+
+			return
+		}
+
+		if err := handler(msg); err != nil {
+			errorw(n.log, "handler failed for message", "subject", msg.Subject, "error", err)
+
+			if err := msg.Nak(); err != nil {
+				errorw(n.log, "unable to nak message", "subject", msg.Subject, "error", err)
+			}
+
+			return
+		}
+
+		if err := msg.Ack(); err != nil {
+			errorw(n.log, "unable to ack message", "subject", msg.Subject, "error", err)
+		}
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to create subscription")
+	}
+
+	return n.unsubscribeOnDone(ctx, ms), nil
+}
+
+// SubOptions configures the durable consumer used by Subscribe()
+type SubOptions struct {
+	// Durable is the name of the durable consumer (required)
+	Durable string
+
+	// DeliverPolicy is only used when the consumer is first created; an
+	// existing consumer resumes from its ack floor. Defaults to
+	// nats.DeliverAllPolicy; the ByStartSequence/ByStartTime policies are not
+	// supported.
+	DeliverPolicy nats.DeliverPolicy
+
+	// AckWait is how long the server waits for an ACK before redelivering a
+	// message. Defaults to the server default (30s). Like DeliverPolicy, only
+	// used when the consumer is first created.
+	AckWait time.Duration
+
+	// MaxDeliver is the max number of delivery attempts per message. Defaults
+	// to unlimited. Like DeliverPolicy, only used when the consumer is first
+	// created.
+	MaxDeliver int
+
+	// MaxPendingMessages is the max number of delivered but not yet ACK'd
+	// messages (the consumer's MaxAckPending); once reached, the server pauses
+	// delivery until messages are ACK'd. Defaults to the server default
+	// (1000). If set, an existing consumer with a different MaxAckPending is
+	// updated.
+	MaxPendingMessages int
+
+	// MaxPendingBytes is the max size of messages buffered by the client that
+	// have not been passed to handler yet. Setting it enables JetStream flow
+	// control on the consumer and sets the subscription's pending limits.
+	// Defaults to the NATS client default (64MB). Flow control cannot be
+	// enabled on an existing consumer, so setting it for a consumer that was
+	// created without it is an error.
+	//
+	// Unlike MaxPendingMessages, this is enforced (in part) client-side: flow
+	// control makes the server pause until the client has caught up, but the
+	// server picks the flow control window itself. Messages arriving while the
+	// client buffer is over the limit are dropped (and logged as a slow
+	// consumer error) and are redelivered by the server once AckWait expires.
+	MaxPendingBytes int64
+}
+
+// subscribeHeartbeatInterval is the idle heartbeat interval used for consumers
+// with flow control enabled (which requires heartbeats)
+const subscribeHeartbeatInterval = 5 * time.Second
+
+// Subscribe creates (or re-attaches to) a durable push consumer on subject
+// (bound to stream) and calls handler for every message. Messages are ACK'd
+// synchronously if handler returns nil and NAK'd (for redelivery) if it
+// returns an error. The subscription is stopped when ctx is cancelled or the
+// returned unsubscribe func is called; the consumer itself is kept (use
+// DeleteConsumer() to remove it).
+//
+// Because ACKs are confirmed by the server before the next message is
+// handled, the consumer's ack floor is always up to date: after a reconnect
+// (or when a new connection subscribes with the same durable), delivery
+// resumes after the last ACK'd message instead of starting over.
+//
+// Use SubOptions.MaxPendingMessages and SubOptions.MaxPendingBytes to bound
+// the number of messages buffered for slow handlers.
+func (n *Natty) Subscribe(ctx context.Context, stream, subject string, opts SubOptions, handler func(*nats.Msg) error) (func() error, error) {
+	if n.isClosed() {
+		return nil, ErrConnectionClosed
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if stream == "" {
+		return nil, ErrEmptyStreamName
+	}
+
+	if subject == "" {
+		return nil, ErrEmptySubject
+	}
+
+	if opts.Durable == "" {
+		return nil, ErrEmptyConsumerName
+	}
+
+	if opts.MaxPendingMessages < 0 || opts.MaxPendingBytes < 0 {
+		return nil, errors.New("max pending limits cannot be negative")
+	}
+
+	if handler == nil {
+		return nil, errors.New("handler cannot be nil")
+	}
+
+	if err := n.ensurePushConsumer(stream, subject, opts); err != nil {
+		return nil, err
+	}
+
+	ms, err := n.subscribeManaged(stream, opts.Durable, subject, func(cb nats.MsgHandler) (*nats.Subscription, error) {
+		sub, err := n.getJS().Subscribe(subject, cb, nats.Bind(stream, opts.Durable), nats.ManualAck())
+		if err != nil {
+			return nil, err
+		}
+
+		if opts.MaxPendingBytes > 0 {
+			if err := sub.SetPendingLimits(nats.DefaultSubPendingMsgsLimit, int(opts.MaxPendingBytes)); err != nil {
+				_ = sub.Unsubscribe()
+				return nil, errors.Wrap(err, "unable to set pending limits")
+			}
+		}
+
+		return sub, nil
+	}, func(msg *nats.Msg) {
+		if err := handler(msg); err != nil {
+			errorw(n.log, "handler failed for message", "subject", msg.Subject, "error", err)
+
+			if err := msg.Nak(); err != nil {
+				errorw(n.log, "unable to nak message", "subject", msg.Subject, "error", err)
+			}
+
+			return
+		}
+
+		if err := msg.AckSync(); err != nil {
+			errorw(n.log, "unable to ack message", "subject", msg.Subject, "error", err)
+		}
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to create subscription")
+	}
+
+	return n.unsubscribeOnDone(ctx, ms), nil
+}
+
+// ensurePushConsumer creates the durable push consumer for Subscribe() unless
+// it already exists, in which case its pending limits are reconciled with
+// opts. The consumer is created here (rather than by the NATS client) because
+// the client deletes consumers it created on Unsubscribe().
+func (n *Natty) ensurePushConsumer(stream, subject string, opts SubOptions) error {
+	info, err := n.getJS().ConsumerInfo(stream, opts.Durable)
+	if err == nil {
+		return n.updatePushConsumer(stream, &info.Config, opts)
+	}
+
+	if err != nats.ErrConsumerNotFound {
+		return errors.Wrap(err, "unable to fetch consumer info")
+	}
+
+	// ByStartSequence/ByStartTime need config that SubOptions does not carry
+	if opts.DeliverPolicy == nats.DeliverByStartSequencePolicy || opts.DeliverPolicy == nats.DeliverByStartTimePolicy {
+		return errors.New("unsupported deliver policy")
+	}
+
+	cfg := &nats.ConsumerConfig{
+		Durable:        opts.Durable,
+		DeliverSubject: nats.NewInbox(),
+		DeliverPolicy:  opts.DeliverPolicy,
+		AckPolicy:      nats.AckExplicitPolicy,
+		AckWait:        opts.AckWait,
+		MaxDeliver:     opts.MaxDeliver,
+		MaxAckPending:  opts.MaxPendingMessages,
+		FilterSubject:  subject,
+	}
+
+	if opts.MaxPendingBytes > 0 {
+		cfg.FlowControl = true
+		cfg.Heartbeat = subscribeHeartbeatInterval
+	}
+
+	if _, err := n.getJS().AddConsumer(stream, cfg); err != nil {
+		return errors.Wrap(err, "unable to create consumer")
+	}
+
+	return nil
+}
+
+// updatePushConsumer updates the MaxAckPending of an existing consumer if it
+// differs from opts.MaxPendingMessages; it errors if opts.MaxPendingBytes is
+// set but the consumer was created without flow control.
+func (n *Natty) updatePushConsumer(stream string, cfg *nats.ConsumerConfig, opts SubOptions) error {
+	if opts.MaxPendingBytes > 0 && !cfg.FlowControl {
+		return errors.Errorf("consumer '%s' was created without flow control; MaxPendingBytes cannot be used with it", opts.Durable)
+	}
+
+	if opts.MaxPendingMessages == 0 || cfg.MaxAckPending == opts.MaxPendingMessages {
+		return nil
+	}
+
+	cfg.MaxAckPending = opts.MaxPendingMessages
+
+	if _, err := n.getJS().UpdateConsumer(stream, cfg); err != nil {
+		return errors.Wrap(err, "unable to update consumer")
+	}
+
+	return nil
+}
+
+// unsubscribeOnDone returns an (idempotent) func that unsubscribes ms; it is
+// also called once ctx is done.
+func (n *Natty) unsubscribeOnDone(ctx context.Context, ms *managedSub) func() error {
+	once := &sync.Once{}
+	stop := make(chan struct{})
+
+	unsubscribe := func() error {
+		var err error
+
+		once.Do(func() {
+			close(stop)
+			err = n.unsubscribe(ms)
+		})
+
+		return err
+	}
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			if err := unsubscribe(); err != nil {
+				errorw(n.log, "unable to unsubscribe", "subject", ms.subject, "error", err)
+			}
+		case <-stop:
+		}
+	}()
+
+	return unsubscribe
+}
@@ -0,0 +1,323 @@
+package natty
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SubscribeWithHeaderFilter", func() {
+	var (
+		n          *Natty
+		streamName string
+	)
+
+	BeforeEach(func() {
+		var err error
+
+		n, err = New(NewConfig())
+		Expect(err).ToNot(HaveOccurred())
+
+		streamName = strings.ToUpper(GetRandomName("test", 1))
+		testStreams = append(testStreams, streamName)
+
+		Expect(n.CreateStream(context.Background(), streamName, []string{streamName + ".*"})).To(Succeed())
+	})
+
+	It("should only call handler for messages with a matching header", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		mutex := &sync.Mutex{}
+		received := make([]string, 0)
+
+		unsubscribe, err := n.SubscribeWithHeaderFilter(ctx, streamName, streamName+".foo", "filtered",
+			"tenant", "acme", func(msg *nats.Msg) error {
+				mutex.Lock()
+				defer mutex.Unlock()
+
+				received = append(received, string(msg.Data))
+
+				return nil
+			})
+		Expect(err).ToNot(HaveOccurred())
+
+		defer unsubscribe()
+
+		for _, m := range []struct {
+			tenant string
+			data   string
+		}{
+			{"acme", "1"},
+			{"other", "2"},
+			{"", "3"},
+			{"acme", "4"},
+		} {
+			msg := nats.NewMsg(streamName + ".foo")
+			msg.Data = []byte(m.data)
+
+			if m.tenant != "" {
+				msg.Header.Set("tenant", m.tenant)
+			}
+
+			_, err := n.js.PublishMsg(msg)
+			Expect(err).ToNot(HaveOccurred())
+		}
+
+		Eventually(func() []string {
+			mutex.Lock()
+			defer mutex.Unlock()
+
+			return append([]string(nil), received...)
+		}, 5*time.Second).Should(Equal([]string{"1", "4"}))
+
+		Consistently(func() int {
+			mutex.Lock()
+			defer mutex.Unlock()
+
+			return len(received)
+		}, time.Second).Should(Equal(2))
+	})
+
+	It("should error on an empty stream name", func() {
+		_, err := n.SubscribeWithHeaderFilter(context.Background(), "", "foo", "", "tenant", "acme",
+			func(msg *nats.Msg) error { return nil })
+		Expect(err).To(Equal(ErrEmptyStreamName))
+	})
+})
+
+var _ = Describe("Subscribe", func() {
+	var (
+		n          *Natty
+		streamName string
+	)
+
+	BeforeEach(func() {
+		var err error
+
+		n, err = New(NewConfig())
+		Expect(err).ToNot(HaveOccurred())
+
+		streamName = strings.ToUpper(GetRandomName("test", 1))
+		testStreams = append(testStreams, streamName)
+
+		Expect(n.CreateStream(context.Background(), streamName, []string{streamName + ".*"})).To(Succeed())
+	})
+
+	publish := func(from, to int) {
+		for i := from; i <= to; i++ {
+			_, err := n.js.Publish(streamName+".foo", []byte(strconv.Itoa(i)))
+			Expect(err).ToNot(HaveOccurred())
+		}
+	}
+
+	It("should resume after the last ACK'd message on reconnect", func() {
+		mutex := &sync.Mutex{}
+		received := make([]string, 0)
+
+		handler := func(msg *nats.Msg) error {
+			mutex.Lock()
+			defer mutex.Unlock()
+
+			received = append(received, string(msg.Data))
+
+			return nil
+		}
+
+		getReceived := func() []string {
+			mutex.Lock()
+			defer mutex.Unlock()
+
+			return append([]string(nil), received...)
+		}
+
+		opts := SubOptions{Durable: "resume", AckWait: time.Second}
+
+		subscriber, err := New(NewConfig())
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = subscriber.Subscribe(context.Background(), streamName, streamName+".foo", opts, handler)
+		Expect(err).ToNot(HaveOccurred())
+
+		publish(1, 5)
+
+		Eventually(getReceived, 5*time.Second).Should(HaveLen(5))
+
+		// Simulate a disconnect; messages published meanwhile must not be lost
+		subscriber.nc.Close()
+
+		publish(6, 10)
+
+		subscriber, err = New(NewConfig())
+		Expect(err).ToNot(HaveOccurred())
+
+		unsubscribe, err := subscriber.Subscribe(context.Background(), streamName, streamName+".foo", opts, handler)
+		Expect(err).ToNot(HaveOccurred())
+
+		defer unsubscribe()
+
+		expected := []string{"1", "2", "3", "4", "5", "6", "7", "8", "9", "10"}
+
+		// Delivery order is not guaranteed across the reconnect
+		Eventually(getReceived, 5*time.Second).Should(ConsistOf(expected))
+
+		// Wait past AckWait to make sure nothing is redelivered
+		Consistently(getReceived, 2*time.Second).Should(ConsistOf(expected))
+
+		// Messages ACK'd before the disconnect must not be delivered again
+		redelivered := func() []string {
+			seen := make(map[string]int)
+			dupes := make([]string, 0)
+
+			for _, data := range getReceived() {
+				seen[data]++
+
+				if seen[data] == 2 {
+					dupes = append(dupes, data)
+				}
+			}
+
+			return dupes
+		}
+
+		Expect(redelivered()).To(BeEmpty())
+	})
+
+	It("should not panic on a nil context", func() {
+		var unsubscribe func() error
+
+		Expect(func() {
+			var err error
+
+			//nolint:staticcheck // exercising the nil ctx fallback
+			unsubscribe, err = n.Subscribe(nil, streamName, streamName+".foo",
+				SubOptions{Durable: "nil-ctx"}, func(msg *nats.Msg) error { return nil })
+			Expect(err).ToNot(HaveOccurred())
+		}).ToNot(Panic())
+
+		Expect(unsubscribe()).To(Succeed())
+	})
+
+	It("should keep the consumer after unsubscribing", func() {
+		unsubscribe, err := n.Subscribe(context.Background(), streamName, streamName+".foo",
+			SubOptions{Durable: "kept"}, func(msg *nats.Msg) error { return nil })
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(unsubscribe()).To(Succeed())
+
+		_, err = n.js.ConsumerInfo(streamName, "kept")
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("should pause delivery once MaxPendingMessages is reached", func() {
+		release := make(chan struct{})
+
+		handled := make(chan string, 10)
+
+		unsubscribe, err := n.Subscribe(context.Background(), streamName, streamName+".foo",
+			SubOptions{Durable: "limited", MaxPendingMessages: 2, MaxPendingBytes: 1024 * 1024},
+			func(msg *nats.Msg) error {
+				<-release
+
+				handled <- string(msg.Data)
+
+				return nil
+			})
+		Expect(err).ToNot(HaveOccurred())
+
+		defer unsubscribe()
+
+		info, err := n.js.ConsumerInfo(streamName, "limited")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(info.Config.MaxAckPending).To(Equal(2))
+		Expect(info.Config.FlowControl).To(BeTrue())
+
+		publish(1, 10)
+
+		getPending := func() uint64 {
+			info, err := n.js.ConsumerInfo(streamName, "limited")
+			Expect(err).ToNot(HaveOccurred())
+
+			return info.NumPending
+		}
+
+		// Only MaxPendingMessages are delivered while the handler is blocked
+		Eventually(getPending, 5*time.Second).Should(Equal(uint64(8)))
+		Consistently(getPending, time.Second).Should(Equal(uint64(8)))
+
+		close(release)
+
+		for i := 1; i <= 10; i++ {
+			Eventually(handled, 5*time.Second).Should(Receive(Equal(strconv.Itoa(i))))
+		}
+	})
+
+	It("should set pending limits when only MaxPendingBytes is set", func() {
+		unsubscribe, err := n.Subscribe(context.Background(), streamName, streamName+".foo",
+			SubOptions{Durable: "bytes", MaxPendingBytes: 1024}, func(msg *nats.Msg) error { return nil })
+		Expect(err).ToNot(HaveOccurred())
+
+		defer unsubscribe()
+
+		info, err := n.js.ConsumerInfo(streamName, "bytes")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(info.Config.FlowControl).To(BeTrue())
+
+		n.subsMutex.Lock()
+		Expect(n.subs).To(HaveLen(1))
+
+		for ms := range n.subs {
+			_, bytesLimit, err := ms.sub.PendingLimits()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(bytesLimit).To(Equal(1024))
+		}
+		n.subsMutex.Unlock()
+	})
+
+	It("should update MaxPendingMessages of an existing consumer", func() {
+		unsubscribe, err := n.Subscribe(context.Background(), streamName, streamName+".foo",
+			SubOptions{Durable: "updated", MaxPendingMessages: 2}, func(msg *nats.Msg) error { return nil })
+		Expect(err).ToNot(HaveOccurred())
+		Expect(unsubscribe()).To(Succeed())
+
+		unsubscribe, err = n.Subscribe(context.Background(), streamName, streamName+".foo",
+			SubOptions{Durable: "updated", MaxPendingMessages: 5}, func(msg *nats.Msg) error { return nil })
+		Expect(err).ToNot(HaveOccurred())
+
+		defer unsubscribe()
+
+		info, err := n.js.ConsumerInfo(streamName, "updated")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(info.Config.MaxAckPending).To(Equal(5))
+	})
+
+	It("should error on MaxPendingBytes for an existing consumer without flow control", func() {
+		unsubscribe, err := n.Subscribe(context.Background(), streamName, streamName+".foo",
+			SubOptions{Durable: "no-flow-control"}, func(msg *nats.Msg) error { return nil })
+		Expect(err).ToNot(HaveOccurred())
+		Expect(unsubscribe()).To(Succeed())
+
+		_, err = n.Subscribe(context.Background(), streamName, streamName+".foo",
+			SubOptions{Durable: "no-flow-control", MaxPendingBytes: 1024}, func(msg *nats.Msg) error { return nil })
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("flow control"))
+	})
+
+	It("should error on negative max pending limits", func() {
+		_, err := n.Subscribe(context.Background(), streamName, streamName+".foo",
+			SubOptions{Durable: "negative", MaxPendingBytes: -1}, func(msg *nats.Msg) error { return nil })
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should error on an empty durable name", func() {
+		_, err := n.Subscribe(context.Background(), streamName, streamName+".foo", SubOptions{},
+			func(msg *nats.Msg) error { return nil })
+		Expect(err).To(Equal(ErrEmptyConsumerName))
+	})
+})
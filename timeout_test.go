@@ -0,0 +1,58 @@
+package natty
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("BucketTimeouts", func() {
+	var (
+		n *Natty
+	)
+
+	BeforeEach(func() {
+		var err error
+
+		n, err = New(NewConfig())
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("should return DeadlineExceeded when a bucket timeout is exceeded", func() {
+		bucket, key, value := NewKVSet()
+
+		Expect(n.Put(context.Background(), bucket, key, value)).To(Succeed())
+
+		cfg := NewConfig()
+		cfg.BucketTimeouts = map[string]time.Duration{bucket: time.Millisecond}
+
+		// Ensure the deadline is exceeded regardless of how fast NATS is
+		cfg.BucketHooks.BeforeGet = func(bucket, key string, err error) {
+			time.Sleep(5 * time.Millisecond)
+		}
+
+		timed, err := New(cfg)
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = timed.Get(context.Background(), bucket, key)
+		Expect(err).To(Equal(context.DeadlineExceeded))
+	})
+
+	It("should not apply the timeout to other buckets", func() {
+		bucket, key, value := NewKVSet()
+
+		Expect(n.Put(context.Background(), bucket, key, value)).To(Succeed())
+
+		cfg := NewConfig()
+		cfg.BucketTimeouts = map[string]time.Duration{"other-bucket": time.Millisecond}
+
+		timed, err := New(cfg)
+		Expect(err).ToNot(HaveOccurred())
+
+		data, err := timed.Get(context.Background(), bucket, key)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(Equal(value))
+	})
+})
@@ -0,0 +1,45 @@
+package natty
+
+import (
+	"context"
+
+	"github.com/nats-io/nats.go"
+)
+
+// Tracer is the common interface for user-provided distributed tracers. An
+// OpenTelemetry implementation lives in the "tracing" sub-package so that
+// users who do not need tracing do not have to import OpenTelemetry.
+type Tracer interface {
+	// StartKV is called at the beginning of a KV operation (one of the KVOp*
+	// constants; key is empty for bucket-wide operations). The returned
+	// context is used for the remainder of the operation and the returned
+	// func is called once the operation completes with the resulting error.
+	StartKV(ctx context.Context, operation, bucket, key string) (context.Context, func(err error))
+
+	// Inject writes the trace context in ctx into the headers of a message
+	// that is about to be published.
+	Inject(ctx context.Context, msg *nats.Msg)
+
+	// Extract returns a copy of ctx containing the trace context found in the
+	// headers of a consumed message.
+	Extract(ctx context.Context, msg *nats.Msg) context.Context
+}
+
+// NoOpTracer is a do-nothing tracer; it is used internally as the default
+// Tracer when none is provided in the Config.
+type NoOpTracer struct {
+}
+
+// StartKV is no-op implementation of Tracer's StartKV.
+func (t *NoOpTracer) StartKV(ctx context.Context, operation, bucket, key string) (context.Context, func(err error)) {
+	return ctx, func(err error) {}
+}
+
+// Inject is no-op implementation of Tracer's Inject.
+func (t *NoOpTracer) Inject(ctx context.Context, msg *nats.Msg) {
+}
+
+// Extract is no-op implementation of Tracer's Extract.
+func (t *NoOpTracer) Extract(ctx context.Context, msg *nats.Msg) context.Context {
+	return ctx
+}
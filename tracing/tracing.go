@@ -0,0 +1,96 @@
+// Package tracing provides an OpenTelemetry implementation of natty.Tracer.
+//
+// Usage:
+//
+//	t := tracing.NewTracer(otel.GetTracerProvider())
+//	n, err := natty.New(cfg.WithTracer(t))
+package tracing
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/nats-io/nats.go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	InstrumentationName = "github.com/batchcorp/natty"
+
+	SpanPrefix = "natty.kv."
+
+	AttributeBucket = "bucket"
+	AttributeKey    = "key"
+)
+
+// Tracer wraps natty KV operations in spans named natty.kv.{operation} and
+// propagates trace context via NATS message headers.
+type Tracer struct {
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+}
+
+// NewTracer creates a Tracer using the given TracerProvider; if tp is nil,
+// the global TracerProvider is used. Trace context is propagated using the
+// global TextMapPropagator if one is set and W3C TraceContext otherwise.
+func NewTracer(tp trace.TracerProvider) *Tracer {
+	if tp == nil {
+		tp = otel.GetTracerProvider()
+	}
+
+	propagator := otel.GetTextMapPropagator()
+
+	if len(propagator.Fields()) == 0 {
+		propagator = propagation.TraceContext{}
+	}
+
+	return &Tracer{
+		tracer:     tp.Tracer(InstrumentationName),
+		propagator: propagator,
+	}
+}
+
+// StartKV implements natty.Tracer
+func (t *Tracer) StartKV(ctx context.Context, operation, bucket, key string) (context.Context, func(err error)) {
+	attrs := []attribute.KeyValue{attribute.String(AttributeBucket, bucket)}
+
+	if key != "" {
+		attrs = append(attrs, attribute.String(AttributeKey, key))
+	}
+
+	ctx, span := t.tracer.Start(ctx, SpanPrefix+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
+
+	return ctx, func(err error) {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+
+		span.End()
+	}
+}
+
+// Inject implements natty.Tracer
+func (t *Tracer) Inject(ctx context.Context, msg *nats.Msg) {
+	if msg.Header == nil {
+		msg.Header = nats.Header{}
+	}
+
+	t.propagator.Inject(ctx, propagation.HeaderCarrier(http.Header(msg.Header)))
+}
+
+// Extract implements natty.Tracer
+func (t *Tracer) Extract(ctx context.Context, msg *nats.Msg) context.Context {
+	if msg.Header == nil {
+		return ctx
+	}
+
+	return t.propagator.Extract(ctx, propagation.HeaderCarrier(http.Header(msg.Header)))
+}
@@ -0,0 +1,87 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+
+	"github.com/nats-io/nats.go"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var _ = Describe("Tracer", func() {
+	var (
+		recorder *tracetest.SpanRecorder
+		tracer   *Tracer
+	)
+
+	BeforeEach(func() {
+		recorder = tracetest.NewSpanRecorder()
+		tracer = NewTracer(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	})
+
+	Describe("StartKV", func() {
+		It("creates a span with bucket and key attributes", func() {
+			ctx, done := tracer.StartKV(context.Background(), "get", "foo", "bar")
+			Expect(trace.SpanContextFromContext(ctx).IsValid()).To(BeTrue())
+
+			done(nil)
+
+			spans := recorder.Ended()
+			Expect(spans).To(HaveLen(1))
+			Expect(spans[0].Name()).To(Equal("natty.kv.get"))
+			Expect(spans[0].Attributes()).To(ConsistOf(
+				attribute.String(AttributeBucket, "foo"),
+				attribute.String(AttributeKey, "bar"),
+			))
+			Expect(spans[0].Status().Code).To(Equal(codes.Unset))
+		})
+
+		It("omits the key attribute for bucket-wide operations", func() {
+			_, done := tracer.StartKV(context.Background(), "keys", "foo", "")
+			done(nil)
+
+			spans := recorder.Ended()
+			Expect(spans).To(HaveLen(1))
+			Expect(spans[0].Attributes()).To(ConsistOf(attribute.String(AttributeBucket, "foo")))
+		})
+
+		It("records errors", func() {
+			_, done := tracer.StartKV(context.Background(), "put", "foo", "bar")
+			done(errors.New("boom"))
+
+			spans := recorder.Ended()
+			Expect(spans).To(HaveLen(1))
+			Expect(spans[0].Status().Code).To(Equal(codes.Error))
+			Expect(spans[0].Status().Description).To(Equal("boom"))
+			Expect(spans[0].Events()).To(HaveLen(1))
+		})
+	})
+
+	Describe("Inject/Extract", func() {
+		It("propagates trace context via message headers", func() {
+			ctx, done := tracer.StartKV(context.Background(), "put", "foo", "bar")
+			defer done(nil)
+
+			msg := &nats.Msg{Subject: "test"}
+			tracer.Inject(ctx, msg)
+			Expect(msg.Header).ToNot(BeEmpty())
+
+			extracted := tracer.Extract(context.Background(), msg)
+
+			Expect(trace.SpanContextFromContext(extracted).TraceID()).
+				To(Equal(trace.SpanContextFromContext(ctx).TraceID()))
+		})
+
+		It("returns ctx as-is when message has no headers", func() {
+			ctx := context.Background()
+
+			Expect(tracer.Extract(ctx, &nats.Msg{Subject: "test"})).To(Equal(ctx))
+		})
+	})
+})
@@ -0,0 +1,135 @@
+package natty
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type tracerCtxKey struct{}
+
+// recordingTracer records started KV operations and propagates a single
+// "Trace-Id" header stored in the context under tracerCtxKey
+type recordingTracer struct {
+	mutex *sync.Mutex
+	ops   []string
+}
+
+func (t *recordingTracer) StartKV(ctx context.Context, operation, bucket, key string) (context.Context, func(err error)) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.ops = append(t.ops, operation+":"+key)
+
+	return ctx, func(err error) {}
+}
+
+func (t *recordingTracer) Inject(ctx context.Context, msg *nats.Msg) {
+	if id, ok := ctx.Value(tracerCtxKey{}).(string); ok {
+		msg.Header.Set("Trace-Id", id)
+	}
+}
+
+func (t *recordingTracer) Extract(ctx context.Context, msg *nats.Msg) context.Context {
+	return context.WithValue(ctx, tracerCtxKey{}, msg.Header.Get("Trace-Id"))
+}
+
+var _ = Describe("Tracing", func() {
+	var (
+		n      *Natty
+		tracer *recordingTracer
+	)
+
+	BeforeEach(func() {
+		var err error
+
+		tracer = &recordingTracer{mutex: &sync.Mutex{}}
+
+		n, err = New(NewConfig().WithTracer(tracer))
+
+		Expect(err).To(BeNil())
+		Expect(n).NotTo(BeNil())
+	})
+
+	It("should trace KV operations", func() {
+		bucket, key, value := NewKVSet()
+
+		Expect(n.Put(context.Background(), bucket, key, value)).To(Succeed())
+
+		_, err := n.Get(context.Background(), bucket, key)
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = n.Keys(context.Background(), bucket)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(tracer.ops).To(Equal([]string{"put:" + key, "get:" + key, "keys:"}))
+	})
+
+	It("should propagate trace context from publisher to consumer", func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		streamName := strings.ToUpper(GetRandomName("test", 1))
+		consumerName := GetRandomName("test", 1)
+		subj := streamName + ".foo"
+
+		Expect(n.CreateStream(ctx, streamName, []string{streamName + ".*"})).To(Succeed())
+		Expect(n.CreateConsumer(ctx, streamName, consumerName)).To(Succeed())
+
+		testStreams = append(testStreams, streamName)
+
+		Expect(n.TryPublish(context.WithValue(ctx, tracerCtxKey{}, "abc123"), subj, []byte("traced"))).To(Succeed())
+
+		traceIDs := make(chan string, 1)
+
+		go func() {
+			defer GinkgoRecover()
+
+			err := n.Consume(ctx, &ConsumerConfig{
+				Subject:      subj,
+				StreamName:   streamName,
+				ConsumerName: consumerName,
+			}, func(ctx context.Context, msg *nats.Msg) error {
+				traceIDs <- ctx.Value(tracerCtxKey{}).(string)
+				cancel()
+				return nil
+			})
+
+			Expect(err).ToNot(HaveOccurred())
+		}()
+
+		Eventually(traceIDs, 10*time.Second).Should(Receive(Equal("abc123")))
+	})
+
+	It("should not modify messages passed to PublishAsyncBatch", func() {
+		ctx := context.WithValue(context.Background(), tracerCtxKey{}, "abc123")
+
+		streamName := strings.ToUpper(GetRandomName("test", 1))
+		testStreams = append(testStreams, streamName)
+
+		Expect(n.CreateStream(ctx, streamName, []string{streamName + ".*"})).To(Succeed())
+
+		msg := nats.NewMsg(streamName + ".foo")
+		msg.Data = []byte("traced")
+
+		results, err := n.PublishAsyncBatch(ctx, []*nats.Msg{msg})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(results[0].Sequence).To(Equal(uint64(1)))
+
+		Expect(msg.Header).To(BeEmpty())
+
+		sub, err := n.JetStream().SubscribeSync(streamName+".foo", nats.DeliverAll())
+		Expect(err).ToNot(HaveOccurred())
+
+		defer sub.Unsubscribe()
+
+		received, err := sub.NextMsg(5 * time.Second)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(received.Header.Get("Trace-Id")).To(Equal("abc123"))
+	})
+})
@@ -0,0 +1,97 @@
+package natty
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// valueTransform is applied to every value written (encode) and read
+// (decode) through a transformKV
+type valueTransform interface {
+	encode(bucket, key string, data []byte) ([]byte, error)
+	decode(bucket, key string, data []byte) ([]byte, error)
+}
+
+// transformKV exposes the KV methods of an INatty that are safe to use with
+// transformed values. INatty is intentionally not embedded: methods that
+// read or write values but are not implemented here (ie. Watch or
+// BatchPutWithTTL) would bypass the transform.
+type transformKV struct {
+	n INatty
+	t valueTransform
+}
+
+func (kv *transformKV) Get(ctx context.Context, bucket string, key string) ([]byte, error) {
+	data, err := kv.n.Get(ctx, bucket, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return kv.t.decode(bucket, key, data)
+}
+
+func (kv *transformKV) GetEntry(ctx context.Context, bucket string, key string) (*KVEntry, error) {
+	entry, err := kv.n.GetEntry(ctx, bucket, key)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := kv.t.decode(bucket, key, entry.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	decoded := *entry
+	decoded.Value = value
+
+	return &decoded, nil
+}
+
+func (kv *transformKV) GetJSON(ctx context.Context, bucket, key string, out interface{}) error {
+	data, err := kv.Get(ctx, bucket, key)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(data, out); err != nil {
+		return &JSONError{Bucket: bucket, Key: key, Err: err}
+	}
+
+	return nil
+}
+
+func (kv *transformKV) Put(ctx context.Context, bucket string, key string, data []byte, ttl ...time.Duration) error {
+	value, err := kv.t.encode(bucket, key, data)
+	if err != nil {
+		return err
+	}
+
+	return kv.n.Put(ctx, bucket, key, value, ttl...)
+}
+
+func (kv *transformKV) Create(ctx context.Context, bucket string, key string, data []byte, keyTTL ...time.Duration) error {
+	value, err := kv.t.encode(bucket, key, data)
+	if err != nil {
+		return err
+	}
+
+	return kv.n.Create(ctx, bucket, key, value, keyTTL...)
+}
+
+func (kv *transformKV) PutJSON(ctx context.Context, bucket, key string, v interface{}, keyTTL ...time.Duration) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return &JSONError{Bucket: bucket, Key: key, Err: err}
+	}
+
+	return kv.Put(ctx, bucket, key, data, keyTTL...)
+}
+
+func (kv *transformKV) Delete(ctx context.Context, bucket string, key string) error {
+	return kv.n.Delete(ctx, bucket, key)
+}
+
+func (kv *transformKV) Keys(ctx context.Context, bucket string) ([]string, error) {
+	return kv.n.Keys(ctx, bucket)
+}
@@ -0,0 +1,105 @@
+package natty
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"sync"
+
+	"github.com/nats-io/nats.go"
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+)
+
+var (
+	// Same rules as nats.go uses for KV keys
+	validKVKeyRe = regexp.MustCompile(`\A[-/_=\.a-zA-Z0-9]+\z`)
+)
+
+// KVTx is a set of KV writes that are committed to a bucket together via
+// Commit(). Every write is published with a Nats-Msg-Id derived from the
+// transaction ID, so committing the same transaction again (ie. on retry)
+// within the bucket's duplicate window (2 minutes or the bucket TTL, whichever
+// is shorter) does not write any key twice.
+//
+// NOTE: JetStream does not support multi-subject transactions; if Commit()
+// fails part way, some keys may have been written. Retrying Commit() will
+// write only the remaining keys.
+type KVTx struct {
+	// ID is used to deduplicate commits; it is generated by NewKVTx()
+	ID string
+
+	n   *Natty
+	mu  sync.Mutex
+	ops map[string][]byte
+}
+
+// NewKVTx creates a new, empty transaction
+func (n *Natty) NewKVTx() *KVTx {
+	return &KVTx{
+		ID:  uuid.NewV4().String(),
+		n:   n,
+		ops: make(map[string][]byte),
+	}
+}
+
+// Set adds a write of value to key to the transaction; setting the same key
+// again replaces the previous value.
+func (tx *KVTx) Set(key string, value []byte) {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+
+	tx.ops[key] = value
+}
+
+// Commit writes all keys in the transaction to bucket (in key order). Will
+// auto-create the bucket if it does not already exist.
+func (tx *KVTx) Commit(ctx context.Context, bucket string) (err error) {
+	n := tx.n
+
+	ctx, done := n.trackKV(ctx, KVOpCommit, bucket, "")
+	defer done(&err)
+
+	if n.isClosed() {
+		return ErrConnectionClosed
+	}
+
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+
+	keys := make([]string, 0, len(tx.ops))
+
+	for key := range tx.ops {
+		if !validKVKeyRe.MatchString(key) {
+			return errors.Wrapf(nats.ErrInvalidKey, "invalid key '%s'", key)
+		}
+
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	if _, err := n.getBucket(ctx, bucket, true, 0); err != nil {
+		return errors.Wrap(err, "unable to fetch bucket")
+	}
+
+	for _, key := range keys {
+		msg := nats.NewMsg(n.kvPublishSubject(bucket, key))
+		msg.Data = tx.ops[key]
+		msg.Header.Set(nats.MsgIdHdr, tx.ID+"."+key)
+
+		err := n.runKV(ctx, bucket, func() error {
+			_, err := n.getJS().PublishMsg(msg)
+			return err
+		})
+		if err != nil {
+			if err == context.DeadlineExceeded {
+				return err
+			}
+
+			return errors.Wrapf(err, "unable to commit key '%s'", key)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,65 @@
+package natty
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/nats-io/nats.go"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("KVTx", func() {
+	var (
+		n   *Natty
+		ctx = context.Background()
+	)
+
+	BeforeEach(func() {
+		var err error
+
+		n, err = New(NewConfig())
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("should write every key exactly once when committed twice", func() {
+		bucket, _, _ := NewKVSet()
+
+		tx := n.NewKVTx()
+
+		for i := 0; i < 5; i++ {
+			tx.Set("key-"+strconv.Itoa(i), []byte("value-"+strconv.Itoa(i)))
+		}
+
+		Expect(tx.Commit(ctx, bucket)).To(Succeed())
+		Expect(tx.Commit(ctx, bucket)).To(Succeed())
+
+		keys, err := n.Keys(ctx, bucket)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(keys).To(HaveLen(5))
+
+		kv, err := n.getBucket(ctx, bucket, false, 0)
+		Expect(err).ToNot(HaveOccurred())
+
+		for i := 0; i < 5; i++ {
+			key := "key-" + strconv.Itoa(i)
+
+			history, err := kv.History(key)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(history).To(HaveLen(1))
+			Expect(history[0].Value()).To(Equal([]byte("value-" + strconv.Itoa(i))))
+		}
+	})
+
+	It("should reject invalid keys", func() {
+		bucket, _, _ := NewKVSet()
+
+		tx := n.NewKVTx()
+		tx.Set("bad key", []byte("value"))
+
+		err := tx.Commit(ctx, bucket)
+		Expect(err).To(HaveOccurred())
+		Expect(errors.Is(err, nats.ErrInvalidKey)).To(BeTrue())
+	})
+})
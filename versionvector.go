@@ -0,0 +1,149 @@
+package natty
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+)
+
+// Ordering is the causal relationship between two VersionVectors
+type Ordering int
+
+const (
+	// OrderingEqual means both vectors have seen exactly the same events
+	OrderingEqual Ordering = iota
+
+	// OrderingBefore means the vector happened-before the other vector
+	OrderingBefore
+
+	// OrderingAfter means the other vector happened-before the vector
+	OrderingAfter
+
+	// OrderingConcurrent means the vectors have diverged; neither
+	// happened-before the other and the values they describe need to be
+	// reconciled
+	OrderingConcurrent
+)
+
+// String implements fmt.Stringer
+func (o Ordering) String() string {
+	switch o {
+	case OrderingEqual:
+		return "equal"
+	case OrderingBefore:
+		return "before"
+	case OrderingAfter:
+		return "after"
+	case OrderingConcurrent:
+		return "concurrent"
+	default:
+		return "unknown"
+	}
+}
+
+// VersionVector tracks the causal history of a value that is updated by
+// multiple replicas; every replica (node) only ever increments its own entry.
+// A missing entry is the same as an entry of 0.
+type VersionVector map[string]uint64
+
+// Increment records a new event on nodeID. The vector must be non-nil.
+func (v VersionVector) Increment(nodeID string) {
+	v[nodeID]++
+}
+
+// Merge sets every entry in v to the max of v and other; the result has
+// seen every event seen by either vector. The vector must be non-nil.
+func (v VersionVector) Merge(other VersionVector) {
+	for node, counter := range other {
+		if counter > v[node] {
+			v[node] = counter
+		}
+	}
+}
+
+// Compare returns the causal relationship of v to other
+func (v VersionVector) Compare(other VersionVector) Ordering {
+	var less, greater bool
+
+	for node, counter := range v {
+		if counter > other[node] {
+			greater = true
+		} else if counter < other[node] {
+			less = true
+		}
+	}
+
+	for node, counter := range other {
+		if _, ok := v[node]; !ok && counter > 0 {
+			less = true
+		}
+	}
+
+	switch {
+	case less && greater:
+		return OrderingConcurrent
+	case less:
+		return OrderingBefore
+	case greater:
+		return OrderingAfter
+	default:
+		return OrderingEqual
+	}
+}
+
+// Clone returns a copy of v
+func (v VersionVector) Clone() VersionVector {
+	clone := make(VersionVector, len(v))
+
+	for node, counter := range v {
+		clone[node] = counter
+	}
+
+	return clone
+}
+
+// Encode serializes v (as JSON)
+func (v VersionVector) Encode() ([]byte, error) {
+	if v == nil {
+		v = VersionVector{}
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to encode version vector")
+	}
+
+	return data, nil
+}
+
+// DecodeVersionVector deserializes a vector created via Encode()
+func DecodeVersionVector(data []byte) (VersionVector, error) {
+	v := VersionVector{}
+
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, errors.Wrap(err, "unable to decode version vector")
+	}
+
+	return v, nil
+}
+
+// PutVersionVector stores v as the value for key
+func PutVersionVector(ctx context.Context, n INatty, bucket, key string, v VersionVector) error {
+	data, err := v.Encode()
+	if err != nil {
+		return err
+	}
+
+	return n.Put(ctx, bucket, key, data)
+}
+
+// GetVersionVector fetches the vector stored for key via PutVersionVector()
+func GetVersionVector(ctx context.Context, n INatty, bucket, key string) (VersionVector, error) {
+	data, err := n.Get(ctx, bucket, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return DecodeVersionVector(data)
+}
@@ -0,0 +1,105 @@
+package natty
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("VersionVector", func() {
+	Describe("Compare", func() {
+		It("should compare equal vectors", func() {
+			a := VersionVector{"a": 1, "b": 2}
+			b := VersionVector{"a": 1, "b": 2, "c": 0}
+
+			Expect(a.Compare(b)).To(Equal(OrderingEqual))
+			Expect(b.Compare(a)).To(Equal(OrderingEqual))
+		})
+
+		It("should compare causally ordered vectors", func() {
+			a := VersionVector{"a": 1}
+
+			b := a.Clone()
+			b.Increment("b")
+
+			Expect(a.Compare(b)).To(Equal(OrderingBefore))
+			Expect(b.Compare(a)).To(Equal(OrderingAfter))
+		})
+
+		It("should return Concurrent for diverged vectors", func() {
+			base := VersionVector{"a": 1, "b": 1}
+
+			a := base.Clone()
+			a.Increment("a")
+
+			b := base.Clone()
+			b.Increment("b")
+
+			Expect(a.Compare(b)).To(Equal(OrderingConcurrent))
+			Expect(b.Compare(a)).To(Equal(OrderingConcurrent))
+		})
+	})
+
+	Describe("Merge", func() {
+		It("should be commutative", func() {
+			a := VersionVector{"a": 3, "b": 1}
+			b := VersionVector{"b": 4, "c": 2}
+
+			ab := a.Clone()
+			ab.Merge(b)
+
+			ba := b.Clone()
+			ba.Merge(a)
+
+			Expect(ab).To(Equal(ba))
+			Expect(ab).To(Equal(VersionVector{"a": 3, "b": 4, "c": 2}))
+		})
+
+		It("should happen after both merged vectors", func() {
+			a := VersionVector{"a": 2}
+			b := VersionVector{"b": 2}
+
+			merged := a.Clone()
+			merged.Merge(b)
+
+			Expect(merged.Compare(a)).To(Equal(OrderingAfter))
+			Expect(merged.Compare(b)).To(Equal(OrderingAfter))
+		})
+	})
+
+	Describe("Encode/DecodeVersionVector", func() {
+		It("should round trip a vector", func() {
+			v := VersionVector{"a": 1, "b": 42}
+
+			data, err := v.Encode()
+			Expect(err).ToNot(HaveOccurred())
+
+			decoded, err := DecodeVersionVector(data)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(decoded).To(Equal(v))
+		})
+
+		It("should error on invalid data", func() {
+			_, err := DecodeVersionVector([]byte("not json"))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("PutVersionVector/GetVersionVector", func() {
+		It("should store a vector as a KV entry", func() {
+			n, err := New(NewConfig())
+			Expect(err).ToNot(HaveOccurred())
+
+			bucket, key, _ := NewKVSet()
+
+			v := VersionVector{"node-1": 3, "node-2": 1}
+
+			Expect(PutVersionVector(context.Background(), n, bucket, key, v)).To(Succeed())
+
+			stored, err := GetVersionVector(context.Background(), n, bucket, key)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(stored).To(Equal(v))
+		})
+	})
+})